@@ -5,8 +5,11 @@
 package tls_test
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"fmt"
+	"net"
 )
 
 func ExampleDial() {
@@ -55,3 +58,17 @@ yuGnBXj8ytqU0CwIPX4WecigUCAkVDNx
 	}
 	conn.Close()
 }
+
+func ExampleDNSDialer() {
+	// Resolving names using DNS over TLS with the servers listed in
+	// /etc/resolv.conf, which must accept it on port 853.
+	r := &net.Resolver{
+		PreferGo: true,
+		Dial:     tls.DNSDialer(nil, nil),
+	}
+	addrs, err := r.LookupHost(context.Background(), "golang.org")
+	if err != nil {
+		panic("failed to look up golang.org: " + err.Error())
+	}
+	fmt.Println(addrs)
+}
@@ -11,6 +11,7 @@ package tls
 // https://www.imperialviolet.org/2013/02/04/luckythirteen.html.
 
 import (
+	"context"
 	"crypto"
 	"crypto/ecdsa"
 	"crypto/rsa"
@@ -170,6 +171,81 @@ func Dial(network, addr string, config *Config) (*Conn, error) {
 	return DialWithDialer(new(net.Dialer), network, addr, config)
 }
 
+// DNSDialer returns a function, suitable for the Dial field of a
+// net.Resolver, that connects to DNS servers using DNS over TLS, as
+// specified by RFC 7858. Whatever the network and port it is given, the
+// function connects to port 853 of the server over TCP with dialer and
+// initiates a TLS handshake, which must complete before the context
+// expires.
+//
+// DNSDialer interprets a nil dialer as the zero net.Dialer and a nil
+// configuration as equivalent to the zero configuration. If no ServerName
+// is set, the server's IP address is used to verify its certificate.
+func DNSDialer(dialer *net.Dialer, config *Config) func(ctx context.Context, network, address string) (net.Conn, error) {
+	if dialer == nil {
+		dialer = new(net.Dialer)
+	}
+	if config == nil {
+		config = defaultConfig()
+	}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		rawConn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, "853"))
+		if err != nil {
+			return nil, err
+		}
+		c := config
+		if c.ServerName == "" {
+			// Make a copy to avoid polluting argument or default.
+			c = c.Clone()
+			c.ServerName = host
+		}
+		conn := Client(rawConn, c)
+		if err := handshakeContext(ctx, conn, rawConn); err != nil {
+			return nil, err
+		}
+		return conn, nil
+	}
+}
+
+// handshakeContext runs the handshake of conn, whose underlying
+// connection is rawConn, giving up when ctx is done. On failure it
+// closes rawConn.
+func handshakeContext(ctx context.Context, conn *Conn, rawConn net.Conn) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	var err error
+	if ctx.Done() == nil {
+		err = conn.Handshake()
+	} else {
+		errChannel := make(chan error, 1)
+		go func() {
+			errChannel <- conn.Handshake()
+		}()
+
+		select {
+		case err = <-errChannel:
+		case <-ctx.Done():
+			// Closing the connection makes the handshake fail,
+			// so that its goroutine does not outlive the dial.
+			rawConn.Close()
+			<-errChannel
+			return ctx.Err()
+		}
+	}
+
+	if err != nil {
+		rawConn.Close()
+		return err
+	}
+	conn.SetDeadline(time.Time{})
+	return nil
+}
+
 // LoadX509KeyPair reads and parses a public/private key pair from a pair
 // of files. The files must contain PEM encoded data. The certificate file
 // may contain intermediate certificates following the leaf certificate to
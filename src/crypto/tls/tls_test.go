@@ -6,11 +6,13 @@ package tls
 
 import (
 	"bytes"
+	"context"
 	"crypto/x509"
 	"errors"
 	"fmt"
 	"internal/testenv"
 	"io"
+	"io/ioutil"
 	"math"
 	"math/rand"
 	"net"
@@ -693,3 +695,28 @@ func BenchmarkLatency(b *testing.B) {
 		}
 	}
 }
+
+func TestHandshakeContextCancel(t *testing.T) {
+	ln := newLocalListener(t)
+	defer ln.Close()
+	go func() {
+		// Accept the connection but never answer the ClientHello.
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		ioutil.ReadAll(c)
+	}()
+
+	rawConn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	conn := Client(rawConn, testConfig.Clone())
+	if err := handshakeContext(ctx, conn, rawConn); err != context.Canceled {
+		t.Fatalf("handshakeContext = %v; want %v", err, context.Canceled)
+	}
+}
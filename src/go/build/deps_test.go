@@ -149,7 +149,7 @@ var pkgDeps = map[string][]string{
 		"syscall",
 	},
 
-	"os":            {"L1", "os", "syscall", "time", "internal/syscall/windows"},
+	"os":            {"L1", "os", "syscall", "time", "internal/syscall/unix", "internal/syscall/windows"},
 	"path/filepath": {"L2", "os", "syscall"},
 	"io/ioutil":     {"L2", "os", "path/filepath", "time"},
 	"os/exec":       {"L2", "os", "context", "path/filepath", "syscall"},
@@ -248,11 +248,19 @@ var pkgDeps = map[string][]string{
 	"flag":                     {"L4", "OS"},
 	"go/build":                 {"L4", "OS", "GOPARSER"},
 	"html":                     {"L4"},
+	"image/bmp":                {"L4"},
+	"image/color/icc":          {"L4", "image/draw"},
 	"image/draw":               {"L4", "image/internal/imageutil"},
 	"image/gif":                {"L4", "compress/lzw", "image/color/palette", "image/draw"},
 	"image/internal/imageutil": {"L4"},
 	"image/jpeg":               {"L4", "image/internal/imageutil"},
 	"image/png":                {"L4", "compress/zlib"},
+	"image/tiff":               {"L4", "compress/zlib", "image/tiff/internal/lzw"},
+	"image/tiff/internal/lzw":  {"L4"},
+	"image/webp":               {"L4", "image/webp/internal/riff", "image/webp/internal/vp8", "image/webp/internal/vp8l"},
+	"image/webp/internal/riff": {"L4", "io/ioutil"},
+	"image/webp/internal/vp8":  {"L4"},
+	"image/webp/internal/vp8l": {"L4"},
 	"index/suffixarray":        {"L4", "regexp"},
 	"internal/singleflight":    {"sync"},
 	"internal/trace":           {"L4", "OS"},
@@ -291,12 +299,14 @@ var pkgDeps = map[string][]string{
 	"os/user": {"L4", "CGO", "io/ioutil", "os", "syscall"},
 
 	// Basic networking.
+	"net/netip": {"L0", "strconv"},
+
 	// Because net must be used by any package that wants to
 	// do networking portably, it must have a small dependency set: just L0+basic os.
 	"net": {
 		"L0", "CGO",
 		"context", "math/rand", "os", "sort", "syscall", "time",
-		"internal/nettrace",
+		"internal/nettrace", "internal/syscall/unix", "net/netip",
 		"internal/syscall/windows", "internal/singleflight", "internal/race",
 		"golang_org/x/net/lif", "golang_org/x/net/route",
 	},
@@ -364,7 +374,7 @@ var pkgDeps = map[string][]string{
 	// SSL/TLS.
 	"crypto/tls": {
 		"L4", "CRYPTO-MATH", "OS",
-		"container/list", "crypto/x509", "encoding/pem", "net", "syscall",
+		"container/list", "context", "crypto/x509", "encoding/pem", "net", "syscall",
 	},
 	"crypto/x509": {
 		"L4", "CRYPTO-MATH", "OS", "CGO",
@@ -401,11 +411,12 @@ var pkgDeps = map[string][]string{
 	// HTTP-using packages.
 	"expvar":             {"L4", "OS", "encoding/json", "net/http"},
 	"net/http/cgi":       {"L4", "NET", "OS", "crypto/tls", "net/http", "regexp"},
-	"net/http/cookiejar": {"L4", "NET", "net/http"},
+	"net/http/cookiejar": {"L4", "NET", "OS", "encoding/json", "net/http"},
 	"net/http/fcgi":      {"L4", "NET", "OS", "net/http", "net/http/cgi"},
-	"net/http/httptest":  {"L4", "NET", "OS", "crypto/tls", "flag", "net/http", "net/http/internal"},
-	"net/http/httputil":  {"L4", "NET", "OS", "context", "net/http", "net/http/internal"},
+	"net/http/httptest":  {"L4", "NET", "OS", "crypto/tls", "crypto/x509", "encoding/json", "flag", "net/http", "net/http/internal"},
+	"net/http/httputil":  {"L4", "NET", "OS", "context", "golang_org/x/net/lex/httplex", "net/http", "net/http/internal"},
 	"net/http/pprof":     {"L4", "OS", "html/template", "net/http", "runtime/pprof", "runtime/trace"},
+	"net/http/websocket": {"L4", "NET", "OS", "CRYPTO", "compress/flate", "context", "crypto/rand", "crypto/tls", "net/http"},
 	"net/rpc":            {"L4", "NET", "encoding/gob", "html/template", "net/http"},
 	"net/rpc/jsonrpc":    {"L4", "NET", "encoding/json", "net/rpc"},
 }
@@ -0,0 +1,85 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package icc
+
+var (
+	// SRGB is the sRGB color space of IEC 61966-2-1.
+	SRGB = newRGBProfile("sRGB IEC61966-2.1", [3][2]float64{
+		{0.64, 0.33},
+		{0.30, 0.60},
+		{0.15, 0.06},
+	})
+
+	// DisplayP3 is the Display P3 color space, which has the primaries of
+	// DCI-P3 and the white point and tone reproduction curve of sRGB.
+	DisplayP3 = newRGBProfile("Display P3", [3][2]float64{
+		{0.680, 0.320},
+		{0.265, 0.690},
+		{0.150, 0.060},
+	})
+)
+
+// srgbCurve is the tone reproduction curve of sRGB.
+var srgbCurve = &paramCurve{
+	g: 2.4,
+	a: 1 / 1.055,
+	b: 0.055 / 1.055,
+	c: 1 / 12.92,
+	d: 0.04045,
+}
+
+// newRGBProfile returns a display profile with the sRGB tone reproduction
+// curve, the D65 white point and the given xy chromaticities of the red,
+// green and blue primaries.
+func newRGBProfile(desc string, primaries [3][2]float64) *Profile {
+	// The RGB to XYZ matrix scales the XYZ values of the primaries so that
+	// RGB white maps to the D65 white point. It is then adapted to the D50
+	// PCS illuminant with the linear Bradford transform.
+	d65 := [3]float64{0.3127 / 0.3290, 1, (1 - 0.3127 - 0.3290) / 0.3290}
+	var p matrix
+	for i, xy := range primaries {
+		x, y := xy[0], xy[1]
+		p.m[i], p.m[3+i], p.m[6+i] = x/y, 1, (1-x-y)/y
+	}
+	inv := p.inverse()
+	var s [3]float64
+	for i := range s {
+		s[i] = inv.m[3*i]*d65[0] + inv.m[3*i+1]*d65[1] + inv.m[3*i+2]*d65[2]
+	}
+	for i := 0; i < 9; i++ {
+		p.m[i] *= s[i%3]
+	}
+
+	bradford := &matrix{m: [9]float64{
+		0.8951, 0.2664, -0.1614,
+		-0.7502, 1.7135, 0.0367,
+		0.0389, -0.0685, 1.0296,
+	}}
+	var scale matrix
+	for i := 0; i < 3; i++ {
+		src := bradford.m[3*i]*d65[0] + bradford.m[3*i+1]*d65[1] + bradford.m[3*i+2]*d65[2]
+		dst := bradford.m[3*i]*d50[0] + bradford.m[3*i+1]*d50[1] + bradford.m[3*i+2]*d50[2]
+		scale.m[4*i] = dst / src
+	}
+	m := bradford.inverse().mul(scale.mul(bradford)).mul(&p)
+
+	toPCS, fromPCS, err := matrixTRC([3]curve{srgbCurve, srgbCurve, srgbCurve}, m.m)
+	if err != nil {
+		panic(err)
+	}
+	prof := &Profile{
+		Version:     0x04300000,
+		Class:       Display,
+		ColorSpace:  RGB,
+		PCS:         XYZ,
+		Intent:      Perceptual,
+		WhitePoint:  d50,
+		Description: desc,
+	}
+	for i := range prof.toPCS {
+		prof.toPCS[i], prof.fromPCS[i] = toPCS, fromPCS
+	}
+	return prof
+}
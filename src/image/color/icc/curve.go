@@ -0,0 +1,177 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package icc
+
+import (
+	"math"
+	"sort"
+)
+
+// A curve maps one channel value, normally in [0, 1], to another.
+type curve interface {
+	eval(x float64) float64
+	inverse() curve
+}
+
+// paramCurve is a parametric curve, as per section 10.16 of the spec. Every
+// function type is represented by the most general one, type 4:
+//
+//	Y = (aX + b)^g + e	for X >= d
+//	Y = cX + f		for X < d
+type paramCurve struct {
+	g, a, b, c, d, e, f float64
+}
+
+var identity = &paramCurve{g: 1, a: 1}
+
+func (p *paramCurve) eval(x float64) float64 {
+	if x < p.d {
+		return p.c*x + p.f
+	}
+	t := p.a*x + p.b
+	if t <= 0 {
+		return p.e
+	}
+	return math.Pow(t, p.g) + p.e
+}
+
+func (p *paramCurve) inverse() curve { return (*invParamCurve)(p) }
+
+// invParamCurve is the inverse of a paramCurve.
+type invParamCurve paramCurve
+
+func (p *invParamCurve) eval(y float64) float64 {
+	if p.a == 0 || p.g == 0 {
+		return 0
+	}
+	if y >= (*paramCurve)(p).eval(p.d) {
+		t := y - p.e
+		if t <= 0 {
+			t = 0
+		}
+		return (math.Pow(t, 1/p.g) - p.b) / p.a
+	}
+	if p.c == 0 {
+		return 0
+	}
+	return (y - p.f) / p.c
+}
+
+func (p *invParamCurve) inverse() curve { return (*paramCurve)(p) }
+
+// tableCurve is a curve sampled at evenly spaced points of [0, 1], and
+// linearly interpolated between them.
+type tableCurve []float64
+
+func (t tableCurve) eval(x float64) float64 {
+	x = clamp(x) * float64(len(t)-1)
+	i := int(x)
+	if i >= len(t)-1 {
+		return t[len(t)-1]
+	}
+	return t[i] + (x-float64(i))*(t[i+1]-t[i])
+}
+
+func (t tableCurve) inverse() curve { return invTableCurve(t) }
+
+// invTableCurve is the inverse of a monotonic tableCurve.
+type invTableCurve []float64
+
+func (t invTableCurve) eval(y float64) float64 {
+	n := len(t)
+	decreasing := t[0] > t[n-1]
+	// i is the first sample past y.
+	i := sort.Search(n, func(i int) bool {
+		if decreasing {
+			return t[i] <= y
+		}
+		return t[i] >= y
+	})
+	switch {
+	case i == 0:
+		return 0
+	case i == n:
+		return 1
+	}
+	x := float64(i - 1)
+	if d := t[i] - t[i-1]; d != 0 {
+		x += (y - t[i-1]) / d
+	}
+	return x / float64(n-1)
+}
+
+func (t invTableCurve) inverse() curve { return tableCurve(t) }
+
+func clamp(x float64) float64 {
+	if x < 0 {
+		return 0
+	}
+	if x > 1 {
+		return 1
+	}
+	return x
+}
+
+// parseCurve parses a curveType or parametricCurveType element. It also
+// returns the length of the element, padded to a multiple of 4 bytes.
+func parseCurve(b []byte) (curve, int, error) {
+	if len(b) < 12 {
+		return nil, 0, FormatError("bad curve")
+	}
+	switch string(b[:4]) {
+	case "curv":
+		n := be32(b[8:12])
+		if uint64(n) > uint64(len(b)-12)/2 {
+			return nil, 0, FormatError("bad curve length")
+		}
+		size := 12 + 2*int(n)
+		switch n {
+		case 0:
+			return identity, align4(size), nil
+		case 1:
+			// The gamma is an unsigned 8.8 fixed point number.
+			return &paramCurve{g: float64(be16(b[12:])) / 0x100, a: 1}, align4(size), nil
+		}
+		t := make(tableCurve, n)
+		for i := range t {
+			t[i] = float64(be16(b[12+2*i:])) / 0xffff
+		}
+		return t, align4(size), nil
+	case "para":
+		nParams := [...]int{1, 3, 4, 5, 7}
+		typ := int(be16(b[8:10]))
+		if typ >= len(nParams) {
+			return nil, 0, FormatError("bad parametric curve type")
+		}
+		size := 12 + 4*nParams[typ]
+		if len(b) < size {
+			return nil, 0, FormatError("bad parametric curve")
+		}
+		var v [7]float64
+		for i := 0; i < nParams[typ]; i++ {
+			v[i] = s15Fixed16(b[12+4*i:])
+		}
+		p := &paramCurve{g: v[0], a: 1}
+		switch typ {
+		case 1, 2:
+			p.a, p.b = v[1], v[2]
+			if p.a != 0 {
+				p.d = -p.b / p.a
+			}
+			// The constant of type 2 is added to both segments.
+			p.e, p.f = v[3], v[3]
+		case 3:
+			p.a, p.b, p.c, p.d = v[1], v[2], v[3], v[4]
+		case 4:
+			p.a, p.b, p.c, p.d, p.e, p.f = v[1], v[2], v[3], v[4], v[5], v[6]
+		}
+		return p, align4(size), nil
+	}
+	return nil, 0, UnsupportedError("curve type " + string(b[:4]))
+}
+
+func align4(n int) int {
+	return (n + 3) &^ 3
+}
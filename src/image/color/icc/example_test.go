@@ -0,0 +1,44 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package icc_test
+
+import (
+	"bytes"
+	"image"
+	"image/color/icc"
+	"image/jpeg"
+	"io/ioutil"
+	"log"
+)
+
+// This example decodes a JPEG image, such as a wide-gamut photo, and
+// converts it from its embedded color profile to sRGB.
+func Example_decodeToSRGB() {
+	data, err := ioutil.ReadFile("photo.jpg")
+	if err != nil {
+		log.Fatal(err)
+	}
+	var m image.Image
+	m, err = jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		log.Fatal(err)
+	}
+	md, err := jpeg.DecodeMetadata(bytes.NewReader(data))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if b := md.ICCProfile(); b != nil {
+		p, err := icc.Parse(b)
+		if err != nil {
+			log.Fatal(err)
+		}
+		t, err := icc.NewTransform(p, icc.SRGB, icc.Perceptual)
+		if err != nil {
+			log.Fatal(err)
+		}
+		m = t.Image(m)
+	}
+	log.Println(m.Bounds())
+}
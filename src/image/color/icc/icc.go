@@ -0,0 +1,363 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package icc implements parsing of ICC color profiles, versions 2 and 4,
+// and the conversion of colors and images between them.
+//
+// Both matrix/TRC profiles and LUT-based profiles are supported, for the
+// gray, RGB and CMYK color spaces. Named color, device link and abstract
+// profiles are not.
+//
+// The ICC specification is at http://www.color.org/specification/ICC1v43_2010-12.pdf.
+package icc
+
+import (
+	"encoding/binary"
+	"strings"
+	"unicode/utf16"
+)
+
+// A FormatError reports that the input is not a valid ICC profile.
+type FormatError string
+
+func (e FormatError) Error() string { return "icc: invalid format: " + string(e) }
+
+// An UnsupportedError reports that the input uses a valid but unimplemented
+// ICC feature.
+type UnsupportedError string
+
+func (e UnsupportedError) Error() string { return "icc: unsupported feature: " + string(e) }
+
+// Class is the signature of a profile's class.
+type Class string
+
+const (
+	Input      Class = "scnr"
+	Display    Class = "mntr"
+	Output     Class = "prtr"
+	Link       Class = "link"
+	Abstract   Class = "abst"
+	Space      Class = "spac"
+	NamedColor Class = "nmcl"
+)
+
+// ColorSpace is the signature of a color space.
+type ColorSpace string
+
+const (
+	Gray ColorSpace = "GRAY"
+	RGB  ColorSpace = "RGB "
+	CMYK ColorSpace = "CMYK"
+	XYZ  ColorSpace = "XYZ "
+	Lab  ColorSpace = "Lab "
+)
+
+// Channels returns the number of channels of the color space, or 0 if it is
+// unknown.
+func (s ColorSpace) Channels() int {
+	switch s {
+	case Gray:
+		return 1
+	case RGB, XYZ, Lab, "YCbr", "Yxy ", "Luv ", "HSV ", "HLS ", "CMY ":
+		return 3
+	case CMYK:
+		return 4
+	}
+	// The generic color spaces "2CLR" to "FCLR" have 2 to 15 channels.
+	if len(s) == 4 && s[1:] == "CLR" {
+		switch c := s[0]; {
+		case '2' <= c && c <= '9':
+			return int(c - '0')
+		case 'A' <= c && c <= 'F':
+			return int(c-'A') + 10
+		}
+	}
+	return 0
+}
+
+// Intent is a rendering intent.
+type Intent int
+
+const (
+	Perceptual Intent = iota
+	RelativeColorimetric
+	Saturation
+	AbsoluteColorimetric
+	nIntent
+)
+
+// d50 is the XYZ value of the illuminant of the profile connection space.
+var d50 = [3]float64{0.9642, 1, 0.8249}
+
+// Profile is an ICC color profile.
+type Profile struct {
+	// Version is the profile's version, as encoded in its header. For
+	// example, version 4.3 is 0x04300000.
+	Version uint32
+	// Class is the profile's class.
+	Class Class
+	// ColorSpace is the color space of the device, and PCS is the profile
+	// connection space, which is either XYZ or Lab.
+	ColorSpace ColorSpace
+	PCS        ColorSpace
+	// Intent is the rendering intent of the profile's header.
+	Intent Intent
+	// WhitePoint is the XYZ value of the media white point.
+	WhitePoint [3]float64
+	// Description and Copyright are the texts of the profile's desc and
+	// cprt tags.
+	Description string
+	Copyright   string
+
+	tags map[string][]byte
+
+	// toPCS and fromPCS hold the pipelines that convert device colors to
+	// and from XYZ values, for each rendering intent. A nil pipeline means
+	// that the profile does not support that conversion.
+	toPCS, fromPCS [nIntent]pipeline
+}
+
+// Tag returns the raw data of the tag with the signature sig, such as
+// "rXYZ", or nil if the profile has no such tag.
+func (p *Profile) Tag(sig string) []byte {
+	return p.tags[sig]
+}
+
+func be16(b []byte) uint16 { return binary.BigEndian.Uint16(b) }
+func be32(b []byte) uint32 { return binary.BigEndian.Uint32(b) }
+
+// s15Fixed16 decodes a signed 15.16 fixed point number.
+func s15Fixed16(b []byte) float64 {
+	return float64(int32(be32(b))) / 0x10000
+}
+
+// Parse parses an ICC profile.
+func Parse(b []byte) (*Profile, error) {
+	if len(b) < 132 {
+		return nil, FormatError("profile is too short")
+	}
+	size := be32(b[0:4])
+	if size < 132 || uint64(size) > uint64(len(b)) {
+		return nil, FormatError("bad profile size")
+	}
+	// The tags refer to the profile's data, which is copied so that the
+	// caller may reuse b.
+	b = append([]byte(nil), b[:size]...)
+	if string(b[36:40]) != "acsp" {
+		return nil, FormatError("bad profile signature")
+	}
+	p := &Profile{
+		Version:    be32(b[8:12]),
+		Class:      Class(b[12:16]),
+		ColorSpace: ColorSpace(b[16:20]),
+		PCS:        ColorSpace(b[20:24]),
+		Intent:     Intent(be32(b[64:68])),
+		WhitePoint: d50,
+		tags:       make(map[string][]byte),
+	}
+	switch p.Class {
+	case Link, Abstract, NamedColor:
+		return nil, UnsupportedError("profile class " + string(p.Class))
+	}
+	if p.PCS != XYZ && p.PCS != Lab {
+		return nil, FormatError("bad profile connection space")
+	}
+	if p.ColorSpace.Channels() == 0 {
+		return nil, UnsupportedError("color space " + string(p.ColorSpace))
+	}
+	if p.Intent >= nIntent {
+		p.Intent = Perceptual
+	}
+
+	n := be32(b[128:132])
+	if uint64(n) > uint64(len(b)-132)/12 {
+		return nil, FormatError("bad tag count")
+	}
+	for i := 0; i < int(n); i++ {
+		t := b[132+12*i:]
+		off, size := uint64(be32(t[4:8])), uint64(be32(t[8:12]))
+		if off+size > uint64(len(b)) || size < 8 {
+			return nil, FormatError("bad tag offset")
+		}
+		// Cap the tag so that parsing it cannot reach the next one.
+		p.tags[string(t[:4])] = b[off : off+size : off+size]
+	}
+
+	if t := p.tags["wtpt"]; t != nil {
+		xyz, err := parseXYZ(t)
+		if err != nil {
+			return nil, err
+		}
+		p.WhitePoint = xyz
+	}
+	p.Description = p.text("desc")
+	p.Copyright = p.text("cprt")
+
+	if err := p.buildPipelines(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// parseXYZ parses an XYZType tag with a single value.
+func parseXYZ(b []byte) ([3]float64, error) {
+	if len(b) < 20 || string(b[:4]) != "XYZ " {
+		return [3]float64{}, FormatError("bad XYZ tag")
+	}
+	return [3]float64{s15Fixed16(b[8:]), s15Fixed16(b[12:]), s15Fixed16(b[16:])}, nil
+}
+
+// text returns the text of a textual tag, or "" if the tag is missing or
+// malformed. The English text of multi-localized tags is preferred.
+func (p *Profile) text(sig string) string {
+	b := p.tags[sig]
+	if len(b) < 12 {
+		return ""
+	}
+	switch string(b[:4]) {
+	case "text":
+		return strings.TrimRight(string(b[8:]), "\x00")
+	case "desc":
+		n := be32(b[8:12])
+		if uint64(n) > uint64(len(b)-12) {
+			return ""
+		}
+		return strings.TrimRight(string(b[12:12+n]), "\x00")
+	case "mluc":
+		if len(b) < 16 {
+			return ""
+		}
+		n, size := be32(b[8:12]), be32(b[12:16])
+		if size < 12 || uint64(n)*uint64(size) > uint64(len(b)-16) {
+			return ""
+		}
+		var s string
+		for i := 0; i < int(n); i++ {
+			r := b[16+i*int(size):]
+			off, l := uint64(be32(r[8:12])), uint64(be32(r[4:8]))
+			if off+l > uint64(len(b)) {
+				return ""
+			}
+			u := make([]uint16, l/2)
+			for j := range u {
+				u[j] = be16(b[off+2*uint64(j):])
+			}
+			if i == 0 || string(r[:2]) == "en" {
+				s = string(utf16.Decode(u))
+			}
+			if string(r[:2]) == "en" {
+				break
+			}
+		}
+		return strings.TrimRight(s, "\x00")
+	}
+	return ""
+}
+
+// lutTag returns the signatures of the AToB and BToA tags of the intent.
+func lutTag(intent Intent) (aToB, bToA string) {
+	switch intent {
+	case RelativeColorimetric, AbsoluteColorimetric:
+		return "A2B1", "B2A1"
+	case Saturation:
+		return "A2B2", "B2A2"
+	}
+	return "A2B0", "B2A0"
+}
+
+// buildPipelines builds the pipelines of p from its tags. The LUT-based
+// tags of an intent are used if present. Otherwise, the AToB0 and BToA0 tags
+// are used, and the matrix/TRC or gray TRC tags as a last resort.
+func (p *Profile) buildPipelines() error {
+	luts := make(map[string]pipeline)
+	lut := func(sig string, toPCS bool) (pipeline, error) {
+		if l, ok := luts[sig]; ok {
+			return l, nil
+		}
+		b := p.tags[sig]
+		if b == nil {
+			return nil, nil
+		}
+		var l pipeline
+		var err error
+		if toPCS {
+			l, err = parseLutToPCS(b, p.ColorSpace.Channels(), p.PCS)
+		} else {
+			l, err = parseLutFromPCS(b, p.ColorSpace.Channels(), p.PCS)
+		}
+		if err != nil {
+			return nil, err
+		}
+		luts[sig] = l
+		return l, nil
+	}
+	toTRC, fromTRC, err := p.trcPipelines()
+	if err != nil {
+		return err
+	}
+	for i := Intent(0); i < nIntent; i++ {
+		aToB, bToA := lutTag(i)
+		for _, sig := range []string{aToB, "A2B0"} {
+			if p.toPCS[i], err = lut(sig, true); err != nil {
+				return err
+			} else if p.toPCS[i] != nil {
+				break
+			}
+		}
+		if p.toPCS[i] == nil {
+			p.toPCS[i] = toTRC
+		}
+		for _, sig := range []string{bToA, "B2A0"} {
+			if p.fromPCS[i], err = lut(sig, false); err != nil {
+				return err
+			} else if p.fromPCS[i] != nil {
+				break
+			}
+		}
+		if p.fromPCS[i] == nil {
+			p.fromPCS[i] = fromTRC
+		}
+	}
+	if p.toPCS[Perceptual] == nil && p.fromPCS[Perceptual] == nil {
+		return UnsupportedError("profile has neither LUT nor TRC tags")
+	}
+	return nil
+}
+
+// trcPipelines returns the pipelines of the matrix/TRC tags of an RGB
+// profile, or of the gray TRC tag of a gray profile, or nil pipelines if the
+// profile has no such tags.
+func (p *Profile) trcPipelines() (toPCS, fromPCS pipeline, err error) {
+	switch p.ColorSpace {
+	case RGB:
+		var curves [3]curve
+		var m [9]float64
+		for i, c := range "rgb" {
+			trc, col := p.tags[string(c)+"TRC"], p.tags[string(c)+"XYZ"]
+			if trc == nil || col == nil {
+				return nil, nil, nil
+			}
+			if curves[i], _, err = parseCurve(trc); err != nil {
+				return nil, nil, err
+			}
+			xyz, err := parseXYZ(col)
+			if err != nil {
+				return nil, nil, err
+			}
+			m[i], m[3+i], m[6+i] = xyz[0], xyz[1], xyz[2]
+		}
+		return matrixTRC(curves, m)
+	case Gray:
+		trc := p.tags["kTRC"]
+		if trc == nil {
+			return nil, nil, nil
+		}
+		c, _, err := parseCurve(trc)
+		if err != nil {
+			return nil, nil, err
+		}
+		return grayTRC(c, p.PCS)
+	}
+	return nil, nil, nil
+}
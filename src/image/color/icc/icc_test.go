@@ -0,0 +1,547 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package icc
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+	"testing"
+	"unicode/utf16"
+)
+
+// The following functions encode tags and profiles, for testing the parser.
+
+func u32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
+
+func u16(v uint16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, v)
+	return b
+}
+
+func fixed(v float64) []byte {
+	return u32(uint32(int32(math.Floor(v*0x10000 + 0.5))))
+}
+
+func cat(bs ...[]byte) []byte {
+	var b []byte
+	for _, x := range bs {
+		b = append(b, x...)
+	}
+	return b
+}
+
+func pad4(b []byte) []byte {
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+func xyzTag(v [3]float64) []byte {
+	return cat([]byte("XYZ \x00\x00\x00\x00"), fixed(v[0]), fixed(v[1]), fixed(v[2]))
+}
+
+func paraTag(typ uint16, params ...float64) []byte {
+	b := cat([]byte("para\x00\x00\x00\x00"), u16(typ), u16(0))
+	for _, p := range params {
+		b = append(b, fixed(p)...)
+	}
+	return b
+}
+
+func curvTag(v ...uint16) []byte {
+	b := cat([]byte("curv\x00\x00\x00\x00"), u32(uint32(len(v))))
+	for _, x := range v {
+		b = append(b, u16(x)...)
+	}
+	return pad4(b)
+}
+
+func mlucTag(s string) []byte {
+	u := utf16.Encode([]rune(s))
+	b := cat([]byte("mluc\x00\x00\x00\x00"), u32(1), u32(12), []byte("enUS"), u32(uint32(2*len(u))), u32(28))
+	for _, c := range u {
+		b = append(b, u16(c)...)
+	}
+	return pad4(b)
+}
+
+func descTag(s string) []byte {
+	b := cat([]byte("desc\x00\x00\x00\x00"), u32(uint32(len(s)+1)), []byte(s), []byte{0})
+	// The Unicode and ScriptCode descriptions are empty.
+	return pad4(cat(b, make([]byte, 4+4+2+1+67)))
+}
+
+type tag struct {
+	sig  string
+	data []byte
+}
+
+func buildProfile(version uint32, class Class, space, pcs ColorSpace, tags []tag) []byte {
+	h := make([]byte, 128)
+	copy(h[8:], u32(version))
+	copy(h[12:], class)
+	copy(h[16:], space)
+	copy(h[20:], pcs)
+	copy(h[36:], "acsp")
+	copy(h[68:], cat(fixed(d50[0]), fixed(d50[1]), fixed(d50[2])))
+	table := u32(uint32(len(tags)))
+	var data []byte
+	off := 128 + 4 + 12*len(tags)
+	for _, t := range tags {
+		table = cat(table, []byte(t.sig), u32(uint32(off+len(data))), u32(uint32(len(t.data))))
+		data = append(data, pad4(t.data)...)
+	}
+	b := cat(h, table, data)
+	copy(b, u32(uint32(len(b))))
+	return b
+}
+
+// srgbTags returns the matrix/TRC tags of sRGB.
+func srgbTags() []tag {
+	m := SRGB.toPCS[0][1].(*matrix)
+	trc := paraTag(3, 2.4, 1/1.055, 0.055/1.055, 1/12.92, 0.04045)
+	return []tag{
+		{"desc", mlucTag("sRGB test")},
+		{"cprt", mlucTag("No copyright")},
+		{"wtpt", xyzTag(d50)},
+		{"rXYZ", xyzTag([3]float64{m.m[0], m.m[3], m.m[6]})},
+		{"gXYZ", xyzTag([3]float64{m.m[1], m.m[4], m.m[7]})},
+		{"bXYZ", xyzTag([3]float64{m.m[2], m.m[5], m.m[8]})},
+		{"rTRC", trc},
+		{"gTRC", trc},
+		{"bTRC", trc},
+	}
+}
+
+func near(a, b []float64, tolerance float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > tolerance {
+			return false
+		}
+	}
+	return true
+}
+
+// convert converts the color v with t.
+func convert(t *Transform, v ...float64) []float64 {
+	var buf [maxChannels]float64
+	return append([]float64(nil), t.eval(append(buf[:0], v...))...)
+}
+
+func TestParseMatrixTRC(t *testing.T) {
+	b := buildProfile(0x04300000, Display, RGB, XYZ, srgbTags())
+	p, err := Parse(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Version != 0x04300000 || p.Class != Display || p.ColorSpace != RGB || p.PCS != XYZ {
+		t.Errorf("got header %x %q %q %q", p.Version, p.Class, p.ColorSpace, p.PCS)
+	}
+	if p.Description != "sRGB test" || p.Copyright != "No copyright" {
+		t.Errorf("got description %q, copyright %q", p.Description, p.Copyright)
+	}
+	if !near(p.WhitePoint[:], d50[:], 1e-4) {
+		t.Errorf("got white point %v", p.WhitePoint)
+	}
+	if len(p.Tag("rTRC")) != 32 || p.Tag("A2B0") != nil {
+		t.Errorf("got rTRC tag %x", p.Tag("rTRC"))
+	}
+
+	// The profile is a copy of the built-in sRGB profile.
+	tr, err := NewTransform(p, SRGB, Perceptual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range [][]float64{{0, 0, 0}, {1, 1, 1}, {1, 0, 0}, {0.2, 0.5, 0.8}, {0.01, 0.03, 0.02}} {
+		if got := convert(tr, c...); !near(got, c, 1e-3) {
+			t.Errorf("%v: got %v", c, got)
+		}
+	}
+}
+
+func TestParseV2(t *testing.T) {
+	tags := srgbTags()
+	tags[0] = tag{"desc", descTag("Version 2")}
+	tags[1] = tag{"cprt", []byte("text\x00\x00\x00\x00Public domain\x00")}
+	// A gamma of 2.2, rather than the sRGB curve.
+	for i := 6; i < 9; i++ {
+		tags[i].data = curvTag(0x0233)
+	}
+	p, err := Parse(buildProfile(0x02100000, Display, RGB, XYZ, tags))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Description != "Version 2" || p.Copyright != "Public domain" {
+		t.Errorf("got description %q, copyright %q", p.Description, p.Copyright)
+	}
+	tr, err := NewTransform(p, SRGB, RelativeColorimetric)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := math.Pow(0.5, 0x0233/256.0)
+	want := srgbCurve.inverse().eval(g)
+	if got := convert(tr, 0.5, 0.5, 0.5); !near(got, []float64{want, want, want}, 1e-4) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestParseTruncatedText(t *testing.T) {
+	mluc := mlucTag("Truncated")
+	for _, desc := range [][]byte{
+		mluc[:8],
+		mluc[:12],
+		mluc[:14],
+		mluc[:27],
+		// The record's text lies past the end of the tag.
+		mluc[:30],
+		// The record size does not fit the tag.
+		cat(mluc[:12], u32(0xfffffff0), mluc[16:]),
+		descTag("Truncated")[:12],
+		cat([]byte("desc\x00\x00\x00\x00"), u32(0xfffffff0)),
+	} {
+		tags := srgbTags()
+		tags[0] = tag{"desc", desc}
+		p, err := Parse(buildProfile(0x04300000, Display, RGB, XYZ, tags))
+		if err != nil {
+			t.Errorf("%q: %v", desc, err)
+			continue
+		}
+		if p.Description != "" || p.Copyright != "No copyright" {
+			t.Errorf("%q: got description %q, copyright %q", desc, p.Description, p.Copyright)
+		}
+	}
+}
+
+func TestSRGBToDisplayP3(t *testing.T) {
+	tr, err := NewTransform(SRGB, DisplayP3, Perceptual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		in, want []float64
+	}{
+		{[]float64{1, 1, 1}, []float64{1, 1, 1}},
+		{[]float64{0, 0, 0}, []float64{0, 0, 0}},
+		{[]float64{1, 0, 0}, []float64{0.9175, 0.2003, 0.1386}},
+		{[]float64{0, 1, 0}, []float64{0.4584, 0.9853, 0.2983}},
+		{[]float64{0, 0, 1}, []float64{0, 0, 0.9596}},
+	}
+	for _, tc := range testCases {
+		if got := convert(tr, tc.in...); !near(got, tc.want, 2e-3) {
+			t.Errorf("%v: got %v, want %v", tc.in, got, tc.want)
+		}
+	}
+
+	// Display P3 red is outside of the sRGB gamut.
+	tr, err = NewTransform(DisplayP3, SRGB, Perceptual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := convert(tr, 1, 0, 0); !near(got, []float64{1, 0, 0}, 1e-9) {
+		t.Errorf("got %v, want clamped red", got)
+	}
+}
+
+func TestGrayProfile(t *testing.T) {
+	for _, pcs := range []ColorSpace{XYZ, Lab} {
+		p, err := Parse(buildProfile(0x04300000, Display, Gray, pcs, []tag{
+			{"kTRC", curvTag(0, 0x4000, 0xffff)},
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tr, err := NewTransform(p, p, Perceptual)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, g := range []float64{0, 0.25, 0.5, 0.75, 1} {
+			if got := convert(tr, g); !near(got, []float64{g}, 1e-4) {
+				t.Errorf("%s: %v: got %v", pcs, g, got)
+			}
+		}
+	}
+}
+
+// sample returns the CLUT data of the function f with the given number of
+// inputs, outputs and grid points, encoded with the precision in bytes.
+func sample(in, out, grid, precision int, f func(v []float64) []float64) []byte {
+	n := 1
+	for i := 0; i < in; i++ {
+		n *= grid
+	}
+	var b []byte
+	for i := 0; i < n; i++ {
+		var buf [maxChannels]float64
+		v := buf[:in]
+		for j, k := in-1, i; j >= 0; j, k = j-1, k/grid {
+			v[j] = float64(k%grid) / float64(grid-1)
+		}
+		for _, x := range f(v)[:out] {
+			x = clamp(x)
+			if precision == 1 {
+				b = append(b, uint8(x*0xff+0.5))
+			} else {
+				b = append(b, u16(uint16(x*0xffff+0.5))...)
+			}
+		}
+	}
+	return b
+}
+
+func identityTables(n, entries int) []byte {
+	var b []byte
+	for i := 0; i < n; i++ {
+		for j := 0; j < entries; j++ {
+			b = append(b, u16(uint16(j*0xffff/(entries-1)))...)
+		}
+	}
+	return b
+}
+
+func lut16Tag(in, out, grid int, f func(v []float64) []float64) []byte {
+	b := cat([]byte("mft2\x00\x00\x00\x00"), []byte{byte(in), byte(out), byte(grid), 0})
+	for i := 0; i < 9; i++ {
+		if i%4 == 0 {
+			b = append(b, fixed(1)...)
+		} else {
+			b = append(b, fixed(0)...)
+		}
+	}
+	b = cat(b, u16(2), u16(2), identityTables(in, 2), sample(in, out, grid, 2, f), identityTables(out, 2))
+	return b
+}
+
+func TestLut16Lab(t *testing.T) {
+	// The A2B0 and B2A0 tags are sampled from the built-in sRGB profile,
+	// using the legacy Lab encoding.
+	toLab := func(v []float64) []float64 {
+		return labEncoder(lutLegacy).eval(xyzToLab(SRGB.toPCS[0].eval(v)))
+	}
+	fromLab := func(v []float64) []float64 {
+		return SRGB.fromPCS[0].eval(labToXYZ(labDecoder(lutLegacy).eval(v)))
+	}
+	p, err := Parse(buildProfile(0x02100000, Output, RGB, Lab, []tag{
+		{"A2B0", lut16Tag(3, 3, 33, toLab)},
+		{"B2A0", lut16Tag(3, 3, 65, fromLab)},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, intent := range []Intent{Perceptual, RelativeColorimetric, Saturation, AbsoluteColorimetric} {
+		if p.toPCS[intent] == nil || p.fromPCS[intent] == nil {
+			t.Fatalf("intent %d: missing pipeline", intent)
+		}
+	}
+	to, err := NewTransform(SRGB, p, Perceptual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, err := NewTransform(p, SRGB, Perceptual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range [][]float64{{0, 0, 0}, {1, 1, 1}, {1, 0, 0}, {0.2, 0.5, 0.8}, {0.5, 0.5, 0.5}} {
+		if got := convert(from, c...); !near(got, c, 0.01) {
+			t.Errorf("A2B0 %v: got %v", c, got)
+		}
+	}
+	// The B2A0 tag is less accurate near the edges of the gamut, where
+	// the grid points are clamped.
+	for _, c := range [][]float64{{0.2, 0.5, 0.8}, {0.5, 0.5, 0.5}, {0.7, 0.3, 0.4}} {
+		if got := convert(from, convert(to, c...)...); !near(got, c, 0.01) {
+			t.Errorf("round trip %v: got %v", c, got)
+		}
+	}
+}
+
+// naiveCMYK converts between sRGB and CMYK without any color management.
+func naiveCMYK(v []float64) []float64 {
+	c, m, y, k := v[0], v[1], v[2], v[3]
+	v = v[:3]
+	v[0], v[1], v[2] = (1-c)*(1-k), (1-m)*(1-k), (1-y)*(1-k)
+	return v
+}
+
+func naiveRGB(v []float64) []float64 {
+	r, g, b := v[0], v[1], v[2]
+	k := 1 - math.Max(r, math.Max(g, b))
+	v = v[:4]
+	v[0], v[1], v[2], v[3] = 0, 0, 0, k
+	if k < 1 {
+		v[0], v[1], v[2] = (1-r-k)/(1-k), (1-g-k)/(1-k), (1-b-k)/(1-k)
+	}
+	return v
+}
+
+func lutABTag(sig string, in, out, grid int, f func(v []float64) []float64) []byte {
+	// The elements are B curves, CLUT and A curves, in that order.
+	curves := func(n int) []byte {
+		var b []byte
+		for i := 0; i < n; i++ {
+			b = append(b, paraTag(0, 1)...)
+		}
+		return b
+	}
+	nA, nB := in, out
+	if sig == "mBA " {
+		nA, nB = out, in
+	}
+	bCurves := curves(nB)
+	clutHeader := make([]byte, 20)
+	for i := 0; i < in; i++ {
+		clutHeader[i] = byte(grid)
+	}
+	clutHeader[16] = 2
+	clut := pad4(cat(clutHeader, sample(in, out, grid, 2, f)))
+	bOff := 32
+	clutOff := bOff + len(bCurves)
+	aOff := clutOff + len(clut)
+	return cat([]byte(sig), make([]byte, 4), []byte{byte(in), byte(out), 0, 0},
+		u32(uint32(bOff)), u32(0), u32(0), u32(uint32(clutOff)), u32(uint32(aOff)),
+		bCurves, clut, curves(nA))
+}
+
+func TestLutABCMYK(t *testing.T) {
+	toXYZ := func(v []float64) []float64 {
+		return xyzEncode(SRGB.toPCS[0].eval(naiveCMYK(v)))
+	}
+	fromXYZ := func(v []float64) []float64 {
+		return naiveRGB(SRGB.fromPCS[0].eval(xyzDecode(v)))
+	}
+	p, err := Parse(buildProfile(0x04300000, Output, CMYK, XYZ, []tag{
+		{"A2B0", lutABTag("mAB ", 4, 3, 9, toXYZ)},
+		{"B2A0", lutABTag("mBA ", 3, 4, 65, fromXYZ)},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, err := NewTransform(p, SRGB, Perceptual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := NewTransform(SRGB, p, Perceptual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		cmyk, rgb []float64
+	}{
+		{[]float64{0, 0, 0, 0}, []float64{1, 1, 1}},
+		{[]float64{0, 0, 0, 1}, []float64{0, 0, 0}},
+		{[]float64{0, 1, 1, 0}, []float64{1, 0, 0}},
+		{[]float64{1, 0, 1, 0}, []float64{0, 1, 0}},
+	}
+	for _, tc := range testCases {
+		if got := convert(from, tc.cmyk...); !near(got, tc.rgb, 0.02) {
+			t.Errorf("%v to RGB: got %v, want %v", tc.cmyk, got, tc.rgb)
+		}
+	}
+	for _, c := range [][]float64{{0.2, 0.5, 0.8}, {0.5, 0.5, 0.5}, {0.7, 0.3, 0.4}} {
+		if got := convert(from, convert(to, c...)...); !near(got, c, 0.02) {
+			t.Errorf("round trip %v: got %v", c, got)
+		}
+	}
+}
+
+func TestCLUT(t *testing.T) {
+	// Multi-linear interpolation is exact for linear functions.
+	f := func(v []float64) []float64 {
+		x, y, z := v[0], v[1], v[2]
+		v = v[:2]
+		v[0], v[1] = (x+y+z)/3, 0.5*x+0.25*z
+		return v
+	}
+	c, _, err := parseCLUT(sample(3, 2, 5, 2, f), []int{5, 5, 5}, 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, in := range [][]float64{{0, 0, 0}, {1, 1, 1}, {0.1, 0.7, 0.35}, {0.9, 0.05, 0.5}, {-1, 2, 0.5}} {
+		var buf1, buf2 [maxChannels]float64
+		got := c.eval(append(buf1[:0], in...))
+		v := append(buf2[:0], in...)
+		for i := range v {
+			v[i] = clamp(v[i])
+		}
+		if want := f(v); !near(got, want, 1e-4) {
+			t.Errorf("%v: got %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestCurves(t *testing.T) {
+	testCases := [][]byte{
+		curvTag(),
+		curvTag(0x0200),
+		curvTag(0, 0x1000, 0x5000, 0xffff),
+		paraTag(0, 1.8),
+		paraTag(1, 2.2, 1.1, -0.1),
+		paraTag(2, 2.2, 1, 0, 0.1),
+		paraTag(3, 2.4, 1/1.055, 0.055/1.055, 1/12.92, 0.04045),
+		paraTag(4, 2.4, 1/1.055, 0.055/1.055, 1/12.92, 0.04045, 0.01, 0.01),
+	}
+	for i, b := range testCases {
+		c, n, err := parseCurve(b)
+		if err != nil {
+			t.Errorf("%d: %v", i, err)
+			continue
+		}
+		if n != len(b) {
+			t.Errorf("%d: got length %d, want %d", i, n, len(b))
+		}
+		inv := c.inverse()
+		// The curves are strictly increasing above 0.2.
+		for x := 0.2; x <= 1; x += 0.1 {
+			if got := inv.eval(c.eval(x)); math.Abs(got-x) > 1e-9 {
+				t.Errorf("%d: %v: got %v", i, x, got)
+			}
+		}
+		var ys []float64
+		for x := 0.0; x <= 1; x += 0.01 {
+			ys = append(ys, c.eval(x))
+		}
+		if !sort.Float64sAreSorted(ys) {
+			t.Errorf("%d: curve is not increasing", i)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	valid := buildProfile(0x04300000, Display, RGB, XYZ, srgbTags())
+	corrupt := func(off int, b ...byte) []byte {
+		c := append([]byte(nil), valid...)
+		copy(c[off:], b)
+		return c
+	}
+	testCases := [][]byte{
+		nil,
+		valid[:131],
+		valid[:len(valid)-1],
+		corrupt(0, 0xff, 0xff, 0xff, 0xff),
+		corrupt(36, 'x'),
+		corrupt(12, []byte("link")...),
+		corrupt(16, []byte("ABCD")...),
+		corrupt(20, []byte("RGB ")...),
+		corrupt(128, 0xff),
+		corrupt(132+4, 0xff),
+		buildProfile(0x04300000, Display, RGB, XYZ, nil),
+		buildProfile(0x04300000, Display, RGB, XYZ, []tag{{"A2B0", []byte("mft2\x00\x00\x00\x00\x03\x03")}}),
+		buildProfile(0x04300000, Display, RGB, XYZ, []tag{{"rTRC", paraTag(5)}}),
+	}
+	for i, b := range testCases {
+		if _, err := Parse(b); err == nil {
+			t.Errorf("%d: got nil error, want non-nil", i)
+		}
+	}
+}
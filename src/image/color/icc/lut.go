@@ -0,0 +1,422 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package icc
+
+// maxChannels is the maximum number of channels of a color.
+const maxChannels = 16
+
+// A stage is one step of a pipeline. It maps the channels v of a color, in
+// place, and returns the resulting channels, which may differ in number. The
+// capacity of v is at least maxChannels.
+type stage interface {
+	eval(v []float64) []float64
+}
+
+// A pipeline is a sequence of stages.
+type pipeline []stage
+
+func (p pipeline) eval(v []float64) []float64 {
+	for _, s := range p {
+		v = s.eval(v)
+	}
+	return v
+}
+
+// curves applies one curve to each channel.
+type curves []curve
+
+func (c curves) eval(v []float64) []float64 {
+	for i := range v {
+		v[i] = c[i].eval(v[i])
+	}
+	return v
+}
+
+func (c curves) inverse() curves {
+	inv := make(curves, len(c))
+	for i := range c {
+		inv[i] = c[i].inverse()
+	}
+	return inv
+}
+
+// matrix multiplies three channels by a row-major 3×3 matrix m, and adds
+// the offset o.
+type matrix struct {
+	m [9]float64
+	o [3]float64
+}
+
+func (m *matrix) eval(v []float64) []float64 {
+	x, y, z := v[0], v[1], v[2]
+	v = v[:3]
+	for i := range v {
+		v[i] = m.m[3*i]*x + m.m[3*i+1]*y + m.m[3*i+2]*z + m.o[i]
+	}
+	return v
+}
+
+// inverse returns the inverse of m, which must have no offset, or nil if m
+// is singular.
+func (m *matrix) inverse() *matrix {
+	a := &m.m
+	det := a[0]*(a[4]*a[8]-a[5]*a[7]) - a[1]*(a[3]*a[8]-a[5]*a[6]) + a[2]*(a[3]*a[7]-a[4]*a[6])
+	if det == 0 {
+		return nil
+	}
+	return &matrix{m: [9]float64{
+		(a[4]*a[8] - a[5]*a[7]) / det,
+		(a[2]*a[7] - a[1]*a[8]) / det,
+		(a[1]*a[5] - a[2]*a[4]) / det,
+		(a[5]*a[6] - a[3]*a[8]) / det,
+		(a[0]*a[8] - a[2]*a[6]) / det,
+		(a[2]*a[3] - a[0]*a[5]) / det,
+		(a[3]*a[7] - a[4]*a[6]) / det,
+		(a[1]*a[6] - a[0]*a[7]) / det,
+		(a[0]*a[4] - a[1]*a[3]) / det,
+	}}
+}
+
+func (m *matrix) mul(n *matrix) *matrix {
+	var r matrix
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				r.m[3*i+j] += m.m[3*i+k] * n.m[3*k+j]
+			}
+		}
+	}
+	return &r
+}
+
+// clut is a multi-dimensional color lookup table. Its inputs are clamped
+// to [0, 1] and multi-linearly interpolated between the grid points.
+type clut struct {
+	in, out int
+	grid    []int
+	// data holds the grid points' outputs, with the last input varying
+	// fastest.
+	data []float64
+}
+
+func (c *clut) eval(v []float64) []float64 {
+	var (
+		base, stride [maxChannels]int
+		frac         [maxChannels]float64
+	)
+	s := c.out
+	for i := c.in - 1; i >= 0; i-- {
+		g := c.grid[i]
+		x := clamp(v[i]) * float64(g-1)
+		j := int(x)
+		if j > g-2 {
+			j = g - 2
+		}
+		if j < 0 {
+			j = 0
+		}
+		base[i], frac[i], stride[i] = j, x-float64(j), s
+		s *= g
+	}
+	var out [maxChannels]float64
+	for corner := 0; corner < 1<<uint(c.in); corner++ {
+		w, off := 1.0, 0
+		for i := 0; i < c.in; i++ {
+			if corner>>uint(i)&1 != 0 {
+				w *= frac[i]
+				off += (base[i] + 1) * stride[i]
+			} else {
+				w *= 1 - frac[i]
+				off += base[i] * stride[i]
+			}
+		}
+		if w == 0 {
+			continue
+		}
+		for k := 0; k < c.out; k++ {
+			out[k] += w * c.data[off+k]
+		}
+	}
+	v = v[:c.out]
+	copy(v, out[:c.out])
+	return v
+}
+
+// parseCLUT parses the grid points of a CLUT with the given grid sizes,
+// whose values are stored with the given precision in bytes.
+func parseCLUT(b []byte, grid []int, out, precision int) (*clut, int, error) {
+	n := out
+	for _, g := range grid {
+		if g < 1 {
+			return nil, 0, FormatError("bad CLUT grid size")
+		}
+		n *= g
+		if n > 1<<24 {
+			return nil, 0, UnsupportedError("CLUT size")
+		}
+	}
+	if len(b) < n*precision {
+		return nil, 0, FormatError("CLUT is too short")
+	}
+	c := &clut{in: len(grid), out: out, grid: grid, data: make([]float64, n)}
+	for i := range c.data {
+		if precision == 1 {
+			c.data[i] = float64(b[i]) / 0xff
+		} else {
+			c.data[i] = float64(be16(b[2*i:])) / 0xffff
+		}
+	}
+	return c, n * precision, nil
+}
+
+// parseCurves parses n consecutive curve elements.
+func parseCurves(b []byte, n int) (curves, error) {
+	c := make(curves, n)
+	for i := range c {
+		var (
+			size int
+			err  error
+		)
+		c[i], size, err = parseCurve(b)
+		if err != nil {
+			return nil, err
+		}
+		if size > len(b) {
+			size = len(b)
+		}
+		b = b[size:]
+	}
+	return c, nil
+}
+
+// parseTables parses the n input or output tables of a lut8 or lut16 tag,
+// each of the given number of entries of the given precision in bytes.
+func parseTables(b []byte, n, entries, precision int) (curves, int, error) {
+	if entries < 2 {
+		return nil, 0, FormatError("bad LUT table size")
+	}
+	if len(b) < n*entries*precision {
+		return nil, 0, FormatError("LUT tables are too short")
+	}
+	c := make(curves, n)
+	for i := range c {
+		t := make(tableCurve, entries)
+		for j := range t {
+			if precision == 1 {
+				t[j] = float64(b[i*entries+j]) / 0xff
+			} else {
+				t[j] = float64(be16(b[2*(i*entries+j):])) / 0xffff
+			}
+		}
+		c[i] = t
+	}
+	return c, n * entries * precision, nil
+}
+
+// parseMatrix parses the 3×3 matrix, and the offset if withOffset, of s15Fixed16 numbers.
+func parseMatrix(b []byte, withOffset bool) (*matrix, error) {
+	n := 9
+	if withOffset {
+		n = 12
+	}
+	if len(b) < 4*n {
+		return nil, FormatError("matrix is too short")
+	}
+	m := new(matrix)
+	for i := 0; i < 9; i++ {
+		m.m[i] = s15Fixed16(b[4*i:])
+	}
+	if withOffset {
+		for i := 0; i < 3; i++ {
+			m.o[i] = s15Fixed16(b[36+4*i:])
+		}
+	}
+	return m, nil
+}
+
+// lutEncoding is how a LUT-based tag encodes the PCS values as channel
+// values in [0, 1].
+type lutEncoding int
+
+const (
+	// lutV4 is the Lab encoding of lut8, lutAToB and lutBToA tags.
+	lutV4 lutEncoding = iota
+	// lutLegacy is the Lab encoding of lut16 tags.
+	lutLegacy
+)
+
+// parseLut parses a lut8, lut16, lutAToB or lutBToA tag, with the given
+// number of input and output channels. The matrix of lut8 and lut16 tags is
+// only used if the input is XYZ.
+func parseLut(b []byte, in, out int, inputXYZ bool) (pipeline, lutEncoding, error) {
+	if len(b) < 32 {
+		return nil, 0, FormatError("LUT tag is too short")
+	}
+	if int(b[8]) != in || int(b[9]) != out {
+		return nil, 0, FormatError("bad LUT channel count")
+	}
+	switch string(b[:4]) {
+	case "mft1", "mft2":
+		if len(b) < 52 {
+			return nil, 0, FormatError("LUT tag is too short")
+		}
+		grid := make([]int, in)
+		for i := range grid {
+			grid[i] = int(b[10])
+		}
+		m, err := parseMatrix(b[12:], false)
+		if err != nil {
+			return nil, 0, err
+		}
+		precision, inEntries, outEntries, enc := 1, 256, 256, lutV4
+		off := 48
+		if string(b[:4]) == "mft2" {
+			precision, enc = 2, lutLegacy
+			inEntries, outEntries = int(be16(b[48:])), int(be16(b[50:]))
+			off = 52
+		}
+		var p pipeline
+		if inputXYZ {
+			p = append(p, m)
+		}
+		inCurves, n, err := parseTables(b[off:], in, inEntries, precision)
+		if err != nil {
+			return nil, 0, err
+		}
+		off += n
+		c, n, err := parseCLUT(b[off:], grid, out, precision)
+		if err != nil {
+			return nil, 0, err
+		}
+		off += n
+		outCurves, _, err := parseTables(b[off:], out, outEntries, precision)
+		if err != nil {
+			return nil, 0, err
+		}
+		return append(p, inCurves, c, outCurves), enc, nil
+
+	case "mAB ", "mBA ":
+		var offs [5]int
+		for i := range offs {
+			offs[i] = int(be32(b[12+4*i:]))
+			if offs[i] < 0 || offs[i] >= len(b) {
+				return nil, 0, FormatError("bad LUT element offset")
+			}
+		}
+		bOff, mtxOff, mOff, clutOff, aOff := offs[0], offs[1], offs[2], offs[3], offs[4]
+		aToB := string(b[:4]) == "mAB "
+		// The number of channels of the A curves and B curves.
+		nA, nB := in, out
+		if !aToB {
+			nA, nB = out, in
+		}
+		var aCurves, bCurves, mCurves curves
+		var mtx *matrix
+		var c *clut
+		var err error
+		if bOff == 0 {
+			return nil, 0, FormatError("LUT tag has no B curves")
+		}
+		if bCurves, err = parseCurves(b[bOff:], nB); err != nil {
+			return nil, 0, err
+		}
+		if mtxOff != 0 {
+			if nB != 3 {
+				return nil, 0, FormatError("bad LUT matrix")
+			}
+			if mtx, err = parseMatrix(b[mtxOff:], true); err != nil {
+				return nil, 0, err
+			}
+		}
+		if mOff != 0 {
+			if mCurves, err = parseCurves(b[mOff:], nB); err != nil {
+				return nil, 0, err
+			}
+		}
+		if clutOff != 0 {
+			if len(b) < clutOff+20 {
+				return nil, 0, FormatError("CLUT is too short")
+			}
+			// The CLUT maps the A channels to the B channels, or vice versa.
+			gridIn, nOut := nA, nB
+			if !aToB {
+				gridIn, nOut = nB, nA
+			}
+			grid := make([]int, gridIn)
+			for i := range grid {
+				grid[i] = int(b[clutOff+i])
+			}
+			precision := int(b[clutOff+16])
+			if precision != 1 && precision != 2 {
+				return nil, 0, FormatError("bad CLUT precision")
+			}
+			if c, _, err = parseCLUT(b[clutOff+20:], grid, nOut, precision); err != nil {
+				return nil, 0, err
+			}
+		}
+		if aOff != 0 {
+			if aCurves, err = parseCurves(b[aOff:], nA); err != nil {
+				return nil, 0, err
+			}
+		}
+		if (c != nil) != (aCurves != nil) || (c == nil && in != out) {
+			return nil, 0, FormatError("bad LUT elements")
+		}
+		// A to B: A curves, CLUT, M curves, matrix, B curves.
+		// B to A: B curves, matrix, M curves, CLUT, A curves.
+		var p pipeline
+		if aToB {
+			if aCurves != nil {
+				p = append(p, aCurves, c)
+			}
+			if mCurves != nil {
+				p = append(p, mCurves)
+			}
+			if mtx != nil {
+				p = append(p, mtx)
+			}
+			p = append(p, bCurves)
+		} else {
+			p = append(p, bCurves)
+			if mtx != nil {
+				p = append(p, mtx)
+			}
+			if mCurves != nil {
+				p = append(p, mCurves)
+			}
+			if aCurves != nil {
+				p = append(p, c, aCurves)
+			}
+		}
+		return p, lutV4, nil
+	}
+	return nil, 0, UnsupportedError("LUT type " + string(b[:4]))
+}
+
+// parseLutToPCS parses an AToB tag of a profile with the given number of
+// device channels, and returns a pipeline from device colors to XYZ.
+func parseLutToPCS(b []byte, channels int, pcs ColorSpace) (pipeline, error) {
+	p, enc, err := parseLut(b, channels, 3, false)
+	if err != nil {
+		return nil, err
+	}
+	if pcs == Lab {
+		return append(p, labDecoder(enc), stageFunc(labToXYZ)), nil
+	}
+	return append(p, stageFunc(xyzDecode)), nil
+}
+
+// parseLutFromPCS parses a BToA tag of a profile with the given number of
+// device channels, and returns a pipeline from XYZ to device colors.
+func parseLutFromPCS(b []byte, channels int, pcs ColorSpace) (pipeline, error) {
+	p, enc, err := parseLut(b, 3, channels, pcs == XYZ)
+	if err != nil {
+		return nil, err
+	}
+	if pcs == Lab {
+		return append(pipeline{stageFunc(xyzToLab), labEncoder(enc)}, p...), nil
+	}
+	return append(pipeline{stageFunc(xyzEncode)}, p...), nil
+}
@@ -0,0 +1,139 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package icc
+
+import (
+	"math"
+)
+
+// stageFunc is a stage implemented by a function.
+type stageFunc func(v []float64) []float64
+
+func (f stageFunc) eval(v []float64) []float64 { return f(v) }
+
+// xyzScale is the ratio between XYZ values and their encoding as channel
+// values, as per section 6.5.4 of the spec. The u1Fixed15 encoding of 0xffff
+// is 1 + 32767/32768.
+const xyzScale = 0xffff / 32768.0
+
+func xyzDecode(v []float64) []float64 {
+	for i := range v {
+		v[i] *= xyzScale
+	}
+	return v
+}
+
+func xyzEncode(v []float64) []float64 {
+	for i := range v {
+		v[i] = clamp(v[i] / xyzScale)
+	}
+	return v
+}
+
+// labDecoder returns a stage that decodes Lab values from channel values.
+func labDecoder(enc lutEncoding) stage {
+	// The legacy encoding maps 0xff00, rather than 0xffff, to L* = 100 and
+	// a*, b* = 127.
+	s := 1.0
+	if enc == lutLegacy {
+		s = 0xffff / float64(0xff00)
+	}
+	return stageFunc(func(v []float64) []float64 {
+		v[0] = v[0] * s * 100
+		v[1] = v[1]*s*255 - 128
+		v[2] = v[2]*s*255 - 128
+		return v
+	})
+}
+
+// labEncoder returns a stage that encodes Lab values as channel values.
+func labEncoder(enc lutEncoding) stage {
+	s := 1.0
+	if enc == lutLegacy {
+		s = 0xffff / float64(0xff00)
+	}
+	return stageFunc(func(v []float64) []float64 {
+		v[0] = clamp(v[0] / 100 / s)
+		v[1] = clamp((v[1] + 128) / 255 / s)
+		v[2] = clamp((v[2] + 128) / 255 / s)
+		return v
+	})
+}
+
+// labToXYZ converts CIELAB values to XYZ values, relative to the D50
+// illuminant.
+func labToXYZ(v []float64) []float64 {
+	const e = 6.0 / 29
+	f := func(t float64) float64 {
+		if t > e {
+			return t * t * t
+		}
+		return 3 * e * e * (t - 4.0/29)
+	}
+	fy := (v[0] + 16) / 116
+	fx := fy + v[1]/500
+	fz := fy - v[2]/200
+	v[0], v[1], v[2] = d50[0]*f(fx), d50[1]*f(fy), d50[2]*f(fz)
+	return v
+}
+
+// xyzToLab converts XYZ values, relative to the D50 illuminant, to CIELAB
+// values.
+func xyzToLab(v []float64) []float64 {
+	const e = 6.0 / 29
+	f := func(t float64) float64 {
+		if t > e*e*e {
+			return math.Cbrt(t)
+		}
+		return t/(3*e*e) + 4.0/29
+	}
+	fx, fy, fz := f(v[0]/d50[0]), f(v[1]/d50[1]), f(v[2]/d50[2])
+	v[0], v[1], v[2] = 116*fy-16, 500*(fx-fy), 200*(fy-fz)
+	return v
+}
+
+// matrixTRC returns the pipelines of an RGB profile whose colorants, the
+// columns of m, and tone reproduction curves are c.
+func matrixTRC(c [3]curve, m [9]float64) (toPCS, fromPCS pipeline, err error) {
+	mtx := &matrix{m: m}
+	inv := mtx.inverse()
+	if inv == nil {
+		return nil, nil, FormatError("singular colorant matrix")
+	}
+	trc := curves(c[:])
+	return pipeline{trc, mtx}, pipeline{inv, trc.inverse()}, nil
+}
+
+// grayTRC returns the pipelines of a gray profile whose tone reproduction
+// curve is c. The curve maps gray values to Y or, if the PCS is Lab, to L*.
+func grayTRC(c curve, pcs ColorSpace) (toPCS, fromPCS pipeline, err error) {
+	inv := c.inverse()
+	if pcs == Lab {
+		toPCS = pipeline{stageFunc(func(v []float64) []float64 {
+			v = v[:3]
+			v[0], v[1], v[2] = 100*c.eval(v[0]), 0, 0
+			return labToXYZ(v)
+		})}
+		fromPCS = pipeline{stageFunc(func(v []float64) []float64 {
+			v = xyzToLab(v)[:1]
+			v[0] = inv.eval(v[0] / 100)
+			return v
+		})}
+		return toPCS, fromPCS, nil
+	}
+	toPCS = pipeline{stageFunc(func(v []float64) []float64 {
+		y := c.eval(v[0])
+		v = v[:3]
+		v[0], v[1], v[2] = y*d50[0], y*d50[1], y*d50[2]
+		return v
+	})}
+	fromPCS = pipeline{stageFunc(func(v []float64) []float64 {
+		y := v[1]
+		v = v[:1]
+		v[0] = inv.eval(y)
+		return v
+	})}
+	return toPCS, fromPCS, nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package icc
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// Transform converts colors from the color space of one profile to that of
+// another. It is a color.Model, and a draw.Drawer that draws converted
+// source images.
+//
+// The colors of images with the gray and RGB color spaces are
+// non-alpha-premultiplied, and their alpha is left unchanged. Images with
+// the CMYK color space are opaque.
+type Transform struct {
+	src, dst       ColorSpace
+	toPCS, fromPCS pipeline
+	// scale is the ratio between the source and destination media white
+	// points, for the absolute colorimetric intent.
+	scale *[3]float64
+}
+
+// NewTransform returns a Transform that converts colors from the color
+// space of src to that of dst, using the given rendering intent. Both
+// profiles' color spaces must be Gray, RGB or CMYK.
+func NewTransform(src, dst *Profile, intent Intent) (*Transform, error) {
+	if intent < 0 || intent >= nIntent {
+		return nil, errors.New("icc: invalid rendering intent")
+	}
+	for _, p := range []*Profile{src, dst} {
+		switch p.ColorSpace {
+		case Gray, RGB, CMYK:
+		default:
+			return nil, UnsupportedError("conversion of color space " + string(p.ColorSpace))
+		}
+	}
+	t := &Transform{
+		src:     src.ColorSpace,
+		dst:     dst.ColorSpace,
+		toPCS:   src.toPCS[intent],
+		fromPCS: dst.fromPCS[intent],
+	}
+	if t.toPCS == nil {
+		return nil, UnsupportedError("source profile has no conversion to the PCS")
+	}
+	if t.fromPCS == nil {
+		return nil, UnsupportedError("destination profile has no conversion from the PCS")
+	}
+	if intent == AbsoluteColorimetric {
+		t.scale = new([3]float64)
+		for i := range t.scale {
+			t.scale[i] = src.WhitePoint[i] / dst.WhitePoint[i]
+		}
+	}
+	return t, nil
+}
+
+// eval converts the source color v, whose capacity is maxChannels.
+func (t *Transform) eval(v []float64) []float64 {
+	v = t.toPCS.eval(v)
+	if t.scale != nil {
+		for i := range v {
+			v[i] *= t.scale[i]
+		}
+	}
+	v = t.fromPCS.eval(v)
+	for i := range v {
+		v[i] = clamp(v[i])
+	}
+	return v
+}
+
+// Convert converts c to the destination color space. It returns a
+// color.NRGBA64 for the RGB color space, a color.Gray16, or color.NRGBA64 if
+// c is not opaque, for the gray color space, and a color.CMYK for the CMYK
+// color space.
+func (t *Transform) Convert(c color.Color) color.Color {
+	var buf [maxChannels]float64
+	v := buf[:0]
+	alpha := uint16(0xffff)
+	switch t.src {
+	case Gray, RGB:
+		n := color.NRGBA64Model.Convert(c).(color.NRGBA64)
+		alpha = n.A
+		if t.src == Gray {
+			// This is the same luma as color.Gray16Model's.
+			y := (19595*uint32(n.R) + 38470*uint32(n.G) + 7471*uint32(n.B) + 1<<15) >> 16
+			v = append(v, float64(y)/0xffff)
+		} else {
+			v = append(v, float64(n.R)/0xffff, float64(n.G)/0xffff, float64(n.B)/0xffff)
+		}
+	case CMYK:
+		k := color.CMYKModel.Convert(c).(color.CMYK)
+		v = append(v, float64(k.C)/0xff, float64(k.M)/0xff, float64(k.Y)/0xff, float64(k.K)/0xff)
+	}
+	v = t.eval(v)
+
+	q16 := func(x float64) uint16 { return uint16(x*0xffff + 0.5) }
+	q8 := func(x float64) uint8 { return uint8(x*0xff + 0.5) }
+	switch t.dst {
+	case Gray:
+		y := q16(v[0])
+		if alpha == 0xffff {
+			return color.Gray16{y}
+		}
+		return color.NRGBA64{y, y, y, alpha}
+	case CMYK:
+		return color.CMYK{q8(v[0]), q8(v[1]), q8(v[2]), q8(v[3])}
+	}
+	return color.NRGBA64{q16(v[0]), q16(v[1]), q16(v[2]), alpha}
+}
+
+// Draw converts the colors of the part of src starting at sp and draws them
+// on the rectangle r of dst, replacing its contents.
+func (t *Transform) Draw(dst draw.Image, r image.Rectangle, src image.Image, sp image.Point) {
+	orig := r.Min
+	r = r.Intersect(dst.Bounds())
+	r = r.Intersect(src.Bounds().Add(orig.Sub(sp)))
+	sp = sp.Add(r.Min.Sub(orig))
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		sy := sp.Y + y - r.Min.Y
+		for x := r.Min.X; x < r.Max.X; x++ {
+			dst.Set(x, y, t.Convert(src.At(sp.X+x-r.Min.X, sy)))
+		}
+	}
+}
+
+// Image returns a copy of m converted to the destination color space. Its
+// type is *image.NRGBA64 for the RGB and gray color spaces, and *image.CMYK
+// for the CMYK color space.
+func (t *Transform) Image(m image.Image) draw.Image {
+	b := m.Bounds()
+	var dst draw.Image
+	if t.dst == CMYK {
+		dst = image.NewCMYK(b)
+	} else {
+		dst = image.NewNRGBA64(b)
+	}
+	t.Draw(dst, b, m, b.Min)
+	return dst
+}
@@ -0,0 +1,104 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package icc
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	srgb, err := NewTransform(SRGB, SRGB, Perceptual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gray, err := Parse(buildProfile(0x04300000, Display, Gray, XYZ, []tag{{"kTRC", paraTag(3, 2.4, 1/1.055, 0.055/1.055, 1/12.92, 0.04045)}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	toGray, err := NewTransform(SRGB, gray, Perceptual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		t    *Transform
+		in   color.Color
+		want color.Color
+	}{
+		{srgb, color.RGBA{0x80, 0x40, 0x20, 0xff}, color.NRGBA64{0x8080, 0x4040, 0x2020, 0xffff}},
+		// The colors are converted without alpha premultiplication.
+		{srgb, color.RGBA{0x40, 0x20, 0x10, 0x80}, color.NRGBA64{0x7fff, 0x3fff, 0x1fff, 0x8080}},
+		{srgb, color.Gray{0x80}, color.NRGBA64{0x8080, 0x8080, 0x8080, 0xffff}},
+		{toGray, color.White, color.Gray16{0xffff}},
+		{toGray, color.NRGBA{0, 0, 0, 0x80}, color.NRGBA64{0, 0, 0, 0x8080}},
+	}
+	for i, tc := range testCases {
+		if got := tc.t.Convert(tc.in); got != tc.want {
+			t.Errorf("%d: got %v, want %v", i, got, tc.want)
+		}
+	}
+}
+
+func TestDraw(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for i := range src.Pix {
+		src.Pix[i] = uint8(i * 13)
+	}
+	tr, err := NewTransform(SRGB, DisplayP3, Perceptual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := NewTransform(DisplayP3, SRGB, Perceptual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d draw.Drawer = back
+	p3 := tr.Image(src)
+	dst := image.NewNRGBA(image.Rect(-4, -4, 12, 12))
+	d.Draw(dst, dst.Bounds(), p3, image.Pt(-4, -4))
+	for y := 0; y < 12; y++ {
+		for x := 0; x < 12; x++ {
+			// The colors of sRGB are within the gamut of Display P3.
+			c0, c1 := src.NRGBAAt(x, y), dst.NRGBAAt(x, y)
+			if !nearByte(c0.R, c1.R) || !nearByte(c0.G, c1.G) || !nearByte(c0.B, c1.B) || c0.A != c1.A {
+				t.Fatalf("(%d, %d): got %v, want %v", x, y, c1, c0)
+			}
+		}
+	}
+	if c := dst.NRGBAAt(-1, -1); c != (color.NRGBA{}) {
+		t.Errorf("pixel outside of the source: got %v", c)
+	}
+}
+
+func nearByte(a, b uint8) bool {
+	return a-b+1 <= 2
+}
+
+func TestNewTransformErrors(t *testing.T) {
+	p := *SRGB
+	p.ColorSpace = Lab
+	if _, err := NewTransform(&p, SRGB, Perceptual); err == nil {
+		t.Error("Lab color space: got nil error")
+	}
+	if _, err := NewTransform(SRGB, SRGB, 4); err == nil {
+		t.Error("bad intent: got nil error")
+	}
+	// A profile with only a device to PCS conversion cannot be a
+	// destination.
+	toPCS, err := Parse(buildProfile(0x04300000, Input, RGB, Lab, []tag{
+		{"A2B0", lut16Tag(3, 3, 2, func(v []float64) []float64 { return v })},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewTransform(toPCS, SRGB, Perceptual); err != nil {
+		t.Error(err)
+	}
+	if _, err := NewTransform(SRGB, toPCS, Perceptual); err == nil {
+		t.Error("no BToA tag: got nil error")
+	}
+}
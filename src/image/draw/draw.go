@@ -2,7 +2,8 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// Package draw provides image composition functions.
+// Package draw provides image composition, scaling and transformation
+// functions.
 //
 // See "The Go image/draw package" for an introduction to this package:
 // https://golang.org/doc/articles/image_draw.html
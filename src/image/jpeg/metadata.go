@@ -0,0 +1,208 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jpeg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const (
+	app1Marker = 0xe1 // EXIF.
+	app2Marker = 0xe2 // ICC profile.
+)
+
+var (
+	exifHeader = []byte("Exif\x00\x00")
+	iccHeader  = []byte("ICC_PROFILE\x00")
+)
+
+// maxSegmentData is the maximum payload of a marker segment, whose length
+// field is 16 bits and includes itself.
+const maxSegmentData = 0xffff - 2
+
+// A Segment is an application specific (APPn) marker segment.
+type Segment struct {
+	// Marker is the segment's marker, from 0xe0 (APP0) to 0xef (APP15).
+	Marker uint8
+	// Data is the segment's payload, excluding the marker and length.
+	Data []byte
+}
+
+// Metadata is the metadata of a JPEG image.
+type Metadata struct {
+	// Segments are the image's APPn segments, in the order they appear.
+	Segments []Segment
+
+	// Orientation is the EXIF orientation of the image, from 1 to 8, or 0 if
+	// the image has no valid EXIF orientation. Values 2 to 8 mean that the
+	// stored image must be flipped and/or rotated before display: 2 flip
+	// horizontally, 3 rotate 180°, 4 flip vertically, 5 transpose, 6 rotate
+	// 90° clockwise, 7 transverse and 8 rotate 90° counter-clockwise.
+	Orientation int
+}
+
+// EXIF returns the payload of the first EXIF APP1 segment, without the
+// "Exif\x00\x00" header, or nil if there is no such segment.
+func (m *Metadata) EXIF() []byte {
+	for _, s := range m.Segments {
+		if s.Marker == app1Marker && bytes.HasPrefix(s.Data, exifHeader) {
+			return s.Data[len(exifHeader):]
+		}
+	}
+	return nil
+}
+
+// ICCProfile returns the ICC color profile reassembled from the image's APP2
+// segments, or nil if there is no complete profile.
+func (m *Metadata) ICCProfile() []byte {
+	// Each chunk's header is followed by its 1-based sequence number and the
+	// total number of chunks.
+	var chunks [][]byte
+	for _, s := range m.Segments {
+		if s.Marker != app2Marker || !bytes.HasPrefix(s.Data, iccHeader) || len(s.Data) < len(iccHeader)+2 {
+			continue
+		}
+		seq, n := int(s.Data[len(iccHeader)]), int(s.Data[len(iccHeader)+1])
+		if chunks == nil {
+			chunks = make([][]byte, n)
+		}
+		if n != len(chunks) || seq < 1 || seq > n || chunks[seq-1] != nil {
+			return nil
+		}
+		chunks[seq-1] = s.Data[len(iccHeader)+2:]
+	}
+	var profile []byte
+	for _, c := range chunks {
+		if c == nil {
+			return nil
+		}
+		profile = append(profile, c...)
+	}
+	return profile
+}
+
+// saveAppMarker reads an APPn segment and appends it to d.meta. APP0 (JFIF)
+// and APP14 (Adobe) segments are also interpreted, as by processApp0Marker
+// and processApp14Marker.
+func (d *decoder) saveAppMarker(marker uint8, n int) error {
+	data := make([]byte, n)
+	if err := d.readFull(data); err != nil {
+		return err
+	}
+	d.meta.Segments = append(d.meta.Segments, Segment{marker, data})
+	switch marker {
+	case app0Marker:
+		if len(data) >= 5 {
+			d.jfif = bytes.HasPrefix(data, []byte("JFIF\x00"))
+		}
+	case app14Marker:
+		if len(data) >= 12 && bytes.HasPrefix(data, []byte("Adobe")) {
+			d.adobeTransformValid = true
+			d.adobeTransform = data[11]
+		}
+	}
+	return nil
+}
+
+// exifOrientation returns the orientation tag of the TIFF structured EXIF
+// data, or 0 if it has no valid orientation tag.
+func exifOrientation(exif []byte) int {
+	const orientationTag = 0x0112
+	if len(exif) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(exif[:4]) {
+	case "II*\x00":
+		order = binary.LittleEndian
+	case "MM\x00*":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	// Only the first IFD, which describes the main image, is searched.
+	ifd := order.Uint32(exif[4:8])
+	if ifd > uint32(len(exif)-2) {
+		return 0
+	}
+	n := int(order.Uint16(exif[ifd:]))
+	entries := exif[ifd+2:]
+	for i := 0; i < n && len(entries) >= 12; i, entries = i+1, entries[12:] {
+		if order.Uint16(entries) != orientationTag {
+			continue
+		}
+		// The orientation is a single SHORT value.
+		if order.Uint16(entries[2:]) != 3 || order.Uint32(entries[4:]) != 1 {
+			return 0
+		}
+		if o := int(order.Uint16(entries[8:])); 1 <= o && o <= 8 {
+			return o
+		}
+		return 0
+	}
+	return 0
+}
+
+// appSegments returns the APPn segments that Encode writes for the options o.
+func appSegments(o *Options) ([]Segment, error) {
+	if o == nil {
+		return nil, nil
+	}
+	var segments []Segment
+	for _, s := range o.Segments {
+		if s.Marker < app0Marker || s.Marker > app15Marker {
+			return nil, errors.New("jpeg: invalid APPn segment marker")
+		}
+		if len(s.Data) > maxSegmentData {
+			return nil, errors.New("jpeg: APPn segment is too large")
+		}
+		if s.Marker == app14Marker {
+			continue
+		}
+		segments = append(segments, s)
+	}
+	if len(o.EXIF) > 0 {
+		if len(exifHeader)+len(o.EXIF) > maxSegmentData {
+			return nil, errors.New("jpeg: EXIF data is too large")
+		}
+		data := append(exifHeader[:len(exifHeader):len(exifHeader)], o.EXIF...)
+		segments = append(segments, Segment{app1Marker, data})
+	}
+	if len(o.ICCProfile) > 0 {
+		// The profile is split into chunks of at most chunkSize bytes.
+		const chunkSize = maxSegmentData - 14
+		n := (len(o.ICCProfile) + chunkSize - 1) / chunkSize
+		if n > 255 {
+			return nil, errors.New("jpeg: ICC profile is too large")
+		}
+		for i, p := 0, o.ICCProfile; i < n; i++ {
+			c := p
+			if len(c) > chunkSize {
+				c = c[:chunkSize]
+			}
+			p = p[len(c):]
+			data := make([]byte, 0, len(iccHeader)+2+len(c))
+			data = append(data, iccHeader...)
+			data = append(data, byte(i+1), byte(n))
+			data = append(data, c...)
+			segments = append(segments, Segment{app2Marker, data})
+		}
+	}
+	return segments, nil
+}
+
+// DecodeMetadata returns the metadata of a JPEG image without decoding the
+// image data. It reads the segments of r up to the first scan.
+func DecodeMetadata(r io.Reader) (*Metadata, error) {
+	d := decoder{meta: new(Metadata)}
+	if _, err := d.decode(r, true); err != nil {
+		return nil, err
+	}
+	d.meta.Orientation = exifOrientation(d.meta.EXIF())
+	return d.meta, nil
+}
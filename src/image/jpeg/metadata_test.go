@@ -0,0 +1,114 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jpeg
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+// exifWithOrientation returns big-endian TIFF structured EXIF data whose only
+// tag is the given orientation.
+func exifWithOrientation(o uint8) []byte {
+	return []byte("MM\x00*\x00\x00\x00\x08" +
+		"\x00\x01" + // One IFD entry.
+		"\x01\x12\x00\x03\x00\x00\x00\x01\x00" + string(o) + "\x00\x00" +
+		"\x00\x00\x00\x00") // No next IFD.
+}
+
+func TestMetadataRoundTrip(t *testing.T) {
+	m := image.NewGray(image.Rect(0, 0, 16, 16))
+	exif := exifWithOrientation(6)
+	// The profile is large enough to need three APP2 segments.
+	icc := make([]byte, 150000)
+	for i := range icc {
+		icc[i] = uint8(i * 7)
+	}
+	custom := Segment{Marker: 0xe5, Data: []byte("custom data")}
+	adobe := Segment{Marker: app14Marker, Data: []byte("Adobe\x00\x64\x00\x00\x00\x00\x00")}
+
+	var buf bytes.Buffer
+	err := Encode(&buf, m, &Options{
+		Segments:   []Segment{custom, adobe},
+		EXIF:       exif,
+		ICCProfile: icc,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	md, err := DecodeMetadata(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(md.Segments), 5; got != want {
+		t.Fatalf("got %d segments, want %d", got, want)
+	}
+	if s := md.Segments[0]; s.Marker != custom.Marker || !bytes.Equal(s.Data, custom.Data) {
+		t.Errorf("first segment: got %x %q, want %x %q", s.Marker, s.Data, custom.Marker, custom.Data)
+	}
+	if !bytes.Equal(md.EXIF(), exif) {
+		t.Errorf("EXIF: got %q, want %q", md.EXIF(), exif)
+	}
+	if md.Orientation != 6 {
+		t.Errorf("Orientation: got %d, want 6", md.Orientation)
+	}
+	if !bytes.Equal(md.ICCProfile(), icc) {
+		t.Errorf("ICC profile does not match")
+	}
+
+	// The image still decodes, and its metadata can be written back out.
+	if _, err := Decode(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	var buf2 bytes.Buffer
+	if err := Encode(&buf2, m, &Options{Segments: md.Segments}); err != nil {
+		t.Fatal(err)
+	}
+	md2, err := DecodeMetadata(&buf2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md2.Orientation != 6 || !bytes.Equal(md2.ICCProfile(), icc) {
+		t.Errorf("metadata was not preserved: orientation %d", md2.Orientation)
+	}
+}
+
+func TestExifOrientation(t *testing.T) {
+	testCases := []struct {
+		exif []byte
+		want int
+	}{
+		{nil, 0},
+		{[]byte("MM\x00*"), 0},
+		{exifWithOrientation(1), 1},
+		{exifWithOrientation(8), 8},
+		{exifWithOrientation(9), 0},
+		{[]byte("II*\x00\x08\x00\x00\x00\x01\x00\x12\x01\x03\x00\x01\x00\x00\x00\x03\x00\x00\x00"), 3},
+		// The IFD offset is out of range.
+		{[]byte("II*\x00\xff\x00\x00\x00\x01\x00"), 0},
+		// The entry count exceeds the data.
+		{[]byte("MM\x00*\x00\x00\x00\x08\x00\x09\x01\x12"), 0},
+	}
+	for i, tc := range testCases {
+		if got := exifOrientation(tc.exif); got != tc.want {
+			t.Errorf("%d: got %d, want %d", i, got, tc.want)
+		}
+	}
+}
+
+func TestEncodeSegmentErrors(t *testing.T) {
+	m := image.NewGray(image.Rect(0, 0, 8, 8))
+	testCases := []*Options{
+		{Segments: []Segment{{Marker: 0xd8}}},
+		{Segments: []Segment{{Marker: 0xe1, Data: make([]byte, 70000)}}},
+		{EXIF: make([]byte, 70000)},
+	}
+	for i, o := range testCases {
+		if err := Encode(&bytes.Buffer{}, m, o); err == nil {
+			t.Errorf("%d: got nil error, want non-nil", i)
+		}
+	}
+}
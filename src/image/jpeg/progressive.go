@@ -0,0 +1,240 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jpeg
+
+import (
+	"image"
+)
+
+// progComponent holds the quantized coefficients of one component of an image
+// being encoded progressively.
+type progComponent struct {
+	// blocks holds the coefficients, in zig-zag order, of a bw×bh grid of
+	// blocks that covers a whole number of MCUs.
+	blocks []block
+	bw, bh int
+	// w and h are the number of columns and rows of blocks that cover the
+	// component's samples. Non-interleaved scans only code those blocks, as
+	// per section A.2.2 of the spec.
+	w, h int
+}
+
+// progScan is one scan of a progressive image, coding the spectral band
+// [ss, se] of the given components.
+//
+// The encoder uses spectral selection but not successive approximation, so
+// each scan codes its band to full precision and Ah and Al are always zero.
+type progScan struct {
+	comps  []int
+	ss, se int
+}
+
+var (
+	progScansY = []progScan{
+		{[]int{0}, 0, 0},
+		{[]int{0}, 1, 5},
+		{[]int{0}, 6, 63},
+	}
+	progScansYCbCr = []progScan{
+		{[]int{0, 1, 2}, 0, 0},
+		{[]int{0}, 1, 5},
+		{[]int{2}, 1, 63},
+		{[]int{1}, 1, 63},
+		{[]int{0}, 6, 63},
+	}
+)
+
+// quantize applies the forward DCT to the natural order block b, and writes
+// the coefficients, quantized with the given table, to dst in zig-zag order.
+func (e *encoder) quantize(dst, b *block, q quantIndex) {
+	fdct(b)
+	for zig := 0; zig < blockSize; zig++ {
+		dst[zig] = div(b[unzig[zig]], 8*int32(e.quant[q][zig]))
+	}
+}
+
+// writeProgressive writes the scans of a progressive image, each preceded by
+// the optimal Huffman tables for that scan.
+func (e *encoder) writeProgressive(m image.Image, nComponent int) {
+	// Every scan visits every block, so the whole image is transformed and
+	// quantized up front.
+	var comps [3]progComponent
+	b := m.Bounds()
+	if nComponent == 1 {
+		w, h := (b.Dx()+7)/8, (b.Dy()+7)/8
+		comps[0] = progComponent{make([]block, w*h), w, h, w, h}
+	} else {
+		mw, mh := (b.Dx()+8*e.h-1)/(8*e.h), (b.Dy()+8*e.v-1)/(8*e.v)
+		w, h := (b.Dx()+7)/8, (b.Dy()+7)/8
+		comps[0] = progComponent{make([]block, mw*e.h*mh*e.v), mw * e.h, mh * e.v, w, h}
+		// The chroma dimensions are rounded up, as per section A.1.1.
+		cw, ch := (b.Dx()+e.h-1)/e.h, (b.Dy()+e.v-1)/e.v
+		w, h = (cw+7)/8, (ch+7)/8
+		comps[1] = progComponent{make([]block, mw*mh), mw, mh, w, h}
+		comps[2] = progComponent{make([]block, mw*mh), mw, mh, w, h}
+	}
+	e.forEachBlock(m, func(c, bx, by int, b *block) {
+		q := quantIndexLuminance
+		if c > 0 {
+			q = quantIndexChrominance
+		}
+		p := &comps[c]
+		e.quantize(&p.blocks[by*p.bw+bx], b, q)
+	})
+
+	scans := progScansYCbCr
+	if nComponent == 1 {
+		scans = progScansY
+	}
+	for _, s := range scans {
+		var hs []huffIndex
+		for _, c := range s.comps {
+			h := huffIndexLuminanceDC
+			if c > 0 {
+				h = huffIndexChrominanceDC
+			}
+			if s.ss > 0 {
+				h++
+			}
+			hs = append(hs, h)
+		}
+		if len(hs) == 3 {
+			// The Cb and Cr components share Huffman tables.
+			hs = hs[:2]
+		}
+		e.countHuff(hs, func() { e.writeProgScan(&comps, s) })
+		e.writeDHT(hs)
+		e.writeProgSOS(s)
+		e.writeProgScan(&comps, s)
+	}
+}
+
+// writeProgSOS writes the StartOfScan marker for a scan of a progressive
+// image.
+func (e *encoder) writeProgSOS(s progScan) {
+	n := len(s.comps)
+	e.writeMarkerHeader(sosMarker, 6+2*n)
+	e.buf[0] = uint8(n)
+	for i, c := range s.comps {
+		e.buf[2*i+1] = uint8(c + 1)
+		// The luma component uses DC and AC table 0, the chroma components
+		// use DC and AC table 1.
+		e.buf[2*i+2] = 0x00
+		if c > 0 {
+			e.buf[2*i+2] = 0x11
+		}
+	}
+	e.buf[2*n+1] = uint8(s.ss)
+	e.buf[2*n+2] = uint8(s.se)
+	e.buf[2*n+3] = 0x00
+	e.write(e.buf[:2*n+4])
+}
+
+// writeProgScan writes the entropy-coded data of a scan of a progressive
+// image. DC scans are specified in section G.1.2.1 of the spec, and AC scans
+// in section G.1.2.2.
+func (e *encoder) writeProgScan(comps *[3]progComponent, s progScan) {
+	if s.ss == 0 {
+		// DC components are delta-encoded.
+		var prevDC [3]int32
+		dc := func(c int, b *block) {
+			h := huffIndexLuminanceDC
+			if c > 0 {
+				h = huffIndexChrominanceDC
+			}
+			e.emitHuffRLE(h, 0, b[0]-prevDC[c])
+			prevDC[c] = b[0]
+		}
+		if len(s.comps) == 1 {
+			c := s.comps[0]
+			p := &comps[c]
+			for by := 0; by < p.h; by++ {
+				for bx := 0; bx < p.w; bx++ {
+					dc(c, &p.blocks[by*p.bw+bx])
+				}
+			}
+		} else {
+			// An interleaved scan codes MCU by MCU. Only the luma component
+			// is sampled at more than one block per MCU.
+			mw, mh := comps[1].bw, comps[1].bh
+			for my := 0; my < mh; my++ {
+				for mx := 0; mx < mw; mx++ {
+					for _, c := range s.comps {
+						p, h, v := &comps[c], 1, 1
+						if c == 0 {
+							h, v = e.h, e.v
+						}
+						for j := 0; j < v; j++ {
+							for i := 0; i < h; i++ {
+								dc(c, &p.blocks[(my*v+j)*p.bw+mx*h+i])
+							}
+						}
+					}
+				}
+			}
+		}
+	} else {
+		c := s.comps[0]
+		p, h := &comps[c], huffIndexLuminanceAC
+		if c > 0 {
+			h = huffIndexChrominanceAC
+		}
+		// eobRun is the number of consecutive blocks whose remaining band
+		// coefficients are all zero.
+		eobRun := int32(0)
+		for by := 0; by < p.h; by++ {
+			for bx := 0; bx < p.w; bx++ {
+				b := &p.blocks[by*p.bw+bx]
+				runLength := int32(0)
+				for zig := s.ss; zig <= s.se; zig++ {
+					if b[zig] == 0 {
+						runLength++
+						continue
+					}
+					if eobRun > 0 {
+						e.emitEOBRun(h, eobRun)
+						eobRun = 0
+					}
+					for runLength > 15 {
+						e.emitHuff(h, 0xf0)
+						runLength -= 16
+					}
+					e.emitHuffRLE(h, runLength, b[zig])
+					runLength = 0
+				}
+				if runLength > 0 {
+					eobRun++
+					if eobRun == 0x7fff {
+						e.emitEOBRun(h, eobRun)
+						eobRun = 0
+					}
+				}
+			}
+		}
+		if eobRun > 0 {
+			e.emitEOBRun(h, eobRun)
+		}
+	}
+	// Pad the last byte with 1's.
+	e.emit(0x7f, 7)
+	e.bits, e.nBits = 0, 0
+}
+
+// emitEOBRun emits an end-of-band run of n blocks, where 0 < n < 0x8000.
+func (e *encoder) emitEOBRun(h huffIndex, n int32) {
+	var nBits uint32
+	if n < 0x100 {
+		nBits = uint32(bitCount[n])
+	} else {
+		nBits = 8 + uint32(bitCount[n>>8])
+	}
+	// The EOBn symbol is followed by the n-1 low bits of the run length, the
+	// most significant bit being implied.
+	nBits--
+	e.emitHuff(h, int32(nBits<<4))
+	if nBits > 0 {
+		e.emit(uint32(n)&(1<<nBits-1), nBits)
+	}
+}
@@ -139,6 +139,9 @@ type decoder struct {
 	huff       [maxTc + 1][maxTh + 1]huffman
 	quant      [maxTq + 1]block // Quantization tables, in zig-zag order.
 	tmp        [2 * blockSize]byte
+
+	// meta, if non-nil, collects the image's metadata.
+	meta *Metadata
 }
 
 // fill fills up the d.bytes.buf buffer from the underlying io.Reader. It
@@ -594,11 +597,18 @@ func (d *decoder) decode(r io.Reader, configOnly bool) (image.Image, error) {
 			return nil, FormatError("short segment length")
 		}
 
+		if d.meta != nil && app0Marker <= marker && marker <= app15Marker {
+			if err := d.saveAppMarker(marker, n); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
 		switch marker {
 		case sof0Marker, sof1Marker, sof2Marker:
 			d.progressive = marker == sof2Marker
 			err = d.processSOF(n)
-			if configOnly && d.jfif {
+			if configOnly && d.jfif && d.meta == nil {
 				return nil, err
 			}
 		case dhtMarker:
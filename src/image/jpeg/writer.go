@@ -207,6 +207,97 @@ func init() {
 	}
 }
 
+// optimalHuffmanSpec returns a Huffman encoding specification that is
+// optimal for the given symbol frequencies, limited to 16-bit codewords. The
+// procedure is described in section K.2 of the spec. At least one frequency
+// must be non-zero.
+func optimalHuffmanSpec(freq *[256]int) huffmanSpec {
+	// The extra symbol 256, with a frequency of 1, reserves the all-ones
+	// codeword so that no real codeword consists entirely of 1 bits.
+	var (
+		f        [257]int
+		codeSize [257]int
+		others   [257]int
+	)
+	copy(f[:], freq[:])
+	f[256] = 1
+	for i := range others {
+		others[i] = -1
+	}
+	for {
+		// Find the least frequent symbol c1, and the next least frequent c2.
+		// Ties are broken in favor of the larger symbol value.
+		c1, c2 := -1, -1
+		for i, v := range f {
+			if v != 0 && (c1 < 0 || v <= f[c1]) {
+				c1 = i
+			}
+		}
+		for i, v := range f {
+			if v != 0 && i != c1 && (c2 < 0 || v <= f[c2]) {
+				c2 = i
+			}
+		}
+		if c2 < 0 {
+			break
+		}
+		// Merge the two subtrees.
+		f[c1] += f[c2]
+		f[c2] = 0
+		codeSize[c1]++
+		for others[c1] >= 0 {
+			c1 = others[c1]
+			codeSize[c1]++
+		}
+		others[c1] = c2
+		codeSize[c2]++
+		for others[c2] >= 0 {
+			c2 = others[c2]
+			codeSize[c2]++
+		}
+	}
+
+	// Count the codes of each length. Lengths can be at most 256 bits.
+	var count [257]int
+	for _, n := range codeSize {
+		if n > 0 {
+			count[n]++
+		}
+	}
+	// Limit the code lengths to 16 bits, as per figure K.3.
+	for i := 256; i > 16; i-- {
+		for count[i] > 0 {
+			j := i - 2
+			for count[j] == 0 {
+				j--
+			}
+			count[i] -= 2
+			count[i-1]++
+			count[j+1] += 2
+			count[j]--
+		}
+	}
+	// Remove the reserved symbol, which has the longest code.
+	i := 16
+	for count[i] == 0 {
+		i--
+	}
+	count[i]--
+
+	var s huffmanSpec
+	for i := range s.count {
+		s.count[i] = byte(count[i+1])
+	}
+	for n := 1; n <= 256; n++ {
+		for v := 0; v < 256; v++ {
+			if codeSize[v] == n {
+				s.value = append(s.value, byte(v))
+			}
+		}
+	}
+	return s
+}
+
 // writer is a buffered writer.
 type writer interface {
 	Flush() error
@@ -226,6 +317,15 @@ type encoder struct {
 	bits, nBits uint32
 	// quant is the scaled quantization tables, in zig-zag order.
 	quant [nQuantIndex][blockSize]byte
+	// h and v are the luma sampling factors. The chroma components are never
+	// sampled at more than one block per MCU.
+	h, v int
+	// huffSpec and huffLUT are the Huffman encodings in use.
+	huffSpec [nHuffIndex]huffmanSpec
+	huffLUT  [nHuffIndex]huffmanLUT
+	// freq, if non-nil, means that Huffman-coded symbols are counted instead
+	// of written, so that optimal Huffman encodings can be computed.
+	freq *[nHuffIndex][256]int
 }
 
 func (e *encoder) flush() {
@@ -252,6 +352,9 @@ func (e *encoder) writeByte(b byte) {
 // emit emits the least significant nBits bits of bits to the bit-stream.
 // The precondition is bits < 1<<nBits && nBits <= 16.
 func (e *encoder) emit(bits, nBits uint32) {
+	if e.freq != nil {
+		return
+	}
 	nBits += e.nBits
 	bits <<= 32 - nBits
 	bits |= e.bits
@@ -269,7 +372,11 @@ func (e *encoder) emit(bits, nBits uint32) {
 
 // emitHuff emits the given value with the given Huffman encoder.
 func (e *encoder) emitHuff(h huffIndex, value int32) {
-	x := theHuffmanLUT[h][value]
+	if e.freq != nil {
+		e.freq[h][value]++
+		return
+	}
+	x := e.huffLUT[h][value]
 	e.emit(x&(1<<24-1), x>>24)
 }
 
@@ -292,6 +399,27 @@ func (e *encoder) emitHuffRLE(h huffIndex, runLength, value int32) {
 	}
 }
 
+// countHuff calls f with symbol counting enabled and then replaces the
+// Huffman encodings given by hs with ones that are optimal for the symbols
+// emitted by f.
+func (e *encoder) countHuff(hs []huffIndex, f func()) {
+	var freq [nHuffIndex][256]int
+	e.freq = &freq
+	f()
+	e.freq = nil
+	for _, h := range hs {
+		if freq[h] == ([256]int{}) {
+			// No symbol was coded with this table, as for an empty
+			// image, so keep the standard one.
+			e.huffSpec[h] = theHuffmanSpec[h]
+			e.huffLUT[h] = theHuffmanLUT[h]
+			continue
+		}
+		e.huffSpec[h] = optimalHuffmanSpec(&freq[h])
+		e.huffLUT[h].init(e.huffSpec[h])
+	}
+}
+
 // writeMarkerHeader writes the header for a marker with the given length.
 func (e *encoder) writeMarkerHeader(marker uint8, markerlen int) {
 	e.buf[0] = 0xff
@@ -311,10 +439,11 @@ func (e *encoder) writeDQT() {
 	}
 }
 
-// writeSOF0 writes the Start Of Frame (Baseline) marker.
-func (e *encoder) writeSOF0(size image.Point, nComponent int) {
+// writeSOF writes the Start Of Frame marker, which is sof0Marker for
+// baseline images and sof2Marker for progressive ones.
+func (e *encoder) writeSOF(marker uint8, size image.Point, nComponent int) {
 	markerlen := 8 + 3*nComponent
-	e.writeMarkerHeader(sof0Marker, markerlen)
+	e.writeMarkerHeader(marker, markerlen)
 	e.buf[0] = 8 // 8-bit color.
 	e.buf[1] = uint8(size.Y >> 8)
 	e.buf[2] = uint8(size.Y & 0xff)
@@ -329,30 +458,42 @@ func (e *encoder) writeSOF0(size image.Point, nComponent int) {
 	} else {
 		for i := 0; i < nComponent; i++ {
 			e.buf[3*i+6] = uint8(i + 1)
-			// We use 4:2:0 chroma subsampling.
-			e.buf[3*i+7] = "\x22\x11\x11"[i]
+			// Only the luma component is sampled at more than 1x1.
+			e.buf[3*i+7] = 0x11
+			if i == 0 {
+				e.buf[3*i+7] = uint8(e.h<<4 | e.v)
+			}
 			e.buf[3*i+8] = "\x00\x01\x01"[i]
 		}
 	}
 	e.write(e.buf[:3*(nComponent-1)+9])
 }
 
-// writeDHT writes the Define Huffman Table marker.
-func (e *encoder) writeDHT(nComponent int) {
+// writeDHT writes the Define Huffman Table marker for the given Huffman
+// encodings.
+func (e *encoder) writeDHT(hs []huffIndex) {
 	markerlen := 2
-	specs := theHuffmanSpec[:]
+	for _, h := range hs {
+		markerlen += 1 + 16 + len(e.huffSpec[h].value)
+	}
+	e.writeMarkerHeader(dhtMarker, markerlen)
+	for _, h := range hs {
+		e.writeByte("\x00\x10\x01\x11"[h])
+		e.write(e.huffSpec[h].count[:])
+		e.write(e.huffSpec[h].value)
+	}
+}
+
+// huffIndexes returns the Huffman encodings used by a baseline image with
+// nComponent components.
+func huffIndexes(nComponent int) []huffIndex {
 	if nComponent == 1 {
 		// Drop the Chrominance tables.
-		specs = specs[:2]
-	}
-	for _, s := range specs {
-		markerlen += 1 + 16 + len(s.value)
+		return []huffIndex{huffIndexLuminanceDC, huffIndexLuminanceAC}
 	}
-	e.writeMarkerHeader(dhtMarker, markerlen)
-	for i, s := range specs {
-		e.writeByte("\x00\x10\x01\x11"[i])
-		e.write(s.count[:])
-		e.write(s.value)
+	return []huffIndex{
+		huffIndexLuminanceDC, huffIndexLuminanceAC,
+		huffIndexChrominanceDC, huffIndexChrominanceAC,
 	}
 }
 
@@ -456,6 +597,80 @@ func scale(dst *block, src *[4]block) {
 	}
 }
 
+// scaleH scales the 16x8 region represented by the first 2 src blocks to the
+// 8x8 dst block.
+func scaleH(dst *block, src *[4]block) {
+	for i := 0; i < 2; i++ {
+		dstOff := i << 2
+		for y := 0; y < 8; y++ {
+			for x := 0; x < 4; x++ {
+				j := 8*y + 2*x
+				sum := src[i][j] + src[i][j+1]
+				dst[8*y+x+dstOff] = (sum + 1) >> 1
+			}
+		}
+	}
+}
+
+// downsample scales the chroma blocks of one MCU to the 8x8 dst block, given
+// the luma sampling factors h and v.
+func downsample(dst *block, src *[4]block, h, v int) {
+	switch {
+	case h == 2 && v == 2:
+		scale(dst, src)
+	case h == 2:
+		scaleH(dst, src)
+	default:
+		*dst = src[0]
+	}
+}
+
+// forEachBlock calls f for each 8x8 block of m, in the order that a baseline
+// scan encodes them: MCU by MCU, with the e.h*e.v luma blocks of each MCU
+// followed by its Cb and Cr blocks. Grayscale images only have luma blocks.
+// The component c is 0, 1 or 2 for Y, Cb or Cr, and bx and by locate the
+// block in that component's grid of blocks. The blocks are in natural (not
+// zig-zag) order, and f may modify them.
+func (e *encoder) forEachBlock(m image.Image, f func(c, bx, by int, b *block)) {
+	var (
+		// Scratch buffers to hold the YCbCr values.
+		b      block
+		cb, cr [4]block
+	)
+	bounds := m.Bounds()
+	switch m := m.(type) {
+	// TODO(wathiede): switch on m.ColorModel() instead of type.
+	case *image.Gray:
+		for y, by := bounds.Min.Y, 0; y < bounds.Max.Y; y, by = y+8, by+1 {
+			for x, bx := bounds.Min.X, 0; x < bounds.Max.X; x, bx = x+8, bx+1 {
+				grayToY(m, image.Pt(x, y), &b)
+				f(0, bx, by, &b)
+			}
+		}
+	default:
+		rgba, _ := m.(*image.RGBA)
+		for y, my := bounds.Min.Y, 0; y < bounds.Max.Y; y, my = y+8*e.v, my+1 {
+			for x, mx := bounds.Min.X, 0; x < bounds.Max.X; x, mx = x+8*e.h, mx+1 {
+				for i := 0; i < e.h*e.v; i++ {
+					xOff := (i % e.h) * 8
+					yOff := (i / e.h) * 8
+					p := image.Pt(x+xOff, y+yOff)
+					if rgba != nil {
+						rgbaToYCbCr(rgba, p, &b, &cb[i], &cr[i])
+					} else {
+						toYCbCr(m, p, &b, &cb[i], &cr[i])
+					}
+					f(0, mx*e.h+i%e.h, my*e.v+i/e.h, &b)
+				}
+				downsample(&b, &cb, e.h, e.v)
+				f(1, mx, my, &b)
+				downsample(&b, &cr, e.h, e.v)
+				f(2, mx, my, &b)
+			}
+		}
+	}
+}
+
 // sosHeaderY is the SOS marker "\xff\xda" followed by 8 bytes:
 //	- the marker length "\x00\x08",
 //	- the number of components "\x01",
@@ -481,7 +696,8 @@ var sosHeaderYCbCr = []byte{
 	0x11, 0x03, 0x11, 0x00, 0x3f, 0x00,
 }
 
-// writeSOS writes the StartOfScan marker.
+// writeSOS writes the StartOfScan marker and the scan data of a baseline
+// image.
 func (e *encoder) writeSOS(m image.Image) {
 	switch m.(type) {
 	case *image.Gray:
@@ -489,47 +705,20 @@ func (e *encoder) writeSOS(m image.Image) {
 	default:
 		e.write(sosHeaderYCbCr)
 	}
-	var (
-		// Scratch buffers to hold the YCbCr values.
-		// The blocks are in natural (not zig-zag) order.
-		b      block
-		cb, cr [4]block
-		// DC components are delta-encoded.
-		prevDCY, prevDCCb, prevDCCr int32
-	)
-	bounds := m.Bounds()
-	switch m := m.(type) {
-	// TODO(wathiede): switch on m.ColorModel() instead of type.
-	case *image.Gray:
-		for y := bounds.Min.Y; y < bounds.Max.Y; y += 8 {
-			for x := bounds.Min.X; x < bounds.Max.X; x += 8 {
-				p := image.Pt(x, y)
-				grayToY(m, p, &b)
-				prevDCY = e.writeBlock(&b, 0, prevDCY)
-			}
-		}
-	default:
-		rgba, _ := m.(*image.RGBA)
-		for y := bounds.Min.Y; y < bounds.Max.Y; y += 16 {
-			for x := bounds.Min.X; x < bounds.Max.X; x += 16 {
-				for i := 0; i < 4; i++ {
-					xOff := (i & 1) * 8
-					yOff := (i & 2) * 4
-					p := image.Pt(x+xOff, y+yOff)
-					if rgba != nil {
-						rgbaToYCbCr(rgba, p, &b, &cb[i], &cr[i])
-					} else {
-						toYCbCr(m, p, &b, &cb[i], &cr[i])
-					}
-					prevDCY = e.writeBlock(&b, 0, prevDCY)
-				}
-				scale(&b, &cb)
-				prevDCCb = e.writeBlock(&b, 1, prevDCCb)
-				scale(&b, &cr)
-				prevDCCr = e.writeBlock(&b, 1, prevDCCr)
-			}
+	e.writeBaselineScan(m)
+}
+
+// writeBaselineScan writes the entropy-coded data of a baseline scan.
+func (e *encoder) writeBaselineScan(m image.Image) {
+	// DC components are delta-encoded.
+	var prevDC [3]int32
+	e.forEachBlock(m, func(c, bx, by int, b *block) {
+		q := quantIndexLuminance
+		if c > 0 {
+			q = quantIndexChrominance
 		}
-	}
+		prevDC[c] = e.writeBlock(b, q, prevDC[c])
+	})
 	// Pad the last byte with 1's.
 	e.emit(0x7f, 7)
 }
@@ -537,19 +726,67 @@ func (e *encoder) writeSOS(m image.Image) {
 // DefaultQuality is the default quality encoding parameter.
 const DefaultQuality = 75
 
+// Subsampling is the chroma subsampling used when encoding a color image.
+type Subsampling int
+
+const (
+	// Subsampling420 halves the chroma resolution both horizontally and
+	// vertically. It is the default.
+	Subsampling420 Subsampling = iota
+	// Subsampling422 halves the chroma resolution horizontally.
+	Subsampling422
+	// Subsampling444 keeps the full chroma resolution.
+	Subsampling444
+)
+
 // Options are the encoding parameters.
 // Quality ranges from 1 to 100 inclusive, higher is better.
 type Options struct {
 	Quality int
+
+	// Progressive selects progressive encoding instead of baseline. A
+	// progressive image is transmitted as a series of scans that refine the
+	// whole image, and always uses optimized Huffman tables.
+	Progressive bool
+
+	// Subsampling is the chroma subsampling of color images. It is ignored
+	// for grayscale images. Encode returns an error for values other than
+	// Subsampling420, Subsampling422 and Subsampling444.
+	Subsampling Subsampling
+
+	// OptimizeHuffman selects Huffman tables computed from the image instead
+	// of the standard tables from section K.3 of the spec. It produces
+	// smaller files at the cost of a second encoding pass.
+	OptimizeHuffman bool
+
+	// Segments are APPn segments to write at the start of the image, such as
+	// those returned by DecodeMetadata. Adobe APP14 segments are not written,
+	// since they describe a color transform chosen by the encoder.
+	Segments []Segment
+
+	// EXIF, if non-empty, is written as an APP1 segment. It holds the TIFF
+	// structured EXIF data, without the "Exif\x00\x00" header.
+	EXIF []byte
+
+	// ICCProfile, if non-empty, is written as one or more APP2 segments.
+	ICCProfile []byte
 }
 
-// Encode writes the Image m to w in JPEG 4:2:0 baseline format with the given
-// options. Default parameters are used if a nil *Options is passed.
+// Encode writes the Image m to w in JPEG format with the given options. By
+// default, the image is encoded as 4:2:0 baseline. Default parameters are
+// used if a nil *Options is passed.
 func Encode(w io.Writer, m image.Image, o *Options) error {
 	b := m.Bounds()
 	if b.Dx() >= 1<<16 || b.Dy() >= 1<<16 {
 		return errors.New("jpeg: image is too large to encode")
 	}
+	if o != nil && (o.Subsampling < Subsampling420 || o.Subsampling > Subsampling444) {
+		return errors.New("jpeg: invalid subsampling")
+	}
+	segments, err := appSegments(o)
+	if err != nil {
+		return err
+	}
 	var e encoder
 	if ww, ok := w.(writer); ok {
 		e.w = ww
@@ -586,6 +823,9 @@ func Encode(w io.Writer, m image.Image, o *Options) error {
 			e.quant[i][j] = uint8(x)
 		}
 	}
+	// Initialize the Huffman tables.
+	e.huffSpec = theHuffmanSpec
+	e.huffLUT = theHuffmanLUT
 	// Compute number of components based on input image type.
 	nComponent := 3
 	switch m.(type) {
@@ -593,18 +833,44 @@ func Encode(w io.Writer, m image.Image, o *Options) error {
 	case *image.Gray:
 		nComponent = 1
 	}
+	// Compute the luma sampling factors.
+	e.h, e.v = 2, 2
+	if o != nil && nComponent == 3 {
+		switch o.Subsampling {
+		case Subsampling422:
+			e.h, e.v = 2, 1
+		case Subsampling444:
+			e.h, e.v = 1, 1
+		}
+	}
 	// Write the Start Of Image marker.
 	e.buf[0] = 0xff
 	e.buf[1] = 0xd8
 	e.write(e.buf[:2])
+	// Write the application specific segments.
+	for _, s := range segments {
+		e.writeMarkerHeader(s.Marker, 2+len(s.Data))
+		e.write(s.Data)
+	}
 	// Write the quantization tables.
 	e.writeDQT()
-	// Write the image dimensions.
-	e.writeSOF0(b.Size(), nComponent)
-	// Write the Huffman tables.
-	e.writeDHT(nComponent)
-	// Write the image data.
-	e.writeSOS(m)
+	if o != nil && o.Progressive {
+		// Write the image dimensions.
+		e.writeSOF(sof2Marker, b.Size(), nComponent)
+		// Write the Huffman tables and image data, scan by scan.
+		e.writeProgressive(m, nComponent)
+	} else {
+		// Write the image dimensions.
+		e.writeSOF(sof0Marker, b.Size(), nComponent)
+		// Write the Huffman tables.
+		hs := huffIndexes(nComponent)
+		if o != nil && o.OptimizeHuffman {
+			e.countHuff(hs, func() { e.writeBaselineScan(m) })
+		}
+		e.writeDHT(hs)
+		// Write the image data.
+		e.writeSOS(m)
+	}
 	// Write the End Of Image marker.
 	e.buf[0] = 0xff
 	e.buf[1] = 0xd9
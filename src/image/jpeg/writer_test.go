@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"image"
 	"image/color"
+	"image/draw"
 	"image/png"
 	"io/ioutil"
 	"math/rand"
@@ -160,6 +161,115 @@ func TestWriter(t *testing.T) {
 	}
 }
 
+// TestWriterOptions tests that images encoded with each combination of
+// progressive mode, chroma subsampling and Huffman table optimization survive
+// a round-trip through encode/decode cycle.
+func TestWriterOptions(t *testing.T) {
+	m0, err := readPng("../testdata/video-001.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Crop the image so that its dimensions are not a multiple of the MCU
+	// size.
+	m0 = m0.(*image.RGBA).SubImage(image.Rect(3, 5, 140, 111))
+	gray := image.NewGray(m0.Bounds())
+	draw.Draw(gray, gray.Bounds(), m0, m0.Bounds().Min, draw.Src)
+	for _, m := range []image.Image{m0, gray} {
+		for _, progressive := range []bool{false, true} {
+			for _, optimize := range []bool{false, true} {
+				for _, subsampling := range []Subsampling{Subsampling420, Subsampling422, Subsampling444} {
+					o := &Options{
+						Quality:         90,
+						Progressive:     progressive,
+						Subsampling:     subsampling,
+						OptimizeHuffman: optimize,
+					}
+					var buf bytes.Buffer
+					if err := Encode(&buf, m, o); err != nil {
+						t.Errorf("%T %+v: Encode: %v", m, *o, err)
+						continue
+					}
+					m1, err := Decode(&buf)
+					if err != nil {
+						t.Errorf("%T %+v: Decode: %v", m, *o, err)
+						continue
+					}
+					if m.Bounds().Size() != m1.Bounds().Size() {
+						t.Errorf("%T %+v: sizes differ: %v and %v", m, *o, m.Bounds().Size(), m1.Bounds().Size())
+						continue
+					}
+					m1 = translate(m1, m.Bounds().Min)
+					if got, want := averageDelta(m, m1), int64(4<<8); got > want {
+						t.Errorf("%T %+v: average delta too high; got %d, want <= %d", m, *o, got, want)
+					}
+				}
+			}
+		}
+	}
+}
+
+// translate returns m with its bounds translated to start at p.
+func translate(m image.Image, p image.Point) image.Image {
+	dst := image.NewRGBA(m.Bounds().Sub(m.Bounds().Min).Add(p))
+	draw.Draw(dst, dst.Bounds(), m, m.Bounds().Min, draw.Src)
+	return dst
+}
+
+// TestWriterOptionsEmpty tests that empty images can be encoded with any
+// options, even though no Huffman table is used.
+func TestWriterOptionsEmpty(t *testing.T) {
+	for _, m := range []image.Image{
+		image.NewRGBA(image.Rect(0, 0, 0, 0)),
+		image.NewGray(image.Rect(0, 0, 0, 0)),
+	} {
+		for _, o := range []*Options{
+			nil,
+			{Progressive: true},
+			{OptimizeHuffman: true},
+			{Progressive: true, Subsampling: Subsampling444},
+		} {
+			var buf bytes.Buffer
+			if err := Encode(&buf, m, o); err != nil {
+				t.Errorf("%T %+v: Encode: %v", m, o, err)
+			}
+		}
+	}
+}
+
+// TestWriterInvalidSubsampling tests that unknown subsampling values are
+// rejected rather than encoded as 4:2:0.
+func TestWriterInvalidSubsampling(t *testing.T) {
+	m := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for _, s := range []Subsampling{-1, 7} {
+		var buf bytes.Buffer
+		if err := Encode(&buf, m, &Options{Subsampling: s}); err == nil {
+			t.Errorf("Subsampling %d: Encode succeeded", s)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("Subsampling %d: wrote %d bytes", s, buf.Len())
+		}
+	}
+}
+
+// TestWriterOptimizeHuffman tests that optimized Huffman tables produce
+// smaller output than the standard tables.
+func TestWriterOptimizeHuffman(t *testing.T) {
+	m, err := readPng("../testdata/video-001.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var std, opt bytes.Buffer
+	if err := Encode(&std, m, &Options{Quality: 75}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Encode(&opt, m, &Options{Quality: 75, OptimizeHuffman: true}); err != nil {
+		t.Fatal(err)
+	}
+	if opt.Len() >= std.Len() {
+		t.Errorf("optimized size %d, want less than standard size %d", opt.Len(), std.Len())
+	}
+}
+
 // TestWriteGrayscale tests that a grayscale images survives a round-trip
 // through encode/decode cycle.
 func TestWriteGrayscale(t *testing.T) {
@@ -0,0 +1,418 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package png
+
+import (
+	"bufio"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"io"
+	"time"
+)
+
+// Disposal operations, as per the APNG spec. They specify how the area of a
+// frame is treated before rendering the next frame.
+const (
+	DisposeNone       = 0 // The canvas is left as is.
+	DisposeBackground = 1 // The area is cleared to fully transparent black.
+	DisposePrevious   = 2 // The area is reverted to its previous contents.
+)
+
+// Blend operations, as per the APNG spec. They specify how a frame is
+// rendered onto the canvas.
+const (
+	BlendSource = 0 // The frame replaces the area's contents.
+	BlendOver   = 1 // The frame is composited over the area's contents.
+)
+
+// APNG represents the possibly multiple frames of an animated PNG image.
+type APNG struct {
+	// Image holds the successive frames. The bounds of each frame are its
+	// position on the canvas.
+	Image []image.Image
+	// Delay holds the successive delay times, one per frame.
+	Delay []time.Duration
+	// Disposal holds the successive disposal operations, one per frame. If
+	// Disposal is nil, every frame uses DisposeNone.
+	Disposal []byte
+	// Blend holds the successive blend operations, one per frame. If Blend
+	// is nil, every frame uses BlendSource.
+	Blend []byte
+	// NumPlays is the number of times the animation is played. Zero means
+	// that it loops forever.
+	NumPlays int
+
+	// Default is the static image shown by decoders that do not support
+	// animation. If Default is nil, the first frame is the static image.
+	// Otherwise, the first frame is not shown by such decoders, and Default
+	// is not part of the animation.
+	Default image.Image
+
+	// Config is the canvas's color model and dimensions. When encoding, a
+	// zero Width and Height mean the dimensions of Default or, if it is nil,
+	// of the first frame.
+	Config image.Config
+}
+
+// frameControl is the contents of an fcTL chunk.
+type frameControl struct {
+	width, height int
+	x, y          int
+	delay         time.Duration
+	dispose       byte
+	blend         byte
+}
+
+// checkSequence reads the sequence number of an fcTL or fdAT chunk.
+// Sequence numbers start at zero and must not skip or repeat.
+func (d *decoder) checkSequence() error {
+	if _, err := io.ReadFull(d.r, d.tmp[:4]); err != nil {
+		return err
+	}
+	d.crc.Write(d.tmp[:4])
+	if binary.BigEndian.Uint32(d.tmp[:4]) != d.seq {
+		return FormatError("bad APNG sequence number")
+	}
+	d.seq++
+	return nil
+}
+
+func (d *decoder) parseacTL(length uint32) error {
+	if length != 8 {
+		return FormatError("bad acTL length")
+	}
+	if _, err := io.ReadFull(d.r, d.tmp[:8]); err != nil {
+		return err
+	}
+	d.crc.Write(d.tmp[:8])
+	d.numFrames = binary.BigEndian.Uint32(d.tmp[:4])
+	if d.numFrames == 0 {
+		return FormatError("bad acTL frame count")
+	}
+	d.apng.NumPlays = int(binary.BigEndian.Uint32(d.tmp[4:8]))
+	d.animated = true
+	return d.verifyChecksum()
+}
+
+func (d *decoder) parsefcTL(length uint32) error {
+	if length != 26 {
+		return FormatError("bad fcTL length")
+	}
+	if d.hasFrame {
+		return FormatError("fcTL chunk without frame data")
+	}
+	if err := d.checkSequence(); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(d.r, d.tmp[:22]); err != nil {
+		return err
+	}
+	d.crc.Write(d.tmp[:22])
+	f := frameControl{
+		width:   int(binary.BigEndian.Uint32(d.tmp[0:4])),
+		height:  int(binary.BigEndian.Uint32(d.tmp[4:8])),
+		x:       int(binary.BigEndian.Uint32(d.tmp[8:12])),
+		y:       int(binary.BigEndian.Uint32(d.tmp[12:16])),
+		dispose: d.tmp[20],
+		blend:   d.tmp[21],
+	}
+	num := time.Duration(binary.BigEndian.Uint16(d.tmp[16:18]))
+	den := time.Duration(binary.BigEndian.Uint16(d.tmp[18:20]))
+	if den == 0 {
+		den = 100
+	}
+	f.delay = num * time.Second / den
+	if f.width <= 0 || f.height <= 0 || f.x < 0 || f.y < 0 ||
+		f.x > d.width-f.width || f.y > d.height-f.height {
+		return FormatError("bad fcTL frame region")
+	}
+	if d.stage < dsSeenIDAT && (f.x != 0 || f.y != 0 || f.width != d.width || f.height != d.height) {
+		return FormatError("bad fcTL frame region")
+	}
+	if f.dispose > DisposePrevious || f.blend > BlendOver {
+		return FormatError("bad fcTL operation")
+	}
+	d.frame, d.hasFrame = f, true
+	return d.verifyChecksum()
+}
+
+func (d *decoder) parsefdAT(length uint32) error {
+	if !d.hasFrame {
+		return FormatError("fdAT chunk without fcTL")
+	}
+	if length < 4 {
+		return FormatError("bad fdAT length")
+	}
+	if err := d.checkSequence(); err != nil {
+		return err
+	}
+	d.idatLength = length - 4
+	// Frames are decoded like the default image, but with the frame's
+	// dimensions and with the data of consecutive fdAT chunks.
+	width, height := d.width, d.height
+	d.width, d.height, d.fdat = d.frame.width, d.frame.height, true
+	img, err := d.decode()
+	d.width, d.height, d.fdat = width, height, false
+	if err != nil {
+		return err
+	}
+	d.appendFrame(translate(img, image.Pt(d.frame.x, d.frame.y)))
+	return d.verifyChecksum()
+}
+
+// appendFrame appends the frame img, whose fcTL chunk is d.frame, to d.apng.
+func (d *decoder) appendFrame(img image.Image) {
+	a := d.apng
+	a.Image = append(a.Image, img)
+	a.Delay = append(a.Delay, d.frame.delay)
+	a.Disposal = append(a.Disposal, d.frame.dispose)
+	a.Blend = append(a.Blend, d.frame.blend)
+	d.hasFrame = false
+}
+
+// translate moves the image img, as returned by readImagePass, by p.
+func translate(img image.Image, p image.Point) image.Image {
+	switch m := img.(type) {
+	case *image.Gray:
+		m.Rect = m.Rect.Add(p)
+	case *image.Gray16:
+		m.Rect = m.Rect.Add(p)
+	case *image.NRGBA:
+		m.Rect = m.Rect.Add(p)
+	case *image.NRGBA64:
+		m.Rect = m.Rect.Add(p)
+	case *image.Paletted:
+		m.Rect = m.Rect.Add(p)
+	case *image.RGBA:
+		m.Rect = m.Rect.Add(p)
+	case *image.RGBA64:
+		m.Rect = m.Rect.Add(p)
+	}
+	return img
+}
+
+// DecodeAll reads a PNG image from r and returns its sequential frames and
+// timing information. An image without animation is returned as a single
+// frame.
+func DecodeAll(r io.Reader) (*APNG, error) {
+	d := &decoder{
+		r:    r,
+		crc:  crc32.NewIEEE(),
+		apng: new(APNG),
+	}
+	if err := d.checkHeader(); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	for d.stage != dsSeenIEND {
+		if err := d.parseChunk(); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+	}
+	a := d.apng
+	if !d.animated {
+		a.Image = []image.Image{d.img}
+		a.Delay = []time.Duration{0}
+		a.Disposal = []byte{DisposeNone}
+		a.Blend = []byte{BlendSource}
+	} else if uint32(len(a.Image)) != d.numFrames {
+		return nil, FormatError("wrong number of APNG frames")
+	}
+	a.Config = image.Config{
+		ColorModel: d.colorModel(),
+		Width:      d.width,
+		Height:     d.height,
+	}
+	return a, nil
+}
+
+// delayFraction returns the numerator and denominator of the fcTL delay
+// closest to t.
+func delayFraction(t time.Duration) (num, den uint16) {
+	if t <= 0 {
+		return 0, 100
+	}
+	// Prefer the hundredths of a second of GIF, then milliseconds.
+	for _, den := range []time.Duration{100, 1000} {
+		if unit := time.Second / den; t%unit == 0 && t/unit <= 0xffff {
+			return uint16(t / unit), uint16(den)
+		}
+	}
+	if t > 0xffff*time.Second {
+		t = 0xffff * time.Second
+	}
+	d := time.Duration(0xffff)
+	if t > time.Second {
+		d = 0xffff * time.Second / t
+	}
+	n := (t*d + time.Second/2) / time.Second
+	return uint16(n), uint16(d)
+}
+
+// mergeColorBits returns a color type and bit depth that can represent the
+// images encoded with a and b.
+func mergeColorBits(a, b int) int {
+	switch {
+	case a == b:
+		return a
+	case a < cbG16 && b < cbG16:
+		return cbTCA8
+	}
+	return cbTCA16
+}
+
+func samePalette(p, q color.Palette) bool {
+	if len(p) != len(q) {
+		return false
+	}
+	for i := range p {
+		r0, g0, b0, a0 := p[i].RGBA()
+		r1, g1, b1, a1 := q[i].RGBA()
+		if r0 != r1 || g0 != g1 || b0 != b1 || a0 != a1 {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *encoder) writeacTL(numFrames, numPlays int) {
+	writeUint32(e.tmp[0:4], uint32(numFrames))
+	writeUint32(e.tmp[4:8], uint32(numPlays))
+	e.writeChunk(e.tmp[:8], "acTL")
+}
+
+func (e *encoder) writefcTL(m image.Image, delay time.Duration, dispose, blend byte) {
+	b := m.Bounds()
+	num, den := delayFraction(delay)
+	writeUint32(e.tmp[0:4], e.seq)
+	writeUint32(e.tmp[4:8], uint32(b.Dx()))
+	writeUint32(e.tmp[8:12], uint32(b.Dy()))
+	writeUint32(e.tmp[12:16], uint32(b.Min.X))
+	writeUint32(e.tmp[16:20], uint32(b.Min.Y))
+	binary.BigEndian.PutUint16(e.tmp[20:22], num)
+	binary.BigEndian.PutUint16(e.tmp[22:24], den)
+	e.tmp[24] = dispose
+	e.tmp[25] = blend
+	e.writeChunk(e.tmp[:26], "fcTL")
+	e.seq++
+}
+
+// EncodeAll writes the frames of a to w in APNG format.
+func EncodeAll(w io.Writer, a *APNG) error {
+	var e Encoder
+	return e.EncodeAll(w, a)
+}
+
+// EncodeAll writes the frames of a to w in APNG format.
+func (enc *Encoder) EncodeAll(w io.Writer, a *APNG) error {
+	if len(a.Image) == 0 {
+		return FormatError("must provide at least one image")
+	}
+	if len(a.Image) != len(a.Delay) {
+		return FormatError("mismatched image and delay lengths")
+	}
+	if a.Disposal != nil && len(a.Image) != len(a.Disposal) {
+		return FormatError("mismatched image and disposal lengths")
+	}
+	if a.Blend != nil && len(a.Image) != len(a.Blend) {
+		return FormatError("mismatched image and blend lengths")
+	}
+	if a.NumPlays < 0 || int64(a.NumPlays) >= 1<<31 {
+		return FormatError("bad play count")
+	}
+
+	first := a.Default
+	if first == nil {
+		first = a.Image[0]
+	}
+	canvas := image.Rect(0, 0, a.Config.Width, a.Config.Height)
+	if canvas.Empty() {
+		canvas = first.Bounds()
+	}
+	if first.Bounds() != canvas || canvas.Min != (image.Point{}) {
+		return FormatError("first image does not fill the canvas")
+	}
+	mw, mh := int64(canvas.Dx()), int64(canvas.Dy())
+	if mw >= 1<<31 || mh >= 1<<31 {
+		return FormatError("invalid image size")
+	}
+
+	var e encoder
+	e.enc = enc
+	e.w = w
+	e.m = first
+
+	// All of the frames share the color type and bit depth of the image
+	// header, and the palette if any.
+	e.cb, e.pal = colorBits(first)
+	for _, m := range a.Image {
+		b := m.Bounds()
+		if b.Empty() || !b.In(canvas) {
+			return FormatError("frame is outside the canvas")
+		}
+		cb, pal := colorBits(m)
+		if cb == cbP8 && e.cb == cbP8 && !samePalette(pal, e.pal) {
+			cb = cbTCA8
+		}
+		e.cb = mergeColorBits(e.cb, cb)
+	}
+	if e.cb != cbP8 {
+		e.pal = nil
+	}
+
+	_, e.err = io.WriteString(w, pngHeader)
+	e.writeIHDR()
+	e.writeMetadata()
+	e.writeacTL(len(a.Image), a.NumPlays)
+	if e.pal != nil {
+		e.writePLTEAndTRNS(e.pal)
+	}
+	if a.Default != nil {
+		e.writeIDATs()
+	}
+	for i, m := range a.Image {
+		var dispose, blend byte
+		if a.Disposal != nil {
+			dispose = a.Disposal[i]
+		}
+		if a.Blend != nil {
+			blend = a.Blend[i]
+		}
+		if dispose > DisposePrevious || blend > BlendOver {
+			return FormatError("bad frame operation")
+		}
+		e.writefcTL(m, a.Delay[i], dispose, blend)
+		e.m = m
+		if i == 0 && a.Default == nil {
+			e.writeIDATs()
+		} else {
+			e.writefdATs()
+		}
+	}
+	e.writeIEND()
+	return e.err
+}
+
+// writefdATs writes the image data of the frame e.m to one or more fdAT
+// chunks, like writeIDATs.
+func (e *encoder) writefdATs() {
+	if e.err != nil {
+		return
+	}
+	e.fdat = true
+	bw := bufio.NewWriterSize(e, 1<<15)
+	e.err = writeImage(bw, e.m, e.cb, levelToZlib(e.enc.CompressionLevel), e.enc.Filter)
+	if e.err == nil {
+		e.err = bw.Flush()
+	}
+	e.fdat = false
+}
@@ -0,0 +1,221 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package png
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+	"time"
+)
+
+// chunks splits PNG data into its chunks, excluding the PNG header.
+func chunks(b []byte) [][]byte {
+	var cs [][]byte
+	for b = b[len(pngHeader):]; len(b) >= 12; {
+		n := 12 + int(binary.BigEndian.Uint32(b))
+		cs = append(cs, b[:n])
+		b = b[n:]
+	}
+	return cs
+}
+
+func testFrames() *APNG {
+	p := color.Palette{
+		color.RGBA{0x00, 0x00, 0x00, 0xff},
+		color.RGBA{0xff, 0x00, 0x00, 0xff},
+		color.NRGBA{0x00, 0xff, 0x00, 0x80},
+		color.RGBA{0x00, 0x00, 0x00, 0x00},
+	}
+	a := &APNG{
+		Delay:    []time.Duration{100 * time.Millisecond, 1234 * time.Millisecond, 5 * time.Second},
+		Disposal: []byte{DisposeNone, DisposeBackground, DisposePrevious},
+		Blend:    []byte{BlendSource, BlendOver, BlendSource},
+		NumPlays: 3,
+	}
+	for i, r := range []image.Rectangle{
+		image.Rect(0, 0, 20, 10),
+		image.Rect(3, 2, 13, 9),
+		image.Rect(19, 9, 20, 10),
+	} {
+		m := image.NewPaletted(r, p)
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				m.SetColorIndex(x, y, uint8(x+y+i)%4)
+			}
+		}
+		a.Image = append(a.Image, m)
+	}
+	return a
+}
+
+func TestAPNGRoundTrip(t *testing.T) {
+	a := testFrames()
+	var buf bytes.Buffer
+	if err := EncodeAll(&buf, a); err != nil {
+		t.Fatal(err)
+	}
+	b, err := DecodeAll(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Image) != len(a.Image) {
+		t.Fatalf("got %d frames, want %d", len(b.Image), len(a.Image))
+	}
+	for i := range a.Image {
+		if got, want := b.Image[i].Bounds(), a.Image[i].Bounds(); got != want {
+			t.Errorf("frame %d: got bounds %v, want %v", i, got, want)
+		}
+		if _, ok := b.Image[i].(*image.Paletted); !ok {
+			t.Errorf("frame %d: got %T, want *image.Paletted", i, b.Image[i])
+		}
+		if err := diff(a.Image[i], b.Image[i]); err != nil {
+			t.Errorf("frame %d: %v", i, err)
+		}
+		if b.Delay[i] != a.Delay[i] || b.Disposal[i] != a.Disposal[i] || b.Blend[i] != a.Blend[i] {
+			t.Errorf("frame %d: got %v %d %d, want %v %d %d", i,
+				b.Delay[i], b.Disposal[i], b.Blend[i], a.Delay[i], a.Disposal[i], a.Blend[i])
+		}
+	}
+	if b.NumPlays != 3 || b.Default != nil {
+		t.Errorf("got NumPlays %d, Default %v", b.NumPlays, b.Default)
+	}
+	if b.Config.Width != 20 || b.Config.Height != 10 {
+		t.Errorf("got canvas %dx%d, want 20x10", b.Config.Width, b.Config.Height)
+	}
+
+	// Decoders without animation support see the first frame.
+	m, err := Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := diff(a.Image[0], m); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAPNGDefaultImage(t *testing.T) {
+	a := testFrames()
+	def := image.NewGray(image.Rect(0, 0, 20, 10))
+	for i := range def.Pix {
+		def.Pix[i] = uint8(i)
+	}
+	a.Default = def
+	// The frames no longer share a color type with the default image.
+	var buf bytes.Buffer
+	if err := EncodeAll(&buf, a); err != nil {
+		t.Fatal(err)
+	}
+	b, err := DecodeAll(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Default == nil {
+		t.Fatal("no default image")
+	}
+	if err := diff(def, b.Default); err != nil {
+		t.Error(err)
+	}
+	if len(b.Image) != len(a.Image) {
+		t.Fatalf("got %d frames, want %d", len(b.Image), len(a.Image))
+	}
+	for i := range a.Image {
+		if err := diff(a.Image[i], b.Image[i]); err != nil {
+			t.Errorf("frame %d: %v", i, err)
+		}
+	}
+	m, err := Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := diff(def, m); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAPNGStatic(t *testing.T) {
+	m0, err := readPNG("testdata/pngsuite/basn2c08.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, m0); err != nil {
+		t.Fatal(err)
+	}
+	a, err := DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Image) != 1 || len(a.Delay) != 1 || a.Default != nil {
+		t.Fatalf("got %d frames, default %v", len(a.Image), a.Default)
+	}
+	if err := diff(m0, a.Image[0]); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAPNGErrors(t *testing.T) {
+	m := image.NewGray(image.Rect(0, 0, 4, 4))
+	testCases := []*APNG{
+		{},
+		{Image: []image.Image{m}},
+		{Image: []image.Image{m}, Delay: []time.Duration{0}, Blend: []byte{0, 0}},
+		{Image: []image.Image{m.SubImage(image.Rect(1, 1, 4, 4))}, Delay: []time.Duration{0}},
+		{Image: []image.Image{m, image.NewGray(image.Rect(2, 2, 6, 6))}, Delay: []time.Duration{0, 0}},
+		{Image: []image.Image{m}, Delay: []time.Duration{0}, Disposal: []byte{3}},
+	}
+	for i, a := range testCases {
+		if err := EncodeAll(&bytes.Buffer{}, a); err == nil {
+			t.Errorf("%d: got nil error, want non-nil", i)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeAll(&buf, testFrames()); err != nil {
+		t.Fatal(err)
+	}
+	// Drop the last fdAT chunk.
+	cs := chunks(buf.Bytes())
+	var b []byte
+	b = append(b, pngHeader...)
+	for i, c := range cs {
+		if i != len(cs)-2 {
+			b = append(b, c...)
+		}
+	}
+	if string(cs[len(cs)-2][4:8]) != "fdAT" {
+		t.Fatalf("got %q chunk, want fdAT", cs[len(cs)-2][4:8])
+	}
+	if _, err := DecodeAll(bytes.NewReader(b)); err == nil {
+		t.Error("missing frame: got nil error, want non-nil")
+	}
+	// The image is still a valid PNG.
+	if _, err := Decode(bytes.NewReader(b)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDelayFraction(t *testing.T) {
+	testCases := []struct {
+		d        time.Duration
+		num, den uint16
+	}{
+		{0, 0, 100},
+		{-time.Second, 0, 100},
+		{40 * time.Millisecond, 4, 100},
+		{1234 * time.Millisecond, 1234, 1000},
+		{time.Second / 3, 21845, 0xffff},
+		{10 * time.Minute, 60000, 100},
+		{100000 * time.Second, 0xffff, 1},
+	}
+	for _, tc := range testCases {
+		num, den := delayFraction(tc.d)
+		if num != tc.num || den != tc.den {
+			t.Errorf("%v: got %d/%d, want %d/%d", tc.d, num, den, tc.num, tc.den)
+		}
+	}
+}
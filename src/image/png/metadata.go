@@ -0,0 +1,323 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package png
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"unicode/utf8"
+)
+
+// Metadata is the ancillary metadata of a PNG image.
+type Metadata struct {
+	// Text holds the image's tEXt, zTXt and iTXt chunks, in the order they
+	// appear.
+	Text []Text
+
+	// ICCProfileName and ICCProfile are the name and the uncompressed data
+	// of the image's embedded ICC color profile (iCCP chunk), if any.
+	ICCProfileName string
+	ICCProfile     []byte
+
+	// Gamma is the image gamma of the gAMA chunk, such as 0.45455, or 0 if
+	// the image has no gAMA chunk.
+	Gamma float64
+}
+
+// A Text is a keyword and text pair of a textual chunk.
+type Text struct {
+	Keyword string
+	Text    string
+
+	// Compressed is whether the text is zlib compressed, as in zTXt
+	// chunks and compressed iTXt chunks.
+	Compressed bool
+
+	// International is whether the text is stored in an iTXt chunk.
+	// Unlike tEXt and zTXt chunks, which are limited to Latin-1, iTXt
+	// chunks hold UTF-8 text, and may also hold a language tag and a
+	// translation of the keyword.
+	International     bool
+	LanguageTag       string
+	TranslatedKeyword string
+}
+
+// readChunkData reads the remaining length bytes of a chunk, as well as its
+// checksum. Unlike io.ReadFull into a buffer of the given size, it only
+// allocates as much memory as there is data.
+func (d *decoder) readChunkData(length uint32) ([]byte, error) {
+	if length > 0x7fffffff {
+		return nil, FormatError("bad chunk length")
+	}
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, io.TeeReader(d.r, d.crc), int64(length)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return buf.Bytes(), d.verifyChecksum()
+}
+
+// parseText parses a tEXt, zTXt or iTXt chunk into d.meta.
+func (d *decoder) parseText(name string, length uint32) error {
+	data, err := d.readChunkData(length)
+	if err != nil {
+		return err
+	}
+	i := bytes.IndexByte(data, 0)
+	if i < 1 || i > 79 {
+		return FormatError("bad " + name + " keyword")
+	}
+	t := Text{Keyword: latin1ToUTF8(data[:i])}
+	data = data[i+1:]
+	switch name {
+	case "tEXt":
+		t.Text = latin1ToUTF8(data)
+	case "zTXt":
+		if len(data) < 1 || data[0] != 0 {
+			return UnsupportedError("zTXt compression method")
+		}
+		if data, err = inflate(data[1:]); err != nil {
+			return err
+		}
+		t.Text = latin1ToUTF8(data)
+		t.Compressed = true
+	case "iTXt":
+		if len(data) < 2 || data[0] > 1 || (data[0] == 1 && data[1] != 0) {
+			return FormatError("bad iTXt compression")
+		}
+		t.International = true
+		t.Compressed = data[0] == 1
+		data = data[2:]
+		var fields [2]string
+		for j := range fields {
+			i := bytes.IndexByte(data, 0)
+			if i < 0 {
+				return FormatError("bad iTXt chunk")
+			}
+			fields[j], data = string(data[:i]), data[i+1:]
+		}
+		t.LanguageTag, t.TranslatedKeyword = fields[0], fields[1]
+		if t.Compressed {
+			if data, err = inflate(data); err != nil {
+				return err
+			}
+		}
+		if !utf8.Valid(data) {
+			return FormatError("iTXt text is not UTF-8")
+		}
+		t.Text = string(data)
+	}
+	d.meta.Text = append(d.meta.Text, t)
+	return nil
+}
+
+// parseiCCP parses an iCCP chunk into d.meta.
+func (d *decoder) parseiCCP(length uint32) error {
+	data, err := d.readChunkData(length)
+	if err != nil {
+		return err
+	}
+	i := bytes.IndexByte(data, 0)
+	if i < 1 || i > 79 || i+1 >= len(data) {
+		return FormatError("bad iCCP chunk")
+	}
+	if data[i+1] != 0 {
+		return UnsupportedError("iCCP compression method")
+	}
+	profile, err := inflate(data[i+2:])
+	if err != nil {
+		return err
+	}
+	d.meta.ICCProfileName = latin1ToUTF8(data[:i])
+	d.meta.ICCProfile = profile
+	return nil
+}
+
+// parsegAMA parses a gAMA chunk into d.meta.
+func (d *decoder) parsegAMA(length uint32) error {
+	if length != 4 {
+		return FormatError("bad gAMA length")
+	}
+	if _, err := io.ReadFull(d.r, d.tmp[:4]); err != nil {
+		return err
+	}
+	d.crc.Write(d.tmp[:4])
+	d.meta.Gamma = float64(binary.BigEndian.Uint32(d.tmp[:4])) / 100000
+	return d.verifyChecksum()
+}
+
+// maxInflatedSize is the largest size the compressed data of a zTXt,
+// iTXt or iCCP chunk may decompress to. It keeps a small chunk from
+// expanding into an arbitrary amount of memory.
+const maxInflatedSize = 8 << 20
+
+// inflate returns the decompressed zlib data b.
+func inflate(b []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, FormatError(err.Error())
+	}
+	defer r.Close()
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(io.LimitReader(r, maxInflatedSize+1)); err != nil {
+		return nil, FormatError(err.Error())
+	}
+	if buf.Len() > maxInflatedSize {
+		return nil, FormatError("compressed chunk data too large")
+	}
+	return buf.Bytes(), nil
+}
+
+// deflate returns the zlib compressed data b.
+func deflate(b []byte, level int) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := zlib.NewWriterLevel(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+	w.Write(b)
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func latin1ToUTF8(b []byte) string {
+	r := make([]rune, len(b))
+	for i, c := range b {
+		r[i] = rune(c)
+	}
+	return string(r)
+}
+
+// utf8ToLatin1 returns the Latin-1 encoding of s, and whether s could be
+// encoded at all.
+func utf8ToLatin1(s string) ([]byte, bool) {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > 0xff {
+			return nil, false
+		}
+		b = append(b, byte(r))
+	}
+	return b, true
+}
+
+// validKeyword returns the Latin-1 encoding of the keyword k of a textual or
+// iCCP chunk, which must be 1 to 79 bytes long and must not contain NUL.
+func validKeyword(k string) ([]byte, bool) {
+	b, ok := utf8ToLatin1(k)
+	if !ok || len(b) < 1 || len(b) > 79 || bytes.IndexByte(b, 0) >= 0 {
+		return nil, false
+	}
+	return b, true
+}
+
+// writeMetadata writes the gAMA, iCCP and textual chunks of e.enc.Metadata.
+func (e *encoder) writeMetadata() {
+	m := e.enc.Metadata
+	if m == nil || e.err != nil {
+		return
+	}
+	level := levelToZlib(e.enc.CompressionLevel)
+	if level == zlib.NoCompression {
+		level = zlib.DefaultCompression
+	}
+	if m.Gamma != 0 {
+		g := m.Gamma*100000 + 0.5
+		if g < 1 || g >= 1<<32 {
+			e.err = FormatError("bad gamma")
+			return
+		}
+		writeUint32(e.tmp[:4], uint32(g))
+		e.writeChunk(e.tmp[:4], "gAMA")
+	}
+	if len(m.ICCProfile) > 0 {
+		name := m.ICCProfileName
+		if name == "" {
+			name = "ICC profile"
+		}
+		k, ok := validKeyword(name)
+		if !ok {
+			e.err = FormatError("bad ICC profile name")
+			return
+		}
+		profile, err := deflate(m.ICCProfile, level)
+		if err != nil {
+			e.err = err
+			return
+		}
+		e.writeChunk(append(append(k, 0, 0), profile...), "iCCP")
+	}
+	for _, t := range m.Text {
+		k, ok := validKeyword(t.Keyword)
+		if !ok {
+			e.err = FormatError("bad text keyword")
+			return
+		}
+		text, latin1 := utf8ToLatin1(t.Text)
+		name := "tEXt"
+		if t.International || !latin1 || t.LanguageTag != "" || t.TranslatedKeyword != "" {
+			name = "iTXt"
+			text = []byte(t.Text)
+		} else if t.Compressed {
+			name = "zTXt"
+		}
+		b := append(k, 0)
+		switch name {
+		case "zTXt":
+			b = append(b, 0)
+		case "iTXt":
+			if t.Compressed {
+				b = append(b, 1, 0)
+			} else {
+				b = append(b, 0, 0)
+			}
+			b = append(b, t.LanguageTag...)
+			b = append(b, 0)
+			b = append(b, t.TranslatedKeyword...)
+			b = append(b, 0)
+		}
+		if t.Compressed {
+			var err error
+			if text, err = deflate(text, level); err != nil {
+				e.err = err
+				return
+			}
+		}
+		e.writeChunk(append(b, text...), name)
+	}
+}
+
+// DecodeMetadata returns the metadata of a PNG image without decoding the
+// image data. Since textual chunks may follow the image data, it reads all
+// of the chunks of r.
+func DecodeMetadata(r io.Reader) (*Metadata, error) {
+	d := &decoder{
+		r:    r,
+		crc:  crc32.NewIEEE(),
+		meta: new(Metadata),
+	}
+	if err := d.checkHeader(); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	for d.stage != dsSeenIEND {
+		if err := d.parseChunk(); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+	}
+	return d.meta, nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package png
+
+import (
+	"bytes"
+	"image"
+	"reflect"
+	"testing"
+)
+
+func TestMetadataRoundTrip(t *testing.T) {
+	m0, err := readPNG("testdata/pngsuite/basn3p08.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	icc := make([]byte, 3000)
+	for i := range icc {
+		icc[i] = uint8(i / 10)
+	}
+	md := &Metadata{
+		Text: []Text{
+			{Keyword: "Title", Text: "Café"},
+			{Keyword: "Comment", Text: "compressed text", Compressed: true},
+			{Keyword: "Author", Text: "Gopher", International: true, LanguageTag: "en", TranslatedKeyword: "Auteur"},
+			{Keyword: "Description", Text: "Hello, 世界", International: true, Compressed: true},
+		},
+		ICCProfileName: "test profile",
+		ICCProfile:     icc,
+		Gamma:          0.45455,
+	}
+	var buf bytes.Buffer
+	if err := (&Encoder{Metadata: md}).Encode(&buf, m0); err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecodeMetadata(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, md) {
+		t.Errorf("got %+v, want %+v", got, md)
+	}
+
+	var names []string
+	for _, c := range chunks(buf.Bytes()) {
+		names = append(names, string(c[4:8]))
+	}
+	want := []string{"IHDR", "gAMA", "iCCP", "tEXt", "zTXt", "iTXt", "iTXt", "PLTE", "IDAT", "IEND"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("got chunks %q, want %q", names, want)
+	}
+
+	m1, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := diff(m0, m1); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMetadataNonLatin1(t *testing.T) {
+	// Text that tEXt chunks cannot hold is written to an iTXt chunk.
+	md := &Metadata{Text: []Text{{Keyword: "Title", Text: "世界"}}}
+	var buf bytes.Buffer
+	m := image.NewGray(image.Rect(0, 0, 1, 1))
+	if err := (&Encoder{Metadata: md}).Encode(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecodeMetadata(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Text{{Keyword: "Title", Text: "世界", International: true}}
+	if !reflect.DeepEqual(got.Text, want) {
+		t.Errorf("got %+v, want %+v", got.Text, want)
+	}
+}
+
+func TestMetadataErrors(t *testing.T) {
+	m := image.NewGray(image.Rect(0, 0, 1, 1))
+	testCases := []*Metadata{
+		{Text: []Text{{Keyword: ""}}},
+		{Text: []Text{{Keyword: string(bytes.Repeat([]byte("k"), 80))}}},
+		{Text: []Text{{Keyword: "世界"}}},
+		{Text: []Text{{Keyword: "a\x00b"}}},
+		{Gamma: -1},
+		{ICCProfile: []byte{1}, ICCProfileName: "世界"},
+	}
+	for i, md := range testCases {
+		if err := (&Encoder{Metadata: md}).Encode(&bytes.Buffer{}, m); err == nil {
+			t.Errorf("%d: got nil error, want non-nil", i)
+		}
+	}
+}
+
+func TestInflateLimit(t *testing.T) {
+	for _, n := range []int{maxInflatedSize, maxInflatedSize + 1} {
+		b, err := deflate(make([]byte, n), 9)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := inflate(b)
+		if n <= maxInflatedSize {
+			if err != nil || len(data) != n {
+				t.Errorf("%d bytes: got %d bytes, %v; want %d bytes, nil", n, len(data), err, n)
+			}
+		} else if _, ok := err.(FormatError); !ok {
+			t.Errorf("%d bytes: got %v; want FormatError", n, err)
+		}
+	}
+}
@@ -2,9 +2,11 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// Package png implements a PNG image decoder and encoder.
+// Package png implements a PNG image decoder and encoder, including the
+// frames of animated PNG (APNG) images.
 //
-// The PNG specification is at http://www.w3.org/TR/PNG/.
+// The PNG specification is at http://www.w3.org/TR/PNG/. The APNG
+// specification is at https://wiki.mozilla.org/APNG_Specification.
 package png
 
 import (
@@ -113,6 +115,23 @@ type decoder struct {
 	idatLength    uint32
 	tmp           [3 * 256]byte
 	interlace     int
+
+	// meta is non-nil when decoding metadata, in which case the image data
+	// is skipped.
+	meta *Metadata
+
+	// apng is non-nil when decoding all of the frames of an image.
+	// animated is whether the image has an acTL chunk, numFrames is its
+	// frame count, seq is the next sequence number, and frame is the last
+	// fcTL chunk, if hasFrame. fdat is whether the image data being read
+	// is in fdAT chunks rather than IDAT chunks.
+	apng      *APNG
+	animated  bool
+	numFrames uint32
+	seq       uint32
+	frame     frameControl
+	hasFrame  bool
+	fdat      bool
 }
 
 // A FormatError reports that the input is not a valid PNG.
@@ -279,8 +298,9 @@ func (d *decoder) parsetRNS(length uint32) error {
 	return d.verifyChecksum()
 }
 
-// Read presents one or more IDAT chunks as one continuous stream (minus the
-// intermediate chunk headers and footers). If the PNG data looked like:
+// Read presents one or more IDAT (or, for the frames of an animated image,
+// fdAT) chunks as one continuous stream (minus the intermediate chunk headers
+// and footers, and fdAT sequence numbers). If the PNG data looked like:
 //   ... len0 IDAT xxx crc0 len1 IDAT yy crc1 len2 IEND crc2
 // then this reader presents xxxyy. For well-formed PNG data, the decoder state
 // immediately before the first Read call is that d.r is positioned between the
@@ -301,11 +321,24 @@ func (d *decoder) Read(p []byte) (int, error) {
 			return 0, err
 		}
 		d.idatLength = binary.BigEndian.Uint32(d.tmp[:4])
-		if string(d.tmp[4:8]) != "IDAT" {
+		name := "IDAT"
+		if d.fdat {
+			name = "fdAT"
+		}
+		if string(d.tmp[4:8]) != name {
 			return 0, FormatError("not enough pixel data")
 		}
 		d.crc.Reset()
 		d.crc.Write(d.tmp[4:8])
+		if d.fdat {
+			if d.idatLength < 4 {
+				return 0, FormatError("bad fdAT length")
+			}
+			if err := d.checkSequence(); err != nil {
+				return 0, err
+			}
+			d.idatLength -= 4
+		}
 	}
 	if int(d.idatLength) < 0 {
 		return 0, UnsupportedError("IDAT chunk length overflow")
@@ -674,6 +707,14 @@ func (d *decoder) parseIDAT(length uint32) (err error) {
 	if err != nil {
 		return err
 	}
+	if d.animated {
+		// The default image is the first frame if it has an fcTL chunk.
+		if d.hasFrame {
+			d.appendFrame(d.img)
+		} else {
+			d.apng.Default = d.img
+		}
+	}
 	return d.verifyChecksum()
 }
 
@@ -726,6 +767,9 @@ func (d *decoder) parseChunk() error {
 			break
 		}
 		d.stage = dsSeenIDAT
+		if d.meta != nil {
+			break
+		}
 		return d.parseIDAT(length)
 	case "IEND":
 		if d.stage != dsSeenIDAT {
@@ -733,6 +777,51 @@ func (d *decoder) parseChunk() error {
 		}
 		d.stage = dsSeenIEND
 		return d.parseIEND(length)
+	case "acTL":
+		if d.apng == nil {
+			break
+		}
+		if d.stage < dsSeenIHDR || d.stage >= dsSeenIDAT || d.animated {
+			return chunkOrderError
+		}
+		return d.parseacTL(length)
+	case "fcTL":
+		if !d.animated {
+			break
+		}
+		return d.parsefcTL(length)
+	case "fdAT":
+		if !d.animated {
+			break
+		}
+		if d.stage != dsSeenIDAT {
+			return chunkOrderError
+		}
+		return d.parsefdAT(length)
+	case "tEXt", "zTXt", "iTXt":
+		if d.meta == nil {
+			break
+		}
+		if d.stage < dsSeenIHDR {
+			return chunkOrderError
+		}
+		return d.parseText(string(d.tmp[4:8]), length)
+	case "iCCP":
+		if d.meta == nil {
+			break
+		}
+		if d.stage < dsSeenIHDR {
+			return chunkOrderError
+		}
+		return d.parseiCCP(length)
+	case "gAMA":
+		if d.meta == nil {
+			break
+		}
+		if d.stage < dsSeenIHDR {
+			return chunkOrderError
+		}
+		return d.parsegAMA(length)
 	}
 	if length > 0x7fffffff {
 		return FormatError(fmt.Sprintf("Bad chunk length: %d", length))
@@ -795,6 +884,32 @@ func Decode(r io.Reader) (image.Image, error) {
 	return d.img, nil
 }
 
+// colorModel returns the color model of the decoded image.
+func (d *decoder) colorModel() color.Model {
+	var cm color.Model
+	switch d.cb {
+	case cbG1, cbG2, cbG4, cbG8:
+		cm = color.GrayModel
+	case cbGA8:
+		cm = color.NRGBAModel
+	case cbTC8:
+		cm = color.RGBAModel
+	case cbP1, cbP2, cbP4, cbP8:
+		cm = d.palette
+	case cbTCA8:
+		cm = color.NRGBAModel
+	case cbG16:
+		cm = color.Gray16Model
+	case cbGA16:
+		cm = color.NRGBA64Model
+	case cbTC16:
+		cm = color.RGBA64Model
+	case cbTCA16:
+		cm = color.NRGBA64Model
+	}
+	return cm
+}
+
 // DecodeConfig returns the color model and dimensions of a PNG image without
 // decoding the entire image.
 func DecodeConfig(r io.Reader) (image.Config, error) {
@@ -823,29 +938,8 @@ func DecodeConfig(r io.Reader) (image.Config, error) {
 			break
 		}
 	}
-	var cm color.Model
-	switch d.cb {
-	case cbG1, cbG2, cbG4, cbG8:
-		cm = color.GrayModel
-	case cbGA8:
-		cm = color.NRGBAModel
-	case cbTC8:
-		cm = color.RGBAModel
-	case cbP1, cbP2, cbP4, cbP8:
-		cm = d.palette
-	case cbTCA8:
-		cm = color.NRGBAModel
-	case cbG16:
-		cm = color.Gray16Model
-	case cbGA16:
-		cm = color.NRGBA64Model
-	case cbTC16:
-		cm = color.RGBA64Model
-	case cbTCA16:
-		cm = color.NRGBA64Model
-	}
 	return image.Config{
-		ColorModel: cm,
+		ColorModel: d.colorModel(),
 		Width:      d.width,
 		Height:     d.height,
 	}, nil
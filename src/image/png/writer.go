@@ -17,6 +17,12 @@ import (
 // Encoder configures encoding PNG images.
 type Encoder struct {
 	CompressionLevel CompressionLevel
+
+	// Filter is the strategy for filtering image rows before compression.
+	Filter FilterStrategy
+
+	// Metadata, if non-nil, is written to the image's ancillary chunks.
+	Metadata *Metadata
 }
 
 type encoder struct {
@@ -24,10 +30,17 @@ type encoder struct {
 	w      io.Writer
 	m      image.Image
 	cb     int
+	pal    color.Palette
 	err    error
 	header [8]byte
 	footer [4]byte
 	tmp    [4 * 256]byte
+
+	// seq is the next APNG sequence number, and fdat is whether the image
+	// data is written to fdAT chunks rather than IDAT chunks.
+	seq  uint32
+	fdat bool
+	fbuf []byte
 }
 
 type CompressionLevel int
@@ -38,8 +51,30 @@ const (
 	BestSpeed          CompressionLevel = -2
 	BestCompression    CompressionLevel = -3
 
-	// Positive CompressionLevel values are reserved to mean a numeric zlib
-	// compression level, although that is not implemented yet.
+	// Positive CompressionLevel values are numeric zlib compression
+	// levels, from 1 (fastest) to 9 (smallest). Levels above 9 are
+	// treated as 9.
+)
+
+// FilterStrategy selects the filter types applied to the image rows before
+// compression.
+type FilterStrategy int
+
+const (
+	// FilterDefault is FilterAdaptive, except for paletted images and
+	// NoCompression, where it is FilterNone.
+	FilterDefault FilterStrategy = iota
+	// FilterNone, FilterSub, FilterUp, FilterAverage and FilterPaeth apply
+	// the same filter type to every row.
+	FilterNone
+	FilterSub
+	FilterUp
+	FilterAverage
+	FilterPaeth
+	// FilterAdaptive chooses, for every row, the filter type that minimizes
+	// the sum of absolute differences. This is the same heuristic that
+	// libpng uses.
+	FilterAdaptive
 )
 
 // Big-endian.
@@ -172,7 +207,16 @@ func (e *encoder) writePLTEAndTRNS(p color.Palette) {
 // This method should only be called from writeIDATs (via writeImage).
 // No other code should treat an encoder as an io.Writer.
 func (e *encoder) Write(b []byte) (int, error) {
-	e.writeChunk(b, "IDAT")
+	if e.fdat {
+		// An fdAT chunk is an IDAT chunk prefixed with a sequence number.
+		e.fbuf = append(e.fbuf[:0], 0, 0, 0, 0)
+		writeUint32(e.fbuf, e.seq)
+		e.fbuf = append(e.fbuf, b...)
+		e.writeChunk(e.fbuf, "fdAT")
+		e.seq++
+	} else {
+		e.writeChunk(b, "IDAT")
+	}
 	if e.err != nil {
 		return 0, e.err
 	}
@@ -273,7 +317,28 @@ func filter(cr *[nFilter][]byte, pr []byte, bpp int) int {
 	return filter
 }
 
-func writeImage(w io.Writer, m image.Image, cb int, level int) error {
+// applyFilter writes the row cdat, filtered with the filter type f, to dst.
+// pdat is the previous row.
+func applyFilter(dst, cdat, pdat []byte, f, bpp int) {
+	for i := range cdat {
+		var a, c uint8
+		if i >= bpp {
+			a, c = cdat[i-bpp], pdat[i-bpp]
+		}
+		switch f {
+		case ftSub:
+			dst[i] = cdat[i] - a
+		case ftUp:
+			dst[i] = cdat[i] - pdat[i]
+		case ftAverage:
+			dst[i] = cdat[i] - uint8((int(a)+int(pdat[i]))/2)
+		case ftPaeth:
+			dst[i] = cdat[i] - paeth(a, pdat[i], c)
+		}
+	}
+}
+
+func writeImage(w io.Writer, m image.Image, cb int, level int, fs FilterStrategy) error {
 	zw, err := zlib.NewWriterLevel(w, level)
 	if err != nil {
 		return err
@@ -420,12 +485,20 @@ func writeImage(w io.Writer, m image.Image, cb int, level int) error {
 		}
 
 		// Apply the filter.
-		// Skip filter for NoCompression and paletted images (cbP8) as
-		// "filters are rarely useful on palette images" and will result
+		// By default, skip filter for NoCompression and paletted images (cbP8)
+		// as "filters are rarely useful on palette images" and will result
 		// in larger files (see http://www.libpng.org/pub/png/book/chapter09.html).
 		f := ftNone
-		if level != zlib.NoCompression && cb != cbP8 {
+		switch fs {
+		case FilterDefault:
+			if level != zlib.NoCompression && cb != cbP8 {
+				f = filter(&cr, pr, bpp)
+			}
+		case FilterAdaptive:
 			f = filter(&cr, pr, bpp)
+		case FilterSub, FilterUp, FilterAverage, FilterPaeth:
+			f = int(fs - FilterNone)
+			applyFilter(cr[f][1:], cr[0][1:], pr[1:], f, bpp)
 		}
 
 		// Write the compressed bytes.
@@ -446,7 +519,7 @@ func (e *encoder) writeIDATs() {
 	}
 	var bw *bufio.Writer
 	bw = bufio.NewWriterSize(e, 1<<15)
-	e.err = writeImage(bw, e.m, e.cb, levelToZlib(e.enc.CompressionLevel))
+	e.err = writeImage(bw, e.m, e.cb, levelToZlib(e.enc.CompressionLevel), e.enc.Filter)
 	if e.err != nil {
 		return
 	}
@@ -466,6 +539,13 @@ func levelToZlib(l CompressionLevel) int {
 	case BestCompression:
 		return zlib.BestCompression
 	default:
+		if l > zlib.BestCompression {
+			// zlib.NewWriterLevel rejects levels above zlib.BestCompression.
+			return zlib.BestCompression
+		}
+		if l > 0 {
+			return int(l)
+		}
 		return zlib.DefaultCompression
 	}
 }
@@ -493,41 +573,44 @@ func (enc *Encoder) Encode(w io.Writer, m image.Image) error {
 	e.enc = enc
 	e.w = w
 	e.m = m
+	e.cb, e.pal = colorBits(m)
+
+	_, e.err = io.WriteString(w, pngHeader)
+	e.writeIHDR()
+	e.writeMetadata()
+	if e.pal != nil {
+		e.writePLTEAndTRNS(e.pal)
+	}
+	e.writeIDATs()
+	e.writeIEND()
+	return e.err
+}
 
+// colorBits returns the color type and bit depth used to encode m, and its
+// palette if it is encoded as a paletted image.
+func colorBits(m image.Image) (int, color.Palette) {
 	var pal color.Palette
 	// cbP8 encoding needs PalettedImage's ColorIndexAt method.
 	if _, ok := m.(image.PalettedImage); ok {
 		pal, _ = m.ColorModel().(color.Palette)
 	}
 	if pal != nil {
-		e.cb = cbP8
-	} else {
-		switch m.ColorModel() {
-		case color.GrayModel:
-			e.cb = cbG8
-		case color.Gray16Model:
-			e.cb = cbG16
-		case color.RGBAModel, color.NRGBAModel, color.AlphaModel:
-			if opaque(m) {
-				e.cb = cbTC8
-			} else {
-				e.cb = cbTCA8
-			}
-		default:
-			if opaque(m) {
-				e.cb = cbTC16
-			} else {
-				e.cb = cbTCA16
-			}
+		return cbP8, pal
+	}
+	switch m.ColorModel() {
+	case color.GrayModel:
+		return cbG8, nil
+	case color.Gray16Model:
+		return cbG16, nil
+	case color.RGBAModel, color.NRGBAModel, color.AlphaModel:
+		if opaque(m) {
+			return cbTC8, nil
 		}
+		return cbTCA8, nil
+	default:
+		if opaque(m) {
+			return cbTC16, nil
+		}
+		return cbTCA16, nil
 	}
-
-	_, e.err = io.WriteString(w, pngHeader)
-	e.writeIHDR()
-	if pal != nil {
-		e.writePLTEAndTRNS(pal)
-	}
-	e.writeIDATs()
-	e.writeIEND()
-	return e.err
 }
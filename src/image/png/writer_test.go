@@ -100,6 +100,46 @@ func TestWriterLevels(t *testing.T) {
 	}
 }
 
+func TestWriterFilters(t *testing.T) {
+	m0, err := readPNG("testdata/pngsuite/basn6a08.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filters := []FilterStrategy{
+		FilterDefault, FilterNone, FilterSub, FilterUp,
+		FilterAverage, FilterPaeth, FilterAdaptive,
+	}
+	for _, level := range []CompressionLevel{NoCompression, BestSpeed, 1, 5, 9} {
+		for _, f := range filters {
+			var b bytes.Buffer
+			enc := &Encoder{CompressionLevel: level, Filter: f}
+			if err := enc.Encode(&b, m0); err != nil {
+				t.Errorf("level %d, filter %d: %v", level, f, err)
+				continue
+			}
+			m1, err := Decode(&b)
+			if err != nil {
+				t.Errorf("level %d, filter %d: %v", level, f, err)
+				continue
+			}
+			if err := diff(m0, m1); err != nil {
+				t.Errorf("level %d, filter %d: %v", level, f, err)
+			}
+		}
+	}
+
+	var b9, b10 bytes.Buffer
+	if err := (&Encoder{CompressionLevel: 9}).Encode(&b9, m0); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&Encoder{CompressionLevel: 10}).Encode(&b10, m0); err != nil {
+		t.Fatalf("compression level 10: %v", err)
+	}
+	if !bytes.Equal(b9.Bytes(), b10.Bytes()) {
+		t.Error("compression level 10 encoding differs from level 9")
+	}
+}
+
 func TestSubImage(t *testing.T) {
 	m0 := image.NewRGBA(image.Rect(0, 0, 256, 256))
 	for y := 0; y < 256; y++ {
@@ -42,4 +42,11 @@ type Trace struct {
 	// Dials made during DNS lookups. It may also be called multiple
 	// times, like ConnectStart.
 	ConnectDone func(network, addr string, err error)
+
+	// ConnectWinner is called once a Dial has established its
+	// connection, with the address of the connection attempt that
+	// succeeded. With DualStack or HappyEyeballs dialing, the
+	// other attempts are abandoned and report their outcome to
+	// ConnectDone.
+	ConnectWinner func(network, addr string)
 }
@@ -0,0 +1,29 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !386,!s390x
+
+package unix
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// Getsockopt calls the getsockopt system call for the option name at
+// level on the socket fd. The option value is stored in the *vallen
+// bytes at val, and *vallen is set to the length of the value.
+func Getsockopt(fd, level, name int, val unsafe.Pointer, vallen *uint32) error {
+	_, _, errno := syscall.Syscall6(syscall.SYS_GETSOCKOPT,
+		uintptr(fd),
+		uintptr(level),
+		uintptr(name),
+		uintptr(val),
+		uintptr(unsafe.Pointer(vallen)),
+		0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux,386 linux,s390x
+
+package unix
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// On these architectures socket operations are multiplexed through
+// the socketcall system call, which takes its arguments in an array.
+const socketcallGetsockopt = 15
+
+// Getsockopt calls the getsockopt system call for the option name at
+// level on the socket fd. The option value is stored in the *vallen
+// bytes at val, and *vallen is set to the length of the value.
+func Getsockopt(fd, level, name int, val unsafe.Pointer, vallen *uint32) error {
+	args := [5]uintptr{
+		uintptr(fd),
+		uintptr(level),
+		uintptr(name),
+		uintptr(val),
+		uintptr(unsafe.Pointer(vallen)),
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_SOCKETCALL, socketcallGetsockopt, uintptr(unsafe.Pointer(&args)), 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package unix
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// Mmsghdr is the Linux mmsghdr structure used by the recvmmsg and
+// sendmmsg system calls.
+type Mmsghdr struct {
+	Hdr syscall.Msghdr
+	Len uint32
+}
+
+// Recvmmsg calls the Linux recvmmsg system call, which receives up to
+// len(msgs) messages from the socket fd. It returns the number of
+// messages received.
+func Recvmmsg(fd int, msgs []Mmsghdr, flags int) (int, error) {
+	if len(msgs) == 0 {
+		return 0, nil
+	}
+	r1, _, errno := syscall.Syscall6(recvmmsgTrap,
+		uintptr(fd),
+		uintptr(unsafe.Pointer(&msgs[0])),
+		uintptr(len(msgs)),
+		uintptr(flags),
+		0, 0)
+	if errno != 0 {
+		return 0, errno
+	}
+	return int(r1), nil
+}
+
+// Sendmmsg calls the Linux sendmmsg system call, which sends up to
+// len(msgs) messages on the socket fd. It returns the number of
+// messages sent.
+func Sendmmsg(fd int, msgs []Mmsghdr, flags int) (int, error) {
+	if len(msgs) == 0 {
+		return 0, nil
+	}
+	r1, _, errno := syscall.Syscall6(sendmmsgTrap,
+		uintptr(fd),
+		uintptr(unsafe.Pointer(&msgs[0])),
+		uintptr(len(msgs)),
+		uintptr(flags),
+		0, 0)
+	if errno != 0 {
+		return 0, errno
+	}
+	return int(r1), nil
+}
@@ -0,0 +1,44 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package unix
+
+import "syscall"
+
+// Flags for the splice system call.
+const (
+	SPLICE_F_MOVE     = 0x1
+	SPLICE_F_NONBLOCK = 0x2
+)
+
+// Splice calls the Linux splice system call, which moves up to n
+// bytes from rfd to wfd at their current offsets. One of rfd and wfd
+// must refer to a pipe. Unlike syscall.Splice, it has the same
+// signature on all architectures.
+func Splice(rfd, wfd, n, flags int) (int, error) {
+	r1, _, errno := syscall.Syscall6(syscall.SYS_SPLICE,
+		uintptr(rfd), 0,
+		uintptr(wfd), 0,
+		uintptr(n),
+		uintptr(flags))
+	if errno != 0 {
+		return 0, errno
+	}
+	return int(r1), nil
+}
+
+// CopyFileRange calls the Linux copy_file_range system call, which
+// copies up to n bytes from rfd to wfd at their current offsets
+// within the kernel.
+func CopyFileRange(rfd, wfd, n int) (int, error) {
+	r1, _, errno := syscall.Syscall6(copyFileRangeTrap,
+		uintptr(rfd), 0,
+		uintptr(wfd), 0,
+		uintptr(n),
+		0)
+	if errno != 0 {
+		return 0, errno
+	}
+	return int(r1), nil
+}
@@ -0,0 +1,12 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package unix
+
+// Linux system call numbers that package syscall lacks.
+const (
+	recvmmsgTrap      uintptr = 337
+	sendmmsgTrap      uintptr = 345
+	copyFileRangeTrap uintptr = 377
+)
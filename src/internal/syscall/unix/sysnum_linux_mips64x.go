@@ -0,0 +1,14 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build mips64 mips64le
+
+package unix
+
+// Linux system call numbers that package syscall lacks.
+const (
+	recvmmsgTrap      uintptr = 5294
+	sendmmsgTrap      uintptr = 5302
+	copyFileRangeTrap uintptr = 5320
+)
@@ -309,7 +309,7 @@ func TestConfHostLookupOrder(t *testing.T) {
 	}
 	for _, tt := range tests {
 		for _, ht := range tt.hostTests {
-			gotOrder := tt.c.hostLookupOrder(ht.host)
+			gotOrder := tt.c.hostLookupOrder(nil, ht.host)
 			if gotOrder != ht.want {
 				t.Errorf("%s: hostLookupOrder(%q) = %v; want %v", tt.name, ht.host, gotOrder, ht.want)
 			}
@@ -0,0 +1,282 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !nacl,!plan9
+
+package net
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+// contextConn is implemented by TCPConn, UDPConn and UnixConn.
+type contextConn interface {
+	Conn
+	ReadContext(context.Context, []byte) (int, error)
+	WriteContext(context.Context, []byte) (int, error)
+}
+
+// contextListener is implemented by TCPListener and UnixListener.
+type contextListener interface {
+	Listener
+	AcceptContext(context.Context) (Conn, error)
+}
+
+func newContextConnPair(t *testing.T, network string) (contextConn, contextConn) {
+	ln, err := newLocalListener(network)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	ch := make(chan Conn, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			t.Error(err)
+		}
+		ch <- c
+	}()
+	c, err := Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := <-ch
+	if sc == nil {
+		c.Close()
+		t.FailNow()
+	}
+	return c.(contextConn), sc.(contextConn)
+}
+
+func TestReadContext(t *testing.T) {
+	for _, network := range []string{"tcp", "unix"} {
+		if !testableNetwork(network) {
+			continue
+		}
+		c, peer := newContextConnPair(t, network)
+		defer c.Close()
+		defer peer.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+		var b [8]byte
+		if n, err := c.ReadContext(ctx, b[:]); n != 0 || err != context.Canceled {
+			t.Fatalf("%s: got %d, %v; want 0, %v", network, n, err, context.Canceled)
+		}
+		if n, err := c.ReadContext(ctx, b[:]); n != 0 || err != context.Canceled {
+			t.Fatalf("%s: with done context got %d, %v; want 0, %v", network, n, err, context.Canceled)
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+		if n, err := c.ReadContext(ctx, b[:]); n != 0 || err != context.DeadlineExceeded {
+			t.Fatalf("%s: got %d, %v; want 0, %v", network, n, err, context.DeadlineExceeded)
+		}
+		cancel()
+
+		// The connection must still work, for plain reads and for
+		// reads with a context that is never done.
+		if _, err := peer.Write([]byte("hello")); err != nil {
+			t.Fatal(err)
+		}
+		if n, err := c.Read(b[:]); err != nil || string(b[:n]) != "hello" {
+			t.Fatalf("%s: got %q, %v; want %q, nil", network, b[:n], err, "hello")
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, func() { peer.Write([]byte("world")) })
+		if n, err := c.ReadContext(ctx, b[:]); err != nil || string(b[:n]) != "world" {
+			t.Fatalf("%s: got %q, %v; want %q, nil", network, b[:n], err, "world")
+		}
+		cancel()
+		if _, err := peer.Write([]byte("again")); err != nil {
+			t.Fatal(err)
+		}
+		if n, err := c.Read(b[:]); err != nil || string(b[:n]) != "again" {
+			t.Fatalf("%s: after cancel got %q, %v; want %q, nil", network, b[:n], err, "again")
+		}
+	}
+}
+
+func TestReadContextKeepsDeadline(t *testing.T) {
+	c, peer := newContextConnPair(t, "tcp")
+	defer c.Close()
+	defer peer.Close()
+
+	if err := c.SetReadDeadline(time.Now().Add(100 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	var b [8]byte
+	if _, err := c.ReadContext(ctx, b[:]); err != context.DeadlineExceeded {
+		t.Fatalf("got %v; want %v", err, context.DeadlineExceeded)
+	}
+	_, err := c.Read(b[:])
+	if nerr, ok := err.(Error); !ok || !nerr.Timeout() {
+		t.Fatalf("got %v; want timeout", err)
+	}
+}
+
+func TestReadContextConcurrentRead(t *testing.T) {
+	c, peer := newContextConnPair(t, "tcp")
+	defer c.Close()
+	defer peer.Close()
+
+	// A plain Read that is in progress when a ReadContext on the
+	// same connection gives up must not be interrupted.
+	type result struct {
+		s   string
+		err error
+	}
+	plain := make(chan result, 1)
+	go func() {
+		var b [8]byte
+		n, err := c.Read(b[:])
+		plain <- result{string(b[:n]), err}
+	}()
+	time.Sleep(10 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	withContext := make(chan error, 1)
+	go func() {
+		var b [8]byte
+		_, err := c.ReadContext(ctx, b[:])
+		withContext <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case r := <-plain:
+		t.Fatalf("Read returned %q, %v before any data was written", r.s, r.err)
+	default:
+	}
+	if _, err := peer.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if r := <-plain; r.err != nil || r.s != "hello" {
+		t.Fatalf("Read: got %q, %v; want %q, nil", r.s, r.err, "hello")
+	}
+	if err := <-withContext; err != context.DeadlineExceeded {
+		t.Fatalf("ReadContext: got %v; want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestReadContextUDP(t *testing.T) {
+	c, err := newLocalPacketListener("udp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	uc := c.(*UDPConn)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	var b [8]byte
+	if _, err := uc.ReadContext(ctx, b[:]); err != context.Canceled {
+		t.Fatalf("got %v; want %v", err, context.Canceled)
+	}
+
+	if _, err := uc.WriteTo([]byte("hello"), uc.LocalAddr()); err != nil {
+		t.Fatal(err)
+	}
+	if n, _, err := uc.ReadFrom(b[:]); err != nil || string(b[:n]) != "hello" {
+		t.Fatalf("got %q, %v; want %q, nil", b[:n], err, "hello")
+	}
+}
+
+func TestWriteContext(t *testing.T) {
+	c, peer := newContextConnPair(t, "tcp")
+	defer c.Close()
+	defer peer.Close()
+
+	// Write more than the socket buffers hold, with nobody reading.
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	b := make([]byte, 64<<20)
+	n, err := c.WriteContext(ctx, b)
+	if err != context.Canceled {
+		t.Fatalf("got %d, %v; want %v", n, err, context.Canceled)
+	}
+	if n >= len(b) {
+		t.Fatalf("wrote %d bytes; want fewer than %d", n, len(b))
+	}
+
+	// The connection must still work.
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.Write([]byte("hello"))
+		done <- err
+	}()
+	rest := n + len("hello")
+	buf := make([]byte, 1<<20)
+	var got []byte
+	for rest > 0 {
+		m, err := peer.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rest -= m
+		if rest < len("hello") {
+			got = append(got, buf[:m]...)
+		}
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if len(got) < len("hello") || string(got[len(got)-len("hello"):]) != "hello" {
+		t.Errorf("did not receive the final write")
+	}
+}
+
+func TestAcceptContext(t *testing.T) {
+	for _, network := range []string{"tcp", "unix"} {
+		if !testableNetwork(network) {
+			continue
+		}
+		ln, err := newLocalListener(network)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ln.Close()
+		if network == "unix" {
+			defer os.Remove(ln.Addr().String())
+		}
+		cl := ln.(contextListener)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+		if c, err := cl.AcceptContext(ctx); c != nil || err != context.Canceled {
+			t.Fatalf("%s: got %v, %v; want nil, %v", network, c, err, context.Canceled)
+		}
+
+		// The listener must still accept connections.
+		go func() {
+			c, err := Dial(ln.Addr().Network(), ln.Addr().String())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			c.Close()
+		}()
+		c, err := cl.AcceptContext(context.Background())
+		if err != nil {
+			t.Fatalf("%s: %v", network, err)
+		}
+		c.Close()
+	}
+}
+
+func TestContextIOClosed(t *testing.T) {
+	c, peer := newContextConnPair(t, "tcp")
+	peer.Close()
+	c.Close()
+	var b [8]byte
+	if _, err := c.ReadContext(context.Background(), b[:]); err == nil {
+		t.Error("ReadContext on closed connection succeeded")
+	}
+	if _, err := c.WriteContext(context.Background(), b[:]); err == nil {
+		t.Error("WriteContext on closed connection succeeded")
+	}
+}
@@ -7,6 +7,8 @@ package net
 import (
 	"context"
 	"internal/nettrace"
+	"sync"
+	"syscall"
 	"time"
 )
 
@@ -49,10 +51,32 @@ type Dialer struct {
 	DualStack bool
 
 	// FallbackDelay specifies the length of time to wait before
-	// spawning a fallback connection, when DualStack is enabled.
-	// If zero, a default delay of 300ms is used.
+	// spawning a fallback connection, when DualStack is enabled,
+	// or before starting the next connection attempt, when
+	// HappyEyeballs is enabled.
+	// If zero, a default delay of 300ms is used with DualStack and
+	// of 250ms with HappyEyeballs.
 	FallbackDelay time.Duration
 
+	// HappyEyeballs enables RFC 8305-compliant "Happy Eyeballs
+	// Version 2" dialing when the network is "tcp" and the
+	// destination is a host name. The IPv6 and IPv4 addresses of
+	// the host are looked up concurrently and tried in alternating
+	// order, starting with IPv6. A new connection attempt starts
+	// whenever the previous one fails or FallbackDelay passes
+	// without it completing, so several attempts may be in flight
+	// at once. The first connection established is returned and
+	// the other attempts are abandoned.
+	//
+	// HappyEyeballs takes precedence over DualStack.
+	HappyEyeballs bool
+
+	// ResolutionDelay specifies the length of time to wait for the
+	// IPv6 addresses of a host once its IPv4 addresses are known,
+	// when HappyEyeballs is enabled.
+	// If zero, a default delay of 50ms is used.
+	ResolutionDelay time.Duration
+
 	// KeepAlive specifies the keep-alive period for an active
 	// network connection.
 	// If zero, keep-alives are not enabled. Network protocols
@@ -68,6 +92,26 @@ type Dialer struct {
 	//
 	// Deprecated: Use DialContext instead.
 	Cancel <-chan struct{}
+
+	// If Control is not nil, it is called after creating the network
+	// connection but before actually dialing.
+	//
+	// Network and address parameters passed to Control method are not
+	// necessarily the ones passed to Dial. For example, passing "tcp" to Dial
+	// will cause the Control function to be called with "tcp4" or "tcp6".
+	Control func(network, address string, c syscall.RawConn) error
+
+	// ProxyHeader optionally specifies a PROXY protocol header to
+	// send on each connection as soon as it is established, ahead
+	// of any other data. See WriteProxyHeader for how a header
+	// without addresses is filled in. It is only supported on the
+	// stream networks "tcp", "tcp4", "tcp6", "unix" and "vsock";
+	// dialing other networks with a ProxyHeader fails.
+	ProxyHeader *ProxyHeader
+
+	// If multipathTCP is set, TCP dials use Multipath TCP where the
+	// system supports it.
+	multipathTCP bool
 }
 
 func minNonzeroTime(a, b time.Time) time.Time {
@@ -95,6 +139,23 @@ func (d *Dialer) deadline(ctx context.Context, now time.Time) (earliest time.Tim
 	return minNonzeroTime(earliest, d.Deadline)
 }
 
+// MultipathTCP reports whether the dialer asks for Multipath TCP when
+// dialing TCP networks.
+func (d *Dialer) MultipathTCP() bool {
+	return d.multipathTCP
+}
+
+// SetMultipathTCP directs Dial to use, or not to use, Multipath TCP
+// (MPTCP, RFC 8684) when dialing TCP networks. MPTCP lets a single
+// connection use several network paths at once.
+//
+// If the operating system or the peer does not support MPTCP, the
+// connection falls back to plain TCP. Use TCPConn.MultipathTCP to find
+// out whether MPTCP is in use. MPTCP is supported on Linux only.
+func (d *Dialer) SetMultipathTCP(use bool) {
+	d.multipathTCP = use
+}
+
 func (d *Dialer) resolver() *Resolver {
 	if d.Resolver != nil {
 		return d.Resolver
@@ -134,6 +195,20 @@ func (d *Dialer) fallbackDelay() time.Duration {
 	}
 }
 
+func (d *Dialer) attemptDelay() time.Duration {
+	if d.FallbackDelay > 0 {
+		return d.FallbackDelay
+	}
+	return 250 * time.Millisecond
+}
+
+func (d *Dialer) resolutionDelay() time.Duration {
+	if d.ResolutionDelay > 0 {
+		return d.ResolutionDelay
+	}
+	return 50 * time.Millisecond
+}
+
 func parseNetwork(ctx context.Context, net string) (afnet string, proto int, err error) {
 	i := last(net, ':')
 	if i < 0 { // no colon
@@ -142,6 +217,7 @@ func parseNetwork(ctx context.Context, net string) (afnet string, proto int, err
 		case "udp", "udp4", "udp6":
 		case "ip", "ip4", "ip6":
 		case "unix", "unixgram", "unixpacket":
+		case "vsock", "vsockpacket":
 		default:
 			return "", 0, UnknownNetworkError(net)
 		}
@@ -184,6 +260,15 @@ func (r *Resolver) resolveAddrList(ctx context.Context, op, network, addr string
 			return nil, &AddrError{Err: "mismatched local address type", Addr: hint.String()}
 		}
 		return addrList{addr}, nil
+	case "vsock", "vsockpacket":
+		addr, err := ResolveVsockAddr(afnet, addr)
+		if err != nil {
+			return nil, err
+		}
+		if op == "dial" && hint != nil && addr.Network() != hint.Network() {
+			return nil, &AddrError{Err: "mismatched local address type", Addr: hint.String()}
+		}
+		return addrList{addr}, nil
 	}
 	addrs, err := r.internetAddrList(ctx, afnet, addr)
 	if err != nil || op != "dial" || hint == nil {
@@ -239,8 +324,8 @@ func (r *Resolver) resolveAddrList(ctx context.Context, op, network, addr string
 //
 // Known networks are "tcp", "tcp4" (IPv4-only), "tcp6" (IPv6-only),
 // "udp", "udp4" (IPv4-only), "udp6" (IPv6-only), "ip", "ip4"
-// (IPv4-only), "ip6" (IPv6-only), "unix", "unixgram" and
-// "unixpacket".
+// (IPv4-only), "ip6" (IPv6-only), "unix", "unixgram",
+// "unixpacket", "vsock" and "vsockpacket".
 //
 // For TCP and UDP networks, addresses have the form host:port.
 // If host is a literal IPv6 address it must be enclosed
@@ -265,6 +350,9 @@ func (r *Resolver) resolveAddrList(ctx context.Context, op, network, addr string
 //	Dial("ip6:ipv6-icmp", "2001:db8::1")
 //
 // For Unix networks, the address must be a file system path.
+//
+// For vsock networks, which are supported on Linux only, the address
+// has the form cid:port; see ResolveVsockAddr.
 func Dial(network, address string) (Conn, error) {
 	var d Dialer
 	return d.Dial(network, address)
@@ -305,6 +393,9 @@ func (d *Dialer) DialContext(ctx context.Context, network, address string) (Conn
 	if ctx == nil {
 		panic("nil context")
 	}
+	if d.ProxyHeader != nil && !isStreamNetwork(network) {
+		return nil, &OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: errProxyHeaderNetwork}
+	}
 	deadline := d.deadline(ctx, time.Now())
 	if !deadline.IsZero() {
 		if d, ok := ctx.Deadline(); !ok || deadline.Before(d) {
@@ -332,45 +423,68 @@ func (d *Dialer) DialContext(ctx context.Context, network, address string) (Conn
 		shadow := *trace
 		shadow.ConnectStart = nil
 		shadow.ConnectDone = nil
+		shadow.ConnectWinner = nil
 		resolveCtx = context.WithValue(resolveCtx, nettrace.TraceKey{}, &shadow)
 	}
 
-	addrs, err := d.resolver().resolveAddrList(resolveCtx, "dial", network, address, d.LocalAddr)
-	if err != nil {
-		return nil, &OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: err}
-	}
-
 	dp := &dialParam{
 		Dialer:  *d,
 		network: network,
 		address: address,
 	}
 
-	var primaries, fallbacks addrList
-	if d.DualStack && network == "tcp" {
-		primaries, fallbacks = addrs.partition(isIPv4)
-	} else {
-		primaries = addrs
-	}
-
 	var c Conn
-	if len(fallbacks) > 0 {
-		c, err = dialParallel(ctx, dp, primaries, fallbacks)
+	var err error
+	if host, port, ok := happyEyeballsTarget(dp); ok {
+		c, err = dialHappyEyeballs(ctx, resolveCtx, dp, d.resolver(), host, port)
 	} else {
-		c, err = dialSerial(ctx, dp, primaries)
+		c, err = dialAddrList(ctx, resolveCtx, dp, d.resolver())
 	}
 	if err != nil {
 		return nil, err
 	}
+	if trace, _ := ctx.Value(nettrace.TraceKey{}).(*nettrace.Trace); trace != nil && trace.ConnectWinner != nil {
+		if ra := c.RemoteAddr(); ra != nil {
+			trace.ConnectWinner(network, ra.String())
+		}
+	}
 
 	if tc, ok := c.(*TCPConn); ok && d.KeepAlive > 0 {
 		setKeepAlive(tc.fd, true)
 		setKeepAlivePeriod(tc.fd, d.KeepAlive)
 		testHookSetKeepAlive()
 	}
+	if d.ProxyHeader != nil {
+		if err := WriteProxyHeader(c, d.ProxyHeader); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
 	return c, nil
 }
 
+// dialAddrList resolves the destination of dp up front and then
+// connects to the addresses found, racing the two address families
+// against each other when DualStack is enabled.
+func dialAddrList(ctx, resolveCtx context.Context, dp *dialParam, r *Resolver) (Conn, error) {
+	addrs, err := r.resolveAddrList(resolveCtx, "dial", dp.network, dp.address, dp.LocalAddr)
+	if err != nil {
+		return nil, &OpError{Op: "dial", Net: dp.network, Source: nil, Addr: nil, Err: err}
+	}
+
+	var primaries, fallbacks addrList
+	if dp.DualStack && dp.network == "tcp" {
+		primaries, fallbacks = addrs.partition(isIPv4)
+	} else {
+		primaries = addrs
+	}
+
+	if len(fallbacks) > 0 {
+		return dialParallel(ctx, dp, primaries, fallbacks)
+	}
+	return dialSerial(ctx, dp, primaries)
+}
+
 // dialParallel races two copies of dialSerial, giving the first a
 // head start. It returns the first established connection and
 // closes the others. Otherwise it returns an error from the first
@@ -447,6 +561,245 @@ func dialParallel(ctx context.Context, dp *dialParam, primaries, fallbacks addrL
 	}
 }
 
+// happyEyeballsTarget reports whether dp is to be dialed with
+// dialHappyEyeballs, and if so returns the host name and port to
+// connect to.
+func happyEyeballsTarget(dp *dialParam) (host, port string, ok bool) {
+	if !dp.HappyEyeballs || dp.network != "tcp" {
+		return "", "", false
+	}
+	if dp.LocalAddr != nil {
+		if _, ok := dp.LocalAddr.(*TCPAddr); !ok {
+			return "", "", false
+		}
+	}
+	host, port, err := SplitHostPort(dp.address)
+	if err != nil || host == "" {
+		return "", "", false
+	}
+	if ip := parseIPv4(host); ip != nil {
+		return "", "", false
+	}
+	if ip, _ := parseIPv6(host, true); ip != nil {
+		return "", "", false
+	}
+	return host, port, true
+}
+
+// dialHappyEyeballs connects to the IPv6 and IPv4 addresses of host
+// as described in RFC 8305. The two address families are looked up
+// concurrently and connection attempts start as soon as the IPv6
+// addresses are known, or once the resolution delay has passed after
+// the IPv4 addresses are known. Attempts alternate between the
+// families and are staggered by the attempt delay, or start
+// immediately when the previous attempt fails. It returns the first
+// established connection and closes the others. Otherwise it returns
+// the error from the first attempt, or from the lookup when no
+// address was found.
+func dialHappyEyeballs(ctx, resolveCtx context.Context, dp *dialParam, r *Resolver, host, port string) (Conn, error) {
+	portnum, err := r.LookupPort(resolveCtx, "tcp", port)
+	if err != nil {
+		return nil, &OpError{Op: "dial", Net: dp.network, Source: nil, Addr: nil, Err: err}
+	}
+
+	var families []string
+	if la, _ := dp.LocalAddr.(*TCPAddr); la != nil && !la.isWildcard() {
+		if la.IP.To4() != nil {
+			families = []string{"ip4"}
+		} else {
+			families = []string{"ip6"}
+		}
+	} else {
+		if supportsIPv6 {
+			families = append(families, "ip6")
+		}
+		if supportsIPv4 {
+			families = append(families, "ip4")
+		}
+	}
+
+	trace, _ := ctx.Value(nettrace.TraceKey{}).(*nettrace.Trace)
+	if trace != nil && trace.DNSStart != nil {
+		trace.DNSStart(host)
+	}
+
+	type lookupResult struct {
+		network string
+		addrs   []IPAddr
+		err     error
+	}
+	lookups := make(chan lookupResult, len(families))
+	lookupCtx, lookupCancel := context.WithCancel(resolveCtx)
+	defer lookupCancel()
+	for _, family := range families {
+		go func(network string) {
+			addrs, err := r.lookupIPAddrFamily(lookupCtx, network, host)
+			lookups <- lookupResult{network: network, addrs: addrs, err: err}
+		}(family)
+	}
+
+	type dialResult struct {
+		Conn
+		error
+	}
+	results := make(chan dialResult) // unbuffered
+
+	// On return, the attempts still in flight are canceled and
+	// waited for, so that none of them outlives the dial.
+	returned := make(chan struct{})
+	attemptCtx, attemptCancel := context.WithCancel(ctx)
+	var attempts sync.WaitGroup
+	defer func() {
+		close(returned)
+		attemptCancel()
+		attempts.Wait()
+	}()
+	startAttempt := func(ra Addr) {
+		defer attempts.Done()
+		c, err := dialSingle(attemptCtx, dp, ra)
+		select {
+		case results <- dialResult{Conn: c, error: err}:
+		case <-returned:
+			if c != nil {
+				c.Close()
+			}
+		}
+	}
+
+	var (
+		pending   = len(families) // lookups in flight
+		dialing   int             // connection attempts in flight
+		started   bool            // whether attempts have begun
+		found     []IPAddr
+		ras6      addrList
+		ras4      addrList
+		next6     = true
+		lookupErr error
+		firstErr  error
+	)
+	timer := time.NewTimer(0)
+	if !timer.Stop() {
+		<-timer.C
+	}
+	defer timer.Stop()
+	resetTimer := func(d time.Duration) {
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(d)
+	}
+	dnsDone := func(err error) {
+		if trace != nil && trace.DNSDone != nil {
+			trace.DNSDone(ipAddrsEface(found), false, err)
+		}
+	}
+	// nextAttempt starts a connection attempt to the next address,
+	// alternating between the address families, and arms the timer
+	// for the attempt after it.
+	nextAttempt := func() {
+		var ra Addr
+		if next6 && len(ras6) > 0 || len(ras4) == 0 {
+			ra, ras6 = ras6[0], ras6[1:]
+			next6 = false
+		} else {
+			ra, ras4 = ras4[0], ras4[1:]
+			next6 = true
+		}
+		dialing++
+		attempts.Add(1)
+		go startAttempt(ra)
+		if len(ras6)+len(ras4) > 0 {
+			resetTimer(dp.attemptDelay())
+		} else {
+			timer.Stop()
+		}
+	}
+	begin := func() {
+		started = true
+		dnsDone(nil)
+		nextAttempt()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			err := mapErr(ctx.Err())
+			if !started {
+				dnsDone(err)
+			}
+			return nil, &OpError{Op: "dial", Net: dp.network, Source: dp.LocalAddr, Addr: nil, Err: err}
+
+		case res := <-lookups:
+			pending--
+			if res.err != nil && lookupErr == nil {
+				lookupErr = res.err
+			}
+			var ras addrList
+			for _, ip := range res.addrs {
+				found = append(found, ip)
+				ras = append(ras, &TCPAddr{IP: ip.IP, Port: portnum, Zone: ip.Zone})
+			}
+			queued := len(ras6)+len(ras4) > 0
+			if res.network == "ip6" {
+				ras6 = append(ras6, ras...)
+			} else {
+				ras4 = append(ras4, ras...)
+			}
+			switch {
+			case len(ras6)+len(ras4) == 0:
+			case started && !queued && dialing == 0:
+				// The earlier attempts have all failed;
+				// go on with the late arrivals at once.
+				nextAttempt()
+			case started && !queued:
+				// The timer was left stopped when the
+				// queue ran dry; arm it again.
+				resetTimer(dp.attemptDelay())
+			case started:
+			case res.network == "ip6" || pending == 0:
+				begin()
+			default:
+				// Give the IPv6 addresses a little longer
+				// to arrive before settling for IPv4.
+				resetTimer(dp.resolutionDelay())
+			}
+
+		case <-timer.C:
+			if !started {
+				begin()
+			} else if len(ras6)+len(ras4) > 0 {
+				nextAttempt()
+			}
+
+		case res := <-results:
+			dialing--
+			if res.error == nil {
+				return res.Conn, nil
+			}
+			if firstErr == nil {
+				firstErr = res.error
+			}
+			if len(ras6)+len(ras4) > 0 {
+				nextAttempt()
+			}
+		}
+
+		if pending == 0 && dialing == 0 && len(ras6)+len(ras4) == 0 {
+			if started {
+				return nil, firstErr
+			}
+			if lookupErr == nil {
+				lookupErr = errNoSuitableAddress
+			}
+			dnsDone(lookupErr)
+			return nil, &OpError{Op: "dial", Net: dp.network, Source: nil, Addr: nil, Err: lookupErr}
+		}
+	}
+}
+
 // dialSerial connects to a list of addresses in sequence, returning
 // either the first successful connection, or the first error.
 func dialSerial(ctx context.Context, dp *dialParam, ras addrList) (Conn, error) {
@@ -507,16 +860,23 @@ func dialSingle(ctx context.Context, dp *dialParam, ra Addr) (c Conn, err error)
 	switch ra := ra.(type) {
 	case *TCPAddr:
 		la, _ := la.(*TCPAddr)
-		c, err = dialTCP(ctx, dp.network, la, ra)
+		if dp.multipathTCP {
+			c, err = dialMPTCP(ctx, dp.network, la, ra, dp.Control)
+		} else {
+			c, err = dialTCP(ctx, dp.network, la, ra, dp.Control)
+		}
 	case *UDPAddr:
 		la, _ := la.(*UDPAddr)
-		c, err = dialUDP(ctx, dp.network, la, ra)
+		c, err = dialUDP(ctx, dp.network, la, ra, dp.Control)
 	case *IPAddr:
 		la, _ := la.(*IPAddr)
-		c, err = dialIP(ctx, dp.network, la, ra)
+		c, err = dialIP(ctx, dp.network, la, ra, dp.Control)
 	case *UnixAddr:
 		la, _ := la.(*UnixAddr)
-		c, err = dialUnix(ctx, dp.network, la, ra)
+		c, err = dialUnix(ctx, dp.network, la, ra, dp.Control)
+	case *VsockAddr:
+		la, _ := la.(*VsockAddr)
+		c, err = dialVsock(ctx, dp.network, la, ra, dp.Control)
 	default:
 		return nil, &OpError{Op: "dial", Net: dp.network, Source: la, Addr: ra, Err: &AddrError{Err: "unexpected address type", Addr: dp.address}}
 	}
@@ -526,58 +886,115 @@ func dialSingle(ctx context.Context, dp *dialParam, ra Addr) (c Conn, err error)
 	return c, nil
 }
 
-// Listen announces on the local network address laddr.
-// The network net must be a stream-oriented network: "tcp", "tcp4",
-// "tcp6", "unix" or "unixpacket".
-// For TCP and UDP, the syntax of laddr is "host:port", like "127.0.0.1:8080".
-// If host is omitted, as in ":8080", Listen listens on all available interfaces
-// instead of just the interface with the given host address.
-// See Dial for more details about address syntax.
-func Listen(net, laddr string) (Listener, error) {
-	addrs, err := DefaultResolver.resolveAddrList(context.Background(), "listen", net, laddr, nil)
+// ListenConfig contains options for listening to an address.
+type ListenConfig struct {
+	// If Control is not nil, it is called after creating the network
+	// connection but before binding it to the operating system.
+	//
+	// Network and address parameters passed to Control method are not
+	// necessarily the ones passed to Listen. For example, passing "tcp" to
+	// Listen will cause the Control function to be called with "tcp4" or "tcp6".
+	Control func(network, address string, c syscall.RawConn) error
+
+	// If multipathTCP is set, TCP listeners use Multipath TCP where the
+	// system supports it.
+	multipathTCP bool
+}
+
+// MultipathTCP reports whether the listen config asks for Multipath
+// TCP when listening on TCP networks.
+func (lc *ListenConfig) MultipathTCP() bool {
+	return lc.multipathTCP
+}
+
+// SetMultipathTCP directs Listen to use, or not to use, Multipath TCP
+// (MPTCP, RFC 8684) when listening on TCP networks. Connections from
+// peers that do not support MPTCP fall back to plain TCP.
+//
+// If the operating system does not support MPTCP, Listen uses plain
+// TCP. MPTCP is supported on Linux only.
+func (lc *ListenConfig) SetMultipathTCP(use bool) {
+	lc.multipathTCP = use
+}
+
+// Listen announces on the local network address.
+//
+// See func Listen for a description of the network and address
+// parameters.
+func (lc *ListenConfig) Listen(ctx context.Context, network, address string) (Listener, error) {
+	addrs, err := DefaultResolver.resolveAddrList(ctx, "listen", network, address, nil)
 	if err != nil {
-		return nil, &OpError{Op: "listen", Net: net, Source: nil, Addr: nil, Err: err}
+		return nil, &OpError{Op: "listen", Net: network, Source: nil, Addr: nil, Err: err}
 	}
 	var l Listener
-	switch la := addrs.first(isIPv4).(type) {
+	la := addrs.first(isIPv4)
+	switch la := la.(type) {
 	case *TCPAddr:
-		l, err = ListenTCP(net, la)
+		if lc.multipathTCP {
+			l, err = listenMPTCP(ctx, network, la, lc.Control)
+		} else {
+			l, err = listenTCP(ctx, network, la, lc.Control)
+		}
 	case *UnixAddr:
-		l, err = ListenUnix(net, la)
+		l, err = listenUnix(ctx, network, la, lc.Control)
+	case *VsockAddr:
+		l, err = listenVsock(ctx, network, la, lc.Control)
 	default:
-		return nil, &OpError{Op: "listen", Net: net, Source: nil, Addr: la, Err: &AddrError{Err: "unexpected address type", Addr: laddr}}
+		return nil, &OpError{Op: "listen", Net: network, Source: nil, Addr: la, Err: &AddrError{Err: "unexpected address type", Addr: address}}
 	}
 	if err != nil {
-		return nil, err // l is non-nil interface containing nil pointer
+		return nil, &OpError{Op: "listen", Net: network, Source: nil, Addr: la, Err: err} // l is non-nil interface containing nil pointer
 	}
 	return l, nil
 }
 
-// ListenPacket announces on the local network address laddr.
-// The network net must be a packet-oriented network: "udp", "udp4",
-// "udp6", "ip", "ip4", "ip6" or "unixgram".
-// For TCP and UDP, the syntax of laddr is "host:port", like "127.0.0.1:8080".
-// If host is omitted, as in ":8080", ListenPacket listens on all available interfaces
-// instead of just the interface with the given host address.
-// See Dial for the syntax of laddr.
-func ListenPacket(net, laddr string) (PacketConn, error) {
-	addrs, err := DefaultResolver.resolveAddrList(context.Background(), "listen", net, laddr, nil)
+// ListenPacket announces on the local network address.
+//
+// See func ListenPacket for a description of the network and address
+// parameters.
+func (lc *ListenConfig) ListenPacket(ctx context.Context, network, address string) (PacketConn, error) {
+	addrs, err := DefaultResolver.resolveAddrList(ctx, "listen", network, address, nil)
 	if err != nil {
-		return nil, &OpError{Op: "listen", Net: net, Source: nil, Addr: nil, Err: err}
+		return nil, &OpError{Op: "listen", Net: network, Source: nil, Addr: nil, Err: err}
 	}
-	var l PacketConn
-	switch la := addrs.first(isIPv4).(type) {
+	var c PacketConn
+	la := addrs.first(isIPv4)
+	switch la := la.(type) {
 	case *UDPAddr:
-		l, err = ListenUDP(net, la)
+		c, err = listenUDP(ctx, network, la, lc.Control)
 	case *IPAddr:
-		l, err = ListenIP(net, la)
+		c, err = listenIP(ctx, network, la, lc.Control)
 	case *UnixAddr:
-		l, err = ListenUnixgram(net, la)
+		c, err = listenUnixgram(ctx, network, la, lc.Control)
 	default:
-		return nil, &OpError{Op: "listen", Net: net, Source: nil, Addr: la, Err: &AddrError{Err: "unexpected address type", Addr: laddr}}
+		return nil, &OpError{Op: "listen", Net: network, Source: nil, Addr: la, Err: &AddrError{Err: "unexpected address type", Addr: address}}
 	}
 	if err != nil {
-		return nil, err // l is non-nil interface containing nil pointer
+		return nil, &OpError{Op: "listen", Net: network, Source: nil, Addr: la, Err: err} // c is non-nil interface containing nil pointer
 	}
-	return l, nil
+	return c, nil
+}
+
+// Listen announces on the local network address laddr.
+// The network net must be a stream-oriented network: "tcp", "tcp4",
+// "tcp6", "unix", "unixpacket", "vsock" or "vsockpacket".
+// For TCP and UDP, the syntax of laddr is "host:port", like "127.0.0.1:8080".
+// If host is omitted, as in ":8080", Listen listens on all available interfaces
+// instead of just the interface with the given host address.
+// See Dial for more details about address syntax.
+func Listen(net, laddr string) (Listener, error) {
+	var lc ListenConfig
+	return lc.Listen(context.Background(), net, laddr)
+}
+
+// ListenPacket announces on the local network address laddr.
+// The network net must be a packet-oriented network: "udp", "udp4",
+// "udp6", "ip", "ip4", "ip6" or "unixgram".
+// For TCP and UDP, the syntax of laddr is "host:port", like "127.0.0.1:8080".
+// If host is omitted, as in ":8080", ListenPacket listens on all available interfaces
+// instead of just the interface with the given host address.
+// See Dial for the syntax of laddr.
+func ListenPacket(net, laddr string) (PacketConn, error) {
+	var lc ListenConfig
+	return lc.ListenPacket(context.Background(), net, laddr)
 }
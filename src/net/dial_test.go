@@ -7,9 +7,12 @@ package net
 import (
 	"bufio"
 	"context"
+	"errors"
+	"internal/nettrace"
 	"internal/testenv"
 	"io"
 	"net/internal/socktest"
+	"reflect"
 	"runtime"
 	"sync"
 	"testing"
@@ -192,7 +195,7 @@ const (
 // more quickly than expected. This test hook prevents dialTCP from returning
 // before the deadline.
 func slowDialTCP(ctx context.Context, net string, laddr, raddr *TCPAddr) (*TCPConn, error) {
-	c, err := doDialTCP(ctx, net, laddr, raddr)
+	c, err := doDialTCP(ctx, net, laddr, raddr, nil)
 	if ParseIP(slowDst4).Equal(raddr.IP) || ParseIP(slowDst6).Equal(raddr.IP) {
 		// Wait for the deadline, or indefinitely if none exists.
 		<-ctx.Done()
@@ -514,7 +517,7 @@ func TestDialParallelSpuriousConnection(t *testing.T) {
 		// Now ignore the provided context (which will be canceled) and use a
 		// different one to make sure this completes with a valid connection,
 		// which we hope to be closed below:
-		return doDialTCP(context.Background(), net, laddr, raddr)
+		return doDialTCP(context.Background(), net, laddr, raddr, nil)
 	}
 
 	d := Dialer{
@@ -935,3 +938,197 @@ func TestCancelAfterDial(t *testing.T) {
 		try()
 	}
 }
+
+const (
+	failDst4 = "198.18.0.253"
+	failDst6 = "2001:2::253"
+)
+
+var errHappyEyeballsRefused = errors.New("connection refused")
+
+// happyEyeballsDialTCP dials the loopback addresses for real, blocks
+// on slowDst4 and slowDst6 until the dial is abandoned and refuses
+// failDst4 and failDst6 at once.
+func happyEyeballsDialTCP(ctx context.Context, net string, laddr, raddr *TCPAddr) (*TCPConn, error) {
+	switch {
+	case raddr.IP.Equal(ParseIP(slowDst4)), raddr.IP.Equal(ParseIP(slowDst6)):
+		<-ctx.Done()
+		return nil, mapErr(ctx.Err())
+	case raddr.IP.Equal(ParseIP(failDst4)), raddr.IP.Equal(ParseIP(failDst6)):
+		return nil, errHappyEyeballsRefused
+	}
+	return doDialTCP(ctx, net, laddr, raddr, nil)
+}
+
+var dialerHappyEyeballsTests = []struct {
+	ip6, ip4       []string
+	delay6, delay4 time.Duration // lookup delays
+	connects       []string      // hosts of the connection attempts, in order
+	expectElapsed  time.Duration
+}{
+	// IPv6 is preferred.
+	{[]string{"::1"}, []string{"127.0.0.1"}, 0, 0, []string{"::1"}, 0},
+	// A refused attempt is followed by the next one at once.
+	{[]string{failDst6}, []string{"127.0.0.1"}, 0, 0, []string{failDst6, "127.0.0.1"}, 0},
+	// A stalled attempt is followed by the next one after the
+	// attempt delay, alternating between the address families.
+	{[]string{slowDst6, "::1"}, []string{slowDst4}, 0, 0, []string{slowDst6, slowDst4, "::1"}, 2 * happyEyeballsAttemptDelay},
+	// IPv4 waits for IPv6 during the resolution delay ...
+	{[]string{"::1"}, []string{"127.0.0.1"}, happyEyeballsResolutionDelay / 2, 0, []string{"::1"}, happyEyeballsResolutionDelay / 2},
+	// ... but no longer.
+	{[]string{"::1"}, []string{"127.0.0.1"}, time.Hour, 0, []string{"127.0.0.1"}, happyEyeballsResolutionDelay},
+	// Addresses that arrive late are still tried.
+	{[]string{"::1"}, []string{failDst4}, 2 * happyEyeballsResolutionDelay, 0, []string{failDst4, "::1"}, 2 * happyEyeballsResolutionDelay},
+	// A family without addresses is skipped.
+	{nil, []string{"127.0.0.1"}, 0, 0, []string{"127.0.0.1"}, 0},
+}
+
+const (
+	happyEyeballsAttemptDelay    = 200 * time.Millisecond
+	happyEyeballsResolutionDelay = 200 * time.Millisecond
+)
+
+func TestDialerHappyEyeballs(t *testing.T) {
+	if !supportsIPv4 || !supportsIPv6 {
+		t.Skip("both IPv4 and IPv6 are required")
+	}
+
+	origTestHookLookupIPFamily := testHookLookupIPFamily
+	defer func() { testHookLookupIPFamily = origTestHookLookupIPFamily }()
+	origTestHookDialTCP := testHookDialTCP
+	defer func() { testHookDialTCP = origTestHookDialTCP }()
+	testHookDialTCP = happyEyeballsDialTCP
+
+	handler := func(dss *dualStackServer, ln Listener) {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}
+	dss, err := newDualStackServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dss.teardown()
+	if err := dss.buildup(handler); err != nil {
+		t.Fatal(err)
+	}
+
+	d := &Dialer{
+		HappyEyeballs:   true,
+		FallbackDelay:   happyEyeballsAttemptDelay,
+		ResolutionDelay: happyEyeballsResolutionDelay,
+	}
+	const tolerance = 95 * time.Millisecond
+	for i, tt := range dialerHappyEyeballsTests {
+		testHookLookupIPFamily = func(ctx context.Context, fn func(context.Context, string) ([]IPAddr, error), network, host string) ([]IPAddr, error) {
+			ips, delay := tt.ip4, tt.delay4
+			if network == "ip6" {
+				ips, delay = tt.ip6, tt.delay6
+			}
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			var addrs []IPAddr
+			for _, ip := range ips {
+				addrs = append(addrs, IPAddr{IP: ParseIP(ip)})
+			}
+			return addrs, nil
+		}
+
+		var mu sync.Mutex
+		var starts []string
+		var winner string
+		trace := &nettrace.Trace{
+			ConnectStart: func(network, addr string) {
+				mu.Lock()
+				starts = append(starts, addr)
+				mu.Unlock()
+			},
+			ConnectWinner: func(network, addr string) {
+				winner = addr
+			},
+		}
+		ctx := context.WithValue(context.Background(), nettrace.TraceKey{}, trace)
+
+		start := time.Now()
+		c, err := d.DialContext(ctx, "tcp", JoinHostPort("happy.example", dss.port))
+		elapsed := time.Since(start)
+		if err != nil {
+			t.Errorf("#%d: %v", i, err)
+			continue
+		}
+		c.Close()
+
+		mu.Lock()
+		var want []string
+		for _, host := range tt.connects {
+			want = append(want, JoinHostPort(host, dss.port))
+		}
+		if !reflect.DeepEqual(starts, want) {
+			t.Errorf("#%d: got connection attempts %v; want %v", i, starts, want)
+		}
+		mu.Unlock()
+		if want := want[len(want)-1]; winner != want {
+			t.Errorf("#%d: got winner %s; want %s", i, winner, want)
+		}
+		if elapsed < tt.expectElapsed || elapsed > tt.expectElapsed+tolerance {
+			t.Errorf("#%d: got %v; want %v to %v", i, elapsed, tt.expectElapsed, tt.expectElapsed+tolerance)
+		}
+	}
+}
+
+func TestDialerHappyEyeballsError(t *testing.T) {
+	if !supportsIPv4 || !supportsIPv6 {
+		t.Skip("both IPv4 and IPv6 are required")
+	}
+
+	origTestHookLookupIPFamily := testHookLookupIPFamily
+	defer func() { testHookLookupIPFamily = origTestHookLookupIPFamily }()
+	origTestHookDialTCP := testHookDialTCP
+	defer func() { testHookDialTCP = origTestHookDialTCP }()
+	testHookDialTCP = happyEyeballsDialTCP
+
+	d := &Dialer{HappyEyeballs: true}
+
+	testHookLookupIPFamily = func(ctx context.Context, fn func(context.Context, string) ([]IPAddr, error), network, host string) ([]IPAddr, error) {
+		if network == "ip6" {
+			return []IPAddr{{IP: ParseIP(failDst6)}}, nil
+		}
+		return []IPAddr{{IP: ParseIP(failDst4)}}, nil
+	}
+	_, err := d.Dial("tcp", "happy.example:80")
+	if err, ok := err.(*OpError); !ok || err.Err != errHappyEyeballsRefused || !err.Addr.(*TCPAddr).IP.Equal(ParseIP(failDst6)) {
+		t.Errorf("got %v; want refused error for first attempt to %s", err, failDst6)
+	}
+
+	lookupErr := &DNSError{Err: errNoSuchHost.Error(), Name: "happy.example"}
+	testHookLookupIPFamily = func(ctx context.Context, fn func(context.Context, string) ([]IPAddr, error), network, host string) ([]IPAddr, error) {
+		return nil, lookupErr
+	}
+	_, err = d.Dial("tcp", "happy.example:80")
+	if err, ok := err.(*OpError); !ok || err.Err != lookupErr {
+		t.Errorf("got %v; want %v", err, lookupErr)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	testHookLookupIPFamily = func(ctx context.Context, fn func(context.Context, string) ([]IPAddr, error), network, host string) ([]IPAddr, error) {
+		if network == "ip6" {
+			return []IPAddr{{IP: ParseIP(slowDst6)}}, nil
+		}
+		return []IPAddr{{IP: ParseIP(slowDst4)}}, nil
+	}
+	_, err = d.DialContext(ctx, "tcp", "happy.example:80")
+	if perr := parseDialError(err); perr != nil {
+		t.Fatal(perr)
+	}
+	if nerr, ok := err.(Error); !ok || !nerr.Timeout() {
+		t.Errorf("got %v; want timeout error", err)
+	}
+}
@@ -140,9 +140,52 @@ func (d *Dialer) dialDNS(ctx context.Context, network, server string) (dnsConn,
 	panic("unreachable")
 }
 
+// A resolverDialer is a dnsDialer that uses the Dial function of a
+// Resolver.
+type resolverDialer func(ctx context.Context, network, address string) (Conn, error)
+
+func (f resolverDialer) dialDNS(ctx context.Context, network, server string) (dnsConn, error) {
+	c, err := f(ctx, network, server)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	if _, ok := c.(PacketConn); ok {
+		return &dnsPacketConn{c}, nil
+	}
+	return &dnsStreamConn{c}, nil
+}
+
+// dnsPacketConn implements the dnsConn interface for a packet-oriented
+// connection returned by Resolver.Dial.
+type dnsPacketConn struct {
+	Conn
+}
+
+func (c *dnsPacketConn) dnsRoundTrip(query *dnsMsg) (*dnsMsg, error) {
+	return dnsRoundTripUDP(c, query)
+}
+
+// dnsStreamConn implements the dnsConn interface for a stream-oriented
+// connection returned by Resolver.Dial.
+type dnsStreamConn struct {
+	Conn
+}
+
+func (c *dnsStreamConn) dnsRoundTrip(query *dnsMsg) (*dnsMsg, error) {
+	return dnsRoundTripTCP(c, query)
+}
+
+// dnsDialer returns the dialer used to connect to DNS servers.
+func (r *Resolver) dnsDialer() dnsDialer {
+	if r != nil && r.Dial != nil {
+		return resolverDialer(r.Dial)
+	}
+	return testHookDNSDialer()
+}
+
 // exchange sends a query on the connection and hopes for a response.
-func exchange(ctx context.Context, server, name string, qtype uint16, timeout time.Duration) (*dnsMsg, error) {
-	d := testHookDNSDialer()
+func (r *Resolver) exchange(ctx context.Context, server, name string, qtype uint16, timeout time.Duration) (*dnsMsg, error) {
+	d := r.dnsDialer()
 	out := dnsMsg{
 		dnsMsgHdr: dnsMsgHdr{
 			recursion_desired: true,
@@ -181,7 +224,7 @@ func exchange(ctx context.Context, server, name string, qtype uint16, timeout ti
 
 // Do a lookup for a single name, which must be rooted
 // (otherwise answer will not find the answers).
-func tryOneName(ctx context.Context, cfg *dnsConfig, name string, qtype uint16) (string, []dnsRR, error) {
+func (r *Resolver) tryOneName(ctx context.Context, cfg *dnsConfig, name string, qtype uint16) (string, []dnsRR, error) {
 	var lastErr error
 	serverOffset := cfg.serverOffset()
 	sLen := uint32(len(cfg.servers))
@@ -190,7 +233,7 @@ func tryOneName(ctx context.Context, cfg *dnsConfig, name string, qtype uint16)
 		for j := uint32(0); j < sLen; j++ {
 			server := cfg.servers[(serverOffset+j)%sLen]
 
-			msg, err := exchange(ctx, server, name, qtype, cfg.timeout)
+			msg, err := r.exchange(ctx, server, name, qtype, cfg.timeout)
 			if err != nil {
 				lastErr = &DNSError{
 					Err:    err.Error(),
@@ -314,7 +357,7 @@ func (conf *resolverConfig) releaseSema() {
 	<-conf.ch
 }
 
-func lookup(ctx context.Context, name string, qtype uint16) (cname string, rrs []dnsRR, err error) {
+func (r *Resolver) lookup(ctx context.Context, name string, qtype uint16) (cname string, rrs []dnsRR, err error) {
 	if !isDomainName(name) {
 		return "", nil, &DNSError{Err: "invalid domain name", Name: name}
 	}
@@ -323,7 +366,7 @@ func lookup(ctx context.Context, name string, qtype uint16) (cname string, rrs [
 	conf := resolvConf.dnsConfig
 	resolvConf.mu.RUnlock()
 	for _, fqdn := range conf.nameList(name) {
-		cname, rrs, err = tryOneName(ctx, conf, fqdn, qtype)
+		cname, rrs, err = r.tryOneName(ctx, conf, fqdn, qtype)
 		if err == nil {
 			break
 		}
@@ -418,11 +461,11 @@ func (o hostLookupOrder) String() string {
 // Normally we let cgo use the C library resolver instead of
 // depending on our lookup code, so that Go and C get the same
 // answers.
-func goLookupHost(ctx context.Context, name string) (addrs []string, err error) {
-	return goLookupHostOrder(ctx, name, hostLookupFilesDNS)
+func (r *Resolver) goLookupHost(ctx context.Context, name string) (addrs []string, err error) {
+	return r.goLookupHostOrder(ctx, name, hostLookupFilesDNS)
 }
 
-func goLookupHostOrder(ctx context.Context, name string, order hostLookupOrder) (addrs []string, err error) {
+func (r *Resolver) goLookupHostOrder(ctx context.Context, name string, order hostLookupOrder) (addrs []string, err error) {
 	if order == hostLookupFilesDNS || order == hostLookupFiles {
 		// Use entries from /etc/hosts if they match.
 		addrs = lookupStaticHost(name)
@@ -430,7 +473,7 @@ func goLookupHostOrder(ctx context.Context, name string, order hostLookupOrder)
 			return
 		}
 	}
-	ips, err := goLookupIPOrder(ctx, name, order)
+	ips, err := r.goLookupIPOrder(ctx, name, order)
 	if err != nil {
 		return
 	}
@@ -456,14 +499,21 @@ func goLookupIPFiles(name string) (addrs []IPAddr) {
 
 // goLookupIP is the native Go implementation of LookupIP.
 // The libc versions are in cgo_*.go.
-func goLookupIP(ctx context.Context, host string) (addrs []IPAddr, err error) {
-	order := systemConf().hostLookupOrder(host)
-	return goLookupIPOrder(ctx, host, order)
+func (r *Resolver) goLookupIP(ctx context.Context, host string) (addrs []IPAddr, err error) {
+	order := systemConf().hostLookupOrder(r, host)
+	return r.goLookupIPOrder(ctx, host, order)
+}
+
+func (r *Resolver) goLookupIPOrder(ctx context.Context, name string, order hostLookupOrder) (addrs []IPAddr, err error) {
+	return r.goLookupIPFamilyOrder(ctx, "ip", name, order)
 }
 
-func goLookupIPOrder(ctx context.Context, name string, order hostLookupOrder) (addrs []IPAddr, err error) {
+// goLookupIPFamilyOrder is like goLookupIPOrder but returns only the
+// addresses of network, which is "ip", "ip4" or "ip6". For "ip4" and
+// "ip6" only the A or AAAA query, respectively, is sent.
+func (r *Resolver) goLookupIPFamilyOrder(ctx context.Context, network, name string, order hostLookupOrder) (addrs []IPAddr, err error) {
 	if order == hostLookupFilesDNS || order == hostLookupFiles {
-		addrs = goLookupIPFiles(name)
+		addrs = filterIPAddrs(network, goLookupIPFiles(name))
 		if len(addrs) > 0 || order == hostLookupFiles {
 			return addrs, nil
 		}
@@ -481,12 +531,18 @@ func goLookupIPOrder(ctx context.Context, name string, order hostLookupOrder) (a
 		error
 	}
 	lane := make(chan racer, 1)
-	qtypes := [...]uint16{dnsTypeA, dnsTypeAAAA}
+	qtypes := []uint16{dnsTypeA, dnsTypeAAAA}
+	switch network {
+	case "ip4":
+		qtypes = qtypes[:1]
+	case "ip6":
+		qtypes = qtypes[1:]
+	}
 	var lastErr error
 	for _, fqdn := range conf.nameList(name) {
 		for _, qtype := range qtypes {
 			go func(qtype uint16) {
-				_, rrs, err := tryOneName(ctx, conf, fqdn, qtype)
+				_, rrs, err := r.tryOneName(ctx, conf, fqdn, qtype)
 				lane <- racer{fqdn, rrs, err}
 			}(qtype)
 		}
@@ -514,7 +570,7 @@ func goLookupIPOrder(ctx context.Context, name string, order hostLookupOrder) (a
 	sortByRFC6724(addrs)
 	if len(addrs) == 0 {
 		if order == hostLookupDNSFiles {
-			addrs = goLookupIPFiles(name)
+			addrs = filterIPAddrs(network, goLookupIPFiles(name))
 		}
 		if len(addrs) == 0 && lastErr != nil {
 			return nil, lastErr
@@ -529,8 +585,8 @@ func goLookupIPOrder(ctx context.Context, name string, order hostLookupOrder) (a
 // Normally we let cgo use the C library resolver instead of
 // depending on our lookup code, so that Go and C get the same
 // answers.
-func goLookupCNAME(ctx context.Context, name string) (cname string, err error) {
-	_, rrs, err := lookup(ctx, name, dnsTypeCNAME)
+func (r *Resolver) goLookupCNAME(ctx context.Context, name string) (cname string, err error) {
+	_, rrs, err := r.lookup(ctx, name, dnsTypeCNAME)
 	if err != nil {
 		return
 	}
@@ -543,7 +599,7 @@ func goLookupCNAME(ctx context.Context, name string) (cname string, err error) {
 // only if cgoLookupPTR is the stub in cgo_stub.go).
 // Normally we let cgo use the C library resolver instead of depending
 // on our lookup code, so that Go and C get the same answers.
-func goLookupPTR(ctx context.Context, addr string) ([]string, error) {
+func (r *Resolver) goLookupPTR(ctx context.Context, addr string) ([]string, error) {
 	names := lookupStaticAddr(addr)
 	if len(names) > 0 {
 		return names, nil
@@ -552,7 +608,7 @@ func goLookupPTR(ctx context.Context, addr string) ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
-	_, rrs, err := lookup(ctx, arpa, dnsTypePTR)
+	_, rrs, err := r.lookup(ctx, arpa, dnsTypePTR)
 	if err != nil {
 		return nil, err
 	}
@@ -10,6 +10,7 @@ import (
 	"context"
 	"fmt"
 	"internal/testenv"
+	"io"
 	"io/ioutil"
 	"os"
 	"path"
@@ -42,7 +43,7 @@ func TestDNSTransportFallback(t *testing.T) {
 	for _, tt := range dnsTransportFallbackTests {
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
-		msg, err := exchange(ctx, tt.server, tt.name, tt.qtype, time.Second)
+		msg, err := DefaultResolver.exchange(ctx, tt.server, tt.name, tt.qtype, time.Second)
 		if err != nil {
 			t.Error(err)
 			continue
@@ -84,7 +85,7 @@ func TestSpecialDomainName(t *testing.T) {
 	for _, tt := range specialDomainNameTests {
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
-		msg, err := exchange(ctx, server, tt.name, tt.qtype, 3*time.Second)
+		msg, err := DefaultResolver.exchange(ctx, server, tt.name, tt.qtype, 3*time.Second)
 		if err != nil {
 			t.Error(err)
 			continue
@@ -141,7 +142,7 @@ func TestAvoidDNSName(t *testing.T) {
 
 // Issue 13705: don't try to resolve onion addresses, etc
 func TestLookupTorOnion(t *testing.T) {
-	addrs, err := goLookupIP(context.Background(), "foo.onion")
+	addrs, err := DefaultResolver.goLookupIP(context.Background(), "foo.onion")
 	if len(addrs) > 0 {
 		t.Errorf("unexpected addresses: %v", addrs)
 	}
@@ -257,7 +258,7 @@ func TestUpdateResolvConf(t *testing.T) {
 			for j := 0; j < N; j++ {
 				go func(name string) {
 					defer wg.Done()
-					ips, err := goLookupIP(context.Background(), name)
+					ips, err := DefaultResolver.goLookupIP(context.Background(), name)
 					if err != nil {
 						t.Error(err)
 						return
@@ -405,7 +406,7 @@ func TestGoLookupIPWithResolverConfig(t *testing.T) {
 			t.Error(err)
 			continue
 		}
-		addrs, err := goLookupIP(context.Background(), tt.name)
+		addrs, err := DefaultResolver.goLookupIP(context.Background(), tt.name)
 		if err != nil {
 			// This test uses external network connectivity.
 			// We need to take care with errors on both
@@ -455,14 +456,14 @@ func TestGoLookupIPOrderFallbackToFile(t *testing.T) {
 		name := fmt.Sprintf("order %v", order)
 
 		// First ensure that we get an error when contacting a non-existent host.
-		_, err := goLookupIPOrder(context.Background(), "notarealhost", order)
+		_, err := DefaultResolver.goLookupIPOrder(context.Background(), "notarealhost", order)
 		if err == nil {
 			t.Errorf("%s: expected error while looking up name not in hosts file", name)
 			continue
 		}
 
 		// Now check that we get an address when the name appears in the hosts file.
-		addrs, err := goLookupIPOrder(context.Background(), "thor", order) // entry is in "testdata/hosts"
+		addrs, err := DefaultResolver.goLookupIPOrder(context.Background(), "thor", order) // entry is in "testdata/hosts"
 		if err != nil {
 			t.Errorf("%s: expected to successfully lookup host entry", name)
 			continue
@@ -518,7 +519,7 @@ func TestErrorForOriginalNameWhenSearching(t *testing.T) {
 		return r, nil
 	}
 
-	_, err = goLookupIP(context.Background(), fqdn)
+	_, err = DefaultResolver.goLookupIP(context.Background(), fqdn)
 	if err == nil {
 		t.Fatal("expected an error")
 	}
@@ -578,7 +579,7 @@ func TestIgnoreLameReferrals(t *testing.T) {
 		return r, nil
 	}
 
-	addrs, err := goLookupIP(context.Background(), "www.golang.org")
+	addrs, err := DefaultResolver.goLookupIP(context.Background(), "www.golang.org")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -597,7 +598,7 @@ func BenchmarkGoLookupIP(b *testing.B) {
 	ctx := context.Background()
 
 	for i := 0; i < b.N; i++ {
-		goLookupIP(ctx, "www.example.com")
+		DefaultResolver.goLookupIP(ctx, "www.example.com")
 	}
 }
 
@@ -606,7 +607,7 @@ func BenchmarkGoLookupIPNoSuchHost(b *testing.B) {
 	ctx := context.Background()
 
 	for i := 0; i < b.N; i++ {
-		goLookupIP(ctx, "some.nonexistent")
+		DefaultResolver.goLookupIP(ctx, "some.nonexistent")
 	}
 }
 
@@ -629,7 +630,7 @@ func BenchmarkGoLookupIPWithBrokenNameServer(b *testing.B) {
 	ctx := context.Background()
 
 	for i := 0; i < b.N; i++ {
-		goLookupIP(ctx, "www.example.com")
+		DefaultResolver.goLookupIP(ctx, "www.example.com")
 	}
 }
 
@@ -788,7 +789,7 @@ func TestRetryTimeout(t *testing.T) {
 		return r, nil
 	}
 
-	_, err = goLookupCNAME(context.Background(), "www.golang.org")
+	_, err = DefaultResolver.goLookupCNAME(context.Background(), "www.golang.org")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -858,7 +859,7 @@ func testRotate(t *testing.T, rotate bool, nameservers, wantServers []string) {
 
 	// len(nameservers) + 1 to allow rotation to get back to start
 	for i := 0; i < len(nameservers)+1; i++ {
-		if _, err := goLookupCNAME(context.Background(), "www.golang.org"); err != nil {
+		if _, err := DefaultResolver.goLookupCNAME(context.Background(), "www.golang.org"); err != nil {
 			t.Fatal(err)
 		}
 	}
@@ -867,3 +868,299 @@ func testRotate(t *testing.T, rotate bool, nameservers, wantServers []string) {
 		t.Fatalf("rotate=%t got used servers:\n%v\nwant:\n%v", rotate, usedServers, wantServers)
 	}
 }
+
+// fakeDNSStreamDial returns a Resolver.Dial function that connects to
+// an in-memory DNS server using the TCP transport, answering queries
+// with rh.
+func fakeDNSStreamDial(t *testing.T, rh func(network, server string, q *dnsMsg) *dnsMsg) func(context.Context, string, string) (Conn, error) {
+	return func(_ context.Context, network, server string) (Conn, error) {
+		c, s := Pipe()
+		go func() {
+			defer s.Close()
+			b := make([]byte, 2+65535)
+			if _, err := io.ReadFull(s, b[:2]); err != nil {
+				t.Error(err)
+				return
+			}
+			n := int(b[0])<<8 | int(b[1])
+			if _, err := io.ReadFull(s, b[:n]); err != nil {
+				t.Error(err)
+				return
+			}
+			q := new(dnsMsg)
+			if !q.Unpack(b[:n]) {
+				t.Error("invalid DNS query")
+				return
+			}
+			m, ok := rh(network, server, q).Pack()
+			if !ok {
+				t.Error("failed to pack DNS response")
+				return
+			}
+			s.Write(append([]byte{byte(len(m) >> 8), byte(len(m))}, m...))
+		}()
+		return c, nil
+	}
+}
+
+func TestResolverDial(t *testing.T) {
+	conf, err := newResolvConfTest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conf.teardown()
+
+	if err := conf.writeAndUpdate([]string{"nameserver 192.0.2.1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var dialed []string
+	r := &Resolver{
+		PreferGo: true,
+		Dial: fakeDNSStreamDial(t, func(network, server string, q *dnsMsg) *dnsMsg {
+			mu.Lock()
+			dialed = append(dialed, network+" "+server)
+			mu.Unlock()
+			resp := &dnsMsg{
+				dnsMsgHdr: dnsMsgHdr{
+					id:                  q.id,
+					response:            true,
+					recursion_available: true,
+				},
+				question: q.question,
+			}
+			if q.question[0].Qtype == dnsTypeA {
+				resp.answer = []dnsRR{
+					&dnsRR_A{
+						Hdr: dnsRR_Header{
+							Name:   q.question[0].Name,
+							Rrtype: dnsTypeA,
+							Class:  dnsClassINET,
+						},
+						A: TestAddr,
+					},
+				}
+			}
+			return resp
+		}),
+	}
+	addrs, err := r.LookupIPAddr(context.Background(), "www.golang.org.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 1 || addrs[0].String() != "192.0.2.1" {
+		t.Errorf("got %v; want [192.0.2.1]", addrs)
+	}
+	want := []string{"udp 192.0.2.1:53", "udp 192.0.2.1:53"} // A and AAAA
+	if !reflect.DeepEqual(dialed, want) {
+		t.Errorf("dialed %v; want %v", dialed, want)
+	}
+}
+
+func TestResolverLookupGroup(t *testing.T) {
+	conf, err := newResolvConfTest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conf.teardown()
+
+	if err := conf.writeAndUpdate([]string{"nameserver 192.0.2.1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	answer := func(q *dnsMsg, ip IP) *dnsMsg {
+		resp := &dnsMsg{
+			dnsMsgHdr: dnsMsgHdr{
+				id:                  q.id,
+				response:            true,
+				recursion_available: true,
+			},
+			question: q.question,
+		}
+		if q.question[0].Qtype == dnsTypeA {
+			resp.answer = []dnsRR{
+				&dnsRR_A{
+					Hdr: dnsRR_Header{
+						Name:   q.question[0].Name,
+						Rrtype: dnsTypeA,
+						Class:  dnsClassINET,
+					},
+					A: uint32(ip[0])<<24 | uint32(ip[1])<<16 | uint32(ip[2])<<8 | uint32(ip[3]),
+				},
+			}
+		}
+		return resp
+	}
+
+	lookups := []struct {
+		name   string
+		lookup func(*Resolver) ([]IPAddr, error)
+	}{
+		{"LookupIPAddr", func(r *Resolver) ([]IPAddr, error) {
+			return r.LookupIPAddr(context.Background(), "www.golang.org.")
+		}},
+		{"lookupIPAddrFamily", func(r *Resolver) ([]IPAddr, error) {
+			return r.lookupIPAddrFamily(context.Background(), "ip4", "www.golang.org.")
+		}},
+	}
+	for _, tt := range lookups {
+		// The first resolver's lookup is held until the second
+		// resolver has sent its own queries, so that the two
+		// lookups of the same host overlap. They must not be
+		// merged.
+		var entered1, entered2 sync.Once
+		started1, started2 := make(chan bool), make(chan bool)
+		r1 := &Resolver{
+			Dial: fakeDNSStreamDial(t, func(_, _ string, q *dnsMsg) *dnsMsg {
+				entered1.Do(func() { close(started1) })
+				select {
+				case <-started2:
+				case <-time.After(5 * time.Second):
+				}
+				return answer(q, IPv4(192, 0, 2, 10).To4())
+			}),
+		}
+		r2 := &Resolver{
+			Dial: fakeDNSStreamDial(t, func(_, _ string, q *dnsMsg) *dnsMsg {
+				entered2.Do(func() { close(started2) })
+				return answer(q, IPv4(198, 51, 100, 20).To4())
+			}),
+		}
+
+		type result struct {
+			addrs []IPAddr
+			err   error
+		}
+		ch := make(chan result)
+		go func() {
+			addrs, err := tt.lookup(r1)
+			ch <- result{addrs, err}
+		}()
+		<-started1
+		addrs2, err := tt.lookup(r2)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		res1 := <-ch
+		if res1.err != nil {
+			t.Fatalf("%s: %v", tt.name, res1.err)
+		}
+		if len(res1.addrs) != 1 || res1.addrs[0].String() != "192.0.2.10" {
+			t.Errorf("%s: first resolver got %v; want [192.0.2.10]", tt.name, res1.addrs)
+		}
+		if len(addrs2) != 1 || addrs2[0].String() != "198.51.100.20" {
+			t.Errorf("%s: second resolver got %v; want [198.51.100.20]", tt.name, addrs2)
+		}
+	}
+}
+
+func TestLookupRecords(t *testing.T) {
+	conf, err := newResolvConfTest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conf.teardown()
+
+	if err := conf.writeAndUpdate([]string{"nameserver 192.0.2.1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	const name = "example.com."
+	rrs := map[uint16][]dnsRR{
+		dnsTypeCNAME: {
+			&dnsRR_CNAME{Hdr: dnsRR_Header{Name: "www." + name, Rrtype: dnsTypeCNAME, Class: dnsClassINET, Ttl: 60}, Cname: name},
+		},
+		dnsTypeMX: {
+			&dnsRR_MX{Hdr: dnsRR_Header{Name: name, Rrtype: dnsTypeMX, Class: dnsClassINET, Ttl: 300}, Pref: 10, Mx: "mx." + name},
+		},
+		dnsTypeCAA: {
+			&dnsRR_Unknown{Hdr: dnsRR_Header{Name: name, Rrtype: dnsTypeCAA, Class: dnsClassINET, Ttl: 3600}, Data: []byte("\x80\x05issueca.example.net")},
+		},
+		dnsTypeTLSA: {
+			&dnsRR_Unknown{Hdr: dnsRR_Header{Name: name, Rrtype: dnsTypeTLSA, Class: dnsClassINET, Ttl: 3600}, Data: []byte{3, 1, 1, 0xde, 0xad, 0xbe, 0xef}},
+		},
+		dnsTypeNAPTR: {
+			&dnsRR_Unknown{Hdr: dnsRR_Header{Name: name, Rrtype: dnsTypeNAPTR, Class: dnsClassINET, Ttl: 10}, Data: []byte("\x00\x64\x00\x0a\x01u\x07E2U+sip\x1b!^.*$!sip:info@example.com!\x00")},
+		},
+		dnsTypeHTTPS: {
+			&dnsRR_Unknown{Hdr: dnsRR_Header{Name: name, Rrtype: dnsTypeHTTPS, Class: dnsClassINET, Ttl: 20}, Data: []byte("\x00\x01\x00\x00\x01\x00\x03\x02h2")},
+		},
+		99: {
+			&dnsRR_Unknown{Hdr: dnsRR_Header{Name: name, Rrtype: 99, Class: dnsClassINET, Ttl: 1}, Data: []byte("raw")},
+		},
+	}
+	r := &Resolver{
+		Dial: fakeDNSStreamDial(t, func(_, _ string, q *dnsMsg) *dnsMsg {
+			resp := &dnsMsg{
+				dnsMsgHdr: dnsMsgHdr{
+					id:                  q.id,
+					response:            true,
+					recursion_available: true,
+				},
+				question: q.question,
+			}
+			if q.question[0].Name == "www."+name {
+				resp.answer = append(resp.answer, rrs[dnsTypeCNAME]...)
+			}
+			resp.answer = append(resp.answer, rrs[q.question[0].Qtype]...)
+			return resp
+		}),
+	}
+
+	tests := []struct {
+		name string
+		typ  DNSType
+		want DNSRecord
+	}{
+		{"www." + name, DNSTypeMX, DNSRecord{name, DNSTypeMX, 300 * time.Second, &MX{Host: "mx." + name, Pref: 10}}},
+		{name, DNSTypeCAA, DNSRecord{name, DNSTypeCAA, time.Hour, &CAA{Flags: 128, Tag: "issue", Value: "ca.example.net"}}},
+		{name, DNSTypeTLSA, DNSRecord{name, DNSTypeTLSA, time.Hour, &TLSA{Usage: 3, Selector: 1, MatchingType: 1, Data: []byte{0xde, 0xad, 0xbe, 0xef}}}},
+		{name, DNSTypeNAPTR, DNSRecord{name, DNSTypeNAPTR, 10 * time.Second, &NAPTR{Order: 100, Preference: 10, Flags: "u", Service: "E2U+sip", Regexp: "!^.*$!sip:info@example.com!", Replacement: "."}}},
+		{name, DNSTypeHTTPS, DNSRecord{name, DNSTypeHTTPS, 20 * time.Second, &SVCB{Priority: 1, Target: ".", Params: []SVCBParam{{Key: 1, Value: []byte("\x02h2")}}}}},
+		{name, 99, DNSRecord{name, 99, time.Second, []byte("raw")}},
+	}
+	for _, tt := range tests {
+		recs, err := r.LookupRecords(context.Background(), tt.name, tt.typ)
+		if err != nil {
+			t.Errorf("LookupRecords(%q, %v): %v", tt.name, tt.typ, err)
+			continue
+		}
+		if len(recs) != 1 || !reflect.DeepEqual(recs[0], tt.want) {
+			t.Errorf("LookupRecords(%q, %v) = %+v; want [%+v]", tt.name, tt.typ, recs, tt.want)
+		}
+	}
+}
+
+func TestDNSTypeString(t *testing.T) {
+	for _, tt := range []struct {
+		typ  DNSType
+		want string
+	}{
+		{DNSTypeAAAA, "AAAA"},
+		{DNSTypeCAA, "CAA"},
+		{99, "TYPE99"},
+	} {
+		if got := tt.typ.String(); got != tt.want {
+			t.Errorf("DNSType(%d).String() = %q; want %q", tt.typ, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeCorruptRecordData(t *testing.T) {
+	for _, tt := range []struct {
+		typ  DNSType
+		data string
+	}{
+		{DNSTypeCAA, ""},
+		{DNSTypeCAA, "\x00\x05iss"},
+		{DNSTypeTLSA, "\x03\x01"},
+		{DNSTypeNAPTR, "\x00\x64\x00\x0a\x01u\x07E2U+sip\x00"},
+		{DNSTypeSVCB, "\x00\x01\x00\x00\x01\x00\x03\x02h"},
+		{DNSTypeSVCB, "\x00\x00\x00\x01"},
+	} {
+		if v, err := decodeRecordData(tt.typ, []byte(tt.data)); err == nil {
+			t.Errorf("decodeRecordData(%v, %q) = %#v; want error", tt.typ, tt.data, v)
+		}
+	}
+}
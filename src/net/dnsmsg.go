@@ -45,6 +45,11 @@ const (
 	dnsTypeTXT   = 16
 	dnsTypeAAAA  = 28
 	dnsTypeSRV   = 33
+	dnsTypeNAPTR = 35
+	dnsTypeTLSA  = 52
+	dnsTypeSVCB  = 64
+	dnsTypeHTTPS = 65
+	dnsTypeCAA   = 257
 
 	// valid dnsQuestion.qtype only
 	dnsTypeAXFR  = 252
@@ -304,6 +309,21 @@ func (rr *dnsRR_AAAA) Walk(f func(v interface{}, name, tag string) bool) bool {
 	return rr.Hdr.Walk(f) && f(rr.AAAA[:], "AAAA", "ipv6")
 }
 
+// dnsRR_Unknown holds the data of a resource record whose type has
+// no specific format, in wire format.
+type dnsRR_Unknown struct {
+	Hdr  dnsRR_Header
+	Data []byte
+}
+
+func (rr *dnsRR_Unknown) Header() *dnsRR_Header {
+	return &rr.Hdr
+}
+
+func (rr *dnsRR_Unknown) Walk(f func(v interface{}, name, tag string) bool) bool {
+	return rr.Hdr.Walk(f) && f(rr.Data, "Data", "")
+}
+
 // Packing and unpacking.
 //
 // All the packers and unpackers take a (msg []byte, off int)
@@ -661,7 +681,14 @@ func unpackRR(msg []byte, off int) (rr dnsRR, off1 int, ok bool) {
 	// again inefficient but doesn't need to be fast.
 	mk, known := rr_mk[int(h.Rrtype)]
 	if !known {
-		return &h, end, true
+		if end > len(msg) {
+			return &h, end, true
+		}
+		// Keep the data as is. Types defined after RFC 1035
+		// can't contain compressed domain names; see RFC 3597.
+		data := make([]byte, end-off)
+		copy(data, msg[off:end])
+		return &dnsRR_Unknown{Hdr: h, Data: data}, end, true
 	}
 	rr = mk()
 	off, ok = unpackStruct(rr, msg, off0)
@@ -0,0 +1,283 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import (
+	"errors"
+	"time"
+)
+
+// A DNSType is the type of a DNS resource record.
+type DNSType uint16
+
+// DNS resource record types.
+const (
+	DNSTypeA     DNSType = dnsTypeA
+	DNSTypeNS    DNSType = dnsTypeNS
+	DNSTypeCNAME DNSType = dnsTypeCNAME
+	DNSTypeSOA   DNSType = dnsTypeSOA
+	DNSTypePTR   DNSType = dnsTypePTR
+	DNSTypeMX    DNSType = dnsTypeMX
+	DNSTypeTXT   DNSType = dnsTypeTXT
+	DNSTypeAAAA  DNSType = dnsTypeAAAA
+	DNSTypeSRV   DNSType = dnsTypeSRV
+	DNSTypeNAPTR DNSType = dnsTypeNAPTR
+	DNSTypeTLSA  DNSType = dnsTypeTLSA
+	DNSTypeSVCB  DNSType = dnsTypeSVCB
+	DNSTypeHTTPS DNSType = dnsTypeHTTPS
+	DNSTypeCAA   DNSType = dnsTypeCAA
+)
+
+var dnsTypeNames = map[DNSType]string{
+	DNSTypeA:     "A",
+	DNSTypeNS:    "NS",
+	DNSTypeCNAME: "CNAME",
+	DNSTypeSOA:   "SOA",
+	DNSTypePTR:   "PTR",
+	DNSTypeMX:    "MX",
+	DNSTypeTXT:   "TXT",
+	DNSTypeAAAA:  "AAAA",
+	DNSTypeSRV:   "SRV",
+	DNSTypeNAPTR: "NAPTR",
+	DNSTypeTLSA:  "TLSA",
+	DNSTypeSVCB:  "SVCB",
+	DNSTypeHTTPS: "HTTPS",
+	DNSTypeCAA:   "CAA",
+}
+
+// String returns the mnemonic of t, such as "AAAA", or for types
+// without a constant, "TYPE" followed by the number of t, as in RFC
+// 3597.
+func (t DNSType) String() string {
+	if s, ok := dnsTypeNames[t]; ok {
+		return s
+	}
+	return "TYPE" + uitoa(uint(t))
+}
+
+// A DNSRecord represents a single DNS resource record.
+type DNSRecord struct {
+	// Name is the owner name of the record. It differs from the
+	// name looked up if the record was found by following CNAME
+	// records.
+	Name string
+
+	Type DNSType
+
+	// TTL is the time for which the record may be cached.
+	TTL time.Duration
+
+	// Value is the decoded data of the record. Its dynamic type
+	// depends on Type:
+	//
+	//	A, AAAA         IP
+	//	CNAME, NS, PTR  string, a domain name
+	//	MX              *MX
+	//	SRV             *SRV
+	//	TXT             string, the concatenated character strings
+	//	NAPTR           *NAPTR
+	//	TLSA            *TLSA
+	//	SVCB, HTTPS     *SVCB
+	//	CAA             *CAA
+	//
+	// The data of records of other types is a []byte holding the
+	// record data in wire format.
+	Value interface{}
+}
+
+// A CAA represents a single DNS CAA record, as specified by RFC 8659.
+type CAA struct {
+	Flags uint8
+	Tag   string
+	Value string
+}
+
+// A TLSA represents a single DNS TLSA record, as specified by RFC 6698.
+type TLSA struct {
+	Usage        uint8
+	Selector     uint8
+	MatchingType uint8
+	Data         []byte
+}
+
+// A NAPTR represents a single DNS NAPTR record, as specified by RFC
+// 3403.
+type NAPTR struct {
+	Order       uint16
+	Preference  uint16
+	Flags       string
+	Service     string
+	Regexp      string
+	Replacement string
+}
+
+// An SVCB represents a single DNS SVCB or HTTPS record, as specified by
+// RFC 9460. A record with a Priority of zero is in alias mode and has no
+// Params.
+type SVCB struct {
+	Priority uint16
+	Target   string
+	Params   []SVCBParam
+}
+
+// An SVCBParam is a service parameter of an SVCB record. Its Value is in
+// wire format.
+type SVCBParam struct {
+	Key   uint16
+	Value []byte
+}
+
+var errInvalidRecord = errors.New("invalid DNS record data")
+
+// newDNSRecord returns the DNSRecord form of rr.
+func newDNSRecord(rr dnsRR) (DNSRecord, error) {
+	h := rr.Header()
+	r := DNSRecord{
+		Name: h.Name,
+		Type: DNSType(h.Rrtype),
+		TTL:  time.Duration(h.Ttl) * time.Second,
+	}
+	switch rr := rr.(type) {
+	case *dnsRR_A:
+		r.Value = IPv4(byte(rr.A>>24), byte(rr.A>>16), byte(rr.A>>8), byte(rr.A))
+	case *dnsRR_AAAA:
+		ip := make(IP, IPv6len)
+		copy(ip, rr.AAAA[:])
+		r.Value = ip
+	case *dnsRR_CNAME:
+		r.Value = rr.Cname
+	case *dnsRR_NS:
+		r.Value = rr.Ns
+	case *dnsRR_PTR:
+		r.Value = rr.Ptr
+	case *dnsRR_MX:
+		r.Value = &MX{Host: rr.Mx, Pref: rr.Pref}
+	case *dnsRR_SRV:
+		r.Value = &SRV{Target: rr.Target, Port: rr.Port, Priority: rr.Priority, Weight: rr.Weight}
+	case *dnsRR_TXT:
+		r.Value = rr.Txt
+	case *dnsRR_Unknown:
+		v, err := decodeRecordData(r.Type, rr.Data)
+		if err != nil {
+			return DNSRecord{}, err
+		}
+		r.Value = v
+	default:
+		// Repack the data of other known types, such as SOA, without
+		// name compression. None of them is longer than 1024 bytes.
+		b := make([]byte, 1024)
+		off, ok := packStruct(h, b, 0)
+		end, ok1 := packStruct(rr, b, 0)
+		if !ok || !ok1 {
+			return DNSRecord{}, errInvalidRecord
+		}
+		r.Value = b[off:end]
+	}
+	return r, nil
+}
+
+// decodeRecordData decodes the wire format data of a record of type t
+// that isn't in rr_mk.
+func decodeRecordData(t DNSType, b []byte) (interface{}, error) {
+	d := recordDecoder{b: b}
+	var v interface{}
+	switch t {
+	case DNSTypeCAA:
+		caa := new(CAA)
+		caa.Flags = d.uint8()
+		caa.Tag = d.string()
+		caa.Value = string(d.rest())
+		v = caa
+	case DNSTypeTLSA:
+		tlsa := new(TLSA)
+		tlsa.Usage = d.uint8()
+		tlsa.Selector = d.uint8()
+		tlsa.MatchingType = d.uint8()
+		tlsa.Data = d.rest()
+		v = tlsa
+	case DNSTypeNAPTR:
+		naptr := new(NAPTR)
+		naptr.Order = d.uint16()
+		naptr.Preference = d.uint16()
+		naptr.Flags = d.string()
+		naptr.Service = d.string()
+		naptr.Regexp = d.string()
+		naptr.Replacement = d.name()
+		v = naptr
+	case DNSTypeSVCB, DNSTypeHTTPS:
+		svcb := new(SVCB)
+		svcb.Priority = d.uint16()
+		svcb.Target = d.name()
+		for !d.err && len(d.b) > 0 {
+			key := d.uint16()
+			n := int(d.uint16())
+			svcb.Params = append(svcb.Params, SVCBParam{Key: key, Value: d.bytes(n)})
+		}
+		v = svcb
+	default:
+		v = d.rest()
+	}
+	if d.err || len(d.b) > 0 {
+		return nil, errInvalidRecord
+	}
+	return v, nil
+}
+
+// A recordDecoder decodes the fields of record data. It sets err, and
+// returns zero values, once the data is exhausted.
+type recordDecoder struct {
+	b   []byte
+	err bool
+}
+
+func (d *recordDecoder) bytes(n int) []byte {
+	if d.err || n > len(d.b) {
+		d.err = true
+		return nil
+	}
+	b := make([]byte, n)
+	copy(b, d.b)
+	d.b = d.b[n:]
+	return b
+}
+
+func (d *recordDecoder) rest() []byte {
+	return d.bytes(len(d.b))
+}
+
+func (d *recordDecoder) uint8() uint8 {
+	b := d.bytes(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (d *recordDecoder) uint16() uint16 {
+	b := d.bytes(2)
+	if b == nil {
+		return 0
+	}
+	return uint16(b[0])<<8 | uint16(b[1])
+}
+
+// string decodes a character string, which has a one byte length.
+func (d *recordDecoder) string() string {
+	return string(d.bytes(int(d.uint8())))
+}
+
+// name decodes an uncompressed domain name.
+func (d *recordDecoder) name() string {
+	if d.err {
+		return ""
+	}
+	s, off, ok := unpackDomainName(d.b, 0)
+	if !ok {
+		d.err = true
+		return ""
+	}
+	d.b = d.b[off:]
+	return s
+}
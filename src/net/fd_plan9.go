@@ -5,6 +5,7 @@
 package net
 
 import (
+	"context"
 	"io"
 	"os"
 	"syscall"
@@ -82,6 +83,18 @@ func (fd *netFD) destroy() {
 	fd.listen = nil
 }
 
+func (fd *netFD) readContext(ctx context.Context, b []byte) (n int, interrupted bool, err error) {
+	return 0, false, syscall.EPLAN9
+}
+
+func (fd *netFD) writeContext(ctx context.Context, b []byte) (n int, interrupted bool, err error) {
+	return 0, false, syscall.EPLAN9
+}
+
+func (fd *netFD) acceptContext(ctx context.Context) (nfd *netFD, interrupted bool, err error) {
+	return nil, false, syscall.EPLAN9
+}
+
 func (fd *netFD) Read(b []byte) (n int, err error) {
 	if !fd.ok() || fd.data == nil {
 		return 0, syscall.EINVAL
@@ -206,3 +219,15 @@ func setWriteBuffer(fd *netFD, bytes int) error {
 func isHangup(err error) bool {
 	return err != nil && stringsHasSuffix(err.Error(), "Hangup")
 }
+
+func (fd *netFD) rawControl(f func(uintptr)) error {
+	return syscall.EPLAN9
+}
+
+func (fd *netFD) rawRead(f func(uintptr) bool) error {
+	return syscall.EPLAN9
+}
+
+func (fd *netFD) rawWrite(f func(uintptr) bool) error {
+	return syscall.EPLAN9
+}
@@ -5,6 +5,7 @@
 package net
 
 import (
+	"context"
 	"runtime"
 	"syscall"
 	"time"
@@ -55,6 +56,10 @@ func (pd *pollDesc) waitCanceledRead() {}
 
 func (pd *pollDesc) waitCanceledWrite() {}
 
+func (fd *netFD) watchContext(ctx context.Context, mode int) (stop func() bool, err error) {
+	return nil, syscall.ENOPROTOOPT
+}
+
 func (fd *netFD) setDeadline(t time.Time) error {
 	return setDeadlineImpl(fd, t, 'r'+'w')
 }
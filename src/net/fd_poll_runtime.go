@@ -7,6 +7,7 @@
 package net
 
 import (
+	"context"
 	"runtime"
 	"sync"
 	"syscall"
@@ -24,6 +25,9 @@ func runtime_pollWaitCanceled(ctx uintptr, mode int) int
 func runtime_pollReset(ctx uintptr, mode int) int
 func runtime_pollSetDeadline(ctx uintptr, d int64, mode int)
 func runtime_pollUnblock(ctx uintptr)
+func runtime_pollArmInterrupt(ctx uintptr, mode int) uint64
+func runtime_pollInterrupt(ctx uintptr, mode int, token uint64)
+func runtime_pollDisarmInterrupt(ctx uintptr, mode int, token uint64) bool
 
 type pollDesc struct {
 	runtimeCtx uintptr
@@ -104,11 +108,44 @@ func convertErr(res int) error {
 		return errClosing
 	case 2:
 		return errTimeout
+	case 3:
+		return errInterrupted
 	}
 	println("unreachable: ", res)
 	panic("unreachable")
 }
 
+// watchContext arranges for the poller to interrupt the I/O in the
+// given mode on fd once ctx is done, without closing fd. The caller
+// must hold fd's read lock for mode 'r', or its write lock for mode
+// 'w', from before watchContext until after stop has returned, so that
+// the interruption cannot reach any other I/O on fd. The returned stop
+// function must be called when the I/O has returned. It reports
+// whether the I/O was interrupted.
+func (fd *netFD) watchContext(ctx context.Context, mode int) (stop func() bool, err error) {
+	if ctx.Done() == nil {
+		return func() bool { return false }, nil
+	}
+	// The lock held by the caller keeps fd open, but the watcher
+	// may outlive it, so it uses its own copy of the descriptor.
+	// The token makes an interruption that arrives after stop a
+	// no-op, even if the descriptor has been reused by then.
+	pd := fd.pd.runtimeCtx
+	token := runtime_pollArmInterrupt(pd, mode)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			runtime_pollInterrupt(pd, mode, token)
+		case <-done:
+		}
+	}()
+	return func() bool {
+		close(done)
+		return runtime_pollDisarmInterrupt(pd, mode, token)
+	}, nil
+}
+
 func (fd *netFD) setDeadline(t time.Time) error {
 	return setDeadlineImpl(fd, t, 'r'+'w')
 }
@@ -7,6 +7,7 @@
 package net
 
 import (
+	"context"
 	"io"
 	"syscall"
 )
@@ -19,3 +20,51 @@ func (fd *netFD) eofError(n int, err error) error {
 	}
 	return err
 }
+
+// readContext is like Read, but the read is interrupted with
+// errInterrupted if ctx is done before it completes. It reports
+// whether that happened.
+func (fd *netFD) readContext(ctx context.Context, p []byte) (n int, interrupted bool, err error) {
+	if err := fd.readLock(); err != nil {
+		return 0, false, err
+	}
+	defer fd.readUnlock()
+	stop, err := fd.watchContext(ctx, 'r')
+	if err != nil {
+		return 0, false, err
+	}
+	n, err = fd.read(p)
+	return n, stop(), err
+}
+
+// writeContext is like Write, but the write is interrupted with
+// errInterrupted if ctx is done before it completes. It reports
+// whether that happened.
+func (fd *netFD) writeContext(ctx context.Context, p []byte) (n int, interrupted bool, err error) {
+	if err := fd.writeLock(); err != nil {
+		return 0, false, err
+	}
+	defer fd.writeUnlock()
+	stop, err := fd.watchContext(ctx, 'w')
+	if err != nil {
+		return 0, false, err
+	}
+	n, err = fd.write(p)
+	return n, stop(), err
+}
+
+// acceptContext is like accept, but waiting for a connection is
+// interrupted with errInterrupted if ctx is done first. It reports
+// whether that happened.
+func (fd *netFD) acceptContext(ctx context.Context) (netfd *netFD, interrupted bool, err error) {
+	if err := fd.readLock(); err != nil {
+		return nil, false, err
+	}
+	defer fd.readUnlock()
+	stop, err := fd.watchContext(ctx, 'r')
+	if err != nil {
+		return nil, false, err
+	}
+	netfd, err = fd.acceptLocked()
+	return netfd, stop(), err
+}
@@ -227,6 +227,11 @@ func (fd *netFD) Read(p []byte) (n int, err error) {
 		return 0, err
 	}
 	defer fd.readUnlock()
+	return fd.read(p)
+}
+
+// read is Read for a caller that holds the read lock.
+func (fd *netFD) read(p []byte) (n int, err error) {
 	if len(p) == 0 {
 		// If the caller wanted a zero byte read, return immediately
 		// without trying. (But after acquiring the readLock.) Otherwise
@@ -316,6 +321,11 @@ func (fd *netFD) Write(p []byte) (nn int, err error) {
 		return 0, err
 	}
 	defer fd.writeUnlock()
+	return fd.write(p)
+}
+
+// write is Write for a caller that holds the write lock.
+func (fd *netFD) write(p []byte) (nn int, err error) {
 	if err := fd.pd.prepareWrite(); err != nil {
 		return 0, err
 	}
@@ -404,7 +414,11 @@ func (fd *netFD) accept() (netfd *netFD, err error) {
 		return nil, err
 	}
 	defer fd.readUnlock()
+	return fd.acceptLocked()
+}
 
+// acceptLocked is accept for a caller that holds the read lock.
+func (fd *netFD) acceptLocked() (netfd *netFD, err error) {
 	var s int
 	var rsa syscall.Sockaddr
 	if err = fd.pd.prepareRead(); err != nil {
@@ -510,3 +524,52 @@ func (fd *netFD) dup() (f *os.File, err error) {
 
 	return os.NewFile(uintptr(ns), fd.name()), nil
 }
+
+// rawControl invokes f on the file descriptor of fd.
+func (fd *netFD) rawControl(f func(uintptr)) error {
+	if err := fd.incref(); err != nil {
+		return err
+	}
+	defer fd.decref()
+	f(uintptr(fd.sysfd))
+	return nil
+}
+
+// rawRead invokes f on the file descriptor of fd until it reports
+// that it is done, waiting for fd to be readable before each retry.
+func (fd *netFD) rawRead(f func(uintptr) bool) error {
+	if err := fd.readLock(); err != nil {
+		return err
+	}
+	defer fd.readUnlock()
+	if err := fd.pd.prepareRead(); err != nil {
+		return err
+	}
+	for {
+		if f(uintptr(fd.sysfd)) {
+			return nil
+		}
+		if err := fd.pd.waitRead(); err != nil {
+			return err
+		}
+	}
+}
+
+// rawWrite is like rawRead but waits for fd to be writable.
+func (fd *netFD) rawWrite(f func(uintptr) bool) error {
+	if err := fd.writeLock(); err != nil {
+		return err
+	}
+	defer fd.writeUnlock()
+	if err := fd.pd.prepareWrite(); err != nil {
+		return err
+	}
+	for {
+		if f(uintptr(fd.sysfd)) {
+			return nil
+		}
+		if err := fd.pd.waitWrite(); err != nil {
+			return err
+		}
+	}
+}
@@ -177,10 +177,10 @@ func (s *ioSrv) ExecIO(o *operation, name string, submit func(o *operation) erro
 		}
 		return int(o.qty), nil
 	}
-	// IO is interrupted by "close" or "timeout"
+	// IO is interrupted by "close", "timeout" or a done context
 	netpollErr := err
 	switch netpollErr {
-	case errClosing, errTimeout:
+	case errClosing, errTimeout, errInterrupted:
 		// will deal with those.
 	default:
 		panic("net: unexpected runtime.netpoll error: " + netpollErr.Error())
@@ -419,6 +419,11 @@ func (fd *netFD) Read(buf []byte) (int, error) {
 		return 0, err
 	}
 	defer fd.readUnlock()
+	return fd.read(buf)
+}
+
+// read is Read for a caller that holds the read lock.
+func (fd *netFD) read(buf []byte) (int, error) {
 	o := &fd.rop
 	o.InitBuf(buf)
 	n, err := rsrv.ExecIO(o, "WSARecv", func(o *operation) error {
@@ -469,6 +474,11 @@ func (fd *netFD) Write(buf []byte) (int, error) {
 		return 0, err
 	}
 	defer fd.writeUnlock()
+	return fd.write(buf)
+}
+
+// write is Write for a caller that holds the write lock.
+func (fd *netFD) write(buf []byte) (int, error) {
 	if race.Enabled {
 		race.ReleaseMerge(unsafe.Pointer(&ioSync))
 	}
@@ -550,7 +560,11 @@ func (fd *netFD) accept() (*netFD, error) {
 		return nil, err
 	}
 	defer fd.readUnlock()
+	return fd.acceptLocked()
+}
 
+// acceptLocked is accept for a caller that holds the read lock.
+func (fd *netFD) acceptLocked() (*netFD, error) {
 	o := &fd.rop
 	var netfd *netFD
 	var err error
@@ -607,3 +621,21 @@ func (fd *netFD) readMsg(p []byte, oob []byte) (n, oobn, flags int, sa syscall.S
 func (fd *netFD) writeMsg(p []byte, oob []byte, sa syscall.Sockaddr) (n int, oobn int, err error) {
 	return 0, 0, syscall.EWINDOWS
 }
+
+// rawControl invokes f on the socket handle of fd.
+func (fd *netFD) rawControl(f func(uintptr)) error {
+	if err := fd.incref(); err != nil {
+		return err
+	}
+	defer fd.decref()
+	f(uintptr(fd.sysfd))
+	return nil
+}
+
+func (fd *netFD) rawRead(f func(uintptr) bool) error {
+	return syscall.EWINDOWS
+}
+
+func (fd *netFD) rawWrite(f func(uintptr) bool) error {
+	return syscall.EWINDOWS
+}
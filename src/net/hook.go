@@ -18,5 +18,12 @@ var (
 	) ([]IPAddr, error) {
 		return fn(ctx, host)
 	}
+	testHookLookupIPFamily = func(
+		ctx context.Context,
+		fn func(context.Context, string) ([]IPAddr, error),
+		network, host string,
+	) ([]IPAddr, error) {
+		return fn(ctx, host)
+	}
 	testHookSetKeepAlive = func() {}
 )
@@ -78,6 +78,44 @@ type Client struct {
 	// RoundTripper implementations should use Request.Cancel
 	// instead of implementing CancelRequest.
 	Timeout time.Duration
+
+	// Interceptors wrap the Transport with client-side middleware,
+	// such as logging, authentication or retries. Each request,
+	// including each one following a redirect, passes through the
+	// Interceptors in order, outermost first, before reaching the
+	// Transport. The Client's Jar, CheckRedirect and Timeout apply
+	// outside of all Interceptors.
+	//
+	// The chain is not kept: the Client calls every Interceptor
+	// again for each request it sends, including each redirect, so
+	// a RoundTripper returned by an Interceptor only ever sees one
+	// request. Middleware that keeps state across requests, such as
+	// a rate limiter, should be wrapped around Transport once
+	// instead.
+	Interceptors []Interceptor
+}
+
+// An Interceptor wraps a RoundTripper with additional behavior. It is
+// called with the next RoundTripper in a Client's chain and returns the
+// RoundTripper to use in its place. A Client calls it for every
+// request it sends.
+//
+// The returned RoundTripper must follow the RoundTripper rules. In
+// particular, it must close the request Body even if it never calls
+// next. One that sends a request more than once must give each attempt
+// after the first a fresh Body obtained from Request.GetBody, and must
+// send the request only once if Body is non-nil and GetBody is nil.
+type Interceptor func(next RoundTripper) RoundTripper
+
+// The RoundTripperFunc type is an adapter to allow the use of
+// ordinary functions as RoundTrippers. If f is a function
+// with the appropriate signature, RoundTripperFunc(f) is a
+// RoundTripper that calls f.
+type RoundTripperFunc func(*Request) (*Response, error)
+
+// RoundTrip calls f(req).
+func (f RoundTripperFunc) RoundTrip(req *Request) (*Response, error) {
+	return f(req)
 }
 
 // DefaultClient is the default Client and is used by Get, Head, and Post.
@@ -197,10 +235,14 @@ func (c *Client) deadline() time.Time {
 }
 
 func (c *Client) transport() RoundTripper {
-	if c.Transport != nil {
-		return c.Transport
+	rt := c.Transport
+	if rt == nil {
+		rt = DefaultTransport
+	}
+	for i := len(c.Interceptors) - 1; i >= 0; i-- {
+		rt = c.Interceptors[i](rt)
 	}
-	return DefaultTransport
+	return rt
 }
 
 // send issues an HTTP request.
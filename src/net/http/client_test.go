@@ -1406,3 +1406,73 @@ func TestClientRedirectTypes(t *testing.T) {
 		res.Body.Close()
 	}
 }
+
+func TestClientInterceptors(t *testing.T) {
+	defer afterTest(t)
+	ts := httptest.NewServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		if r.URL.Path == "/redirect" {
+			Redirect(w, r, "/", StatusFound)
+			return
+		}
+		fmt.Fprintf(w, "%s", r.Header["X-Trace"])
+	}))
+	defer ts.Close()
+
+	var calls []string
+	trace := func(name string) Interceptor {
+		return func(next RoundTripper) RoundTripper {
+			return RoundTripperFunc(func(req *Request) (*Response, error) {
+				calls = append(calls, name+" "+req.URL.Path)
+				r2 := *req
+				r2.Header = Header{"X-Trace": append(req.Header["X-Trace"], name)}
+				return next.RoundTrip(&r2)
+			})
+		}
+	}
+	c := &Client{Interceptors: []Interceptor{trace("outer"), trace("inner")}}
+	res, err := c.Get(ts.URL + "/redirect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	slurp, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(slurp), "[outer inner]"; got != want {
+		t.Errorf("body = %q; want %q", got, want)
+	}
+	want := []string{"outer /redirect", "inner /redirect", "outer /", "inner /"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("interceptor calls = %q; want %q", calls, want)
+	}
+}
+
+func TestClientInterceptorShortCircuit(t *testing.T) {
+	var closed bool
+	c := &Client{
+		Transport: RoundTripperFunc(func(*Request) (*Response, error) {
+			t.Error("Transport called")
+			return nil, errors.New("unreachable")
+		}),
+		Interceptors: []Interceptor{func(RoundTripper) RoundTripper {
+			return RoundTripperFunc(func(req *Request) (*Response, error) {
+				req.Body.Close()
+				closed = true
+				return &Response{
+					StatusCode: StatusTeapot,
+					Body:       ioutil.NopCloser(strings.NewReader("")),
+					Request:    req,
+				}, nil
+			})
+		}},
+	}
+	res, err := c.Post("http://example.com/", "text/plain", strings.NewReader("body"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != StatusTeapot || !closed {
+		t.Errorf("status = %d, body closed = %v; want %d, true", res.StatusCode, closed, StatusTeapot)
+	}
+}
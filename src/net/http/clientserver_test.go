@@ -673,6 +673,33 @@ func testTrailersServerToClient(t *testing.T, h2, flush bool) {
 	}
 }
 
+// Trailers not declared before the header was written can still be
+// sent using TrailerPrefix.
+func TestTrailerPrefix_h1(t *testing.T) { testTrailerPrefix(t, h1Mode) }
+func TestTrailerPrefix_h2(t *testing.T) { testTrailerPrefix(t, h2Mode) }
+
+func testTrailerPrefix(t *testing.T, h2 bool) {
+	defer afterTest(t)
+	const body = "Some body"
+	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+		io.WriteString(w, body)
+		w.(Flusher).Flush()
+		w.Header().Set(TrailerPrefix+"Server-Trailer", "value")
+	}))
+	defer cst.close()
+
+	res, err := cst.c.Get(cst.ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wantBody(res, nil, body); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := res.Trailer, (Header{"Server-Trailer": {"value"}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Trailer = %v; want %v", got, want)
+	}
+}
+
 // Don't allow a Body.Read after Body.Close. Issue 13648.
 func TestResponseBodyReadAfterClose_h1(t *testing.T) { testResponseBodyReadAfterClose(t, h1Mode) }
 func TestResponseBodyReadAfterClose_h2(t *testing.T) { testResponseBodyReadAfterClose(t, h2Mode) }
@@ -1270,3 +1297,29 @@ func testNoSniffExpectRequestBody(t *testing.T, h2 bool) {
 		t.Errorf("status code = %v; want %v", res.StatusCode, StatusUnauthorized)
 	}
 }
+
+func TestPushNotSupported_h1(t *testing.T) { testPushNotSupported(t, h1Mode) }
+func TestPushNotSupported_h2(t *testing.T) { testPushNotSupported(t, h2Mode) }
+
+// The HTTP/1 ResponseWriter cannot push, and the HTTP/2 Transport
+// disables push, so Push reports ErrNotSupported either way.
+func testPushNotSupported(t *testing.T, h2 bool) {
+	defer afterTest(t)
+	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+		p, ok := w.(Pusher)
+		if !ok {
+			t.Errorf("ResponseWriter %T does not implement Pusher", w)
+			return
+		}
+		if err := p.Push("/style.css", nil); err != ErrNotSupported {
+			t.Errorf("Push = %v; want %v", err, ErrNotSupported)
+		}
+	}))
+	defer cst.close()
+
+	res, err := cst.c.Get(cst.ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+}
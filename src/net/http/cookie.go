@@ -32,10 +32,48 @@ type Cookie struct {
 	MaxAge   int
 	Secure   bool
 	HttpOnly bool
+	SameSite SameSite
+
+	// Partitioned requests that the cookie be stored in a jar
+	// partitioned by the top-level site (CHIPS). Partitioned
+	// cookies must also be Secure.
+	Partitioned bool
+
 	Raw      string
 	Unparsed []string // Raw text of unparsed attribute-value pairs
 }
 
+// SameSite allows a server to define a cookie attribute making it impossible for
+// the browser to send this cookie along with cross-site requests. The main
+// goal is to mitigate the risk of cross-origin information leakage, and provide
+// some protection against cross-site request forgery attacks.
+//
+// See https://tools.ietf.org/html/draft-ietf-httpbis-rfc6265bis for details.
+type SameSite int
+
+const (
+	// SameSiteDefaultMode means the SameSite attribute was
+	// present but had an unrecognized value; it is serialized
+	// without the attribute, leaving the policy to the browser.
+	SameSiteDefaultMode SameSite = iota + 1
+	SameSiteLaxMode
+	SameSiteStrictMode
+	SameSiteNoneMode
+)
+
+var sameSiteName = map[SameSite]string{
+	SameSiteDefaultMode: "",
+	SameSiteLaxMode:     "Lax",
+	SameSiteStrictMode:  "Strict",
+	SameSiteNoneMode:    "None",
+}
+
+// String returns the value of the SameSite attribute for s, or the
+// empty string if the attribute should be omitted.
+func (s SameSite) String() string {
+	return sameSiteName[s]
+}
+
 // readSetCookies parses all "Set-Cookie" values from
 // the header h and returns the successfully parsed Cookies.
 func readSetCookies(h Header) []*Cookie {
@@ -90,6 +128,21 @@ func readSetCookies(h Header) []*Cookie {
 			case "httponly":
 				c.HttpOnly = true
 				continue
+			case "samesite":
+				switch strings.ToLower(val) {
+				case "lax":
+					c.SameSite = SameSiteLaxMode
+				case "strict":
+					c.SameSite = SameSiteStrictMode
+				case "none":
+					c.SameSite = SameSiteNoneMode
+				default:
+					c.SameSite = SameSiteDefaultMode
+				}
+				continue
+			case "partitioned":
+				c.Partitioned = true
+				continue
 			case "domain":
 				c.Domain = val
 				continue
@@ -188,6 +241,13 @@ func (c *Cookie) String() string {
 	if c.Secure {
 		b.WriteString("; Secure")
 	}
+	if v := c.SameSite.String(); v != "" {
+		b.WriteString("; SameSite=")
+		b.WriteString(v)
+	}
+	if c.Partitioned {
+		b.WriteString("; Partitioned")
+	}
 	return b.String()
 }
 
@@ -94,6 +94,26 @@ var writeSetCookiesTests = []struct {
 		&Cookie{Name: "empty-value", Value: ""},
 		`empty-value=`,
 	},
+	{
+		&Cookie{Name: "cookie-12", Value: "samesite-default", SameSite: SameSiteDefaultMode},
+		`cookie-12=samesite-default`,
+	},
+	{
+		&Cookie{Name: "cookie-13", Value: "samesite-lax", SameSite: SameSiteLaxMode},
+		`cookie-13=samesite-lax; SameSite=Lax`,
+	},
+	{
+		&Cookie{Name: "cookie-14", Value: "samesite-strict", SameSite: SameSiteStrictMode},
+		`cookie-14=samesite-strict; SameSite=Strict`,
+	},
+	{
+		&Cookie{Name: "cookie-15", Value: "samesite-none", Secure: true, SameSite: SameSiteNoneMode},
+		`cookie-15=samesite-none; Secure; SameSite=None`,
+	},
+	{
+		&Cookie{Name: "cookie-16", Value: "partitioned", Path: "/", Secure: true, Partitioned: true},
+		`cookie-16=partitioned; Path=/; Secure; Partitioned`,
+	},
 	{
 		nil,
 		``,
@@ -266,6 +286,26 @@ var readSetCookiesTests = []struct {
 		Header{"Set-Cookie": {`special-8=","`}},
 		[]*Cookie{{Name: "special-8", Value: ",", Raw: `special-8=","`}},
 	},
+	{
+		Header{"Set-Cookie": {`samesite-default=foo; SameSite`}},
+		[]*Cookie{{Name: "samesite-default", Value: "foo", SameSite: SameSiteDefaultMode, Raw: `samesite-default=foo; SameSite`}},
+	},
+	{
+		Header{"Set-Cookie": {`samesite-lax=foo; SameSite=lax`}},
+		[]*Cookie{{Name: "samesite-lax", Value: "foo", SameSite: SameSiteLaxMode, Raw: `samesite-lax=foo; SameSite=lax`}},
+	},
+	{
+		Header{"Set-Cookie": {`samesite-strict=foo; SameSite=Strict`}},
+		[]*Cookie{{Name: "samesite-strict", Value: "foo", SameSite: SameSiteStrictMode, Raw: `samesite-strict=foo; SameSite=Strict`}},
+	},
+	{
+		Header{"Set-Cookie": {`samesite-none=foo; Secure; SameSite=None`}},
+		[]*Cookie{{Name: "samesite-none", Value: "foo", Secure: true, SameSite: SameSiteNoneMode, Raw: `samesite-none=foo; Secure; SameSite=None`}},
+	},
+	{
+		Header{"Set-Cookie": {`partitioned=foo; Secure; Partitioned`}},
+		[]*Cookie{{Name: "partitioned", Value: "foo", Secure: true, Partitioned: true, Raw: `partitioned=foo; Secure; Partitioned`}},
+	},
 
 	// TODO(bradfitz): users have reported seeing this in the
 	// wild, but do browsers handle it? RFC 6265 just says "don't
@@ -3,6 +3,10 @@
 // license that can be found in the LICENSE file.
 
 // Package cookiejar implements an in-memory RFC 6265-compliant http.CookieJar.
+//
+// The jar also implements the SameSite and Partitioned cookie attributes
+// and the cookie name prefixes of RFC 6265bis, and can keep its cookies
+// across program runs in a Storage.
 package cookiejar
 
 import (
@@ -54,12 +58,25 @@ type Options struct {
 	// secure: it means that the HTTP server for foo.co.uk can set a cookie
 	// for bar.co.uk.
 	PublicSuffixList PublicSuffixList
+
+	// Storage optionally specifies where the jar keeps its cookies
+	// across program runs. If non-nil, New loads the cookies saved
+	// in it, and Jar.Save writes them back.
+	Storage Storage
+
+	// PersistSessionCookies makes Jar.Save also save session
+	// cookies, those without an expiry time, so that a session
+	// outlives the program, as a browser restoring its tabs would.
+	PersistSessionCookies bool
 }
 
 // Jar implements the http.CookieJar interface from the net/http package.
 type Jar struct {
 	psList PublicSuffixList
 
+	storage         Storage
+	persistSessions bool
+
 	// mu locks the remaining fields.
 	mu sync.Mutex
 
@@ -80,6 +97,19 @@ func New(o *Options) (*Jar, error) {
 	}
 	if o != nil {
 		jar.psList = o.PublicSuffixList
+		jar.storage = o.Storage
+		jar.persistSessions = o.PersistSessionCookies
+	}
+	if jar.storage != nil {
+		data, err := jar.storage.Load()
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > 0 {
+			if err := jar.UnmarshalJSON(data); err != nil {
+				return nil, err
+			}
+		}
 	}
 	return jar, nil
 }
@@ -101,14 +131,24 @@ type entry struct {
 	Creation   time.Time
 	LastAccess time.Time
 
+	// SameSite is the same-site-flag of RFC 6265bis: "Strict", "Lax",
+	// "None" or "Default". Partition is the site of the top-level
+	// request a Partitioned cookie was set in, or empty.
+	SameSite  string `json:",omitempty"`
+	Partition string `json:",omitempty"`
+
 	// seqNum is a sequence number so that Cookies returns cookies in a
 	// deterministic order, even for cookies that have equal Path length and
 	// equal Creation time. This simplifies testing.
 	seqNum uint64
 }
 
-// id returns the domain;path;name triple of e as an id.
+// id returns the domain;path;name triple of e as an id. Partitioned
+// cookies are told apart by their partition as well.
 func (e *entry) id() string {
+	if e.Partition != "" {
+		return fmt.Sprintf("%s;%s;%s;%s", e.Domain, e.Path, e.Name, e.Partition)
+	}
 	return fmt.Sprintf("%s;%s;%s", e.Domain, e.Path, e.Name)
 }
 
@@ -119,6 +159,33 @@ func (e *entry) shouldSend(https bool, host, path string) bool {
 	return e.domainMatch(host) && e.pathMatch(path) && (https || !e.Secure)
 }
 
+// sameSiteAllows reports whether e's same-site-flag permits sending it
+// in a request made in a context described by r, according to RFC
+// 6265bis section 5.8.3. Cookies without a recognized flag are treated
+// as "Lax", as browsers do.
+func (e *entry) sameSiteAllows(r *requestSite) bool {
+	if r.sameSite {
+		return true
+	}
+	switch e.SameSite {
+	case "None":
+		return true
+	case "Strict":
+		return false
+	}
+	return r.navigation && isSafeMethod(r.method)
+}
+
+// isSafeMethod reports whether method is "safe" in the sense of
+// RFC 7231 section 4.2.1.
+func isSafeMethod(method string) bool {
+	switch method {
+	case "", "GET", "HEAD", "OPTIONS", "TRACE":
+		return true
+	}
+	return false
+}
+
 // domainMatch implements "domain-match" of RFC 6265 section 5.1.3.
 func (e *entry) domainMatch(host string) bool {
 	if e.Domain == host {
@@ -147,6 +214,65 @@ func hasDotSuffix(s, suffix string) bool {
 	return len(s) > len(suffix) && s[len(s)-len(suffix)-1] == '.' && s[len(s)-len(suffix):] == suffix
 }
 
+// SiteContext describes where a request comes from, so that the Jar
+// can enforce the SameSite and Partitioned cookie attributes.
+//
+// The Cookies and SetCookies methods of the http.CookieJar interface
+// have no such context. They treat each request as a same-site,
+// top-level navigation, as if the user had typed its URL.
+type SiteContext struct {
+	// TopLevel is the URL of the top-level document on whose
+	// behalf the request is made. If nil, the request URL itself
+	// is used, making the request same-site.
+	TopLevel *url.URL
+
+	// Navigation reports whether the request navigates the
+	// top-level document, as opposed to fetching a subresource.
+	Navigation bool
+
+	// Method is the request method. Empty means "GET".
+	Method string
+}
+
+// requestSite is a SiteContext resolved against the request URL.
+type requestSite struct {
+	partition  string // site of the top-level document
+	sameSite   bool   // whether the request is same-site
+	navigation bool
+	method     string
+}
+
+// resolve resolves the context sc of a request for u.
+func (j *Jar) resolve(u *url.URL, sc SiteContext) (*requestSite, error) {
+	site, err := j.site(u)
+	if err != nil {
+		return nil, err
+	}
+	r := &requestSite{
+		partition:  site,
+		sameSite:   true,
+		navigation: sc.Navigation,
+		method:     sc.Method,
+	}
+	if sc.TopLevel != nil {
+		if r.partition, err = j.site(sc.TopLevel); err != nil {
+			return nil, err
+		}
+		r.sameSite = r.partition == site
+	}
+	return r, nil
+}
+
+// site returns the scheme and registrable domain of u, which together
+// identify its site in the "schemeful same-site" sense of RFC 6265bis.
+func (j *Jar) site(u *url.URL) (string, error) {
+	host, err := canonicalHost(u.Host)
+	if err != nil {
+		return "", err
+	}
+	return u.Scheme + "://" + jarKey(host, j.psList), nil
+}
+
 // Cookies implements the Cookies method of the http.CookieJar interface.
 //
 // It returns an empty slice if the URL's scheme is not HTTP or HTTPS.
@@ -154,8 +280,19 @@ func (j *Jar) Cookies(u *url.URL) (cookies []*http.Cookie) {
 	return j.cookies(u, time.Now())
 }
 
+// CookiesFor is like Cookies, but returns only the cookies that may be
+// sent in a request for u made in the context sc.
+func (j *Jar) CookiesFor(u *url.URL, sc SiteContext) (cookies []*http.Cookie) {
+	return j.cookiesFor(u, sc, time.Now())
+}
+
 // cookies is like Cookies but takes the current time as a parameter.
 func (j *Jar) cookies(u *url.URL, now time.Time) (cookies []*http.Cookie) {
+	return j.cookiesFor(u, SiteContext{Navigation: true}, now)
+}
+
+// cookiesFor is like CookiesFor but takes the current time as a parameter.
+func (j *Jar) cookiesFor(u *url.URL, sc SiteContext, now time.Time) (cookies []*http.Cookie) {
 	if u.Scheme != "http" && u.Scheme != "https" {
 		return cookies
 	}
@@ -163,6 +300,10 @@ func (j *Jar) cookies(u *url.URL, now time.Time) (cookies []*http.Cookie) {
 	if err != nil {
 		return cookies
 	}
+	rs, err := j.resolve(u, sc)
+	if err != nil {
+		return cookies
+	}
 	key := jarKey(host, j.psList)
 
 	j.mu.Lock()
@@ -187,7 +328,10 @@ func (j *Jar) cookies(u *url.URL, now time.Time) (cookies []*http.Cookie) {
 			modified = true
 			continue
 		}
-		if !e.shouldSend(https, host, path) {
+		if !e.shouldSend(https, host, path) || !e.sameSiteAllows(rs) {
+			continue
+		}
+		if e.Partition != "" && e.Partition != rs.partition {
 			continue
 		}
 		e.LastAccess = now
@@ -229,8 +373,22 @@ func (j *Jar) SetCookies(u *url.URL, cookies []*http.Cookie) {
 	j.setCookies(u, cookies, time.Now())
 }
 
+// SetCookiesFor is like SetCookies for the cookies of a response to a
+// request for u made in the context sc. Unless the request is
+// same-site or a top-level navigation, it ignores all cookies but
+// those with SameSite=None.
+func (j *Jar) SetCookiesFor(u *url.URL, sc SiteContext, cookies []*http.Cookie) {
+	j.setCookiesFor(u, sc, cookies, time.Now())
+}
+
 // setCookies is like SetCookies but takes the current time as parameter.
 func (j *Jar) setCookies(u *url.URL, cookies []*http.Cookie, now time.Time) {
+	j.setCookiesFor(u, SiteContext{Navigation: true}, cookies, now)
+}
+
+// setCookiesFor is like SetCookiesFor but takes the current time as
+// parameter.
+func (j *Jar) setCookiesFor(u *url.URL, sc SiteContext, cookies []*http.Cookie, now time.Time) {
 	if len(cookies) == 0 {
 		return
 	}
@@ -241,8 +399,13 @@ func (j *Jar) setCookies(u *url.URL, cookies []*http.Cookie, now time.Time) {
 	if err != nil {
 		return
 	}
+	rs, err := j.resolve(u, sc)
+	if err != nil {
+		return
+	}
 	key := jarKey(host, j.psList)
 	defPath := defaultPath(u.Path)
+	https := u.Scheme == "https"
 
 	j.mu.Lock()
 	defer j.mu.Unlock()
@@ -251,10 +414,17 @@ func (j *Jar) setCookies(u *url.URL, cookies []*http.Cookie, now time.Time) {
 
 	modified := false
 	for _, cookie := range cookies {
-		e, remove, err := j.newEntry(cookie, now, defPath, host)
+		e, remove, err := j.newEntry(cookie, now, defPath, host, https)
 		if err != nil {
 			continue
 		}
+		// See RFC 6265bis section 5.7, step 17.
+		if cookie.SameSite != http.SameSiteNoneMode && !rs.sameSite && !rs.navigation {
+			continue
+		}
+		if cookie.Partitioned {
+			e.Partition = rs.partition
+		}
 		id := e.id()
 		if remove {
 			if submap != nil {
@@ -371,16 +541,22 @@ func defaultPath(path string) string {
 
 // newEntry creates an entry from a http.Cookie c. now is the current time and
 // is compared to c.Expires to determine deletion of c. defPath and host are the
-// default-path and the canonical host name of the URL c was received from.
+// default-path and the canonical host name of the URL c was received from,
+// and https reports whether that URL is secure.
 //
 // remove records whether the jar should delete this cookie, as it has already
 // expired with respect to now. In this case, e may be incomplete, but it will
 // be valid to call e.id (which depends on e's Name, Domain and Path).
 //
-// A malformed c.Domain will result in an error.
-func (j *Jar) newEntry(c *http.Cookie, now time.Time, defPath, host string) (e entry, remove bool, err error) {
+// A malformed c.Domain, or a cookie breaking the rules of RFC 6265bis for
+// its name prefix, SameSite=None or Partitioned, will result in an error.
+func (j *Jar) newEntry(c *http.Cookie, now time.Time, defPath, host string, https bool) (e entry, remove bool, err error) {
 	e.Name = c.Name
 
+	if err := checkSecureAttributes(c, https); err != nil {
+		return e, false, err
+	}
+
 	if c.Path == "" || c.Path[0] != '/' {
 		e.Path = defPath
 	} else {
@@ -414,14 +590,54 @@ func (j *Jar) newEntry(c *http.Cookie, now time.Time, defPath, host string) (e e
 	e.Value = c.Value
 	e.Secure = c.Secure
 	e.HttpOnly = c.HttpOnly
+	switch c.SameSite {
+	case http.SameSiteStrictMode:
+		e.SameSite = "Strict"
+	case http.SameSiteLaxMode:
+		e.SameSite = "Lax"
+	case http.SameSiteNoneMode:
+		e.SameSite = "None"
+	default:
+		e.SameSite = "Default"
+	}
 
 	return e, false, nil
 }
 
+// checkSecureAttributes checks the requirements RFC 6265bis section 5.7
+// places on cookies with the "__Secure-" and "__Host-" name prefixes,
+// with SameSite=None and, following CHIPS, on Partitioned cookies. https
+// reports whether the cookie was received over a secure channel.
+func checkSecureAttributes(c *http.Cookie, https bool) error {
+	if hasPrefixFold(c.Name, "__Secure-") && (!c.Secure || !https) {
+		return errSecurePrefix
+	}
+	if hasPrefixFold(c.Name, "__Host-") && (!c.Secure || !https || c.Domain != "" || c.Path != "/") {
+		return errHostPrefix
+	}
+	if c.SameSite == http.SameSiteNoneMode && !c.Secure {
+		return errInsecureSameSiteNone
+	}
+	if c.Partitioned && !c.Secure {
+		return errInsecurePartitioned
+	}
+	return nil
+}
+
+// hasPrefixFold is like strings.HasPrefix, but ignores ASCII case.
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
 var (
 	errIllegalDomain   = errors.New("cookiejar: illegal cookie domain attribute")
 	errMalformedDomain = errors.New("cookiejar: malformed cookie domain attribute")
 	errNoHostname      = errors.New("cookiejar: no host name available (IP only)")
+
+	errSecurePrefix         = errors.New("cookiejar: __Secure- cookie must be Secure and set over https")
+	errHostPrefix           = errors.New("cookiejar: __Host- cookie must be Secure, set over https, host-only and for path /")
+	errInsecureSameSiteNone = errors.New("cookiejar: SameSite=None cookie must be Secure")
+	errInsecurePartitioned  = errors.New("cookiejar: Partitioned cookie must be Secure")
 )
 
 // endOfTime is the time when session (non-persistent) cookies expire.
@@ -1265,3 +1265,161 @@ func TestDomainHandling(t *testing.T) {
 		test.run(t, jar)
 	}
 }
+
+// prefixAndSecurityTests contains tests for the cookie name prefixes and
+// the Secure requirements of RFC 6265bis.
+var prefixAndSecurityTests = [...]jarTest{
+	{
+		"__Secure- cookie set over https.",
+		"https://www.host.test/",
+		[]string{"__Secure-A=a; secure", "__secure-B=b; secure; domain=host.test"},
+		"__Secure-A=a __secure-B=b",
+		[]query{{"https://www.host.test", "__Secure-A=a __secure-B=b"}},
+	},
+	{
+		"__Secure- cookie must be Secure and set over https.",
+		"http://www.host.test/",
+		[]string{"__Secure-A=a; secure", "__Secure-B=b"},
+		"",
+		[]query{{"https://www.host.test", ""}},
+	},
+	{
+		"__Host- cookie must be host-only and for path /.",
+		"https://www.host.test/some/path",
+		[]string{
+			"__Host-A=a; secure; path=/",
+			"__Host-B=b; secure",
+			"__Host-C=c; secure; path=/; domain=www.host.test",
+			"__HOST-D=d; path=/",
+		},
+		"__Host-A=a",
+		[]query{
+			{"https://www.host.test", "__Host-A=a"},
+			{"https://sub.www.host.test", ""},
+		},
+	},
+	{
+		"SameSite=None and Partitioned cookies must be Secure.",
+		"https://www.host.test/",
+		[]string{
+			"A=a; samesite=none",
+			"B=b; samesite=none; secure",
+			"C=c; partitioned",
+			"D=d; partitioned; secure",
+		},
+		"B=b D=d",
+		[]query{{"https://www.host.test", "B=b D=d"}},
+	},
+}
+
+func TestPrefixAndSecurity(t *testing.T) {
+	for _, test := range prefixAndSecurityTests {
+		jar := newTestJar()
+		test.run(t, jar)
+	}
+}
+
+// parseSetCookies parses Set-Cookie header lines.
+func parseSetCookies(lines ...string) []*http.Cookie {
+	return (&http.Response{Header: http.Header{"Set-Cookie": lines}}).Cookies()
+}
+
+// cookieNames returns the names of cookies joined by spaces.
+func cookieNames(cookies []*http.Cookie) string {
+	var s []string
+	for _, c := range cookies {
+		s = append(s, c.Name)
+	}
+	return strings.Join(s, " ")
+}
+
+func TestSameSite(t *testing.T) {
+	jar := newTestJar()
+	u := mustParseURL("https://www.host.test/")
+	jar.setCookies(u, parseSetCookies(
+		"S=s; samesite=strict",
+		"L=l; samesite=lax",
+		"N=n; samesite=none; secure",
+		"D=d",
+	), tNow)
+
+	other := mustParseURL("https://www.other.test/")
+	tests := []struct {
+		sc   SiteContext
+		want string
+	}{
+		{SiteContext{}, "S L N D"},
+		{SiteContext{TopLevel: mustParseURL("https://sub.host.test/")}, "S L N D"},
+		{SiteContext{TopLevel: other}, "N"},
+		{SiteContext{TopLevel: other, Navigation: true}, "L N D"},
+		{SiteContext{TopLevel: other, Navigation: true, Method: "HEAD"}, "L N D"},
+		{SiteContext{TopLevel: other, Navigation: true, Method: "POST"}, "N"},
+		// Sites are schemeful: http and https are different sites.
+		{SiteContext{TopLevel: mustParseURL("http://www.host.test/")}, "N"},
+	}
+	for i, tt := range tests {
+		got := cookieNames(jar.cookiesFor(u, tt.sc, tNow.Add(time.Second)))
+		if got != tt.want {
+			t.Errorf("#%d: got %q, want %q", i, got, tt.want)
+		}
+	}
+}
+
+func TestSetCookiesForCrossSite(t *testing.T) {
+	jar := newTestJar()
+	u := mustParseURL("https://www.host.test/")
+	sc := SiteContext{TopLevel: mustParseURL("https://www.other.test/")}
+	jar.setCookiesFor(u, sc, parseSetCookies(
+		"L=l; samesite=lax",
+		"N=n; samesite=none; secure",
+		"D=d",
+	), tNow)
+	if got, want := cookieNames(jar.cookies(u, tNow.Add(time.Second))), "N"; got != want {
+		t.Errorf("after cross-site subresource request got %q, want %q", got, want)
+	}
+
+	sc.Navigation = true
+	jar.setCookiesFor(u, sc, parseSetCookies("L=l; samesite=lax"), tNow)
+	if got, want := cookieNames(jar.cookies(u, tNow.Add(time.Second))), "N L"; got != want {
+		t.Errorf("after cross-site navigation got %q, want %q", got, want)
+	}
+}
+
+func TestPartitioned(t *testing.T) {
+	jar := newTestJar()
+	u := mustParseURL("https://widget.host.test/")
+	siteA := SiteContext{TopLevel: mustParseURL("https://a.test/")}
+	siteB := SiteContext{TopLevel: mustParseURL("https://b.test/")}
+	jar.setCookiesFor(u, siteA, parseSetCookies(
+		"P=p; samesite=none; secure; partitioned",
+		"U=u; samesite=none; secure",
+	), tNow)
+
+	now := tNow.Add(time.Second)
+	if got, want := cookieNames(jar.cookiesFor(u, siteA, now)), "P U"; got != want {
+		t.Errorf("in partition of a.test got %q, want %q", got, want)
+	}
+	if got, want := cookieNames(jar.cookiesFor(u, siteB, now)), "U"; got != want {
+		t.Errorf("in partition of b.test got %q, want %q", got, want)
+	}
+	if got, want := cookieNames(jar.cookies(u, now)), "U"; got != want {
+		t.Errorf("top-level got %q, want %q", got, want)
+	}
+}
+
+func TestPartitionedSameName(t *testing.T) {
+	jar := newTestJar()
+	u := mustParseURL("https://widget.host.test/")
+	siteA := SiteContext{TopLevel: mustParseURL("https://a.test/")}
+	siteB := SiteContext{TopLevel: mustParseURL("https://b.test/")}
+	jar.setCookiesFor(u, siteA, parseSetCookies("P=a; samesite=none; secure; partitioned"), tNow)
+	jar.setCookiesFor(u, siteB, parseSetCookies("P=b; samesite=none; secure; partitioned"), tNow)
+
+	now := tNow.Add(time.Second)
+	for sc, want := range map[*SiteContext]string{&siteA: "a", &siteB: "b"} {
+		cookies := jar.cookiesFor(u, *sc, now)
+		if len(cookies) != 1 || cookies[0].Value != want {
+			t.Errorf("in partition of %s got %v, want P=%s", sc.TopLevel, cookies, want)
+		}
+	}
+}
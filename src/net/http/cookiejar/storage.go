@@ -0,0 +1,158 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Storage is where a Jar keeps its cookies across program runs.
+//
+// Implementations of Storage must be safe for concurrent use by
+// multiple goroutines.
+type Storage interface {
+	// Load returns the data last passed to Save, or nil if
+	// nothing has been saved yet.
+	Load() ([]byte, error)
+
+	// Save replaces the stored data with data.
+	Save(data []byte) error
+}
+
+// FileStorage returns a Storage that keeps the cookies in the named
+// file. Save replaces the file atomically and makes it readable by
+// its owner only, as it holds credentials.
+func FileStorage(name string) Storage {
+	return fileStorage(name)
+}
+
+type fileStorage string
+
+func (name fileStorage) Load() ([]byte, error) {
+	data, err := ioutil.ReadFile(string(name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+func (name fileStorage) Save(data []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(string(name)), filepath.Base(string(name))+".tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if err := f.Chmod(0600); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, string(name)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// Save writes the cookies of j to the Storage it was created with.
+// Expired cookies are dropped, and so are session cookies unless
+// Options.PersistSessionCookies was set. Save does nothing if j has
+// no Storage.
+func (j *Jar) Save() error {
+	if j.storage == nil {
+		return nil
+	}
+	data, err := j.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	return j.storage.Save(data)
+}
+
+// MarshalJSON implements the json.Marshaler interface. It encodes the
+// cookies of j that Save would store, oldest first.
+func (j *Jar) MarshalJSON() ([]byte, error) {
+	return json.Marshal(j.savedEntries(time.Now()))
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. It adds the
+// unexpired cookies encoded in data to j, replacing those with the same
+// name, domain and path.
+func (j *Jar) UnmarshalJSON(data []byte) error {
+	var entries []entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+	j.loadEntries(entries, time.Now())
+	return nil
+}
+
+// savedEntries returns the entries of j that are to be saved at time
+// now, sorted by creation time.
+func (j *Jar) savedEntries(now time.Time) []entry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var entries []entry
+	for _, submap := range j.entries {
+		for _, e := range submap {
+			if e.Persistent && !e.Expires.After(now) {
+				continue
+			}
+			if !e.Persistent && !j.persistSessions {
+				continue
+			}
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, k int) bool {
+		if !entries[i].Creation.Equal(entries[k].Creation) {
+			return entries[i].Creation.Before(entries[k].Creation)
+		}
+		return entries[i].seqNum < entries[k].seqNum
+	})
+	return entries
+}
+
+// loadEntries adds entries to j, skipping those already expired at
+// time now. The entries must be sorted by creation time, so that the
+// sequence numbers they are given preserve the order of Cookies.
+func (j *Jar) loadEntries(entries []entry, now time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, e := range entries {
+		if e.Persistent && !e.Expires.After(now) {
+			continue
+		}
+		host, err := canonicalHost(e.Domain)
+		if err != nil || host == "" {
+			continue
+		}
+		key := jarKey(host, j.psList)
+		submap := j.entries[key]
+		if submap == nil {
+			submap = make(map[string]entry)
+			j.entries[key] = submap
+		}
+		e.seqNum = j.nextSeqNum
+		j.nextSeqNum++
+		submap[e.id()] = e
+	}
+}
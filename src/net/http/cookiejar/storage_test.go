@@ -0,0 +1,122 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// memStorage is a Storage keeping its data in memory.
+type memStorage struct {
+	data []byte
+}
+
+func (s *memStorage) Load() ([]byte, error) { return s.data, nil }
+
+func (s *memStorage) Save(data []byte) error {
+	s.data = append([]byte(nil), data...)
+	return nil
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	for _, persistSessions := range []bool{false, true} {
+		st := new(memStorage)
+		opts := &Options{PublicSuffixList: testPSL{}, Storage: st, PersistSessionCookies: persistSessions}
+		jar, err := New(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		u := mustParseURL("https://www.host.test/")
+		now := time.Now()
+		jar.setCookies(u, parseSetCookies(
+			"A=a; max-age=3600",
+			"S=s",
+			"B=b; domain=host.test; max-age=3600; samesite=strict",
+		), now)
+		jar.setCookiesFor(u, SiteContext{TopLevel: mustParseURL("https://a.test/")},
+			parseSetCookies("P=p; max-age=3600; secure; samesite=none; partitioned"), now)
+		if err := jar.Save(); err != nil {
+			t.Fatal(err)
+		}
+
+		jar2, err := New(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "A B"
+		if persistSessions {
+			want = "A S B"
+		}
+		if got := cookieNames(jar2.Cookies(u)); got != want {
+			t.Errorf("persistSessions=%v: after reload got %q, want %q", persistSessions, got, want)
+		}
+		sc := SiteContext{TopLevel: mustParseURL("https://a.test/")}
+		if got := cookieNames(jar2.CookiesFor(u, sc)); got != "P" {
+			t.Errorf("persistSessions=%v: partitioned cookie after reload got %q, want %q", persistSessions, got, "P")
+		}
+	}
+}
+
+func TestSaveDropsExpired(t *testing.T) {
+	st := new(memStorage)
+	jar, err := New(&Options{PublicSuffixList: testPSL{}, Storage: st})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := mustParseURL("http://www.host.test/")
+	jar.setCookies(u, parseSetCookies("A=a; max-age=1", "B=b; max-age=3600"), time.Now().Add(-time.Minute))
+	if err := jar.Save(); err != nil {
+		t.Fatal(err)
+	}
+	jar2, err := New(&Options{PublicSuffixList: testPSL{}, Storage: st})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cookieNames(jar2.Cookies(u)); got != "B" {
+		t.Errorf("got %q, want %q", got, "B")
+	}
+}
+
+func TestFileStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cookiejar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "cookies.json")
+	st := FileStorage(name)
+
+	if data, err := st.Load(); err != nil || data != nil {
+		t.Fatalf("Load of missing file = %q, %v; want nil, nil", data, err)
+	}
+	if err := st.Save([]byte("[]")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := st.Load()
+	if err != nil || string(data) != "[]" {
+		t.Fatalf("Load = %q, %v; want %q, nil", data, err, "[]")
+	}
+	if runtime.GOOS != "windows" && runtime.GOOS != "plan9" {
+		fi, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := fi.Mode().Perm(); perm != 0600 {
+			t.Errorf("file mode = %v; want 0600", perm)
+		}
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("found %d files in %s; want 1", len(files), dir)
+	}
+}
@@ -88,6 +88,18 @@ environment variables are currently supported:
 	GODEBUG=http2debug=1   # enable verbose HTTP/2 debug logs
 	GODEBUG=http2debug=2   # ... even more verbose, with frame dumps
 
+Cleartext HTTP/2 ("h2c") is never used implicitly. Servers accept it
+on non-TLS connections when Server.AllowH2C is set, and clients use it
+for the addresses listed in Transport.H2CHosts.
+
+Programs whose ServeMux patterns predate methods and wildcards, and
+that contain spaces or braces, can keep the old pattern syntax and
+matching with
+
+	GODEBUG=httpmuxlegacy=1  # parse and match ServeMux patterns as plain paths
+
+See ServeMux for details.
+
 The GODEBUG variables are not covered by Go's API compatibility promise.
 HTTP/2 support was added in Go 1.6. Please report any issues instead of
 disabling HTTP/2 support: https://golang.org/s/http2bug
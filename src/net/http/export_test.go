@@ -180,3 +180,14 @@ func ExportHttp2ConfigureTransport(t *Transport) error {
 }
 
 var Export_shouldCopyHeaderOnRedirect = shouldCopyHeaderOnRedirect
+
+func SetRateLimitNowForTesting(h Handler, now func() time.Time) {
+	h.(*rateLimitHandler).now = now
+}
+
+func RateLimitBucketsForTesting(h Handler) int {
+	rh := h.(*rateLimitHandler)
+	rh.mu.Lock()
+	defer rh.mu.Unlock()
+	return len(rh.buckets)
+}
@@ -1875,7 +1875,7 @@ func (mh *http2MetaHeadersFrame) checkPseudos() error {
 	pf := mh.PseudoFields()
 	for i, hf := range pf {
 		switch hf.Name {
-		case ":method", ":path", ":scheme", ":authority":
+		case ":method", ":path", ":scheme", ":authority", ":protocol": // net/http hook; see h2_hooks.go
 			isRequest = true
 		case ":status":
 			isResponse = true
@@ -3003,6 +3003,9 @@ type http2ServeConnOpts struct {
 	// requests. If nil, BaseConfig.Handler is used. If BaseConfig
 	// or BaseConfig.Handler is nil, http.DefaultServeMux is used.
 	Handler Handler
+
+	// Additions made by net/http; see h2_hooks.go.
+	http2serveConnOptsHooks
 }
 
 func (o *http2ServeConnOpts) baseConfig() *Server {
@@ -3096,6 +3099,10 @@ func (s *http2Server) ServeConn(c net.Conn, opts *http2ServeConnOpts) {
 		}
 	}
 
+	if !sc.initHooks(opts) { // net/http hook; see h2_hooks.go
+		return
+	}
+
 	if hook := http2testHookGetServerConn; hook != nil {
 		hook(sc)
 	}
@@ -3155,6 +3162,9 @@ type http2serverConn struct {
 	shutdownTimer         *time.Timer      // nil until used
 	freeRequestBodyBuf    []byte           // if non-nil, a free initialWindowSize buffer for getRequestBodyBuf
 
+	// Additions made by net/http; see h2_hooks.go.
+	http2serverConnHooks
+
 	// Owned by the writeFrameAsync goroutine:
 	headerWriteBuf bytes.Buffer
 	hpackEncoder   *hpack.Encoder
@@ -3224,6 +3234,9 @@ func (sc *http2serverConn) state(streamID uint32) (http2streamState, *http2strea
 		return st.state, st
 	}
 
+	if streamID%2 == 0 {
+		return sc.pushedStreamState(streamID), nil // net/http hook; see h2_hooks.go
+	}
 	if streamID <= sc.maxStreamID {
 		return http2stateClosed, nil
 	}
@@ -3235,7 +3248,7 @@ func (sc *http2serverConn) state(streamID uint32) (http2streamState, *http2strea
 // There is currently no plan for StateHijacked or hijacking HTTP/2 connections.
 func (sc *http2serverConn) setConnState(state ConnState) {
 	if sc.hs.ConnState != nil {
-		sc.hs.ConnState(sc.conn, state)
+		sc.hs.ConnState(sc.connForState(), state) // net/http hook; see h2_hooks.go
 	}
 }
 
@@ -3412,11 +3425,11 @@ func (sc *http2serverConn) serve() {
 	}
 
 	sc.writeFrame(http2frameWriteMsg{
-		write: http2writeSettings{
+		write: append(http2writeSettings{
 			{http2SettingMaxFrameSize, sc.srv.maxReadFrameSize()},
 			{http2SettingMaxConcurrentStreams, sc.advMaxStreams},
 			{http2SettingMaxHeaderListSize, sc.maxHeaderListSize()},
-		},
+		}, sc.hookSettings()...), // net/http hook; see h2_hooks.go
 	})
 	sc.unackedSettings++
 
@@ -3430,6 +3443,8 @@ func (sc *http2serverConn) serve() {
 
 	go sc.readFrames()
 
+	sc.serveUpgradeRequest() // net/http hook; see h2_hooks.go
+
 	settingsTimer := time.NewTimer(http2firstSettingsTimeout)
 	loopNum := 0
 	for {
@@ -3450,6 +3465,8 @@ func (sc *http2serverConn) serve() {
 			}
 		case m := <-sc.bodyReadCh:
 			sc.noteBodyRead(m.st, m.n)
+		case msg := <-sc.wantStartPushCh: // net/http hook; see h2_hooks.go
+			sc.startPush(msg)
 		case <-settingsTimer.C:
 			sc.logf("timeout waiting for SETTINGS frames from %v", sc.conn.RemoteAddr())
 			return
@@ -3605,6 +3622,9 @@ func (sc *http2serverConn) startFrameWrite(wm http2frameWriteMsg) {
 			panic(fmt.Sprintf("internal error: attempt to send a write %v on a closed stream", wm))
 		}
 	}
+	if !sc.allocatePushPromise(wm) { // net/http hook; see h2_hooks.go
+		return
+	}
 
 	sc.writingFrame = true
 	sc.needsFrameFlush = true
@@ -3877,10 +3897,7 @@ func (sc *http2serverConn) closeStream(st *http2stream, err error) {
 		panic(fmt.Sprintf("invariant; can't close stream in state %v", st.state))
 	}
 	st.state = http2stateClosed
-	sc.curOpenStreams--
-	if sc.curOpenStreams == 0 {
-		sc.setConnState(StateIdle)
-	}
+	sc.noteStreamClosed(st) // net/http hook; see h2_hooks.go
 	delete(sc.streams, st.id)
 	if p := st.body; p != nil {
 
@@ -4191,6 +4208,10 @@ func http2adjustStreamPriority(streams map[uint32]*http2stream, streamID uint32,
 func (sc *http2serverConn) newWriterAndRequest(st *http2stream, f *http2MetaHeadersFrame) (*http2responseWriter, *Request, error) {
 	sc.serveG.check()
 
+	if f.PseudoValue("protocol") != "" {
+		return sc.newExtendedConnectWriterAndRequest(st, f) // net/http hook; see h2_hooks.go
+	}
+
 	method := f.PseudoValue("method")
 	path := f.PseudoValue("path")
 	scheme := f.PseudoValue("scheme")
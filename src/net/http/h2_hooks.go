@@ -0,0 +1,596 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Additions to the bundled HTTP/2 server.
+//
+// h2_bundle.go is generated from golang.org/x/net/http2 and must not
+// be edited by hand, beyond the calls into this file, each marked
+// "net/http hook", and the embedded fields that hold the state used
+// here. The code here belongs upstream; once x/net/http2 has it, the
+// hooks and this file go away on the next bundle update.
+
+package http
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// serveConnOptsHooks holds the options of ServeConn used by this file.
+type http2serveConnOptsHooks struct {
+	// upgradeRequest is an initial request received on a connection
+	// undergoing an h2c upgrade. The request body must have been
+	// completely read from the connection before calling ServeConn,
+	// and the 101 Switching Protocols response written.
+	upgradeRequest *Request
+
+	// settings is the decoded contents of the HTTP2-Settings header
+	// in an h2c upgrade request.
+	settings []byte
+
+	// stateConn, if non-nil, is the connection reported to
+	// BaseConfig.ConnState instead of the served one, for servers
+	// that wrap a connection they were already tracking.
+	stateConn net.Conn
+}
+
+// serverConnHooks holds the state of a serverConn used by this file.
+type http2serverConnHooks struct {
+	stateConn        net.Conn                    // reported to ConnState instead of conn, if non-nil
+	upgradeReq       *Request                    // h2c upgrade request to serve as stream 1, or nil
+	wantStartPushCh  chan *http2startPushRequest // from handlers -> serve
+	curPushedStreams uint32                      // number of open streams we pushed
+	maxPushPromiseID uint32                      // ID of the last push promise, or 0 if there have been no pushes
+}
+
+// initHooks is called by ServeConn before sc starts serving. It
+// reports false if it rejected the connection.
+func (sc *http2serverConn) initHooks(opts *http2ServeConnOpts) bool {
+	sc.wantStartPushCh = make(chan *http2startPushRequest, 8)
+	// Until the client says otherwise, it accepts any number of
+	// pushed streams.
+	sc.clientMaxStreams = math.MaxUint32
+
+	if opts == nil {
+		return true
+	}
+	sc.stateConn = opts.stateConn
+	sc.upgradeReq = opts.upgradeRequest
+	if opts.settings != nil {
+		fr := &http2SettingsFrame{
+			http2FrameHeader: http2FrameHeader{valid: true},
+			p:                opts.settings,
+		}
+		if err := fr.ForeachSetting(sc.processSetting); err != nil {
+			sc.rejectConn(http2ErrCodeProtocol, "invalid settings")
+			return false
+		}
+	}
+	return true
+}
+
+// connForState returns the connection to report to the Server's
+// ConnState hook.
+func (sc *http2serverConn) connForState() net.Conn {
+	if sc.stateConn != nil {
+		return sc.stateConn
+	}
+	return sc.conn
+}
+
+// serveUpgradeRequest serves the request of an h2c upgrade, if any.
+// It is called by serve once the server's SETTINGS have been written.
+func (sc *http2serverConn) serveUpgradeRequest() {
+	if req := sc.upgradeReq; req != nil {
+		sc.upgradeReq = nil
+		sc.upgradeRequest(req)
+	}
+}
+
+// pushedStreamState returns the state of a stream with an even ID,
+// one that sc may have pushed, if it is not among sc.streams.
+func (sc *http2serverConn) pushedStreamState(streamID uint32) http2streamState {
+	if streamID <= sc.maxPushPromiseID {
+		return http2stateClosed
+	}
+	return http2stateIdle
+}
+
+// allocatePushPromise gives the PUSH_PROMISE frame wm is about to
+// write, if it is one, the ID of its promised stream. If no stream
+// can be promised, it fails the write and reports false.
+func (sc *http2serverConn) allocatePushPromise(wm http2frameWriteMsg) bool {
+	wpp, ok := wm.write.(*http2writePushPromise)
+	if !ok {
+		return true
+	}
+	var err error
+	wpp.promisedID, err = wpp.allocatePromisedID()
+	if err != nil {
+		if ch := wm.done; ch != nil {
+			ch <- err
+		}
+		sc.scheduleFrameWrite()
+		return false
+	}
+	return true
+}
+
+// noteStreamClosed updates the counts of open streams for the closing
+// of st and reports the connection idle once none is left.
+func (sc *http2serverConn) noteStreamClosed(st *http2stream) {
+	if st.isPushed() {
+		sc.curPushedStreams--
+	} else {
+		sc.curOpenStreams--
+	}
+	if sc.curOpenStreams == 0 && sc.curPushedStreams == 0 {
+		sc.setConnState(StateIdle)
+	}
+}
+
+var (
+	http2ErrRecursivePush    = errors.New("http2: recursive push not allowed")
+	http2ErrPushLimitReached = errors.New("http2: push would exceed peer's SETTINGS_MAX_CONCURRENT_STREAMS")
+)
+
+var _ Pusher = (*http2responseWriter)(nil)
+
+// newStream registers a new stream with the given ID and state. The
+// stream depends on parent, if non-nil.
+func (sc *http2serverConn) newStream(id uint32, parent *http2stream, state http2streamState) *http2stream {
+	sc.serveG.check()
+
+	ctx, cancelCtx := http2contextWithCancel(sc.baseCtx)
+	st := &http2stream{
+		sc:        sc,
+		id:        id,
+		state:     state,
+		ctx:       ctx,
+		cancelCtx: cancelCtx,
+		parent:    parent,
+	}
+	st.cw.Init()
+	st.flow.conn = &sc.flow
+	st.flow.add(sc.initialWindowSize)
+	st.inflow.conn = &sc.inflow
+	st.inflow.add(http2initialWindowSize)
+
+	sc.streams[id] = st
+	if st.isPushed() {
+		sc.curPushedStreams++
+	} else {
+		sc.curOpenStreams++
+	}
+	if sc.curOpenStreams+sc.curPushedStreams == 1 {
+		sc.setConnState(StateActive)
+	}
+	return st
+}
+
+// isPushed reports whether the stream is server-initiated.
+func (st *http2stream) isPushed() bool {
+	return st.id%2 == 0
+}
+
+// requestParam holds the parts of a request parsed from the HEADERS
+// frame of a client stream or synthesized for a pushed stream.
+type http2requestParam struct {
+	method                  string
+	scheme, authority, path string
+	protocol                string // of an extended CONNECT request
+	header                  Header
+}
+
+// newWriterAndRequestNoBody creates the responseWriter and Request of
+// stream st for rp. The request's body is empty until the caller
+// attaches a pipe to it.
+func (sc *http2serverConn) newWriterAndRequestNoBody(st *http2stream, rp http2requestParam) (*http2responseWriter, *Request, error) {
+	sc.serveG.check()
+
+	var tlsState *tls.ConnectionState // nil if not scheme https
+	if rp.scheme == "https" {
+		tlsState = sc.tlsState
+	}
+
+	header := rp.header
+	authority := rp.authority
+	if authority == "" {
+		authority = header.Get("Host")
+	}
+	needsContinue := header.Get("Expect") == "100-continue"
+	if needsContinue {
+		header.Del("Expect")
+	}
+
+	if cookies := header["Cookie"]; len(cookies) > 1 {
+		header.Set("Cookie", strings.Join(cookies, "; "))
+	}
+
+	// Setup Trailers
+	var trailer Header
+	for _, v := range header["Trailer"] {
+		for _, key := range strings.Split(v, ",") {
+			key = CanonicalHeaderKey(strings.TrimSpace(key))
+			switch key {
+			case "Transfer-Encoding", "Trailer", "Content-Length":
+
+			default:
+				if trailer == nil {
+					trailer = make(Header)
+				}
+				trailer[key] = nil
+			}
+		}
+	}
+	delete(header, "Trailer")
+
+	body := &http2requestBody{
+		conn:          sc,
+		stream:        st,
+		needsContinue: needsContinue,
+	}
+	var url_ *url.URL
+	var requestURI string
+	if rp.protocol != "" {
+		// Extended CONNECT; handlers find the protocol among
+		// the headers, as the pseudo-header ":protocol".
+		header[":protocol"] = []string{rp.protocol}
+	}
+	if rp.method == "CONNECT" && rp.protocol == "" {
+		url_ = &url.URL{Host: authority}
+		requestURI = authority
+	} else {
+		var err error
+		url_, err = url.ParseRequestURI(rp.path)
+		if err != nil {
+			return nil, nil, http2streamError(st.id, http2ErrCodeProtocol)
+		}
+		requestURI = rp.path
+	}
+	req := &Request{
+		Method:     rp.method,
+		URL:        url_,
+		RemoteAddr: sc.remoteAddrStr,
+		Header:     header,
+		RequestURI: requestURI,
+		Proto:      "HTTP/2.0",
+		ProtoMajor: 2,
+		ProtoMinor: 0,
+		TLS:        tlsState,
+		Host:       authority,
+		Body:       body,
+		Trailer:    trailer,
+	}
+	req = http2requestWithContext(req, st.ctx)
+
+	rws := http2responseWriterStatePool.Get().(*http2responseWriterState)
+	bwSave := rws.bw
+	*rws = http2responseWriterState{}
+	rws.conn = sc
+	rws.bw = bwSave
+	rws.bw.Reset(http2chunkWriter{rws})
+	rws.stream = st
+	rws.req = req
+	rws.body = body
+
+	rw := &http2responseWriter{rws: rws}
+	return rw, req, nil
+}
+
+// upgradeRequest serves req, the HTTP/1.1 request of an h2c upgrade,
+// as stream 1, which the upgrade leaves half-closed (remote).
+func (sc *http2serverConn) upgradeRequest(req *Request) {
+	sc.serveG.check()
+
+	const id = 1
+	sc.maxStreamID = id
+	st := sc.newStream(id, nil, http2stateHalfClosedRemote)
+
+	header := http2cloneHeader(req.Header)
+	for _, k := range []string{"Connection", "Upgrade", "Http2-Settings", "Keep-Alive", "Proxy-Connection", "Transfer-Encoding"} {
+		delete(header, k)
+	}
+	rw, req, err := sc.newWriterAndRequestNoBody(st, http2requestParam{
+		method:    req.Method,
+		scheme:    "http",
+		authority: req.Host,
+		path:      req.RequestURI,
+		header:    header,
+	})
+	if err != nil {
+		sc.resetStream(err.(http2StreamError))
+		return
+	}
+	go sc.runHandler(rw, req, sc.handler.ServeHTTP)
+}
+
+// Push initiates a server push of target on the stream of the request
+// being handled. See the Pusher interface.
+func (w *http2responseWriter) Push(target string, opts *PushOptions) error {
+	st := w.rws.stream
+	sc := st.sc
+	sc.serveG.checkNotOn()
+
+	if st.isPushed() {
+		return http2ErrRecursivePush
+	}
+
+	if opts == nil {
+		opts = new(PushOptions)
+	}
+
+	if opts.Method == "" {
+		opts.Method = "GET"
+	}
+	if opts.Header == nil {
+		opts.Header = Header{}
+	}
+	wantScheme := "http"
+	if w.rws.req.TLS != nil {
+		wantScheme = "https"
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "" {
+		if !strings.HasPrefix(target, "/") {
+			return fmt.Errorf("target must be an absolute URL or an absolute path: %q", target)
+		}
+		u.Scheme = wantScheme
+		u.Host = w.rws.req.Host
+	} else {
+		if u.Scheme != wantScheme {
+			return fmt.Errorf("cannot push URL with scheme %q from request with scheme %q", u.Scheme, wantScheme)
+		}
+		if u.Host == "" {
+			return errors.New("URL must have a host")
+		}
+	}
+	for k := range opts.Header {
+		if strings.HasPrefix(k, ":") {
+			return fmt.Errorf("promised request headers cannot include pseudo header %q", k)
+		}
+
+		switch strings.ToLower(k) {
+		case "content-length", "content-encoding", "trailer", "te", "expect", "host":
+			return fmt.Errorf("promised request headers cannot include %q", k)
+		}
+	}
+	if err := http2checkValidHTTP2Request(&Request{Header: opts.Header}); err != nil {
+		return err
+	}
+
+	if opts.Method != "GET" && opts.Method != "HEAD" {
+		return fmt.Errorf("method %q must be GET or HEAD", opts.Method)
+	}
+
+	msg := &http2startPushRequest{
+		parent: st,
+		method: opts.Method,
+		url:    u,
+		header: http2cloneHeader(opts.Header),
+		done:   http2errChanPool.Get().(chan error),
+	}
+
+	select {
+	case <-sc.doneServing:
+		return http2errClientDisconnected
+	case <-st.cw:
+		return http2errStreamClosed
+	case sc.wantStartPushCh <- msg:
+	}
+
+	select {
+	case <-sc.doneServing:
+		return http2errClientDisconnected
+	case <-st.cw:
+		return http2errStreamClosed
+	case err := <-msg.done:
+		http2errChanPool.Put(msg.done)
+		return err
+	}
+}
+
+type http2startPushRequest struct {
+	parent *http2stream
+	method string
+	url    *url.URL
+	header Header
+	done   chan error
+}
+
+func (sc *http2serverConn) startPush(msg *http2startPushRequest) {
+	sc.serveG.check()
+
+	if msg.parent.state != http2stateOpen && msg.parent.state != http2stateHalfClosedRemote {
+
+		msg.done <- http2errStreamClosed
+		return
+	}
+
+	if !sc.pushEnabled {
+		msg.done <- ErrNotSupported
+		return
+	}
+
+	allocatePromisedID := func() (uint32, error) {
+		sc.serveG.check()
+
+		if !sc.pushEnabled {
+			return 0, ErrNotSupported
+		}
+
+		if sc.curPushedStreams+1 > sc.clientMaxStreams {
+			return 0, http2ErrPushLimitReached
+		}
+
+		if sc.maxPushPromiseID+2 >= 1<<31 {
+			sc.goAway(http2ErrCodeNo)
+			return 0, http2ErrPushLimitReached
+		}
+		sc.maxPushPromiseID += 2
+		promisedID := sc.maxPushPromiseID
+
+		promised := sc.newStream(promisedID, msg.parent, http2stateHalfClosedRemote)
+		rw, req, err := sc.newWriterAndRequestNoBody(promised, http2requestParam{
+			method:    msg.method,
+			scheme:    msg.url.Scheme,
+			authority: msg.url.Host,
+			path:      msg.url.RequestURI(),
+			header:    http2cloneHeader(msg.header),
+		})
+		if err != nil {
+
+			panic(fmt.Sprintf("newWriterAndRequestNoBody(%+v): %v", msg.url, err))
+		}
+
+		go sc.runHandler(rw, req, sc.handler.ServeHTTP)
+		return promisedID, nil
+	}
+
+	sc.writeFrame(http2frameWriteMsg{
+		write: &http2writePushPromise{
+			streamID:           msg.parent.id,
+			method:             msg.method,
+			url:                msg.url,
+			h:                  msg.header,
+			allocatePromisedID: allocatePromisedID,
+		},
+		stream: msg.parent,
+		done:   msg.done,
+	})
+}
+
+// writePushPromise is a request to write a PUSH_PROMISE and 0+ CONTINUATION frames.
+type http2writePushPromise struct {
+	streamID uint32   // pusher stream
+	method   string   // for :method
+	url      *url.URL // for :scheme, :authority, :path
+	h        Header
+
+	// Creates an ID for a pushed stream. This runs on serveG just before
+	// the frame is written. The returned ID is copied to promisedID.
+	allocatePromisedID func() (uint32, error)
+	promisedID         uint32
+}
+
+func (w *http2writePushPromise) writeFrame(ctx http2writeContext) error {
+	enc, buf := ctx.HeaderEncoder()
+	buf.Reset()
+
+	http2encKV(enc, ":method", w.method)
+	http2encKV(enc, ":scheme", w.url.Scheme)
+	http2encKV(enc, ":authority", w.url.Host)
+	http2encKV(enc, ":path", w.url.RequestURI())
+	http2encodeHeaders(enc, w.h, nil)
+
+	headerBlock := buf.Bytes()
+	if len(headerBlock) == 0 {
+		panic("unexpected empty hpack")
+	}
+
+	const maxFrameSize = 16384
+
+	first := true
+	for len(headerBlock) > 0 {
+		frag := headerBlock
+		if len(frag) > maxFrameSize {
+			frag = frag[:maxFrameSize]
+		}
+		headerBlock = headerBlock[len(frag):]
+		endHeaders := len(headerBlock) == 0
+		var err error
+		if first {
+			first = false
+			err = ctx.Framer().WritePushPromise(http2PushPromiseParam{
+				StreamID:      w.streamID,
+				PromiseID:     w.promisedID,
+				BlockFragment: frag,
+				EndHeaders:    endHeaders,
+			})
+		} else {
+			err = ctx.Framer().WriteContinuation(w.streamID, endHeaders, frag)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RFC 8441, section 3
+const http2SettingEnableConnectProtocol http2SettingID = 0x8
+
+func init() {
+	http2settingName[http2SettingEnableConnectProtocol] = "ENABLE_CONNECT_PROTOCOL"
+}
+
+// hookSettings returns the settings the server sends in addition to
+// those of the generated code.
+func (sc *http2serverConn) hookSettings() []http2Setting {
+	if sc.hs.AllowExtendedConnect {
+		return []http2Setting{{http2SettingEnableConnectProtocol, 1}}
+	}
+	return nil
+}
+
+// newExtendedConnectWriterAndRequest is newWriterAndRequest for an
+// extended CONNECT request (RFC 8441), which carries a :protocol
+// pseudo-header and is otherwise formed like any other request. It is
+// malformed unless the server advertised SETTINGS_ENABLE_CONNECT_PROTOCOL.
+func (sc *http2serverConn) newExtendedConnectWriterAndRequest(st *http2stream, f *http2MetaHeadersFrame) (*http2responseWriter, *Request, error) {
+	sc.serveG.check()
+
+	if !sc.hs.AllowExtendedConnect {
+		return nil, nil, http2streamError(f.StreamID, http2ErrCodeProtocol)
+	}
+
+	method := f.PseudoValue("method")
+	path := f.PseudoValue("path")
+	scheme := f.PseudoValue("scheme")
+	authority := f.PseudoValue("authority")
+	protocol := f.PseudoValue("protocol")
+	if method != "CONNECT" || path == "" || authority == "" ||
+		(scheme != "https" && scheme != "http") {
+		return nil, nil, http2streamError(f.StreamID, http2ErrCodeProtocol)
+	}
+
+	header := make(Header)
+	for _, hf := range f.RegularFields() {
+		header.Add(sc.canonicalHeader(hf.Name), hf.Value)
+	}
+
+	rw, req, err := sc.newWriterAndRequestNoBody(st, http2requestParam{
+		method:    method,
+		scheme:    scheme,
+		authority: authority,
+		path:      path,
+		protocol:  protocol,
+		header:    header,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if !f.StreamEnded() {
+		body := req.Body.(*http2requestBody)
+		body.pipe = &http2pipe{
+			b: &http2fixedBuffer{buf: make([]byte, http2initialWindowSize)},
+		}
+		if vv, ok := header["Content-Length"]; ok {
+			req.ContentLength, _ = strconv.ParseInt(vv[0], 10, 64)
+		} else {
+			req.ContentLength = -1
+		}
+	}
+	return rw, req, nil
+}
@@ -0,0 +1,378 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Recording and replaying HTTP interactions.
+
+package httptest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"unicode/utf8"
+)
+
+// An Interaction is a request sent to a server and the response it
+// received.
+type Interaction struct {
+	Request  RecordedRequest
+	Response RecordedResponse
+}
+
+// A RecordedRequest is the request of an Interaction.
+type RecordedRequest struct {
+	Method string
+	URL    string // absolute URL
+	Header http.Header
+	Body   []byte
+}
+
+// A RecordedResponse is the response of an Interaction.
+type RecordedResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// A RecordingTransport is an http.RoundTripper that sends requests
+// with another RoundTripper and records them with their responses, to
+// be saved as a fixture file for a ReplayTransport.
+//
+// Requests that fail are not recorded.
+type RecordingTransport struct {
+	// Transport sends the requests. If nil, http.DefaultTransport
+	// is used.
+	Transport http.RoundTripper
+
+	// Redact, if non-nil, is called with each interaction before it
+	// is recorded, to remove secrets or unstable values from it.
+	// Whether or not it is set, the Authorization,
+	// Proxy-Authorization and Cookie headers of requests are not
+	// recorded.
+	Redact func(*Interaction)
+
+	mu           sync.Mutex
+	interactions []*Interaction
+}
+
+// redactedHeaders are the request headers a RecordingTransport does
+// not record.
+var redactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+// RoundTrip implements the http.RoundTripper interface. It reads the
+// whole request and response bodies.
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		r2 := new(http.Request)
+		*r2 = *req
+		r2.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
+		req = r2
+	}
+	rt := t.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	in := &Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: cloneHeader(req.Header),
+			Body:   reqBody,
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     cloneHeader(resp.Header),
+			Body:       respBody,
+		},
+	}
+	if in.Request.Method == "" {
+		in.Request.Method = "GET"
+	}
+	for _, k := range redactedHeaders {
+		in.Request.Header.Del(k)
+	}
+	if t.Redact != nil {
+		t.Redact(in)
+	}
+	t.mu.Lock()
+	t.interactions = append(t.interactions, in)
+	t.mu.Unlock()
+	return resp, nil
+}
+
+// Interactions returns the interactions recorded so far, in order.
+func (t *RecordingTransport) Interactions() []*Interaction {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*Interaction(nil), t.interactions...)
+}
+
+// Save writes the interactions recorded so far to the fixture file
+// filename, creating its directory if needed. Fixtures usually live in
+// the testdata directory of a package.
+func (t *RecordingTransport) Save(filename string) error {
+	data, err := json.MarshalIndent(toFixture(t.Interactions()), "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0777); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, append(data, '\n'), 0666)
+}
+
+// A ReplayTransport is an http.RoundTripper that answers requests with
+// the responses of recorded interactions, without network access.
+//
+// Each interaction answers at most one request: a request gets the
+// response of the first unused interaction whose request matches it.
+// RoundTrip fails for requests that match none.
+type ReplayTransport struct {
+	Interactions []*Interaction
+
+	// Match reports whether the recorded request rec matches req,
+	// whose body is body. If nil, MatchMethodAndURL is used.
+	Match func(req *http.Request, body []byte, rec *RecordedRequest) bool
+
+	mu   sync.Mutex
+	used map[*Interaction]bool
+}
+
+// NewReplayTransport returns a ReplayTransport for the interactions of
+// the fixture file filename, as written by RecordingTransport.Save.
+func NewReplayTransport(filename string) (*ReplayTransport, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var f fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("httptest: reading fixture %s: %v", filename, err)
+	}
+	ins, err := f.interactions()
+	if err != nil {
+		return nil, fmt.Errorf("httptest: reading fixture %s: %v", filename, err)
+	}
+	return &ReplayTransport{Interactions: ins}, nil
+}
+
+// MatchMethodAndURL reports whether req has the method and URL of rec.
+// It is the default Match function of a ReplayTransport.
+func MatchMethodAndURL(req *http.Request, body []byte, rec *RecordedRequest) bool {
+	method := req.Method
+	if method == "" {
+		method = "GET"
+	}
+	return method == rec.Method && req.URL.String() == rec.URL
+}
+
+// MatchBody reports whether req has the method, URL and body of rec.
+func MatchBody(req *http.Request, body []byte, rec *RecordedRequest) bool {
+	return MatchMethodAndURL(req, body, rec) && bytes.Equal(body, rec.Body)
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	match := t.Match
+	if match == nil {
+		match = MatchMethodAndURL
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, in := range t.Interactions {
+		if t.used[in] || !match(req, body, &in.Request) {
+			continue
+		}
+		if t.used == nil {
+			t.used = make(map[*Interaction]bool)
+		}
+		t.used[in] = true
+		code := in.Response.StatusCode
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", code, http.StatusText(code)),
+			StatusCode:    code,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        cloneHeader(in.Response.Header),
+			Body:          ioutil.NopCloser(bytes.NewReader(in.Response.Body)),
+			ContentLength: int64(len(in.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("httptest: no recorded response for %s %s", req.Method, req.URL)
+}
+
+// Unused returns the interactions that have not answered a request, so
+// that tests can check that all of them were.
+func (t *ReplayTransport) Unused() []*Interaction {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var unused []*Interaction
+	for _, in := range t.Interactions {
+		if !t.used[in] {
+			unused = append(unused, in)
+		}
+	}
+	return unused
+}
+
+var record = flag.Bool("httptest.record", false, "if true, NewFixtureTransport records fixtures instead of replaying them")
+
+// NewFixtureTransport returns an http.RoundTripper for tests that talk
+// to a remote service, backed by the fixture file filename, such as
+// "testdata/api.json".
+//
+// Usually, it returns a ReplayTransport for the file. When the test
+// binary runs with the -httptest.record flag, it instead returns a
+// RecordingTransport using http.DefaultTransport, and the save
+// function writes what was recorded to the file; otherwise save does
+// nothing. Tests call save once they are done with the RoundTripper:
+//
+//	rt, save, err := httptest.NewFixtureTransport("testdata/api.json")
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer func() {
+//		if err := save(); err != nil {
+//			t.Error(err)
+//		}
+//	}()
+//	c := &http.Client{Transport: rt}
+func NewFixtureTransport(filename string) (rt http.RoundTripper, save func() error, err error) {
+	if *record {
+		t := new(RecordingTransport)
+		return t, func() error { return t.Save(filename) }, nil
+	}
+	t, err := NewReplayTransport(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	return t, func() error { return nil }, nil
+}
+
+// A fixture is the JSON form of the interactions of a fixture file.
+// Bodies are stored as text, unless they are not valid UTF-8.
+type fixture struct {
+	Interactions []fixtureInteraction `json:"interactions"`
+}
+
+type fixtureInteraction struct {
+	Request  fixtureMessage `json:"request"`
+	Response fixtureMessage `json:"response"`
+}
+
+type fixtureMessage struct {
+	Method     string      `json:"method,omitempty"`
+	URL        string      `json:"url,omitempty"`
+	StatusCode int         `json:"status_code,omitempty"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	BodyBase64 string      `json:"body_base64,omitempty"`
+}
+
+func toFixture(ins []*Interaction) *fixture {
+	f := &fixture{Interactions: make([]fixtureInteraction, len(ins))}
+	for i, in := range ins {
+		f.Interactions[i] = fixtureInteraction{
+			Request: fixtureMessage{
+				Method: in.Request.Method,
+				URL:    in.Request.URL,
+				Header: in.Request.Header,
+			},
+			Response: fixtureMessage{
+				StatusCode: in.Response.StatusCode,
+				Header:     in.Response.Header,
+			},
+		}
+		f.Interactions[i].Request.setBody(in.Request.Body)
+		f.Interactions[i].Response.setBody(in.Response.Body)
+	}
+	return f
+}
+
+func (m *fixtureMessage) setBody(b []byte) {
+	if utf8.Valid(b) {
+		m.Body = string(b)
+	} else {
+		m.BodyBase64 = base64.StdEncoding.EncodeToString(b)
+	}
+}
+
+func (m *fixtureMessage) body() ([]byte, error) {
+	if m.BodyBase64 != "" {
+		if m.Body != "" {
+			return nil, errors.New("both body and body_base64 set")
+		}
+		return base64.StdEncoding.DecodeString(m.BodyBase64)
+	}
+	if m.Body == "" {
+		return nil, nil
+	}
+	return []byte(m.Body), nil
+}
+
+func (f *fixture) interactions() ([]*Interaction, error) {
+	ins := make([]*Interaction, len(f.Interactions))
+	for i, fi := range f.Interactions {
+		reqBody, err := fi.Request.body()
+		if err != nil {
+			return nil, err
+		}
+		respBody, err := fi.Response.body()
+		if err != nil {
+			return nil, err
+		}
+		ins[i] = &Interaction{
+			Request: RecordedRequest{
+				Method: fi.Request.Method,
+				URL:    fi.Request.URL,
+				Header: fi.Request.Header,
+				Body:   reqBody,
+			},
+			Response: RecordedResponse{
+				StatusCode: fi.Response.StatusCode,
+				Header:     fi.Response.Header,
+				Body:       respBody,
+			},
+		}
+	}
+	return ins, nil
+}
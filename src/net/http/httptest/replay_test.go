@@ -0,0 +1,192 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package httptest
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	ts := NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("X-Path", r.URL.Path)
+		switch r.URL.Path {
+		case "/binary":
+			w.Write([]byte{0xff, 0xfe, 0})
+		case "/echo":
+			w.WriteHeader(http.StatusCreated)
+			w.Write(body)
+		default:
+			w.Write([]byte("hello"))
+		}
+	}))
+	defer ts.Close()
+
+	rec := &RecordingTransport{
+		Redact: func(in *Interaction) {
+			in.Request.Header.Del("X-Secret")
+		},
+	}
+	c := &http.Client{Transport: rec}
+	do := func(c *http.Client, method, path, body string) (int, string, string, error) {
+		req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("X-Secret", "secret")
+		res, err := c.Do(req)
+		if err != nil {
+			return 0, "", "", err
+		}
+		defer res.Body.Close()
+		b, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.StatusCode, res.Header.Get("X-Path"), string(b), nil
+	}
+	type result struct {
+		code       int
+		path, body string
+	}
+	requests := []struct {
+		method, path, body string
+		want               result
+	}{
+		{"GET", "/hello", "", result{200, "/hello", "hello"}},
+		{"POST", "/echo", "one", result{201, "/echo", "one"}},
+		{"POST", "/echo", "two", result{201, "/echo", "two"}},
+		{"GET", "/binary", "", result{200, "/binary", "\xff\xfe\x00"}},
+	}
+	for _, r := range requests {
+		code, path, body, err := do(c, r.method, r.path, r.body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := (result{code, path, body}); got != r.want {
+			t.Fatalf("recording %s %s = %+v; want %+v", r.method, r.path, got, r.want)
+		}
+	}
+	for _, in := range rec.Interactions() {
+		for _, k := range []string{"Authorization", "X-Secret"} {
+			if v := in.Request.Header.Get(k); v != "" {
+				t.Errorf("recorded %s header = %q", k, v)
+			}
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "httptest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "testdata", "fixture.json")
+	if err := rec.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte(`"body": "hello"`)) || !bytes.Contains(data, []byte(`"body_base64": "//4A"`)) {
+		t.Errorf("fixture file does not store bodies as expected:\n%s", data)
+	}
+
+	// Replay in another order, with requests told apart by body.
+	ts.Close()
+	replay, err := NewReplayTransport(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay.Match = MatchBody
+	c = &http.Client{Transport: replay}
+	for _, i := range []int{2, 0, 3, 1} {
+		r := requests[i]
+		code, path, body, err := do(c, r.method, r.path, r.body)
+		if err != nil {
+			t.Fatalf("replaying %s %s: %v", r.method, r.path, err)
+		}
+		if got := (result{code, path, body}); got != r.want {
+			t.Errorf("replaying %s %s = %+v; want %+v", r.method, r.path, got, r.want)
+		}
+	}
+	if unused := replay.Unused(); len(unused) != 0 {
+		t.Errorf("%d unused interactions", len(unused))
+	}
+	// Each interaction answers one request.
+	if _, _, _, err := do(c, "GET", "/hello", ""); err == nil {
+		t.Error("request replayed twice")
+	}
+}
+
+func TestReplayMatchMethodAndURL(t *testing.T) {
+	replay := &ReplayTransport{
+		Interactions: []*Interaction{
+			{
+				Request:  RecordedRequest{Method: "GET", URL: "http://example.com/a"},
+				Response: RecordedResponse{StatusCode: 200, Body: []byte("first")},
+			},
+			{
+				Request:  RecordedRequest{Method: "GET", URL: "http://example.com/a"},
+				Response: RecordedResponse{StatusCode: 404, Body: []byte("second")},
+			},
+			{
+				Request:  RecordedRequest{Method: "POST", URL: "http://example.com/a"},
+				Response: RecordedResponse{StatusCode: 204},
+			},
+		},
+	}
+	for _, want := range []struct {
+		code int
+		body string
+	}{
+		{200, "first"},
+		{404, "second"},
+	} {
+		req, _ := http.NewRequest("GET", "http://example.com/a", nil)
+		res, err := replay.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		if res.StatusCode != want.code || string(body) != want.body {
+			t.Errorf("got %d %q; want %d %q", res.StatusCode, body, want.code, want.body)
+		}
+		if status := fmt.Sprintf("%d %s", want.code, http.StatusText(want.code)); res.Status != status {
+			t.Errorf("Status = %q; want %q", res.Status, status)
+		}
+	}
+	req, _ := http.NewRequest("GET", "http://example.com/b", nil)
+	if _, err := replay.RoundTrip(req); err == nil || !strings.Contains(err.Error(), "no recorded response") {
+		t.Errorf("unmatched request: error = %v", err)
+	}
+	if unused := replay.Unused(); len(unused) != 1 || unused[0].Request.Method != "POST" {
+		t.Errorf("Unused = %v; want the POST interaction", unused)
+	}
+}
+
+func TestNewReplayTransportErrors(t *testing.T) {
+	if _, err := NewReplayTransport(filepath.Join("testdata", "does-not-exist.json")); err == nil {
+		t.Error("missing fixture file: no error")
+	}
+	f, err := ioutil.TempFile("", "httptest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(`{"interactions": [{"request": {"method": "GET", "url": "http://x/"}, "response": {"body": "a", "body_base64": "Yg=="}}]}`)
+	f.Close()
+	if _, err := NewReplayTransport(f.Name()); err == nil {
+		t.Error("fixture with two bodies: no error")
+	}
+}
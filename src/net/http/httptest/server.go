@@ -9,6 +9,7 @@ package httptest
 import (
 	"bytes"
 	"crypto/tls"
+	"crypto/x509"
 	"flag"
 	"fmt"
 	"log"
@@ -36,6 +37,15 @@ type Server struct {
 	// before Start or StartTLS.
 	Config *http.Server
 
+	// EnableH2C, if set on an unstarted server before Start, makes
+	// the server accept cleartext HTTP/2 ("h2c") and the client
+	// returned by Client speak it.
+	EnableH2C bool
+
+	// client is configured for use with the server.
+	// Its transport is automatically closed when Close is called.
+	client *http.Client
+
 	// wg counts the number of outstanding HTTP requests on this server.
 	// Close blocks until all requests are finished.
 	wg sync.WaitGroup
@@ -95,6 +105,12 @@ func (s *Server) Start() {
 		panic("Server already started")
 	}
 	s.URL = "http://" + s.Listener.Addr().String()
+	tr := &http.Transport{}
+	if s.EnableH2C {
+		s.Config.AllowH2C = true
+		tr.H2CHosts = []string{s.Listener.Addr().String()}
+	}
+	s.client = &http.Client{Transport: tr}
 	s.wrap()
 	s.goServe()
 	if *serve != "" {
@@ -125,6 +141,17 @@ func (s *Server) StartTLS() {
 	if len(s.TLS.Certificates) == 0 {
 		s.TLS.Certificates = []tls.Certificate{cert}
 	}
+	certificate, err := x509.ParseCertificate(s.TLS.Certificates[0].Certificate[0])
+	if err != nil {
+		panic(fmt.Sprintf("httptest: NewTLSServer: %v", err))
+	}
+	certpool := x509.NewCertPool()
+	certpool.AddCert(certificate)
+	s.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: certpool},
+		},
+	}
 	s.Listener = tls.NewListener(s.Listener, s.TLS)
 	s.URL = "https://" + s.Listener.Addr().String()
 	s.wrap()
@@ -139,6 +166,14 @@ func NewTLSServer(handler http.Handler) *Server {
 	return ts
 }
 
+// Client returns an HTTP client configured for making requests to
+// the server. It trusts the server's TLS test certificate, speaks
+// h2c if EnableH2C is set, and closes its idle connections on
+// Server.Close.
+func (s *Server) Client() *http.Client {
+	return s.client
+}
+
 type closeIdleTransport interface {
 	CloseIdleConnections()
 }
@@ -187,6 +222,13 @@ func (s *Server) Close() {
 		t.CloseIdleConnections()
 	}
 
+	// Also close the client idle connections.
+	if s.client != nil {
+		if t, ok := s.client.Transport.(closeIdleTransport); ok {
+			t.CloseIdleConnections()
+		}
+	}
+
 	s.wg.Wait()
 }
 
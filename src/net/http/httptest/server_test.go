@@ -98,3 +98,58 @@ func TestServerCloseClientConnections(t *testing.T) {
 		t.Fatalf("Unexpected response: %#v", res)
 	}
 }
+
+func TestTLSServerClient(t *testing.T) {
+	ts := NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	defer ts.Close()
+
+	res, err := ts.Client().Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "hello" {
+		t.Errorf("got %q, want hello", string(got))
+	}
+}
+
+func TestServerEnableH2C(t *testing.T) {
+	ts := NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	ts.EnableH2C = true
+	ts.Start()
+	defer ts.Close()
+
+	for i := 0; i < 2; i++ {
+		res, err := ts.Client().Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Proto != "HTTP/2.0" || string(got) != "HTTP/2.0" {
+			t.Errorf("response proto = %q, request proto = %q; want HTTP/2.0", res.Proto, got)
+		}
+	}
+
+	// Clients without prior knowledge still get HTTP/1.1.
+	res, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if string(got) != "HTTP/1.1" {
+		t.Errorf("request proto = %q; want HTTP/1.1", got)
+	}
+}
@@ -40,8 +40,9 @@ func WithClientTrace(ctx context.Context, trace *ClientTrace) context.Context {
 	ctx = context.WithValue(ctx, clientEventContextKey{}, trace)
 	if trace.hasNetHooks() {
 		nt := &nettrace.Trace{
-			ConnectStart: trace.ConnectStart,
-			ConnectDone:  trace.ConnectDone,
+			ConnectStart:  trace.ConnectStart,
+			ConnectDone:   trace.ConnectDone,
+			ConnectWinner: trace.ConnectWinner,
 		}
 		if trace.DNSStart != nil {
 			nt.DNSStart = func(name string) {
@@ -109,17 +110,26 @@ type ClientTrace struct {
 	DNSDone func(DNSDoneInfo)
 
 	// ConnectStart is called when a new connection's Dial begins.
-	// If net.Dialer.DualStack (IPv6 "Happy Eyeballs") support is
-	// enabled, this may be called multiple times.
+	// If net.Dialer.DualStack (IPv6 "Happy Eyeballs") or
+	// net.Dialer.HappyEyeballs support is enabled, this may be
+	// called multiple times.
 	ConnectStart func(network, addr string)
 
 	// ConnectDone is called when a new connection's Dial
 	// completes. The provided err indicates whether the
 	// connection completedly successfully.
-	// If net.Dialer.DualStack ("Happy Eyeballs") support is
-	// enabled, this may be called multiple times.
+	// If net.Dialer.DualStack ("Happy Eyeballs") or
+	// net.Dialer.HappyEyeballs support is enabled, this may be
+	// called multiple times.
 	ConnectDone func(network, addr string, err error)
 
+	// ConnectWinner is called when a new connection's Dial has
+	// succeeded, with the address that was connected to. When
+	// net.Dialer.DualStack or net.Dialer.HappyEyeballs is enabled,
+	// it tells which of the attempts reported to ConnectStart won;
+	// the errors of the others are reported to ConnectDone.
+	ConnectWinner func(network, addr string)
+
 	// TLSHandshakeStart is called when the TLS handshake is started. When
 	// connecting to a HTTPS site via a HTTP proxy, the handshake happens after
 	// the CONNECT request is processed by the proxy.
@@ -212,7 +222,7 @@ func (t *ClientTrace) hasNetHooks() bool {
 	if t == nil {
 		return false
 	}
-	return t.DNSStart != nil || t.DNSDone != nil || t.ConnectStart != nil || t.ConnectDone != nil
+	return t.DNSStart != nil || t.DNSDone != nil || t.ConnectStart != nil || t.ConnectDone != nil || t.ConnectWinner != nil
 }
 
 // GotConnInfo is the argument to the ClientTrace.GotConn function and
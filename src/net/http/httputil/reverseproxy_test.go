@@ -10,9 +10,11 @@ import (
 	"bufio"
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -581,3 +583,268 @@ func TestReverseProxy_NilBody(t *testing.T) {
 		t.Errorf("status code = %v; want 502 (Gateway Error)", res.Status)
 	}
 }
+
+func TestReverseProxyModifyResponse(t *testing.T) {
+	backendServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("X-Hit-Mod", fmt.Sprintf("%v", r.URL.Path == "/mod"))
+	}))
+	defer backendServer.Close()
+
+	rpURL, _ := url.Parse(backendServer.URL)
+	rproxy := NewSingleHostReverseProxy(rpURL)
+	rproxy.ErrorLog = log.New(ioutil.Discard, "", 0) // quiet for tests
+	rproxy.ModifyResponse = func(resp *http.Response) error {
+		if resp.Header.Get("X-Hit-Mod") != "true" {
+			return fmt.Errorf("tried to by-pass proxy")
+		}
+		return nil
+	}
+
+	frontendProxy := httptest.NewServer(rproxy)
+	defer frontendProxy.Close()
+
+	tests := []struct {
+		url      string
+		wantCode int
+	}{
+		{frontendProxy.URL + "/mod", http.StatusOK},
+		{frontendProxy.URL + "/schedule", http.StatusBadGateway},
+	}
+
+	for i, tt := range tests {
+		resp, err := http.Get(tt.url)
+		if err != nil {
+			t.Fatalf("failed to reach proxy: %v", err)
+		}
+		if g, e := resp.StatusCode, tt.wantCode; g != e {
+			t.Errorf("#%d: got res.StatusCode %d; expected %d", i, g, e)
+		}
+		resp.Body.Close()
+	}
+}
+
+func TestReverseProxyErrorHandler(t *testing.T) {
+	errBackend := errors.New("backend unreachable")
+	tests := []struct {
+		name      string
+		transport http.RoundTripper
+		modify    func(*http.Response) error
+		wantErr   string
+	}{
+		{
+			name: "transport error",
+			transport: RoundTripperFunc(func(*http.Request) (*http.Response, error) {
+				return nil, errBackend
+			}),
+			wantErr: errBackend.Error(),
+		},
+		{
+			name: "modify response error",
+			modify: func(*http.Response) error {
+				return errors.New("rejected by ModifyResponse")
+			},
+			wantErr: "rejected by ModifyResponse",
+		},
+	}
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "backend body")
+	}))
+	defer backend.Close()
+	backendURL, _ := url.Parse(backend.URL)
+
+	for _, tt := range tests {
+		rproxy := NewSingleHostReverseProxy(backendURL)
+		if tt.transport != nil {
+			rproxy.Transport = tt.transport
+		}
+		rproxy.ModifyResponse = tt.modify
+		rproxy.ErrorHandler = func(rw http.ResponseWriter, req *http.Request, err error) {
+			rw.WriteHeader(http.StatusTeapot)
+			io.WriteString(rw, err.Error())
+		}
+		frontend := httptest.NewServer(rproxy)
+
+		res, err := http.Get(frontend.URL)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		frontend.Close()
+		if res.StatusCode != http.StatusTeapot {
+			t.Errorf("%s: status = %d; want %d", tt.name, res.StatusCode, http.StatusTeapot)
+		}
+		if string(body) != tt.wantErr {
+			t.Errorf("%s: body = %q; want %q", tt.name, body, tt.wantErr)
+		}
+	}
+}
+
+func TestReverseProxyUnannouncedTrailer(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "X-Announced")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		w.Header().Set("X-Announced", "a")
+		w.Header().Set(http.TrailerPrefix+"X-Unannounced", "u")
+	}))
+	defer backend.Close()
+	backendURL, _ := url.Parse(backend.URL)
+	frontend := httptest.NewServer(NewSingleHostReverseProxy(backendURL))
+	defer frontend.Close()
+
+	res, err := http.Get(frontend.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ioutil.ReadAll(res.Body); err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	want := http.Header{"X-Announced": {"a"}, "X-Unannounced": {"u"}}
+	if !reflect.DeepEqual(res.Trailer, want) {
+		t.Errorf("Trailer = %v; want %v", res.Trailer, want)
+	}
+}
+
+func TestReverseProxyWebSocket(t *testing.T) {
+	backendServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if upgradeType(r.Header) != "websocket" {
+			t.Error("unexpected backend request")
+			http.Error(w, "unexpected request", 400)
+			return
+		}
+		c, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer c.Close()
+		io.WriteString(c, "HTTP/1.1 101 Switching Protocols\r\nConnection: upgrade\r\nUpgrade: WebSocket\r\n\r\n")
+		bs := bufio.NewScanner(c)
+		if !bs.Scan() {
+			t.Errorf("backend failed to read line from client: %v", bs.Err())
+			return
+		}
+		fmt.Fprintf(c, "backend got %q\n", bs.Text())
+	}))
+	defer backendServer.Close()
+
+	backURL, _ := url.Parse(backendServer.URL)
+	rproxy := NewSingleHostReverseProxy(backURL)
+	rproxy.ErrorLog = log.New(ioutil.Discard, "", 0) // quiet for tests
+	rproxy.ModifyResponse = func(res *http.Response) error {
+		res.Header.Add("X-Modified", "true")
+		return nil
+	}
+
+	frontendProxy := httptest.NewServer(rproxy)
+	defer frontendProxy.Close()
+
+	c, err := net.Dial("tcp", frontendProxy.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetDeadline(time.Now().Add(10 * time.Second))
+
+	io.WriteString(c, "GET / HTTP/1.1\r\nHost: proxy\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+	br := bufio.NewReader(c)
+	res, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %v; want 101", res.Status)
+	}
+	if got, want := res.Header.Get("X-Modified"), "true"; got != want {
+		t.Errorf("response X-Modified header = %q; want %q", got, want)
+	}
+	if upgradeType(res.Header) != "websocket" {
+		t.Fatalf("not websocket upgrade; got %#v", res.Header)
+	}
+
+	io.WriteString(c, "Hello\n")
+	bs := bufio.NewScanner(br)
+	if !bs.Scan() {
+		t.Fatalf("Scan: %v", bs.Err())
+	}
+	if got, want := bs.Text(), `backend got "Hello"`; got != want {
+		t.Errorf("got %#q, want %#q", got, want)
+	}
+}
+
+// Tests that data the client sends right after its upgrade request,
+// before the response, reaches the backend.
+func TestReverseProxyWebSocketEarlyData(t *testing.T) {
+	backendServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer c.Close()
+		io.WriteString(c, "HTTP/1.1 101 Switching Protocols\r\nConnection: upgrade\r\nUpgrade: WebSocket\r\n\r\n")
+		line, err := brw.ReadString('\n')
+		if err != nil {
+			t.Errorf("backend failed to read line from client: %v", err)
+			return
+		}
+		fmt.Fprintf(c, "backend got %q\n", line)
+	}))
+	defer backendServer.Close()
+
+	backURL, _ := url.Parse(backendServer.URL)
+	rproxy := NewSingleHostReverseProxy(backURL)
+	rproxy.ErrorLog = log.New(ioutil.Discard, "", 0) // quiet for tests
+	frontendProxy := httptest.NewServer(rproxy)
+	defer frontendProxy.Close()
+
+	c, err := net.Dial("tcp", frontendProxy.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetDeadline(time.Now().Add(10 * time.Second))
+
+	io.WriteString(c, "GET / HTTP/1.1\r\nHost: proxy\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\nHello\n")
+	br := bufio.NewReader(c)
+	res, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %v; want 101", res.Status)
+	}
+	line, err := br.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := line, "backend got \"Hello\\n\"\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestUpgradeType(t *testing.T) {
+	for _, tt := range []struct {
+		connection, upgrade, want string
+	}{
+		{"Upgrade", "websocket", "websocket"},
+		{"keep-alive, upgrade", "WebSocket", "websocket"},
+		{"Upgrade", "HTTP/2.0, SHTTP/1.3", "http/2.0, shttp/1.3"},
+		{"close", "websocket", ""},
+		{"Upgrade", "", ""},
+		{"Upgrade", "original value", ""},
+		{"Upgrade", "websocket/", ""},
+		{"Upgrade", "websocket, ", ""},
+	} {
+		h := http.Header{"Connection": {tt.connection}}
+		if tt.upgrade != "" {
+			h.Set("Upgrade", tt.upgrade)
+		}
+		if got := upgradeType(h); got != tt.want {
+			t.Errorf("upgradeType(Connection: %q, Upgrade: %q) = %q; want %q", tt.connection, tt.upgrade, got, tt.want)
+		}
+	}
+}
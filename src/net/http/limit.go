@@ -0,0 +1,230 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Handlers limiting the load on a server.
+
+package http
+
+import (
+	"math"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RateLimitHandler returns a Handler that runs h for at most rate
+// requests per second for each key, allowing bursts of up to burst
+// requests. Each key has a token bucket that holds up to burst tokens
+// and refills at rate tokens per second; each request takes a token.
+//
+// The key function returns the key of a request, such as the client's
+// address, an API key or the route; if it is nil, the host of the
+// request's RemoteAddr is used.
+//
+// Requests over the limit get a 429 Too Many Requests reply, with a
+// Retry-After header giving the number of seconds until the next
+// token is available.
+func RateLimitHandler(h Handler, rate float64, burst int, key func(*Request) string) Handler {
+	if rate <= 0 || burst < 1 {
+		panic("http: non-positive rate limit")
+	}
+	if key == nil {
+		key = remoteHost
+	}
+	return &rateLimitHandler{
+		handler: h,
+		rate:    rate,
+		burst:   float64(burst),
+		key:     key,
+		now:     time.Now,
+		buckets: make(map[string]*tokenBucket),
+		sweepAt: minBucketSweep,
+	}
+}
+
+// minBucketSweep is the number of token buckets under which a
+// rateLimitHandler does not look for buckets to remove.
+const minBucketSweep = 1024
+
+type rateLimitHandler struct {
+	handler Handler
+	rate    float64 // tokens per second
+	burst   float64 // bucket capacity
+	key     func(*Request) string
+	now     func() time.Time // for tests
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	sweepAt int // len(buckets) at which to remove full buckets
+}
+
+// A tokenBucket is the token bucket of a key.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time // when tokens was computed
+}
+
+func (h *rateLimitHandler) ServeHTTP(w ResponseWriter, r *Request) {
+	if wait, ok := h.take(h.key(r), h.now()); !ok {
+		w.Header().Set("Retry-After", formatRetryAfter(wait))
+		Error(w, StatusText(StatusTooManyRequests), StatusTooManyRequests)
+		return
+	}
+	h.handler.ServeHTTP(w, r)
+}
+
+// take takes a token from the bucket of key at time now. If the bucket
+// is empty, it returns false and how long until a token is available.
+func (h *rateLimitHandler) take(key string, now time.Time) (wait time.Duration, ok bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	b := h.buckets[key]
+	if b == nil {
+		if len(h.buckets) >= h.sweepAt {
+			h.sweep(now)
+		}
+		b = &tokenBucket{tokens: h.burst, last: now}
+		h.buckets[key] = b
+	}
+	h.refill(b, now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	return time.Duration((1 - b.tokens) / h.rate * float64(time.Second)), false
+}
+
+func (h *rateLimitHandler) refill(b *tokenBucket, now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(h.burst, b.tokens+elapsed.Seconds()*h.rate)
+		b.last = now
+	}
+}
+
+// sweep removes the buckets that are full at time now, which are no
+// different from new ones, so that the keys of past clients do not
+// accumulate.
+func (h *rateLimitHandler) sweep(now time.Time) {
+	for key, b := range h.buckets {
+		if h.refill(b, now); b.tokens >= h.burst {
+			delete(h.buckets, key)
+		}
+	}
+	h.sweepAt = 2 * len(h.buckets)
+	if h.sweepAt < minBucketSweep {
+		h.sweepAt = minBucketSweep
+	}
+}
+
+// remoteHost returns the host of r.RemoteAddr.
+func remoteHost(r *Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// formatRetryAfter formats d as a Retry-After value, in whole seconds
+// rounded up.
+func formatRetryAfter(d time.Duration) string {
+	secs := int64(math.Ceil(d.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	return strconv.FormatInt(secs, 10)
+}
+
+// ConcurrencyLimitHandler returns a Handler that runs at most max calls
+// of h at a time.
+//
+// A request that arrives while max calls are running waits for its
+// turn, with at most queue others, for up to wait, or without a time
+// limit if wait is zero. Requests that find the queue full, or wait
+// too long, or whose context is done while they wait, are shed with
+// a 503 Service Unavailable reply and a Retry-After header.
+func ConcurrencyLimitHandler(h Handler, max, queue int, wait time.Duration) Handler {
+	if max < 1 {
+		panic("http: non-positive concurrency limit")
+	}
+	return &concurrencyLimitHandler{
+		handler: h,
+		sem:     make(chan struct{}, max),
+		queue:   int32(queue),
+		wait:    wait,
+	}
+}
+
+type concurrencyLimitHandler struct {
+	handler Handler
+	sem     chan struct{} // holds a value per running call
+	queue   int32         // maximum number of waiting requests
+	wait    time.Duration
+
+	queued int32 // accessed atomically
+}
+
+func (h *concurrencyLimitHandler) ServeHTTP(w ResponseWriter, r *Request) {
+	select {
+	case h.sem <- struct{}{}:
+	default:
+		if !h.waitTurn(r) {
+			retry := h.wait
+			if retry <= 0 {
+				retry = time.Second
+			}
+			w.Header().Set("Retry-After", formatRetryAfter(retry))
+			Error(w, StatusText(StatusServiceUnavailable), StatusServiceUnavailable)
+			return
+		}
+	}
+	defer func() { <-h.sem }()
+	h.handler.ServeHTTP(w, r)
+}
+
+// waitTurn waits in the queue until r may run, and reports whether it
+// may.
+func (h *concurrencyLimitHandler) waitTurn(r *Request) bool {
+	if atomic.AddInt32(&h.queued, 1) > h.queue {
+		atomic.AddInt32(&h.queued, -1)
+		return false
+	}
+	defer atomic.AddInt32(&h.queued, -1)
+
+	var timeout <-chan time.Time
+	if h.wait > 0 {
+		t := time.NewTimer(h.wait)
+		defer t.Stop()
+		timeout = t.C
+	}
+	select {
+	case h.sem <- struct{}{}:
+		return true
+	case <-timeout:
+	case <-r.Context().Done():
+	}
+	return false
+}
+
+// MaxBytesHandler returns a Handler that runs h with its request body
+// limited to n bytes by MaxBytesReader. Requests that declare a
+// longer Content-Length get a 413 Request Entity Too Large reply
+// without h being run.
+func MaxBytesHandler(h Handler, n int64) Handler {
+	return HandlerFunc(func(w ResponseWriter, r *Request) {
+		if r.ContentLength > n {
+			w.Header().Set("Connection", "close")
+			Error(w, StatusText(StatusRequestEntityTooLarge), StatusRequestEntityTooLarge)
+			return
+		}
+		r2 := new(Request)
+		*r2 = *r
+		if r.Body != nil {
+			r2.Body = MaxBytesReader(w, r.Body, n)
+		}
+		h.ServeHTTP(w, r2)
+	})
+}
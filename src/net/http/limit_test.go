@@ -0,0 +1,215 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	. "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimitHandler(t *testing.T) {
+	now := time.Unix(1e9, 0)
+	h := RateLimitHandler(HandlerFunc(func(w ResponseWriter, r *Request) {}), 0.5, 2, nil)
+	SetRateLimitNowForTesting(h, func() time.Time { return now })
+
+	get := func(addr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = addr
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, r)
+		return rr
+	}
+	for i := 0; i < 2; i++ {
+		if rr := get("10.0.0.1:1234"); rr.Code != StatusOK {
+			t.Fatalf("request %d: code = %d; want %d", i, rr.Code, StatusOK)
+		}
+	}
+	rr := get("10.0.0.1:5678")
+	if rr.Code != StatusTooManyRequests {
+		t.Fatalf("request over the burst: code = %d; want %d", rr.Code, StatusTooManyRequests)
+	}
+	if got := rr.HeaderMap.Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q; want %q", got, "2")
+	}
+	if rr := get("10.0.0.2:1234"); rr.Code != StatusOK {
+		t.Errorf("other client: code = %d; want %d", rr.Code, StatusOK)
+	}
+
+	now = now.Add(1500 * time.Millisecond)
+	if rr := get("10.0.0.1:1234"); rr.Code != StatusTooManyRequests {
+		t.Errorf("after 1.5s: code = %d; want %d", rr.Code, StatusTooManyRequests)
+	} else if got := rr.HeaderMap.Get("Retry-After"); got != "1" {
+		t.Errorf("after 1.5s: Retry-After = %q; want %q", got, "1")
+	}
+	now = now.Add(500 * time.Millisecond)
+	if rr := get("10.0.0.1:1234"); rr.Code != StatusOK {
+		t.Errorf("after 2s: code = %d; want %d", rr.Code, StatusOK)
+	}
+}
+
+func TestRateLimitHandlerKey(t *testing.T) {
+	h := RateLimitHandler(HandlerFunc(func(w ResponseWriter, r *Request) {}), 1, 1, func(r *Request) string {
+		return r.Header.Get("X-Api-Key")
+	})
+	for _, tt := range []struct {
+		key  string
+		code int
+	}{
+		{"a", StatusOK},
+		{"b", StatusOK},
+		{"a", StatusTooManyRequests},
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("X-Api-Key", tt.key)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, r)
+		if rr.Code != tt.code {
+			t.Errorf("key %q: code = %d; want %d", tt.key, rr.Code, tt.code)
+		}
+	}
+}
+
+func TestRateLimitHandlerSweep(t *testing.T) {
+	now := time.Unix(1e9, 0)
+	h := RateLimitHandler(HandlerFunc(func(w ResponseWriter, r *Request) {}), 1, 1, func(r *Request) string {
+		return r.URL.Path
+	})
+	SetRateLimitNowForTesting(h, func() time.Time { return now })
+	for i := 0; i < 1024; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", fmt.Sprintf("/%d", i), nil))
+	}
+	if n := RateLimitBucketsForTesting(h); n != 1024 {
+		t.Fatalf("buckets = %d; want 1024", n)
+	}
+	// Once the buckets have refilled, they are removed as new
+	// keys arrive.
+	now = now.Add(time.Second)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/new", nil))
+	if n := RateLimitBucketsForTesting(h); n != 1 {
+		t.Errorf("buckets after refill = %d; want 1", n)
+	}
+}
+
+func TestConcurrencyLimitHandler(t *testing.T) {
+	for _, queue := range []int{0, 1} {
+		started := make(chan string, 2)
+		release := make(chan bool)
+		h := ConcurrencyLimitHandler(HandlerFunc(func(w ResponseWriter, r *Request) {
+			started <- r.URL.Path
+			<-release
+		}), 1, queue, 0)
+
+		serve := func(path string) chan *httptest.ResponseRecorder {
+			c := make(chan *httptest.ResponseRecorder, 1)
+			go func() {
+				rr := httptest.NewRecorder()
+				h.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+				c <- rr
+			}()
+			return c
+		}
+		first := serve("/first")
+		if got := <-started; got != "/first" {
+			t.Fatalf("queue=%d: started %s; want /first", queue, got)
+		}
+		second := serve("/second")
+		if queue == 0 {
+			rr := <-second
+			if rr.Code != StatusServiceUnavailable {
+				t.Errorf("queue=0: second request: code = %d; want %d", rr.Code, StatusServiceUnavailable)
+			}
+			if got := rr.HeaderMap.Get("Retry-After"); got != "1" {
+				t.Errorf("queue=0: Retry-After = %q; want %q", got, "1")
+			}
+		}
+
+		release <- true
+		if rr := <-first; rr.Code != StatusOK {
+			t.Errorf("queue=%d: first request: code = %d; want %d", queue, rr.Code, StatusOK)
+		}
+		if queue == 1 {
+			// The queued request runs once the first is done.
+			if got := <-started; got != "/second" {
+				t.Fatalf("queue=1: started %s; want /second", got)
+			}
+			release <- true
+			if rr := <-second; rr.Code != StatusOK {
+				t.Errorf("queue=1: second request: code = %d; want %d", rr.Code, StatusOK)
+			}
+		}
+	}
+}
+
+func TestConcurrencyLimitHandlerWait(t *testing.T) {
+	started := make(chan bool)
+	release := make(chan bool)
+	h := ConcurrencyLimitHandler(HandlerFunc(func(w ResponseWriter, r *Request) {
+		started <- true
+		<-release
+	}), 1, 10, 20*time.Millisecond)
+	done := make(chan bool)
+	go func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+		done <- true
+	}()
+	<-started
+	defer func() {
+		close(release)
+		<-done
+	}()
+
+	t0 := time.Now()
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+	if rr.Code != StatusServiceUnavailable {
+		t.Fatalf("code = %d; want %d", rr.Code, StatusServiceUnavailable)
+	}
+	if d := time.Since(t0); d < 20*time.Millisecond {
+		t.Errorf("request shed after %v; want at least 20ms", d)
+	}
+
+	// A request whose context is done leaves the queue.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil).WithContext(ctx))
+	if rr.Code != StatusServiceUnavailable {
+		t.Errorf("canceled request: code = %d; want %d", rr.Code, StatusServiceUnavailable)
+	}
+}
+
+func TestMaxBytesHandler(t *testing.T) {
+	h := MaxBytesHandler(HandlerFunc(func(w ResponseWriter, r *Request) {
+		if _, err := ioutil.ReadAll(r.Body); err != nil {
+			Error(w, err.Error(), StatusRequestEntityTooLarge)
+			return
+		}
+		w.WriteHeader(StatusNoContent)
+	}), 10)
+
+	for _, tt := range []struct {
+		body          string
+		contentLength int64
+		code          int
+	}{
+		{"small", 5, StatusNoContent},
+		{"way too large", 13, StatusRequestEntityTooLarge},
+		{"way too large", -1, StatusRequestEntityTooLarge},
+	} {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+		r.ContentLength = tt.contentLength
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, r)
+		if rr.Code != tt.code {
+			t.Errorf("body %q, length %d: code = %d; want %d", tt.body, tt.contentLength, rr.Code, tt.code)
+		}
+	}
+}
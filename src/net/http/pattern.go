@@ -0,0 +1,387 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Patterns for ServeMux routing.
+
+package http
+
+import (
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// A pattern is a parsed ServeMux pattern:
+//
+//	[METHOD ][HOST]/[PATH]
+//
+// See the ServeMux documentation for its syntax.
+type pattern struct {
+	str      string // as registered
+	method   string // "" matches all methods
+	host     string // "" matches all hosts
+	segments []segment
+}
+
+// A segment is a segment of the path of a pattern. A literal segment
+// matches the path segment s, after unescaping; s is "" for a
+// trailing {$}, which matches the empty segment after a final slash.
+// A wildcard segment matches any non-empty path segment, or, if
+// multi, all remaining segments; s is the wildcard's name, "" for the
+// anonymous wildcard of a pattern ending in a slash.
+type segment struct {
+	s     string
+	wild  bool
+	multi bool
+}
+
+// parsePattern parses a ServeMux pattern.
+func parsePattern(s string) (*pattern, error) {
+	p := &pattern{str: s}
+	rest := s
+	if i := strings.IndexAny(s, " \t"); i >= 0 {
+		p.method = s[:i]
+		rest = strings.TrimLeft(s[i:], " \t")
+		if !validMethod(p.method) {
+			return nil, errors.New("invalid method " + p.method)
+		}
+	}
+	i := strings.IndexByte(rest, '/')
+	if i < 0 {
+		return nil, errors.New("missing /")
+	}
+	p.host = rest[:i]
+	if strings.ContainsAny(p.host, "{}") {
+		return nil, errors.New("host contains '{' or '}'")
+	}
+
+	names := make(map[string]bool)
+	rest = rest[i+1:]
+	for {
+		if rest == "" {
+			// The root, or a trailing slash: the pattern matches
+			// the whole subtree.
+			p.segments = append(p.segments, segment{wild: true, multi: true})
+			break
+		}
+		seg, last := rest, true
+		if i := strings.IndexByte(rest, '/'); i >= 0 {
+			seg, rest, last = rest[:i], rest[i+1:], false
+		}
+		if seg == "" {
+			return nil, errors.New("empty path segment")
+		}
+		if seg[0] != '{' {
+			if strings.ContainsAny(seg, "{}") {
+				return nil, errors.New("wildcard " + seg + " is not a whole segment")
+			}
+			lit, err := url.PathUnescape(seg)
+			if err != nil {
+				return nil, err
+			}
+			p.segments = append(p.segments, segment{s: lit})
+		} else {
+			if seg[len(seg)-1] != '}' {
+				return nil, errors.New("wildcard " + seg + " is not a whole segment")
+			}
+			name := seg[1 : len(seg)-1]
+			if name == "$" {
+				if !last {
+					return nil, errors.New("{$} not at the end")
+				}
+				p.segments = append(p.segments, segment{})
+				break
+			}
+			multi := strings.HasSuffix(name, "...")
+			if multi {
+				name = name[:len(name)-len("...")]
+				if !last {
+					return nil, errors.New("{" + name + "...} not at the end")
+				}
+			}
+			if !isValidWildcardName(name) {
+				return nil, errors.New("invalid wildcard name " + name)
+			}
+			if names[name] {
+				return nil, errors.New("duplicate wildcard name " + name)
+			}
+			names[name] = true
+			p.segments = append(p.segments, segment{s: name, wild: true, multi: multi})
+		}
+		if last {
+			break
+		}
+	}
+	return p, nil
+}
+
+// parseLegacyPattern parses a ServeMux pattern in the syntax used
+// before methods and wildcards, for GODEBUG=httpmuxlegacy=1:
+//
+//	[HOST]/[PATH]
+//
+// PATH is a literal, and a trailing slash names a subtree. The
+// ServeMux matches legacy patterns against the whole unescaped request
+// path rather than segment by segment; see ServeMux.legacyHandler.
+// The segments parsed here only serve to detect conflicts.
+func parseLegacyPattern(s string) (*pattern, error) {
+	p := &pattern{str: s}
+	i := strings.IndexByte(s, '/')
+	if i < 0 {
+		return nil, errors.New("missing /")
+	}
+	p.host = s[:i]
+	for _, seg := range strings.Split(s[i+1:], "/") {
+		p.segments = append(p.segments, segment{s: seg})
+	}
+	if p.lastSegment().s == "" {
+		p.segments[len(p.segments)-1] = segment{wild: true, multi: true}
+	}
+	return p, nil
+}
+
+// isValidWildcardName reports whether s is a Go identifier.
+func isValidWildcardName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		if !unicode.IsLetter(c) && c != '_' && (i == 0 || !unicode.IsDigit(c)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *pattern) lastSegment() segment {
+	return p.segments[len(p.segments)-1]
+}
+
+// wildcardIndex returns the index in the values returned by matchPath
+// of the wildcard called name, or -1.
+func (p *pattern) wildcardIndex(name string) int {
+	i := 0
+	for _, seg := range p.segments {
+		if seg.wild && seg.s != "" {
+			if seg.s == name {
+				return i
+			}
+			i++
+		}
+	}
+	return -1
+}
+
+// splitPath splits the escaped request path into its unescaped
+// segments. It returns nil if path is not rooted.
+func splitPath(path string) []string {
+	if path == "" || path[0] != '/' {
+		return nil
+	}
+	segs := strings.Split(path[1:], "/")
+	for i, s := range segs {
+		if u, err := url.PathUnescape(s); err == nil {
+			segs[i] = u
+		}
+	}
+	return segs
+}
+
+func (p *pattern) matchMethod(method string) bool {
+	return p.method == "" || p.method == method || p.method == "GET" && method == "HEAD"
+}
+
+// matchPath reports whether p matches the path split into segs by
+// splitPath, and returns the values of its named wildcards, in order.
+func (p *pattern) matchPath(segs []string) (matches []string, ok bool) {
+	for i, seg := range p.segments {
+		if i >= len(segs) {
+			return nil, false
+		}
+		switch {
+		case seg.multi:
+			if seg.s != "" {
+				matches = append(matches, strings.Join(segs[i:], "/"))
+			}
+			return matches, true
+		case seg.wild:
+			if segs[i] == "" {
+				return nil, false
+			}
+			matches = append(matches, segs[i])
+		case seg.s != segs[i]:
+			return nil, false
+		}
+	}
+	return matches, len(segs) == len(p.segments)
+}
+
+// exactMatch reports whether p matches the path split into segs
+// without a multi wildcard standing for more than the empty segment
+// after a final slash.
+func (p *pattern) exactMatch(segs []string) bool {
+	if !p.lastSegment().multi {
+		return true
+	}
+	return len(segs) == len(p.segments) && segs[len(segs)-1] == ""
+}
+
+// A relationship describes how the sets of requests matched by two
+// patterns relate.
+type relationship string
+
+const (
+	equivalent   relationship = "equivalent"   // same sets
+	moreGeneral  relationship = "moreGeneral"  // strict superset
+	moreSpecific relationship = "moreSpecific" // strict subset
+	disjoint     relationship = "disjoint"     // no request in common
+	overlaps     relationship = "overlaps"     // none of the above
+)
+
+func inverseRelationship(r relationship) relationship {
+	switch r {
+	case moreGeneral:
+		return moreSpecific
+	case moreSpecific:
+		return moreGeneral
+	}
+	return r
+}
+
+// combineRelationships returns the relationship of two patterns from
+// those of two independent parts of them, such as their methods and
+// their paths.
+func combineRelationships(r1, r2 relationship) relationship {
+	switch r1 {
+	case equivalent:
+		return r2
+	case disjoint:
+		return disjoint
+	case overlaps:
+		if r2 == disjoint {
+			return disjoint
+		}
+		return overlaps
+	}
+	// r1 is moreGeneral or moreSpecific.
+	switch r2 {
+	case equivalent:
+		return r1
+	case inverseRelationship(r1):
+		return overlaps
+	}
+	return r2
+}
+
+// compare returns the relationship of the requests matched by p1 to
+// those matched by p2, ignoring their hosts.
+func (p1 *pattern) compare(p2 *pattern) relationship {
+	mrel := compareMethods(p1.method, p2.method)
+	if mrel == disjoint {
+		return disjoint
+	}
+	return combineRelationships(mrel, p1.comparePaths(p2))
+}
+
+// conflictsWith reports whether p1 and p2 may both match a request
+// without either one taking precedence.
+func (p1 *pattern) conflictsWith(p2 *pattern) bool {
+	if p1.host != p2.host {
+		return false
+	}
+	rel := p1.compare(p2)
+	return rel == equivalent || rel == overlaps
+}
+
+func compareMethods(m1, m2 string) relationship {
+	switch {
+	case m1 == m2:
+		return equivalent
+	case m1 == "":
+		return moreGeneral
+	case m2 == "":
+		return moreSpecific
+	case m1 == "GET" && m2 == "HEAD":
+		return moreGeneral
+	case m1 == "HEAD" && m2 == "GET":
+		return moreSpecific
+	}
+	return disjoint
+}
+
+func (p1 *pattern) comparePaths(p2 *pattern) relationship {
+	segs1, segs2 := p1.segments, p2.segments
+	if len(segs1) != len(segs2) && !p1.lastSegment().multi && !p2.lastSegment().multi {
+		return disjoint
+	}
+	rel := equivalent
+	for ; len(segs1) > 0 && len(segs2) > 0; segs1, segs2 = segs1[1:], segs2[1:] {
+		rel = combineRelationships(rel, compareSegments(segs1[0], segs2[0]))
+		if rel == disjoint {
+			return disjoint
+		}
+	}
+	switch {
+	case len(segs1) == 0 && len(segs2) == 0:
+		return rel
+	case len(segs1) == 0 && p1.lastSegment().multi:
+		// The multi wildcard that ended p1 also matched the rest
+		// of p2.
+		return combineRelationships(rel, moreGeneral)
+	case len(segs2) == 0 && p2.lastSegment().multi:
+		return combineRelationships(rel, moreSpecific)
+	}
+	return disjoint
+}
+
+func compareSegments(s1, s2 segment) relationship {
+	switch {
+	case s1.multi && s2.multi:
+		return equivalent
+	case s1.multi:
+		return moreGeneral
+	case s2.multi:
+		return moreSpecific
+	case s1.wild && s2.wild:
+		return equivalent
+	case s1.wild:
+		// A single wildcard does not match the empty segment {$}
+		// stands for.
+		if s2.s == "" {
+			return disjoint
+		}
+		return moreGeneral
+	case s2.wild:
+		if s1.s == "" {
+			return disjoint
+		}
+		return moreSpecific
+	case s1.s == s2.s:
+		return equivalent
+	}
+	return disjoint
+}
+
+// allowedMethods returns the sorted methods of pats, with HEAD
+// wherever GET is allowed, for an Allow header.
+func allowedMethods(pats []*pattern) []string {
+	seen := make(map[string]bool)
+	var methods []string
+	add := func(m string) {
+		if !seen[m] {
+			seen[m] = true
+			methods = append(methods, m)
+		}
+	}
+	for _, p := range pats {
+		add(p.method)
+		if p.method == "GET" {
+			add("HEAD")
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
@@ -0,0 +1,151 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePattern(t *testing.T) {
+	lit := func(s string) segment { return segment{s: s} }
+	wild := func(name string) segment { return segment{s: name, wild: true} }
+	multi := func(name string) segment { return segment{s: name, wild: true, multi: true} }
+
+	tests := []struct {
+		in   string
+		want pattern
+	}{
+		{"/", pattern{segments: []segment{multi("")}}},
+		{"/a", pattern{segments: []segment{lit("a")}}},
+		{"/a/", pattern{segments: []segment{lit("a"), multi("")}}},
+		{"/a/{$}", pattern{segments: []segment{lit("a"), lit("")}}},
+		{"/{$}", pattern{segments: []segment{lit("")}}},
+		{"GET /users/{id}", pattern{method: "GET", segments: []segment{lit("users"), wild("id")}}},
+		{"POST\t example.com/files/{path...}", pattern{method: "POST", host: "example.com", segments: []segment{lit("files"), multi("path")}}},
+		{"example.com/", pattern{host: "example.com", segments: []segment{multi("")}}},
+		{"/a%20b/{x_1}", pattern{segments: []segment{lit("a b"), wild("x_1")}}},
+	}
+	for _, tt := range tests {
+		got, err := parsePattern(tt.in)
+		if err != nil {
+			t.Errorf("parsePattern(%q): %v", tt.in, err)
+			continue
+		}
+		tt.want.str = tt.in
+		if !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("parsePattern(%q) = %+v; want %+v", tt.in, *got, tt.want)
+		}
+	}
+}
+
+func TestParsePatternError(t *testing.T) {
+	for _, tt := range []struct {
+		in, contains string
+	}{
+		{"", "missing /"},
+		{"GET", "missing /"},
+		{"G@T /", "invalid method"},
+		{"{host}/", "host contains"},
+		{"/a//b", "empty path segment"},
+		{"/a{x}", "not a whole segment"},
+		{"/{x}b", "not a whole segment"},
+		{"/{$}/a", "{$} not at the end"},
+		{"/{x...}/a", "not at the end"},
+		{"/{}", "invalid wildcard name"},
+		{"/{1x}", "invalid wildcard name"},
+		{"/{a-b}", "invalid wildcard name"},
+		{"/{x}/{x}", "duplicate wildcard name"},
+		{"/%zz", "invalid URL escape"},
+	} {
+		_, err := parsePattern(tt.in)
+		if err == nil || !strings.Contains(err.Error(), tt.contains) {
+			t.Errorf("parsePattern(%q) error = %v; want error containing %q", tt.in, err, tt.contains)
+		}
+	}
+}
+
+func TestPatternCompare(t *testing.T) {
+	for _, tt := range []struct {
+		p1, p2 string
+		want   relationship
+	}{
+		{"/a", "/a", equivalent},
+		{"/a", "/b", disjoint},
+		{"/a", "/a/", disjoint},
+		{"/a/", "/", moreSpecific},
+		{"/images/thumbnails/", "/images/", moreSpecific},
+		{"/{x}", "/{y}", equivalent},
+		{"/{x}", "/a", moreGeneral},
+		{"/a/{$}", "/a/", moreSpecific},
+		{"/a/{$}", "/a/{x}", disjoint},
+		{"/a/{rest...}", "/a/", equivalent},
+		{"/users/{id}", "/{kind}/me", overlaps},
+		{"/a/{x}/c", "/a/b/{y}", overlaps},
+		{"GET /a", "/a", moreSpecific},
+		{"GET /a", "HEAD /a", moreGeneral},
+		{"GET /a", "POST /a", disjoint},
+		{"GET /users/", "/users/{id}", overlaps},
+		{"GET /users/me", "/users/{id}", moreSpecific},
+	} {
+		p1, err := parsePattern(tt.p1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p2, err := parsePattern(tt.p2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := p1.compare(p2); got != tt.want {
+			t.Errorf("%q compared to %q = %s; want %s", tt.p1, tt.p2, got, tt.want)
+		}
+		if got, want := p2.compare(p1), inverseRelationship(tt.want); got != want {
+			t.Errorf("%q compared to %q = %s; want %s", tt.p2, tt.p1, got, want)
+		}
+	}
+}
+
+func TestPatternMatchPath(t *testing.T) {
+	for _, tt := range []struct {
+		pat, path string
+		want      []string // nil for no match
+	}{
+		{"/", "/", []string{}},
+		{"/", "/a/b", []string{}},
+		{"/{$}", "/", []string{}},
+		{"/{$}", "/a", nil},
+		{"/a", "/a", []string{}},
+		{"/a", "/a/", nil},
+		{"/a/", "/a", nil},
+		{"/a/", "/a/", []string{}},
+		{"/a/", "/a/b/c", []string{}},
+		{"/a/{x}", "/a/", nil},
+		{"/a/{x}", "/a/b", []string{"b"}},
+		{"/a/{x}", "/a/b%2Fc", []string{"b/c"}},
+		{"/a/{x}/{y}", "/a/b/c", []string{"b", "c"}},
+		{"/a/{rest...}", "/a/", []string{""}},
+		{"/a/{rest...}", "/a/b/c", []string{"b/c"}},
+		{"/a%20b", "/a%20b", []string{}},
+		{"/a", "a", nil},
+	} {
+		p, err := parsePattern(tt.pat)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, ok := p.matchPath(splitPath(tt.path))
+		if !ok {
+			if tt.want != nil {
+				t.Errorf("%q does not match %q", tt.pat, tt.path)
+			}
+			continue
+		}
+		if tt.want == nil {
+			t.Errorf("%q matches %q", tt.pat, tt.path)
+		} else if len(got) != len(tt.want) || len(got) > 0 && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q matching %q = %q; want %q", tt.pat, tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,360 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Tests of the HTTP/2 server push engine and cleartext HTTP/2, driven
+// by a raw framer since the bundled Transport disables push.
+
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang_org/x/net/http2/hpack"
+)
+
+// pushTestConn is the client side of an HTTP/2 connection to a
+// bundled server. It speaks frames directly.
+type pushTestConn struct {
+	t      *testing.T
+	c      net.Conn
+	fr     *http2Framer
+	dec    *hpack.Decoder
+	writes chan func() // run in order by the writer goroutine
+}
+
+func newPushTestConn(t *testing.T, h Handler, settings ...http2Setting) *pushTestConn {
+	return newPushTestConnConfig(t, &Server{}, h, settings...)
+}
+
+// newPushTestConnConfig is like newPushTestConn, with srv as the
+// server's configuration.
+func newPushTestConnConfig(t *testing.T, srv *Server, h Handler, settings ...http2Setting) *pushTestConn {
+	cc, sc := net.Pipe()
+	go new(http2Server).ServeConn(sc, &http2ServeConnOpts{
+		BaseConfig: srv,
+		Handler:    h,
+	})
+	return startPushTestConn(t, cc, cc, settings...)
+}
+
+// startPushTestConn sends the client preface and settings on c,
+// reading the server's frames from r.
+func startPushTestConn(t *testing.T, cc net.Conn, r io.Reader, settings ...http2Setting) *pushTestConn {
+	tc := &pushTestConn{
+		t:      t,
+		c:      cc,
+		fr:     http2NewFramer(cc, r),
+		dec:    hpack.NewDecoder(4096, nil),
+		writes: make(chan func(), 8),
+	}
+	tc.fr.ReadMetaHeaders = tc.dec
+	cc.SetDeadline(time.Now().Add(10 * time.Second))
+	go func() {
+		for fn := range tc.writes {
+			fn()
+		}
+	}()
+	tc.writes <- func() {
+		cc.Write([]byte(http2ClientPreface))
+		tc.fr.WriteSettings(settings...)
+	}
+	return tc
+}
+
+func (tc *pushTestConn) close() {
+	close(tc.writes)
+	tc.c.Close()
+}
+
+func (tc *pushTestConn) get(streamID uint32, path string) {
+	var buf bytes.Buffer
+	enc := hpack.NewEncoder(&buf)
+	http2encKV(enc, ":method", "GET")
+	http2encKV(enc, ":scheme", "https")
+	http2encKV(enc, ":authority", "example.com")
+	http2encKV(enc, ":path", path)
+	tc.writes <- func() {
+		tc.fr.WriteHeaders(http2HeadersFrameParam{
+			StreamID:      streamID,
+			BlockFragment: buf.Bytes(),
+			EndStream:     true,
+			EndHeaders:    true,
+		})
+	}
+}
+
+// readResponses reads frames until the streams in want have all
+// ended, returning each stream's body and the paths promised on
+// each pushed stream.
+func (tc *pushTestConn) readResponses(want ...uint32) (bodies map[uint32]string, promised map[uint32]string) {
+	bodies = make(map[uint32]string)
+	promised = make(map[uint32]string)
+	open := make(map[uint32]bool)
+	for _, id := range want {
+		open[id] = true
+	}
+	for len(open) > 0 {
+		f, err := tc.fr.ReadFrame()
+		if err != nil {
+			tc.t.Fatalf("ReadFrame: %v", err)
+		}
+		switch f := f.(type) {
+		case *http2SettingsFrame:
+			if !f.IsAck() {
+				tc.writes <- func() { tc.fr.WriteSettingsAck() }
+			}
+		case *http2PushPromiseFrame:
+			hfs, err := tc.dec.DecodeFull(f.HeaderBlockFragment())
+			if err != nil {
+				tc.t.Fatalf("decoding PUSH_PROMISE: %v", err)
+			}
+			for _, hf := range hfs {
+				if hf.Name == ":path" {
+					promised[f.PromiseID] = hf.Value
+				}
+			}
+		case *http2MetaHeadersFrame:
+			if f.StreamEnded() {
+				delete(open, f.StreamID)
+			}
+		case *http2DataFrame:
+			bodies[f.StreamID] += string(f.Data())
+			if f.StreamEnded() {
+				delete(open, f.StreamID)
+			}
+		case *http2RSTStreamFrame:
+			tc.t.Fatalf("stream %d reset: %v", f.StreamID, f.ErrCode)
+		}
+	}
+	return bodies, promised
+}
+
+func TestServerPush(t *testing.T) {
+	pushErr := make(chan error, 1)
+	tc := newPushTestConn(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		switch r.URL.Path {
+		case "/":
+			pushErr <- w.(Pusher).Push("/style.css", &PushOptions{
+				Header: Header{"User-Agent": {"pusher"}},
+			})
+			w.Write([]byte("index"))
+		case "/style.css":
+			if got := r.Header.Get("User-Agent"); got != "pusher" {
+				t.Errorf("pushed request User-Agent = %q; want pusher", got)
+			}
+			if r.Host != "example.com" {
+				t.Errorf("pushed request Host = %q; want example.com", r.Host)
+			}
+			if err := w.(Pusher).Push("/other", nil); err != http2ErrRecursivePush {
+				t.Errorf("recursive Push = %v; want %v", err, http2ErrRecursivePush)
+			}
+			w.Write([]byte("css"))
+		}
+	}))
+	defer tc.close()
+
+	tc.get(1, "/")
+	bodies, promised := tc.readResponses(1, 2)
+	if err := <-pushErr; err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	if want := map[uint32]string{2: "/style.css"}; !reflect.DeepEqual(promised, want) {
+		t.Errorf("promised = %v; want %v", promised, want)
+	}
+	if want := map[uint32]string{1: "index", 2: "css"}; !reflect.DeepEqual(bodies, want) {
+		t.Errorf("bodies = %v; want %v", bodies, want)
+	}
+}
+
+func TestServerPushDisabled(t *testing.T) {
+	pushErr := make(chan error, 1)
+	tc := newPushTestConn(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		pushErr <- w.(Pusher).Push("/style.css", nil)
+	}), http2Setting{ID: http2SettingEnablePush, Val: 0})
+	defer tc.close()
+
+	tc.get(1, "/")
+	tc.readResponses(1)
+	if err := <-pushErr; err != ErrNotSupported {
+		t.Errorf("Push = %v; want %v", err, ErrNotSupported)
+	}
+}
+
+func TestServerPushInvalid(t *testing.T) {
+	// The test connection has no TLS, so pushed URLs must be http.
+	tests := []struct {
+		target string
+		opts   *PushOptions
+	}{
+		{"style.css", nil},
+		{"https://example.com/style.css", nil},
+		{"http:///style.css", nil},
+		{"/style.css", &PushOptions{Method: "POST"}},
+		{"/style.css", &PushOptions{Header: Header{":path": {"/x"}}}},
+		{"/style.css", &PushOptions{Header: Header{"Content-Length": {"1"}}}},
+		{"/style.css", &PushOptions{Header: Header{"Connection": {"close"}}}},
+	}
+	errs := make(chan []error, 1)
+	tc := newPushTestConn(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		if r.URL.Path != "/" {
+			// A push that wrongly succeeded.
+			return
+		}
+		var got []error
+		for _, tt := range tests {
+			got = append(got, w.(Pusher).Push(tt.target, tt.opts))
+		}
+		select {
+		case errs <- got:
+		default:
+		}
+	}))
+	defer tc.close()
+
+	tc.get(1, "/")
+	tc.readResponses(1)
+	for i, err := range <-errs {
+		if err == nil {
+			t.Errorf("Push(%q, %+v) succeeded; want error", tests[i].target, tests[i].opts)
+		}
+	}
+}
+
+func TestServerH2CUpgrade(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	srv := &Server{
+		AllowH2C: true,
+		Handler: HandlerFunc(func(w ResponseWriter, r *Request) {
+			if r.ProtoMajor != 2 || r.TLS != nil {
+				t.Errorf("request proto = %s, TLS = %v; want HTTP/2.0 without TLS", r.Proto, r.TLS)
+			}
+			if r.Header.Get("Upgrade") != "" || r.Header.Get("Http2-Settings") != "" {
+				t.Errorf("upgrade headers passed to handler: %v", r.Header)
+			}
+			w.Write([]byte(r.Host + r.URL.Path))
+		}),
+	}
+	go srv.Serve(ln)
+
+	c, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(c, "GET /upgraded HTTP/1.1\r\nHost: example.com\r\n"+
+		"Connection: Upgrade, HTTP2-Settings\r\nUpgrade: h2c\r\n"+
+		"HTTP2-Settings: AAMAAABkAAQAAP__\r\n\r\n")
+	br := bufio.NewReader(c)
+	res, err := ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != StatusSwitchingProtocols || res.Header.Get("Upgrade") != "h2c" {
+		t.Fatalf("upgrade response = %v %v; want 101 with Upgrade: h2c", res.Status, res.Header)
+	}
+
+	tc := startPushTestConn(t, c, br)
+	defer tc.close()
+	tc.get(3, "/second")
+	bodies, _ := tc.readResponses(1, 3)
+	if want := map[uint32]string{1: "example.com/upgraded", 3: "example.com/second"}; !reflect.DeepEqual(bodies, want) {
+		t.Errorf("bodies = %v; want %v", bodies, want)
+	}
+}
+
+// writeExtendedConnect writes the HEADERS of an extended CONNECT
+// request for a WebSocket on stream 1, followed by a DATA frame
+// ending the stream.
+func (tc *pushTestConn) writeExtendedConnect(data string) {
+	var buf bytes.Buffer
+	enc := hpack.NewEncoder(&buf)
+	http2encKV(enc, ":method", "CONNECT")
+	http2encKV(enc, ":protocol", "websocket")
+	http2encKV(enc, ":scheme", "https")
+	http2encKV(enc, ":authority", "example.com")
+	http2encKV(enc, ":path", "/chat")
+	tc.writes <- func() {
+		tc.fr.WriteHeaders(http2HeadersFrameParam{
+			StreamID:      1,
+			BlockFragment: buf.Bytes(),
+			EndHeaders:    true,
+		})
+		tc.fr.WriteData(1, true, []byte(data))
+	}
+}
+
+func TestServerExtendedConnect(t *testing.T) {
+	srv := &Server{AllowExtendedConnect: true}
+	tc := newPushTestConnConfig(t, srv, HandlerFunc(func(w ResponseWriter, r *Request) {
+		if r.Method != "CONNECT" || r.Header.Get(":protocol") != "websocket" || r.URL.Path != "/chat" {
+			t.Errorf("request = %s %s, :protocol %q; want CONNECT /chat, websocket", r.Method, r.URL.Path, r.Header.Get(":protocol"))
+		}
+		w.WriteHeader(200)
+		w.(Flusher).Flush()
+		io.Copy(w, r.Body)
+	}))
+	defer tc.close()
+
+	f, err := tc.fr.ReadFrame()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sf, ok := f.(*http2SettingsFrame)
+	if !ok {
+		t.Fatalf("first frame = %T; want SETTINGS", f)
+	}
+	if v, ok := sf.Value(http2SettingEnableConnectProtocol); !ok || v != 1 {
+		t.Errorf("SETTINGS_ENABLE_CONNECT_PROTOCOL = %v, %v; want 1, true", v, ok)
+	}
+	tc.writes <- func() { tc.fr.WriteSettingsAck() }
+
+	tc.writeExtendedConnect("hello")
+	bodies, _ := tc.readResponses(1)
+	if got := bodies[1]; got != "hello" {
+		t.Errorf("body = %q; want %q", got, "hello")
+	}
+}
+
+func TestServerExtendedConnectDisabled(t *testing.T) {
+	tc := newPushTestConn(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		t.Errorf("handler called for %s %s", r.Method, r.URL.Path)
+	}))
+	defer tc.close()
+
+	f, err := tc.fr.ReadFrame()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sf, ok := f.(*http2SettingsFrame)
+	if !ok {
+		t.Fatalf("first frame = %T; want SETTINGS", f)
+	}
+	if v, ok := sf.Value(http2SettingEnableConnectProtocol); ok {
+		t.Errorf("SETTINGS_ENABLE_CONNECT_PROTOCOL = %v sent by default", v)
+	}
+	tc.writes <- func() { tc.fr.WriteSettingsAck() }
+
+	tc.writeExtendedConnect("hello")
+	for {
+		f, err := tc.fr.ReadFrame()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rst, ok := f.(*http2RSTStreamFrame); ok {
+			if rst.StreamID != 1 || rst.ErrCode != http2ErrCodeProtocol {
+				t.Errorf("RST_STREAM on stream %d with %v; want stream 1 with PROTOCOL_ERROR", rst.StreamID, rst.ErrCode)
+			}
+			return
+		}
+	}
+}
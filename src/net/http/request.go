@@ -151,6 +151,16 @@ type Request struct {
 	// Handler does not need to.
 	Body io.ReadCloser
 
+	// GetBody defines an optional func to return a new copy of
+	// Body. It is used for client requests when a retry, such as
+	// one by RetryTransport or by an Interceptor, requires reading
+	// the body more than once. The Client does not use it when
+	// following redirects, since it never resends a body there.
+	// Use of GetBody still requires setting Body.
+	//
+	// For server requests it is unused.
+	GetBody func() (io.ReadCloser, error)
+
 	// ContentLength records the length of the associated content.
 	// The value -1 indicates that the length is unknown.
 	// Values >= 0 indicate that the given number of bytes may
@@ -275,6 +285,15 @@ type Request struct {
 	// It is unexported to prevent people from using Context wrong
 	// and mutating the contexts held by callers of the same request.
 	ctx context.Context
+
+	// pat is the ServeMux pattern that matched the request, and
+	// matches the values of its named wildcards, in order.
+	pat     *pattern
+	matches []string
+
+	// otherValues holds the values set by SetPathValue for names
+	// that are not wildcards of pat.
+	otherValues map[string]string
 }
 
 // Context returns the request's context. To change the context, use
@@ -307,6 +326,43 @@ func (r *Request) WithContext(ctx context.Context) *Request {
 	return r2
 }
 
+// PathValue returns the value for the named path wildcard in the
+// ServeMux pattern that matched the request, or a value set by
+// SetPathValue. It returns the empty string if the request was not
+// matched against a pattern or there is no such wildcard in the
+// pattern.
+func (r *Request) PathValue(name string) string {
+	if r.pat != nil {
+		if i := r.pat.wildcardIndex(name); i >= 0 {
+			return r.matches[i]
+		}
+	}
+	return r.otherValues[name]
+}
+
+// SetPathValue sets name to value, so that subsequent calls to
+// r.PathValue(name) return value. It does not change the values seen
+// by other copies of the request, such as those made by WithContext.
+func (r *Request) SetPathValue(name, value string) {
+	// Shallow copies of r share its slice and map, so replace
+	// them rather than write to them.
+	if r.pat != nil {
+		if i := r.pat.wildcardIndex(name); i >= 0 {
+			matches := make([]string, len(r.matches))
+			copy(matches, r.matches)
+			matches[i] = value
+			r.matches = matches
+			return
+		}
+	}
+	otherValues := make(map[string]string, len(r.otherValues)+1)
+	for k, v := range r.otherValues {
+		otherValues[k] = v
+	}
+	otherValues[name] = value
+	r.otherValues = otherValues
+}
+
 // ProtoAtLeast reports whether the HTTP protocol used
 // in the request is at least major.minor.
 func (r *Request) ProtoAtLeast(major, minor int) bool {
@@ -738,10 +794,25 @@ func NewRequest(method, urlStr string, body io.Reader) (*Request, error) {
 		switch v := body.(type) {
 		case *bytes.Buffer:
 			req.ContentLength = int64(v.Len())
+			buf := v.Bytes()
+			req.GetBody = func() (io.ReadCloser, error) {
+				r := bytes.NewReader(buf)
+				return ioutil.NopCloser(r), nil
+			}
 		case *bytes.Reader:
 			req.ContentLength = int64(v.Len())
+			snapshot := *v
+			req.GetBody = func() (io.ReadCloser, error) {
+				r := snapshot
+				return ioutil.NopCloser(&r), nil
+			}
 		case *strings.Reader:
 			req.ContentLength = int64(v.Len())
+			snapshot := *v
+			req.GetBody = func() (io.ReadCloser, error) {
+				r := snapshot
+				return ioutil.NopCloser(&r), nil
+			}
 		default:
 			req.ContentLength = -1 // unknown
 		}
@@ -751,6 +822,7 @@ func NewRequest(method, urlStr string, body io.Reader) (*Request, error) {
 		// to set the Body to nil.
 		if req.ContentLength == 0 {
 			req.Body = nil
+			req.GetBody = nil
 		}
 	}
 
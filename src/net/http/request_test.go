@@ -517,6 +517,46 @@ func TestNewRequestContentLength(t *testing.T) {
 	}
 }
 
+func TestNewRequestGetBody(t *testing.T) {
+	tests := []struct {
+		r io.Reader
+	}{
+		{r: strings.NewReader("hello")},
+		{r: bytes.NewReader([]byte("hello"))},
+		{r: bytes.NewBuffer([]byte("hello"))},
+	}
+	for i, tt := range tests {
+		req, err := NewRequest("POST", "http://foo.tld/", tt.r)
+		if err != nil {
+			t.Errorf("test[%d]: %v", i, err)
+			continue
+		}
+		if req.Body == nil {
+			t.Errorf("test[%d]: Body = nil", i)
+		}
+		if req.GetBody == nil {
+			t.Errorf("test[%d]: GetBody = nil", i)
+			continue
+		}
+		// Reading Body must not affect what GetBody returns.
+		ioutil.ReadAll(req.Body)
+		for j := 0; j < 2; j++ {
+			body, err := req.GetBody()
+			if err != nil {
+				t.Errorf("test[%d]: GetBody = %v", i, err)
+				continue
+			}
+			slurp, err := ioutil.ReadAll(body)
+			if err != nil {
+				t.Errorf("test[%d]: ReadAll(GetBody) = %v", i, err)
+			}
+			if string(slurp) != "hello" {
+				t.Errorf("test[%d]: GetBody #%d = %q; want %q", i, j, slurp, "hello")
+			}
+		}
+	}
+}
+
 var parseHTTPVersionTests = []struct {
 	vers         string
 	major, minor int
@@ -17,6 +17,8 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+
+	"golang_org/x/net/lex/httplex"
 )
 
 var respExcludeHeader = map[string]bool{
@@ -56,6 +58,10 @@ type Response struct {
 	//
 	// The Body is automatically dechunked if the server replied
 	// with a "chunked" Transfer-Encoding.
+	//
+	// On a successful "101 Switching Protocols" response, as used by
+	// WebSockets, the Body also implements io.Writer and owns the
+	// underlying connection.
 	Body io.ReadCloser
 
 	// ContentLength records the length of the associated content. The
@@ -213,6 +219,14 @@ func (r *Response) ProtoAtLeast(major, minor int) bool {
 		r.ProtoMajor == major && r.ProtoMinor >= minor
 }
 
+// isProtocolSwitch reports whether r is a response to a successful
+// protocol upgrade.
+func (r *Response) isProtocolSwitch() bool {
+	return r.StatusCode == StatusSwitchingProtocols &&
+		r.Header.Get("Upgrade") != "" &&
+		httplex.HeaderValuesContainsToken(r.Header["Connection"], "Upgrade")
+}
+
 // Write writes r to w in the HTTP/1.x server response format,
 // including the status line, headers, body, and optional trailer.
 //
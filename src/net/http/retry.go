@@ -0,0 +1,254 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Automatic retries of client requests.
+
+package http
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"strconv"
+	"time"
+)
+
+// RetryTransport is a RoundTripper that retries requests which failed
+// because of a transient connection error or a transient server
+// condition, such as a 503 (Service Unavailable) status.
+//
+// Only requests that can safely be sent more than once are retried:
+// those with an idempotent method (GET, HEAD, OPTIONS, TRACE, PUT or
+// DELETE), or with an "Idempotency-Key" or "X-Idempotency-Key" header,
+// and whose Body is nil or can be recreated with GetBody.
+//
+// Between attempts RetryTransport waits with an exponential backoff,
+// or as long as the response's Retry-After header asks, whichever is
+// longer. It stops waiting if the request is canceled.
+//
+// A RetryTransport can also be used as a Client Interceptor through its
+// Intercept method.
+type RetryTransport struct {
+	// Transport makes each attempt. If nil, DefaultTransport
+	// is used.
+	Transport RoundTripper
+
+	// MaxRetries is the maximum number of attempts after the
+	// first. If zero, DefaultMaxRetries is used. If negative,
+	// requests are never retried.
+	MaxRetries int
+
+	// MinBackoff is the wait before the first retry, doubled
+	// for each further one up to MaxBackoff. Each wait is
+	// randomized to between half and all of its value. If zero,
+	// MinBackoff is 100ms and MaxBackoff is 10s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// ShouldRetry optionally reports whether an attempt that
+	// returned resp or err is to be retried, if the request is
+	// retryable at all. If nil, responses with status 429 (Too
+	// Many Requests), 502 (Bad Gateway), 503 (Service Unavailable)
+	// and 504 (Gateway Timeout) are retried, as are errors that may
+	// not recur: connections closed or reset before a response
+	// arrived, timeouts, and other network errors that report
+	// themselves temporary. Other errors, such as a host name that
+	// does not resolve, are not retried.
+	//
+	// A response whose Retry-After header asks to wait longer
+	// than MaxBackoff is never retried, but returned as is.
+	ShouldRetry func(resp *Response, err error) bool
+}
+
+// DefaultMaxRetries is the number of retries a RetryTransport makes
+// if its MaxRetries field is zero.
+const DefaultMaxRetries = 3
+
+// Intercept returns a copy of t that sends its attempts through next.
+// It lets t be used in Client.Interceptors:
+//
+//	c := &http.Client{
+//		Interceptors: []http.Interceptor{(&http.RetryTransport{MaxRetries: 5}).Intercept},
+//	}
+//
+// The Client then calls Intercept for every request it sends. That is
+// cheap, as a RetryTransport keeps no state between requests.
+func (t *RetryTransport) Intercept(next RoundTripper) RoundTripper {
+	t2 := *t
+	t2.Transport = next
+	return &t2
+}
+
+func (t *RetryTransport) transport() RoundTripper {
+	if t.Transport != nil {
+		return t.Transport
+	}
+	return DefaultTransport
+}
+
+func (t *RetryTransport) maxRetries() int {
+	if t.MaxRetries == 0 {
+		return DefaultMaxRetries
+	}
+	return t.MaxRetries
+}
+
+func (t *RetryTransport) backoffBounds() (min, max time.Duration) {
+	min, max = t.MinBackoff, t.MaxBackoff
+	if min <= 0 {
+		min = 100 * time.Millisecond
+	}
+	if max <= 0 {
+		max = 10 * time.Second
+	}
+	if max < min {
+		max = min
+	}
+	return min, max
+}
+
+// backoff returns the randomized wait before retry number n, counting
+// from zero.
+func (t *RetryTransport) backoff(n int) time.Duration {
+	d, max := t.backoffBounds()
+	for i := 0; i < n && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func (t *RetryTransport) shouldRetry(resp *Response, err error) bool {
+	if t.ShouldRetry != nil {
+		return t.ShouldRetry(resp, err)
+	}
+	if err != nil {
+		return isTransientError(err)
+	}
+	switch resp.StatusCode {
+	case StatusTooManyRequests, StatusBadGateway, StatusServiceUnavailable, StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// RoundTrip implements the RoundTripper interface.
+func (t *RetryTransport) RoundTrip(req *Request) (*Response, error) {
+	rt := t.transport()
+	if !isRetryable(req) {
+		return rt.RoundTrip(req)
+	}
+	for n := 0; ; n++ {
+		areq := req
+		if n > 0 && req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			areq = new(Request)
+			*areq = *req // shallow clone
+			areq.Body = body
+		}
+		resp, err := rt.RoundTrip(areq)
+		if n >= t.maxRetries() || !t.shouldRetry(resp, err) {
+			return resp, err
+		}
+		wait := t.backoff(n)
+		if err == nil {
+			if d, ok := retryAfter(resp.Header.get("Retry-After"), time.Now()); ok {
+				if _, max := t.backoffBounds(); d > max {
+					return resp, nil
+				}
+				if d > wait {
+					wait = d
+				}
+			}
+			// Read some of the body so the connection can be reused.
+			const maxBodySlurpSize = 2 << 10
+			if resp.ContentLength == -1 || resp.ContentLength <= maxBodySlurpSize {
+				io.CopyN(ioutil.Discard, resp.Body, maxBodySlurpSize)
+			}
+			resp.Body.Close()
+		}
+		if err := waitRetry(req, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// isRetryable reports whether req may be sent more than once.
+func isRetryable(req *Request) bool {
+	if req.Body != nil && req.GetBody == nil {
+		return false
+	}
+	switch valueOrDefault(req.Method, "GET") {
+	case "GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE":
+		return true
+	}
+	if _, ok := req.Header["Idempotency-Key"]; ok {
+		return true
+	}
+	if _, ok := req.Header["X-Idempotency-Key"]; ok {
+		return true
+	}
+	return false
+}
+
+// isTransientError reports whether err, returned by a RoundTripper,
+// describes a failure to talk to the server that may not recur, as
+// opposed to a problem with the request or a canceled request.
+func isTransientError(err error) bool {
+	switch err {
+	case errRequestCanceled, errRequestCanceledConn, context.Canceled, context.DeadlineExceeded:
+		return false
+	case io.EOF, io.ErrUnexpectedEOF, errServerClosedIdle, http2ErrNoCachedConn, http2errClientConnUnusable:
+		return true
+	}
+	switch err := err.(type) {
+	case nothingWrittenError, transportReadFromServerError, http2GoAwayError:
+		return true
+	case net.Error:
+		// Timeouts and temporary errors, such as a connection
+		// reset by the peer, but not lasting ones, such as a
+		// host that does not exist or refuses connections.
+		return err.Timeout() || err.Temporary()
+	}
+	return false
+}
+
+// retryAfter parses the value of a Retry-After header, which is either
+// a number of seconds or an HTTP-date, into the time to wait from now.
+func retryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.ParseUint(v, 10, 32); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := ParseTime(v); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// waitRetry waits for d, unless req is canceled first.
+func waitRetry(req *Request, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Cancel:
+		return errRequestCanceled
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
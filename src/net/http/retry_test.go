@@ -0,0 +1,259 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	. "net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// retryServer returns a server failing the first fails requests with
+// fail, and answering the others with their body.
+func retryServer(fails int32, fail func(w ResponseWriter)) (*httptest.Server, *int32) {
+	var n int32
+	ts := httptest.NewServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		if atomic.AddInt32(&n, 1) <= fails {
+			fail(w)
+			return
+		}
+		io.Copy(w, r.Body)
+	}))
+	return ts, &n
+}
+
+func fastRetry(max int) *RetryTransport {
+	return &RetryTransport{MaxRetries: max, MinBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond}
+}
+
+func TestRetryStatus(t *testing.T) {
+	defer afterTest(t)
+	ts, n := retryServer(2, func(w ResponseWriter) {
+		w.WriteHeader(StatusServiceUnavailable)
+	})
+	defer ts.Close()
+
+	c := &Client{Interceptors: []Interceptor{fastRetry(3).Intercept}}
+	req, _ := NewRequest("PUT", ts.URL, strings.NewReader("payload"))
+	res, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	slurp, _ := ioutil.ReadAll(res.Body)
+	if res.StatusCode != 200 || string(slurp) != "payload" {
+		t.Errorf("got %d %q; want 200 %q", res.StatusCode, slurp, "payload")
+	}
+	if got := atomic.LoadInt32(n); got != 3 {
+		t.Errorf("server saw %d requests; want 3", got)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	defer afterTest(t)
+	ts, n := retryServer(10, func(w ResponseWriter) {
+		w.WriteHeader(StatusBadGateway)
+	})
+	defer ts.Close()
+
+	res, err := (&Client{Transport: fastRetry(2)}).Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != StatusBadGateway {
+		t.Errorf("status = %d; want %d", res.StatusCode, StatusBadGateway)
+	}
+	if got := atomic.LoadInt32(n); got != 3 {
+		t.Errorf("server saw %d requests; want 3", got)
+	}
+}
+
+func TestRetryNotIdempotent(t *testing.T) {
+	defer afterTest(t)
+	ts, n := retryServer(1, func(w ResponseWriter) {
+		w.WriteHeader(StatusServiceUnavailable)
+	})
+	defer ts.Close()
+
+	c := &Client{Transport: fastRetry(3)}
+	res, err := c.Post(ts.URL, "text/plain", strings.NewReader("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != StatusServiceUnavailable {
+		t.Errorf("POST status = %d; want %d", res.StatusCode, StatusServiceUnavailable)
+	}
+
+	// With an idempotency key, a POST may be retried.
+	req, _ := NewRequest("POST", ts.URL, strings.NewReader("x"))
+	req.Header.Set("Idempotency-Key", "123")
+	atomic.StoreInt32(n, 0)
+	res, err = c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != 200 {
+		t.Errorf("POST with Idempotency-Key status = %d; want 200", res.StatusCode)
+	}
+
+	// A body that cannot be recreated is sent only once.
+	req, _ = NewRequest("PUT", ts.URL, struct{ io.Reader }{strings.NewReader("x")})
+	atomic.StoreInt32(n, 0)
+	res, err = c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != StatusServiceUnavailable {
+		t.Errorf("PUT without GetBody status = %d; want %d", res.StatusCode, StatusServiceUnavailable)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	defer afterTest(t)
+	ts, n := retryServer(1, func(w ResponseWriter) {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(StatusTooManyRequests)
+	})
+	defer ts.Close()
+
+	rt := fastRetry(1)
+	rt.MaxBackoff = 5 * time.Second
+	t0 := time.Now()
+	res, err := (&Client{Transport: rt}).Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != 200 {
+		t.Errorf("status = %d; want 200", res.StatusCode)
+	}
+	if d := time.Since(t0); d < time.Second {
+		t.Errorf("retried after %v; want at least 1s", d)
+	}
+
+	// A Retry-After beyond MaxBackoff is not waited for.
+	atomic.StoreInt32(n, 0)
+	rt.MaxBackoff = 10 * time.Millisecond
+	res, err = (&Client{Transport: rt}).Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != StatusTooManyRequests {
+		t.Errorf("status = %d; want %d", res.StatusCode, StatusTooManyRequests)
+	}
+	if got := atomic.LoadInt32(n); got != 1 {
+		t.Errorf("server saw %d requests; want 1", got)
+	}
+}
+
+func TestRetryConnectionError(t *testing.T) {
+	defer afterTest(t)
+	ts, n := retryServer(1, func(w ResponseWriter) {
+		c, _, err := w.(Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		c.Close()
+	})
+	defer ts.Close()
+
+	res, err := (&Client{Transport: fastRetry(1)}).Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if got := atomic.LoadInt32(n); got != 2 {
+		t.Errorf("server saw %d requests; want 2", got)
+	}
+
+	// Errors not caused by the connection are not retried.
+	var calls int32
+	rt := fastRetry(3)
+	rt.Transport = RoundTripperFunc(func(*Request) (*Response, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, errors.New("bad request")
+	})
+	req, _ := NewRequest("GET", "http://example.com/", nil)
+	if _, err := rt.RoundTrip(req); err == nil {
+		t.Error("RoundTrip succeeded; want error")
+	}
+	if calls != 1 {
+		t.Errorf("transport called %d times; want 1", calls)
+	}
+
+	// Of the network errors, only timeouts and temporary errors
+	// are retried.
+	for _, tt := range []struct {
+		err   error
+		calls int32
+	}{
+		{&net.OpError{Op: "read", Net: "tcp", Err: netError{temporary: true}}, 4},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: netError{timeout: true}}, 4},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, 1},
+		{&net.DNSError{Err: "no such host", Name: "example.com"}, 1},
+	} {
+		calls = 0
+		rt.Transport = RoundTripperFunc(func(*Request) (*Response, error) {
+			atomic.AddInt32(&calls, 1)
+			return nil, tt.err
+		})
+		if _, err := rt.RoundTrip(req); err == nil {
+			t.Errorf("%v: RoundTrip succeeded; want error", tt.err)
+		}
+		if calls != tt.calls {
+			t.Errorf("%v: transport called %d times; want %d", tt.err, calls, tt.calls)
+		}
+	}
+}
+
+// netError is a net.Error that is temporary or a timeout as asked.
+type netError struct {
+	temporary, timeout bool
+}
+
+func (e netError) Error() string   { return "net error" }
+func (e netError) Temporary() bool { return e.temporary }
+func (e netError) Timeout() bool   { return e.timeout }
+
+func TestRetryCanceledWhileWaiting(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rt := &RetryTransport{
+		MinBackoff: time.Hour,
+		MaxBackoff: time.Hour,
+		Transport: RoundTripperFunc(func(*Request) (*Response, error) {
+			cancel()
+			return nil, io.ErrUnexpectedEOF
+		}),
+	}
+	req, _ := NewRequest("GET", "http://example.com/", nil)
+	req = req.WithContext(ctx)
+	done := make(chan error, 1)
+	go func() {
+		_, err := rt.RoundTrip(req)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("RoundTrip = %v; want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RoundTrip still waiting after cancel")
+	}
+}
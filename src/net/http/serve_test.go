@@ -430,6 +430,217 @@ func TestServeMuxHandlerRedirects(t *testing.T) {
 	}
 }
 
+func TestServeMuxPatterns(t *testing.T) {
+	mux := NewServeMux()
+	for _, p := range []string{
+		"/",
+		"/{$}",
+		"GET /users/{id}",
+		"GET /users/me",
+		"DELETE /users/{id}",
+		"/files/{path...}",
+		"GET /posts/{year}/{slug}",
+		"/static/",
+		"example.com/users/{name}",
+	} {
+		p := p
+		mux.HandleFunc(p, func(w ResponseWriter, r *Request) {
+			fmt.Fprintf(w, "%s|%s|%s|%s|%s", p, r.PathValue("id"), r.PathValue("path"), r.PathValue("year")+r.PathValue("slug"), r.PathValue("name"))
+		})
+	}
+
+	tests := []struct {
+		method, host, path string
+		code               int
+		body               string
+	}{
+		{"GET", "", "/", 200, "/{$}||||"},
+		{"GET", "", "/other", 200, "/||||"},
+		{"GET", "", "/users/42", 200, "GET /users/{id}|42|||"},
+		{"HEAD", "", "/users/42", 200, "GET /users/{id}|42|||"},
+		{"DELETE", "", "/users/42", 200, "DELETE /users/{id}|42|||"},
+		{"GET", "", "/users/me", 200, "GET /users/me||||"},
+		{"GET", "", "/users/a%2Fb", 200, "GET /users/{id}|a/b|||"},
+		{"GET", "", "/files/", 200, "/files/{path...}||||"},
+		{"GET", "", "/files/a/b.txt", 200, "/files/{path...}||a/b.txt||"},
+		{"GET", "", "/posts/2017/hello", 200, "GET /posts/{year}/{slug}|||2017hello|"},
+		{"GET", "", "/static/css/site.css", 200, "/static/||||"},
+		{"GET", "example.com", "/users/gopher", 200, "example.com/users/{name}||||gopher"},
+		{"GET", "example.com", "/users/me", 200, "example.com/users/{name}||||me"},
+	}
+	for _, tt := range tests {
+		r := &Request{
+			Method:     tt.method,
+			Host:       tt.host,
+			URL:        &url.URL{Path: tt.path},
+			RequestURI: tt.path,
+		}
+		if u, err := url.Parse(tt.path); err == nil {
+			r.URL = u
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, r)
+		if rr.Code != tt.code || rr.Body.String() != tt.body {
+			t.Errorf("%s %s%s = %d, %q; want %d, %q", tt.method, tt.host, tt.path, rr.Code, rr.Body.String(), tt.code, tt.body)
+		}
+	}
+}
+
+func TestServeMuxMethodNotAllowed(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("GET /items/{id}", func(w ResponseWriter, r *Request) {})
+	mux.HandleFunc("PUT /items/{id}", func(w ResponseWriter, r *Request) {})
+	mux.HandleFunc("POST /items/", func(w ResponseWriter, r *Request) {})
+
+	r := &Request{Method: "PATCH", URL: &url.URL{Path: "/items/1"}}
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, r)
+	if rr.Code != StatusMethodNotAllowed {
+		t.Fatalf("code = %d; want %d", rr.Code, StatusMethodNotAllowed)
+	}
+	if got, want := rr.HeaderMap.Get("Allow"), "GET, HEAD, POST, PUT"; got != want {
+		t.Errorf("Allow = %q; want %q", got, want)
+	}
+
+	r = &Request{Method: "GET", URL: &url.URL{Path: "/other"}}
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, r)
+	if rr.Code != StatusNotFound {
+		t.Errorf("unmatched path: code = %d; want %d", rr.Code, StatusNotFound)
+	}
+}
+
+func TestServeMuxLegacyPatterns(t *testing.T) {
+	defer os.Setenv("GODEBUG", os.Getenv("GODEBUG"))
+	os.Setenv("GODEBUG", os.Getenv("GODEBUG")+",httpmuxlegacy=1")
+
+	mux := NewServeMux()
+	for _, p := range []string{
+		"/",
+		"/a b",
+		"/a/b",
+		"/files/{name}",
+		"/x{y}/",
+		"example.com/{id}",
+	} {
+		p := p
+		mux.HandleFunc(p, func(w ResponseWriter, r *Request) {
+			fmt.Fprintf(w, "%s|%s", p, r.PathValue("name"))
+		})
+	}
+
+	for _, tt := range []struct {
+		method, host, path string
+		code               int
+		body               string
+	}{
+		{"GET", "", "/a%20b", 200, "/a b|"},
+		{"GET", "", "/a%2Fb", 200, "/a/b|"},
+		{"GET", "", "/a/b/c", 200, "/|"},
+		{"POST", "", "/files/%7Bname%7D", 200, "/files/{name}|"},
+		{"GET", "", "/files/x", 200, "/|"},
+		{"GET", "", "/x%7By%7D/z", 200, "/x{y}/|"},
+		{"GET", "", "/x%7By%7D", 301, ""},
+		{"GET", "example.com", "/%7Bid%7D", 200, "example.com/{id}|"},
+		{"GET", "example.com", "/42", 200, "/|"},
+	} {
+		u, err := url.Parse(tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, &Request{Method: tt.method, Host: tt.host, URL: u})
+		if rr.Code != tt.code || tt.code == StatusOK && rr.Body.String() != tt.body {
+			t.Errorf("%s %s%s = %d, %q; want %d, %q", tt.method, tt.host, tt.path, rr.Code, rr.Body.String(), tt.code, tt.body)
+		}
+	}
+}
+
+func TestServeMuxTrailingSlashRedirect(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("/", func(w ResponseWriter, r *Request) {})
+	mux.HandleFunc("GET /docs/{rest...}", func(w ResponseWriter, r *Request) {})
+	mux.HandleFunc("/exact/{$}", func(w ResponseWriter, r *Request) {})
+
+	for _, tt := range []struct {
+		path, loc string
+	}{
+		{"/docs?q=1", "/docs/?q=1"},
+		{"/exact", "/exact/"},
+		{"/docs/a", ""},
+		{"/other", ""},
+	} {
+		u, err := url.Parse(tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, &Request{Method: "GET", URL: u})
+		if loc := rr.HeaderMap.Get("Location"); loc != tt.loc {
+			t.Errorf("GET %s: Location = %q; want %q", tt.path, loc, tt.loc)
+		}
+	}
+}
+
+func TestServeMuxConflicts(t *testing.T) {
+	for _, tt := range []struct {
+		p1, p2 string
+		panics bool
+	}{
+		{"/a", "/a", true},
+		{"/users/{id}", "/users/{name}", true},
+		{"/users/{id}", "/{kind}/me", true},
+		{"GET /users/", "/users/{id}", true},
+		{"/a/", "/a/{rest...}", true},
+		{"/users/{id}", "/users/me", false},
+		{"GET /users/{id}", "/users/{id}", false},
+		{"GET /a", "POST /a", false},
+		{"/users/{id}", "example.com/{kind}/me", false},
+		{"/images/", "/images/thumbnails/", false},
+	} {
+		panicked := func() (panicked bool) {
+			defer func() { panicked = recover() != nil }()
+			mux := NewServeMux()
+			mux.Handle(tt.p1, NotFoundHandler())
+			mux.Handle(tt.p2, NotFoundHandler())
+			return false
+		}()
+		if panicked != tt.panics {
+			t.Errorf("registering %q and %q: panicked = %v; want %v", tt.p1, tt.p2, panicked, tt.panics)
+		}
+	}
+}
+
+func TestRequestSetPathValue(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("/a/{x}", func(w ResponseWriter, r *Request) {
+		r.SetPathValue("x", "changed")
+		r.SetPathValue("y", "extra")
+		io.WriteString(w, r.PathValue("x")+","+r.PathValue("y")+","+r.PathValue("z"))
+	})
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, &Request{Method: "GET", URL: &url.URL{Path: "/a/b"}})
+	if got, want := rr.Body.String(), "changed,extra,"; got != want {
+		t.Errorf("body = %q; want %q", got, want)
+	}
+}
+
+func TestRequestSetPathValueCopies(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("/a/{x}", func(w ResponseWriter, r *Request) {
+		r.SetPathValue("y", "orig")
+		r2 := r.WithContext(r.Context())
+		r2.SetPathValue("x", "changed")
+		r2.SetPathValue("y", "changed")
+		io.WriteString(w, r.PathValue("x")+","+r.PathValue("y")+","+r2.PathValue("x")+","+r2.PathValue("y"))
+	})
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, &Request{Method: "GET", URL: &url.URL{Path: "/a/b"}})
+	if got, want := rr.Body.String(), "b,orig,changed,changed"; got != want {
+		t.Errorf("body = %q; want %q", got, want)
+	}
+}
+
 // Tests for https://golang.org/issue/900
 func TestMuxRedirectLeadingSlashes(t *testing.T) {
 	paths := []string{"//foo.txt", "///foo.txt", "/../../foo.txt"}
@@ -11,6 +11,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
@@ -90,7 +91,8 @@ type ResponseWriter interface {
 	// WriteHeader. Changing the header after a call to
 	// WriteHeader (or Write) has no effect unless the modified
 	// headers were declared as trailers by setting the
-	// "Trailer" header before the call to WriteHeader (see example).
+	// "Trailer" header before the call to WriteHeader (see example),
+	// or their keys carry the TrailerPrefix.
 	// To suppress implicit response headers, set their value to nil.
 	Header() Header
 
@@ -123,6 +125,20 @@ type ResponseWriter interface {
 	WriteHeader(int)
 }
 
+// TrailerPrefix is a magic prefix for ResponseWriter.Header map keys
+// that, if present, signals that the map entry is actually for
+// the response trailers, and not the response headers. The prefix
+// is stripped after the ServeHTTP call finishes and the values are
+// sent in the trailers.
+//
+// This mechanism is intended only for trailers that are not known
+// prior to the headers being written. If the set of trailers is fixed
+// or known before the header is written, the normal Go trailers mechanism
+// is preferred:
+//    https://golang.org/pkg/net/http/#ResponseWriter
+//    https://golang.org/pkg/net/http/#example_ResponseWriter_trailers
+const TrailerPrefix = "Trailer:"
+
 // The Flusher interface is implemented by ResponseWriters that allow
 // an HTTP handler to flush buffered data to the client.
 //
@@ -357,13 +373,7 @@ func (cw *chunkWriter) close() {
 		bw := cw.res.conn.bufw // conn's bufio writer
 		// zero chunk to mark EOF
 		bw.WriteString("0\r\n")
-		if len(cw.res.trailers) > 0 {
-			trailers := make(Header)
-			for _, h := range cw.res.trailers {
-				if vv := cw.res.handlerHeader[h]; len(vv) > 0 {
-					trailers[h] = vv
-				}
-			}
+		if trailers := cw.res.finalTrailers(); trailers != nil {
 			trailers.Write(bw) // the writer handles noting errors
 		}
 		// final blank line after the trailers (whether
@@ -449,6 +459,29 @@ func (w *response) declareTrailer(k string) {
 	w.trailers = append(w.trailers, k)
 }
 
+// finalTrailers is called after the Handler exits and returns a non-nil
+// value if the Handler set any trailers.
+func (w *response) finalTrailers() Header {
+	var t Header
+	for k, vv := range w.handlerHeader {
+		if strings.HasPrefix(k, TrailerPrefix) {
+			if t == nil {
+				t = make(Header)
+			}
+			t[CanonicalHeaderKey(strings.TrimPrefix(k, TrailerPrefix))] = vv
+		}
+	}
+	for _, k := range w.trailers {
+		if vv := w.handlerHeader[k]; len(vv) > 0 {
+			if t == nil {
+				t = make(Header)
+			}
+			t[k] = vv
+		}
+	}
+	return t
+}
+
 // requestTooLarge is called by maxBytesReader when too much input has
 // been read from the client.
 func (w *response) requestTooLarge() {
@@ -1093,6 +1126,15 @@ func (cw *chunkWriter) writeHeader(p []byte) {
 	var setHeader extraHeader
 
 	trailers := false
+	for k := range cw.header {
+		if strings.HasPrefix(k, TrailerPrefix) {
+			if excludeHeader == nil {
+				excludeHeader = make(map[string]bool)
+			}
+			excludeHeader[k] = true
+			trailers = true
+		}
+	}
 	for _, v := range cw.header["Trailer"] {
 		trailers = true
 		foreachHeaderElement(v, cw.res.declareTrailer)
@@ -1666,6 +1708,23 @@ func (c *conn) serve(ctx context.Context) {
 			return
 		}
 
+		if c.tlsState == nil && c.server.h2cEnabled() {
+			if w.req.isH2Upgrade() {
+				w.cancelCtx()
+				c.serveH2C(nil, nil)
+				return
+			}
+			if settings, ok := h2cUpgradeSettings(w.req); ok {
+				w.cancelCtx()
+				io.WriteString(c.bufw, "HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: h2c\r\n\r\n")
+				if c.bufw.Flush() != nil {
+					return
+				}
+				c.serveH2C(w.req, settings)
+				return
+			}
+		}
+
 		// Expect 100 Continue support
 		req := w.req
 		if req.expectsContinue() {
@@ -1713,6 +1772,81 @@ func (c *conn) serve(ctx context.Context) {
 	}
 }
 
+// serveH2C serves c with HTTP/2 once the HTTP/1 server has read
+// either the start of the HTTP/2 connection preface from a client
+// with prior knowledge, or an h2c upgrade request, in which case
+// upgrade is that request and settings its decoded HTTP2-Settings.
+func (c *conn) serveH2C(upgrade *Request, settings []byte) {
+	// Replay the bytes the HTTP/1 server read ahead of the
+	// HTTP/2 frames, including the part of the preface it parsed
+	// as a "PRI * HTTP/2.0" request.
+	var buffered []byte
+	if upgrade == nil {
+		buffered = append(buffered, http2ClientPreface[:len("PRI * HTTP/2.0\r\n\r\n")]...)
+	}
+	if n := c.bufr.Buffered(); n > 0 {
+		p, _ := c.bufr.Peek(n)
+		buffered = append(buffered, p...)
+	}
+
+	// The HTTP/2 server manages its own timeouts and reports its
+	// own connection states, starting from idle like a fresh
+	// connection negotiated with TLSNextProto.
+	c.rwc.SetReadDeadline(time.Time{})
+	c.rwc.SetWriteDeadline(time.Time{})
+	c.setState(c.rwc, StateIdle)
+
+	if http2testHookOnConn != nil {
+		http2testHookOnConn()
+	}
+	new(http2Server).ServeConn(&h2cConn{
+		Conn: c.rwc,
+		r:    io.MultiReader(bytes.NewReader(buffered), c.rwc),
+	}, &http2ServeConnOpts{
+		BaseConfig: c.server,
+		Handler:    serverHandler{c.server},
+		http2serveConnOptsHooks: http2serveConnOptsHooks{
+			upgradeRequest: upgrade,
+			settings:       settings,
+			stateConn:      c.rwc,
+		},
+	})
+}
+
+// h2cConn is the connection handed to the HTTP/2 server for h2c.
+// Reads are served from r, which drains the bytes already consumed
+// from the connection before reading from it directly.
+type h2cConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c *h2cConn) Read(p []byte) (int, error) { return c.r.Read(p) }
+
+// h2cUpgradeSettings reports whether req is a request to upgrade to
+// h2c that the server can honor and returns the decoded contents of
+// its HTTP2-Settings header.
+func h2cUpgradeSettings(req *Request) (settings []byte, ok bool) {
+	if !req.ProtoAtLeast(1, 1) || req.ContentLength != 0 || req.Method == "CONNECT" {
+		return nil, false
+	}
+	conn := req.Header["Connection"]
+	if !httplex.HeaderValuesContainsToken(conn, "Upgrade") ||
+		!httplex.HeaderValuesContainsToken(conn, "HTTP2-Settings") ||
+		!httplex.HeaderValuesContainsToken(req.Header["Upgrade"], "h2c") {
+		return nil, false
+	}
+	vv := req.Header["Http2-Settings"]
+	if len(vv) != 1 {
+		return nil, false
+	}
+	settings, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(vv[0], "="))
+	if err != nil || len(settings)%6 != 0 {
+		return nil, false
+	}
+	return settings, true
+}
+
 func (w *response) sendExpectationFailed() {
 	// TODO(bradfitz): let ServeHTTP handlers handle
 	// requests with non-standard expectation[s]? Seems
@@ -1762,6 +1896,12 @@ func (w *response) CloseNotify() <-chan bool {
 	return w.closeNotifyCh
 }
 
+// Push implements the Pusher interface. Server push is an HTTP/2
+// feature, so Push always returns ErrNotSupported.
+func (w *response) Push(target string, opts *PushOptions) error {
+	return ErrNotSupported
+}
+
 func registerOnHitEOF(rc io.ReadCloser, fn func()) {
 	switch v := rc.(type) {
 	case *expectContinueReader:
@@ -1941,18 +2081,46 @@ func RedirectHandler(url string, code int) Handler {
 // patterns and calls the handler for the pattern that
 // most closely matches the URL.
 //
-// Patterns name fixed, rooted paths, like "/favicon.ico",
-// or rooted subtrees, like "/images/" (note the trailing slash).
-// Longer patterns take precedence over shorter ones, so that
-// if there are handlers registered for both "/images/"
-// and "/images/thumbnails/", the latter handler will be
-// called for paths beginning "/images/thumbnails/" and the
-// former will receive requests for any other paths in the
-// "/images/" subtree.
+// Patterns have the form
+//
+//	[METHOD ][HOST]/[PATH]
+//
+// All three parts are optional, but the slash is required.
 //
-// Note that since a pattern ending in a slash names a rooted subtree,
-// the pattern "/" matches all paths not matched by other registered
-// patterns, not just the URL with Path == "/".
+// A pattern with a METHOD only matches requests with that method, with
+// the exception that a GET pattern also matches HEAD requests. A pattern
+// without one matches all methods.
+//
+// A pattern with a HOST only matches requests for that host.
+// Host-specific patterns take precedence over general patterns, so that a
+// handler might register for the two patterns "/codesearch" and
+// "codesearch.google.com/" without also taking over requests for
+// "http://www.google.com/".
+//
+// The PATH is made of segments separated by slashes, each of which is
+// either a literal, or a wildcard of the form {NAME}, which matches any
+// one non-empty path segment. The last segment may instead be a wildcard
+// of the form {NAME...}, which matches the remainder of the path, or the
+// special wildcard {$}, which only matches the end of a path ending in a
+// slash. Wildcard names are Go identifiers, and the values they matched
+// are available to handlers through Request.PathValue. Literal segments
+// are compared with the unescaped segments of the request path, and
+// wildcard values are unescaped.
+//
+// A pattern ending in a slash names a rooted subtree, like "/images/",
+// and matches all paths in it: it behaves like one ending in an
+// anonymous {NAME...} wildcard. So the pattern "/" matches all paths
+// not matched by other registered patterns, not just the URL with
+// Path == "/"; the pattern "/{$}" matches only the latter.
+// Other patterns, like "/favicon.ico" or "/users/{id}", match whole paths.
+//
+// If two patterns match a request, the more specific one takes
+// precedence: the one that matches a strict subset of the requests the
+// other one matches. For example, "/images/thumbnails/" takes precedence
+// over "/images/", "GET /users/{id}" over "/users/{id}", and "/users/me"
+// over "/users/{id}". Registering two patterns that may match the same
+// request without either one being more specific, like "/users/{id}"
+// and "/{kind}/me", or "GET /users/" and "/users/{id}", panics.
 //
 // If a subtree has been registered and a request is received naming the
 // subtree root without its trailing slash, ServeMux redirects that
@@ -1962,25 +2130,34 @@ func RedirectHandler(url string, code int) Handler {
 // to redirect a request for "/images" to "/images/", unless "/images" has
 // been registered separately.
 //
-// Patterns may optionally begin with a host name, restricting matches to
-// URLs on that host only. Host-specific patterns take precedence over
-// general patterns, so that a handler might register for the two patterns
-// "/codesearch" and "codesearch.google.com/" without also taking over
-// requests for "http://www.google.com/".
+// If patterns match the path of a request but none matches its method,
+// ServeMux replies with 405 Method Not Allowed and an Allow header
+// listing the methods they allow.
+//
+// Methods and wildcards changed the meaning of some patterns that were
+// plain paths before: a space or tab now ends a METHOD, and braces in
+// a path segment now make a wildcard. So "/a b" and "/files/{name}" no
+// longer match the paths they spell, and patterns like "/x{y}" panic.
+// Running a program with GODEBUG=httpmuxlegacy=1 restores the old
+// syntax and matching. Each pattern is then a HOST and a PATH, with
+// neither a METHOD nor wildcards, and the longest pattern that equals
+// the cleaned, unescaped request path, or is a rooted subtree
+// containing it, wins. So "/a b" matches "/a%20b", and "/a/b" matches
+// "/a%2Fb".
 //
 // ServeMux also takes care of sanitizing the URL request path,
 // redirecting any request containing . or .. elements or repeated slashes
 // to an equivalent, cleaner URL.
 type ServeMux struct {
-	mu    sync.RWMutex
-	m     map[string]muxEntry
-	hosts bool // whether any patterns contain hostnames
+	mu     sync.RWMutex
+	es     []muxEntry // in registration order
+	hosts  bool       // whether any patterns contain hostnames
+	legacy bool       // whether patterns use the GODEBUG=httpmuxlegacy=1 syntax
 }
 
 type muxEntry struct {
-	explicit bool
-	h        Handler
-	pattern  string
+	h   Handler
+	pat *pattern
 }
 
 // NewServeMux allocates and returns a new ServeMux.
@@ -1991,19 +2168,6 @@ var DefaultServeMux = &defaultServeMux
 
 var defaultServeMux ServeMux
 
-// Does path match pattern?
-func pathMatch(pattern, path string) bool {
-	if len(pattern) == 0 {
-		// should not happen
-		return false
-	}
-	n := len(pattern)
-	if pattern[n-1] != '/' {
-		return pattern == path
-	}
-	return len(path) >= n && path[0:n] == pattern
-}
-
 // Return the canonical path for p, eliminating . and .. elements.
 func cleanPath(p string) string {
 	if p == "" {
@@ -2021,21 +2185,41 @@ func cleanPath(p string) string {
 	return np
 }
 
-// Find a handler on a handler map given a path string
-// Most-specific (longest) pattern wins
-func (mux *ServeMux) match(path string) (h Handler, pattern string) {
-	var n = 0
-	for k, v := range mux.m {
-		if !pathMatch(k, path) {
-			continue
+// match returns the entry of the most specific pattern matching a
+// request with the given method and host, whose path is split into segs,
+// and the values of the pattern's wildcards. If no pattern matches, it
+// returns the patterns that match all but the method.
+// Host-specific patterns take precedence over generic ones.
+func (mux *ServeMux) match(method, host string, segs []string) (e *muxEntry, matches []string, wrongMethod []*pattern) {
+	hosts := []string{""}
+	if mux.hosts {
+		hosts = []string{host, ""}
+	}
+	for _, host := range hosts {
+		for i := range mux.es {
+			c := &mux.es[i]
+			if c.pat.host != host {
+				continue
+			}
+			m, ok := c.pat.matchPath(segs)
+			if !ok {
+				continue
+			}
+			if !c.pat.matchMethod(method) {
+				wrongMethod = append(wrongMethod, c.pat)
+				continue
+			}
+			// Patterns that match the same request never conflict,
+			// so one of them is the most specific.
+			if e == nil || c.pat.compare(e.pat) == moreSpecific {
+				e, matches = c, m
+			}
 		}
-		if h == nil || len(k) > n {
-			n = len(k)
-			h = v.h
-			pattern = v.pattern
+		if e != nil {
+			return e, matches, nil
 		}
 	}
-	return
+	return nil, nil, wrongMethod
 }
 
 // Handler returns the handler to use for the given request,
@@ -2051,35 +2235,119 @@ func (mux *ServeMux) match(path string) (h Handler, pattern string) {
 // If there is no registered handler that applies to the request,
 // Handler returns a ``page not found'' handler and an empty pattern.
 func (mux *ServeMux) Handler(r *Request) (h Handler, pattern string) {
+	h, pat, _ := mux.findHandler(r)
+	if pat != nil {
+		pattern = pat.str
+	}
+	return
+}
+
+// findHandler is the main implementation of Handler. It also returns
+// the parsed pattern and the values of its wildcards.
+func (mux *ServeMux) findHandler(r *Request) (h Handler, pat *pattern, matches []string) {
 	if r.Method != "CONNECT" {
 		if p := cleanPath(r.URL.Path); p != r.URL.Path {
-			_, pattern = mux.handler(r.Host, p)
 			url := *r.URL
 			url.Path = p
-			return RedirectHandler(url.String(), StatusMovedPermanently), pattern
+			_, pat, _ = mux.handler(r.Method, r.Host, &url)
+			return RedirectHandler(url.String(), StatusMovedPermanently), pat, nil
 		}
 	}
 
-	return mux.handler(r.Host, r.URL.Path)
+	return mux.handler(r.Method, r.Host, r.URL)
 }
 
-// handler is the main implementation of Handler.
-// The path is known to be in canonical form, except for CONNECT methods.
-func (mux *ServeMux) handler(host, path string) (h Handler, pattern string) {
+// handler looks up the handler for a request with the given method,
+// host and URL. The path is known to be in canonical form, except for
+// CONNECT methods.
+func (mux *ServeMux) handler(method, host string, u *url.URL) (h Handler, pat *pattern, matches []string) {
 	mux.mu.RLock()
 	defer mux.mu.RUnlock()
 
+	if mux.legacy {
+		return mux.legacyHandler(host, u)
+	}
+
+	segs := splitPath(u.EscapedPath())
+	e, matches, wrongMethod := mux.match(method, host, segs)
+	if (e == nil || !e.pat.exactMatch(segs)) && len(segs) > 0 && segs[len(segs)-1] != "" {
+		// Redirect a subtree root without its trailing slash
+		// to the subtree.
+		segs = append(segs[:len(segs):len(segs)], "")
+		if e2, _, _ := mux.match(method, host, segs); e2 != nil && e2.pat.exactMatch(segs) {
+			url := &url.URL{Path: cleanPath(u.Path) + "/", RawQuery: u.RawQuery}
+			return RedirectHandler(url.String(), StatusMovedPermanently), e2.pat, nil
+		}
+	}
+	switch {
+	case e != nil:
+		return e.h, e.pat, matches
+	case len(wrongMethod) > 0:
+		return methodNotAllowedHandler(allowedMethods(wrongMethod)), nil, nil
+	}
+	return NotFoundHandler(), nil, nil
+}
+
+// legacyHandler is handler for patterns registered under
+// GODEBUG=httpmuxlegacy=1. It matches the unescaped u.Path, not its
+// segments, with the rules used before methods and wildcards.
+func (mux *ServeMux) legacyHandler(host string, u *url.URL) (h Handler, pat *pattern, matches []string) {
+	var e *muxEntry
+	var redirect bool
 	// Host-specific pattern takes precedence over generic ones
 	if mux.hosts {
-		h, pattern = mux.match(host + path)
-	}
-	if h == nil {
-		h, pattern = mux.match(path)
+		e, redirect = mux.legacyMatch(host + u.Path)
+	}
+	if e == nil {
+		e, redirect = mux.legacyMatch(u.Path)
+	}
+	switch {
+	case e == nil:
+		return NotFoundHandler(), nil, nil
+	case redirect:
+		url := &url.URL{Path: u.Path + "/", RawQuery: u.RawQuery}
+		return RedirectHandler(url.String(), StatusMovedPermanently), e.pat, nil
+	}
+	return e.h, e.pat, nil
+}
+
+// legacyMatch finds the entry of the longest legacy pattern matching
+// path. A pattern matches the path it spells and, if it ends in a
+// slash, the paths that start with it. The path of a subtree root
+// without its trailing slash also matches, unless it is registered
+// itself, and is to be redirected to the subtree.
+func (mux *ServeMux) legacyMatch(path string) (e *muxEntry, redirect bool) {
+	n := 0
+	for i := range mux.es {
+		c := &mux.es[i]
+		k := c.pat.str
+		if legacyPathMatch(k, path) && (len(k) > n || len(k) == n && redirect) {
+			e, n, redirect = c, len(k), false
+		}
+		if len(k) > 1 && k[len(k)-1] == '/' && path == k[:len(k)-1] && len(path) > n {
+			e, n, redirect = c, len(path), true
+		}
 	}
-	if h == nil {
-		h, pattern = NotFoundHandler(), ""
+	return e, redirect
+}
+
+// legacyPathMatch reports whether path matches the legacy pattern.
+func legacyPathMatch(pattern, path string) bool {
+	n := len(pattern)
+	if pattern[n-1] != '/' {
+		return pattern == path
 	}
-	return
+	return len(path) >= n && path[0:n] == pattern
+}
+
+// methodNotAllowedHandler returns a handler that replies to each
+// request with a ``405 method not allowed'' reply and an Allow header
+// listing allow.
+func methodNotAllowedHandler(allow []string) Handler {
+	return HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.Header().Set("Allow", strings.Join(allow, ", "))
+		Error(w, StatusText(StatusMethodNotAllowed), StatusMethodNotAllowed)
+	})
 }
 
 // ServeHTTP dispatches the request to the handler whose
@@ -2092,12 +2360,14 @@ func (mux *ServeMux) ServeHTTP(w ResponseWriter, r *Request) {
 		w.WriteHeader(StatusBadRequest)
 		return
 	}
-	h, _ := mux.Handler(r)
+	h, pat, matches := mux.findHandler(r)
+	r.pat, r.matches = pat, matches
 	h.ServeHTTP(w, r)
 }
 
 // Handle registers the handler for the given pattern.
-// If a handler already exists for pattern, Handle panics.
+// If a handler already exists for pattern, or pattern conflicts with
+// a registered pattern, Handle panics.
 func (mux *ServeMux) Handle(pattern string, handler Handler) {
 	mux.mu.Lock()
 	defer mux.mu.Unlock()
@@ -2108,34 +2378,27 @@ func (mux *ServeMux) Handle(pattern string, handler Handler) {
 	if handler == nil {
 		panic("http: nil handler")
 	}
-	if mux.m[pattern].explicit {
-		panic("http: multiple registrations for " + pattern)
+	parse := parsePattern
+	if strings.Contains(os.Getenv("GODEBUG"), "httpmuxlegacy=1") {
+		parse = parseLegacyPattern
+		mux.legacy = true
 	}
-
-	if mux.m == nil {
-		mux.m = make(map[string]muxEntry)
+	pat, err := parse(pattern)
+	if err != nil {
+		panic("http: invalid pattern " + pattern + ": " + err.Error())
 	}
-	mux.m[pattern] = muxEntry{explicit: true, h: handler, pattern: pattern}
-
-	if pattern[0] != '/' {
-		mux.hosts = true
+	for _, e := range mux.es {
+		if e.pat.str == pattern {
+			panic("http: multiple registrations for " + pattern)
+		}
+		if pat.conflictsWith(e.pat) {
+			panic("http: pattern " + pattern + " conflicts with pattern " + e.pat.str)
+		}
 	}
 
-	// Helpful behavior:
-	// If pattern is /tree/, insert an implicit permanent redirect for /tree.
-	// It can be overridden by an explicit registration.
-	n := len(pattern)
-	if n > 0 && pattern[n-1] == '/' && !mux.m[pattern[0:n-1]].explicit {
-		// If pattern contains a host name, strip it and use remaining
-		// path for redirect.
-		path := pattern
-		if pattern[0] != '/' {
-			// In pattern, at least the last character is a '/', so
-			// strings.Index can't be -1.
-			path = pattern[strings.Index(pattern, "/"):]
-		}
-		url := &url.URL{Path: path}
-		mux.m[pattern[0:n-1]] = muxEntry{h: RedirectHandler(url.String(), StatusMovedPermanently), pattern: pattern}
+	mux.es = append(mux.es, muxEntry{h: handler, pat: pat})
+	if pat.host != "" {
+		mux.hosts = true
 	}
 }
 
@@ -2191,6 +2454,23 @@ type Server struct {
 	// If TLSNextProto is nil, HTTP/2 support is enabled automatically.
 	TLSNextProto map[string]func(*Server, *tls.Conn, Handler)
 
+	// AllowH2C enables cleartext HTTP/2 ("h2c") on connections
+	// not using TLS. A client may start HTTP/2 with prior
+	// knowledge, by sending the HTTP/2 connection preface, or
+	// upgrade an HTTP/1.1 request that has no body by sending
+	// "Upgrade: h2c" and HTTP2-Settings headers. Requests with a
+	// body are served over HTTP/1.1 instead of being upgraded.
+	AllowH2C bool
+
+	// AllowExtendedConnect enables the extended CONNECT method of
+	// RFC 8441 on HTTP/2 connections, which runs another protocol,
+	// such as WebSocket, over an HTTP/2 stream. The server then
+	// advertises SETTINGS_ENABLE_CONNECT_PROTOCOL and passes CONNECT
+	// requests carrying a :protocol pseudo-header to the handler,
+	// which finds the protocol in the ":protocol" request header.
+	// Otherwise such requests are reset as malformed.
+	AllowExtendedConnect bool
+
 	// ConnState specifies an optional callback function that is
 	// called when a client connection changes state. See the
 	// ConnState type and associated constants for details.
@@ -2533,6 +2813,11 @@ func (srv *Server) onceSetNextProtoDefaults_Serve() {
 	}
 }
 
+// h2cEnabled reports whether srv serves cleartext HTTP/2.
+func (srv *Server) h2cEnabled() bool {
+	return srv.AllowH2C && !strings.Contains(os.Getenv("GODEBUG"), "http2server=0")
+}
+
 // onceSetNextProtoDefaults configures HTTP/2, if the user hasn't
 // configured otherwise. (by setting srv.TLSNextProto non-nil)
 // It must only be called via srv.nextProtoOnce (use srv.setupHTTP2_*).
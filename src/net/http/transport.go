@@ -173,6 +173,12 @@ type Transport struct {
 	// If TLSNextProto is nil, HTTP/2 support is enabled automatically.
 	TLSNextProto map[string]func(authority string, c *tls.Conn) RoundTripper
 
+	// H2CHosts lists the addresses, as "host:port", of servers
+	// known to accept cleartext HTTP/2 ("h2c"). Requests for "http"
+	// URLs to these addresses are sent using HTTP/2 with prior
+	// knowledge instead of HTTP/1.1, and are not sent via Proxy.
+	H2CHosts []string
+
 	// MaxResponseHeaderBytes specifies a limit on how many
 	// response bytes are allowed in the server's response
 	// header.
@@ -180,11 +186,20 @@ type Transport struct {
 	// Zero means to use a default limit.
 	MaxResponseHeaderBytes int64
 
+	// ProxyHeader optionally specifies a PROXY protocol header to
+	// send on each new connection before anything else, for servers
+	// behind a load balancer that expect one. It is written by
+	// net.WriteProxyHeader and is not used with DialTLS.
+	ProxyHeader *net.ProxyHeader
+
 	// nextProtoOnce guards initialization of TLSNextProto and
 	// h2transport (via onceSetNextProtoDefaults)
 	nextProtoOnce sync.Once
 	h2transport   *http2Transport // non-nil if http2 wired up
 
+	h2cOnce      sync.Once
+	h2ctransport *http2Transport // for H2CHosts; set via h2cOnce
+
 	// TODO: tunable on max per-host TCP dials in flight (Issue 13957)
 }
 
@@ -230,6 +245,44 @@ func (t *Transport) onceSetNextProtoDefaults() {
 	}
 }
 
+// useH2C reports whether requests for u are sent with h2c.
+func (t *Transport) useH2C(u *url.URL) bool {
+	if len(t.H2CHosts) == 0 || strings.Contains(os.Getenv("GODEBUG"), "http2client=0") {
+		return false
+	}
+	addr := canonicalAddr(u)
+	for _, h := range t.H2CHosts {
+		if h == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// h2cTransport returns the HTTP/2 transport used for H2CHosts. Its
+// connections are dialed like those for HTTP/1.1, without TLS.
+func (t *Transport) h2cTransport() *http2Transport {
+	t.h2cOnce.Do(func() {
+		t2 := &http2Transport{
+			AllowHTTP: true,
+			DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+				return t.dial(context.Background(), network, addr)
+			},
+			t1: t,
+		}
+		if limit1 := t.MaxResponseHeaderBytes; limit1 != 0 {
+			const h2max = 1<<32 - 1
+			if limit1 >= h2max {
+				t2.MaxHeaderListSize = h2max
+			} else {
+				t2.MaxHeaderListSize = uint32(limit1)
+			}
+		}
+		t.h2ctransport = t2
+	})
+	return t.h2ctransport
+}
+
 // ProxyFromEnvironment returns the URL of the proxy to use for a
 // given request, as indicated by the environment variables
 // HTTP_PROXY, HTTPS_PROXY and NO_PROXY (or the lowercase versions
@@ -350,6 +403,9 @@ func (t *Transport) RoundTrip(req *Request) (*Response, error) {
 		req.closeBody()
 		return nil, errors.New("http: no Host in request URL")
 	}
+	if scheme == "http" && t.useH2C(req.URL) {
+		return t.h2cTransport().RoundTrip(req)
+	}
 
 	for {
 		// treq gets modified by roundTrip, so we need to recreate for each retry.
@@ -492,6 +548,9 @@ func (t *Transport) CloseIdleConnections() {
 	if t2 := t.h2transport; t2 != nil {
 		t2.CloseIdleConnections()
 	}
+	if len(t.H2CHosts) > 0 {
+		t.h2cTransport().CloseIdleConnections()
+	}
 }
 
 // CancelRequest cancels an in-flight request by closing its connection.
@@ -595,6 +654,7 @@ var (
 	errServerClosedIdle   = errors.New("http: server closed idle connection")
 	errIdleConnTimeout    = errors.New("http: idle connection timeout")
 	errNotCachingH2Conn   = errors.New("http: not caching alternate protocol's connections")
+	errCallerOwnsConn     = errors.New("http: read loop ending; caller owns writable underlying conn")
 )
 
 // transportReadFromServerError is used by Transport.readLoop when the
@@ -826,6 +886,18 @@ func (t *Transport) replaceReqCanceler(r *Request, fn func(error)) bool {
 var zeroDialer net.Dialer
 
 func (t *Transport) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	c, err := t.dialRaw(ctx, network, addr)
+	if err != nil || c == nil || t.ProxyHeader == nil {
+		return c, err
+	}
+	if err := net.WriteProxyHeader(c, t.ProxyHeader); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (t *Transport) dialRaw(ctx context.Context, network, addr string) (net.Conn, error) {
 	if t.DialContext != nil {
 		return t.DialContext(ctx, network, addr)
 	}
@@ -1505,7 +1577,12 @@ func (pc *persistConn) readLoop() {
 			alive = false
 		}
 
-		if !hasBody {
+		bodyWritable := resp.isProtocolSwitch()
+		if bodyWritable {
+			resp.Body = newReadWriteCloserBody(pc.br, pc.conn)
+		}
+
+		if !hasBody || bodyWritable {
 			pc.t.setReqCanceler(rc.req, nil)
 
 			// Put the idle conn back into the pool before we send the response
@@ -1519,6 +1596,10 @@ func (pc *persistConn) readLoop() {
 				pc.wroteRequest() &&
 				tryPutIdleConn(trace)
 
+			if bodyWritable {
+				closeErr = errCallerOwnsConn
+			}
+
 			select {
 			case rc.ch <- responseAndError{res: resp}:
 			case <-rc.callerGone:
@@ -1647,6 +1728,38 @@ func (pc *persistConn) readResponse(rc requestAndChan, trace *httptrace.ClientTr
 	return
 }
 
+// readWriteCloserBody is the Response.Body type used when we want to
+// give users write access to the Body through the underlying
+// connection (TCP, unless using custom dialers). This is then the
+// concrete type for a Response.Body on the 101 Switching Protocols
+// response, as used by WebSockets.
+type readWriteCloserBody struct {
+	br *bufio.Reader // used until empty
+	io.ReadWriteCloser
+}
+
+func newReadWriteCloserBody(br *bufio.Reader, rwc io.ReadWriteCloser) io.ReadWriteCloser {
+	body := &readWriteCloserBody{ReadWriteCloser: rwc}
+	if br.Buffered() != 0 {
+		body.br = br
+	}
+	return body
+}
+
+func (b *readWriteCloserBody) Read(p []byte) (n int, err error) {
+	if b.br != nil {
+		if n := b.br.Buffered(); len(p) > n {
+			p = p[:n]
+		}
+		n, err = b.br.Read(p)
+		if b.br.Buffered() == 0 {
+			b.br = nil
+		}
+		return n, err
+	}
+	return b.ReadWriteCloser.Read(p)
+}
+
 // waitForContinue returns the function to block until
 // any response, timeout or connection close. After any of them,
 // the function returns a bool which indicates if the body should be sent.
@@ -1950,7 +2063,9 @@ func (pc *persistConn) closeLocked(err error) {
 			// freelist for http2. That's done by the
 			// alternate protocol's RoundTripper.
 		} else {
-			pc.conn.Close()
+			if err != errCallerOwnsConn {
+				pc.conn.Close()
+			}
 			close(pc.closech)
 		}
 	}
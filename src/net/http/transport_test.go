@@ -3833,3 +3833,39 @@ var rgz = []byte{
 	0x00, 0x00, 0x3d, 0xb1, 0x20, 0x85, 0xfa, 0x00,
 	0x00, 0x00,
 }
+
+func TestTransportProxyHeader(t *testing.T) {
+	defer afterTest(t)
+	ts := httptest.NewUnstartedServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		io.WriteString(w, r.RemoteAddr)
+	}))
+	_, lo4, _ := net.ParseCIDR("127.0.0.0/8")
+	_, lo6, _ := net.ParseCIDR("::1/128")
+	ts.Listener = &net.ProxyListener{Listener: ts.Listener, TrustedSources: []*net.IPNet{lo4, lo6}}
+	ts.Start()
+	defer ts.Close()
+
+	tr := &Transport{
+		ProxyHeader: &net.ProxyHeader{
+			Version:     2,
+			Source:      &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 56324},
+			Destination: &net.TCPAddr{IP: net.ParseIP("198.51.100.1"), Port: 80},
+		},
+	}
+	defer tr.CloseIdleConnections()
+	c := &Client{Transport: tr}
+	for i := 0; i < 2; i++ {
+		res, err := c.Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		slurp, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := string(slurp), "192.0.2.1:56324"; got != want {
+			t.Errorf("RemoteAddr = %q; want %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// A Dialer opens WebSocket connections to servers, over HTTP/1.1.
+type Dialer struct {
+	// DialContext specifies the dial function for creating TCP
+	// connections. If nil, a net.Dialer is used.
+	DialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	// TLSClientConfig specifies the TLS configuration for wss
+	// URLs. If nil, the default configuration is used.
+	TLSClientConfig *tls.Config
+
+	// HandshakeTimeout specifies a time limit for the opening
+	// handshake, including the dial. Zero means no limit other
+	// than that of the context passed to Dial.
+	HandshakeTimeout time.Duration
+
+	// Subprotocols lists the application protocols to request, in
+	// order of preference.
+	Subprotocols []string
+
+	// EnableCompression offers the permessage-deflate extension to
+	// the server.
+	EnableCompression bool
+
+	// Jar optionally specifies a cookie jar, whose cookies are
+	// sent with the handshake request and which stores those set
+	// by the handshake response.
+	Jar http.CookieJar
+}
+
+// DefaultDialer is a Dialer with all fields set to their zero value.
+var DefaultDialer = &Dialer{}
+
+// Dial opens a WebSocket connection to the ws or wss URL urlStr.
+// header holds additional headers for the handshake request, such as
+// Origin or Authorization.
+//
+// The returned Response is the server's handshake response, with its
+// Body already read. If the handshake fails because the server does
+// not answer with a valid upgrade, Dial returns ErrBadHandshake with
+// the Response, whose Body then holds the start of the server's reply.
+func (d *Dialer) Dial(ctx context.Context, urlStr string, header http.Header) (*Conn, *http.Response, error) {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme = "https"
+	default:
+		return nil, nil, errors.New("websocket: unsupported URL scheme " + u.Scheme)
+	}
+	if u.User != nil {
+		return nil, nil, errors.New("websocket: user information in URL not supported")
+	}
+
+	var keyBytes [16]byte
+	if _, err := io.ReadFull(rand.Reader, keyBytes[:]); err != nil {
+		return nil, nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(keyBytes[:])
+
+	req := &http.Request{
+		Method:     "GET",
+		URL:        u,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Host:       u.Host,
+	}
+	for k, vv := range header {
+		req.Header[k] = vv
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if len(d.Subprotocols) > 0 {
+		req.Header.Set("Sec-WebSocket-Protocol", strings.Join(d.Subprotocols, ", "))
+	}
+	if d.EnableCompression {
+		req.Header.Set("Sec-WebSocket-Extensions", deflateResponse)
+	}
+	if d.Jar != nil {
+		for _, cookie := range d.Jar.Cookies(u) {
+			req.AddCookie(cookie)
+		}
+	}
+
+	if d.HandshakeTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.HandshakeTimeout)
+		defer cancel()
+	}
+	netConn, err := d.dial(ctx, u)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Abort the handshake when ctx is done.
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		select {
+		case <-ctx.Done():
+			netConn.SetDeadline(time.Unix(1, 0))
+		case <-stop:
+		}
+	}()
+
+	c, resp, err := d.handshake(netConn, req, key)
+	close(stop)
+	<-done
+	if err != nil {
+		netConn.Close()
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return nil, resp, err
+	}
+	netConn.SetDeadline(time.Time{})
+	return c, resp, nil
+}
+
+// dial connects to the host of u, with TLS for https.
+func (d *Dialer) dial(ctx context.Context, u *url.URL) (net.Conn, error) {
+	host := u.Hostname()
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	addr := net.JoinHostPort(host, port)
+	dial := d.DialContext
+	if dial == nil {
+		dial = new(net.Dialer).DialContext
+	}
+	netConn, err := dial(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "https" {
+		return netConn, nil
+	}
+
+	cfg := d.TLSClientConfig
+	if cfg == nil {
+		cfg = new(tls.Config)
+	} else {
+		cfg = cfg.Clone()
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName = host
+	}
+	// Only HTTP/1.1 can be upgraded.
+	cfg.NextProtos = []string{"http/1.1"}
+	tlsConn := tls.Client(netConn, cfg)
+	errc := make(chan error, 1)
+	go func() { errc <- tlsConn.Handshake() }()
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		netConn.Close()
+		<-errc
+		err = ctx.Err()
+	}
+	if err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// handshake sends the opening handshake request req with the given key
+// on netConn and checks the server's response.
+func (d *Dialer) handshake(netConn net.Conn, req *http.Request, key string) (*Conn, *http.Response, error) {
+	if err := req.Write(netConn); err != nil {
+		return nil, nil, err
+	}
+	br := bufio.NewReader(netConn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if d.Jar != nil {
+		if rc := resp.Cookies(); len(rc) > 0 {
+			d.Jar.SetCookies(req.URL, rc)
+		}
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols ||
+		!headerHasToken(resp.Header, "Upgrade", "websocket") ||
+		!headerHasToken(resp.Header, "Connection", "upgrade") ||
+		resp.Header.Get("Sec-Websocket-Accept") != acceptKey(key) {
+		// Keep the start of the body for the caller to inspect.
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body = ioutil.NopCloser(strings.NewReader(string(body)))
+		return nil, resp, ErrBadHandshake
+	}
+	resp.Body = ioutil.NopCloser(strings.NewReader(""))
+
+	subprotocol := resp.Header.Get("Sec-Websocket-Protocol")
+	if subprotocol != "" && !contains(d.Subprotocols, subprotocol) {
+		return nil, resp, errors.New("websocket: server selected a subprotocol that was not requested")
+	}
+	compress := false
+	for _, ext := range parseExtensions(resp.Header) {
+		if !d.EnableCompression || compress || !checkDeflateResponse(ext) {
+			return nil, resp, errors.New("websocket: server selected an unsupported extension " + ext.name)
+		}
+		compress = true
+	}
+
+	c := newConn(br, netConn, nil, netConn, netConn, true)
+	c.subprotocol = subprotocol
+	c.compress = compress
+	return c, resp, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,185 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// The permessage-deflate extension. See RFC 7692.
+//
+// Both ends compress each message on its own, without the sliding
+// window of the previous ones ("no context takeover"): a server always
+// asks for that in its response, and a client in its offer.
+
+package websocket
+
+import (
+	"compress/flate"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// deflateSyncMarker ends the output of each flush of a deflate stream.
+// Senders remove it from compressed messages and receivers put it back.
+const deflateSyncMarker = "\x00\x00\xff\xff"
+
+// deflateFinalBlock is an empty final deflate block, appended to a
+// compressed message so that the decompressor sees a complete stream.
+const deflateFinalBlock = "\x01\x00\x00\xff\xff"
+
+// flateWriter is a pooled deflate compressor.
+type flateWriter struct {
+	*flate.Writer
+}
+
+var flateWriterPool sync.Pool
+
+func getFlateWriter(w io.Writer) *flateWriter {
+	if fw, ok := flateWriterPool.Get().(*flateWriter); ok {
+		fw.Reset(w)
+		return fw
+	}
+	fw, _ := flate.NewWriter(w, flate.BestSpeed)
+	return &flateWriter{fw}
+}
+
+func putFlateWriter(fw *flateWriter) {
+	fw.Reset(nil)
+	flateWriterPool.Put(fw)
+}
+
+var flateReaderPool sync.Pool
+
+// decompressor returns a reader of the decompressed payload of the
+// compressed message mr.
+func (c *Conn) decompressor(mr *messageReader) io.Reader {
+	src := io.MultiReader(mr, strings.NewReader(deflateSyncMarker+deflateFinalBlock))
+	fr, ok := flateReaderPool.Get().(io.ReadCloser)
+	if ok {
+		fr.(flate.Resetter).Reset(src, nil)
+	} else {
+		fr = flate.NewReader(src)
+	}
+	return &flateReader{mr: mr, fr: fr, limit: c.readLimit}
+}
+
+// flateReader reads a compressed message, enforcing the read limit on
+// its decompressed size.
+type flateReader struct {
+	mr    *messageReader
+	fr    io.ReadCloser // nil once done
+	limit int64         // maximum decompressed size, if positive
+	n     int64         // bytes read so far
+}
+
+func (r *flateReader) Read(p []byte) (int, error) {
+	if r.fr == nil {
+		return 0, r.mr.err
+	}
+	n, err := r.fr.Read(p)
+	r.n += int64(n)
+	if r.limit > 0 && r.n > r.limit {
+		r.done()
+		return n, r.mr.end(r.mr.c.fail(StatusMessageTooBig, "message exceeds read limit"))
+	}
+	switch {
+	case err == io.EOF:
+		// The message may have ended its own deflate stream
+		// before its last frame; skip what is left.
+		io.Copy(ioutil.Discard, r.mr)
+		r.done()
+	case err != nil:
+		r.done()
+		if r.mr.err != nil && r.mr.err != io.EOF {
+			return n, r.mr.err
+		}
+		return n, r.mr.end(r.mr.c.fail(StatusInvalidPayload, "invalid compressed message"))
+	}
+	return n, err
+}
+
+func (r *flateReader) done() {
+	flateReaderPool.Put(r.fr)
+	r.fr = nil
+}
+
+// An extension is an offered or accepted WebSocket extension.
+type extension struct {
+	name   string
+	params map[string]string
+}
+
+// parseExtensions parses the Sec-WebSocket-Extensions header fields
+// of h. See RFC 6455, section 9.1.
+func parseExtensions(h http.Header) []extension {
+	var exts []extension
+	for _, v := range h["Sec-Websocket-Extensions"] {
+		for _, e := range strings.Split(v, ",") {
+			parts := strings.Split(e, ";")
+			ext := extension{
+				name:   strings.ToLower(strings.TrimSpace(parts[0])),
+				params: make(map[string]string),
+			}
+			if ext.name == "" {
+				continue
+			}
+			for _, p := range parts[1:] {
+				k, v := p, ""
+				if i := strings.IndexByte(p, '='); i >= 0 {
+					k, v = p[:i], strings.Trim(strings.TrimSpace(p[i+1:]), `"`)
+				}
+				ext.params[strings.ToLower(strings.TrimSpace(k))] = v
+			}
+			exts = append(exts, ext)
+		}
+	}
+	return exts
+}
+
+// acceptDeflate reports whether a server can accept the
+// permessage-deflate offer ext.
+func acceptDeflate(ext extension) bool {
+	if ext.name != "permessage-deflate" {
+		return false
+	}
+	for k, v := range ext.params {
+		switch k {
+		case "server_no_context_takeover", "client_no_context_takeover", "client_max_window_bits":
+		case "server_max_window_bits":
+			// The deflate compressor always uses a 32 KiB window.
+			if v != "15" {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// deflateResponse is the Sec-WebSocket-Extensions value of a server
+// accepting permessage-deflate, and that of a client offering it.
+const deflateResponse = "permessage-deflate; server_no_context_takeover; client_no_context_takeover"
+
+// checkDeflateResponse reports whether a client can use the
+// permessage-deflate extension as the server accepted it in ext.
+func checkDeflateResponse(ext extension) bool {
+	if ext.name != "permessage-deflate" {
+		return false
+	}
+	if _, ok := ext.params["server_no_context_takeover"]; !ok {
+		return false
+	}
+	for k, v := range ext.params {
+		switch k {
+		case "server_no_context_takeover", "client_no_context_takeover", "server_max_window_bits":
+		case "client_max_window_bits":
+			if v != "15" {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
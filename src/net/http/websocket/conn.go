@@ -0,0 +1,816 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package websocket implements the WebSocket protocol of RFC 6455.
+//
+// A server accepts WebSocket connections in an http.Handler with an
+// Upgrader, over HTTP/1.1 or, as RFC 8441 describes, over HTTP/2. The
+// latter needs an http.Server with AllowExtendedConnect set. A client
+// opens connections with a Dialer. Both ends then exchange messages
+// over a Conn.
+//
+// The package supports fragmented messages, ping and pong, the close
+// handshake, and the permessage-deflate compression extension of RFC
+// 7692.
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// MessageType is the type of a data message.
+type MessageType int
+
+const (
+	TextMessage   MessageType = 1 // UTF-8 encoded text
+	BinaryMessage MessageType = 2 // arbitrary bytes
+)
+
+// StatusCode is the status code of a Close frame. See RFC 6455,
+// section 7.4.
+type StatusCode int
+
+const (
+	StatusNormalClosure      StatusCode = 1000
+	StatusGoingAway          StatusCode = 1001
+	StatusProtocolError      StatusCode = 1002
+	StatusUnsupportedData    StatusCode = 1003
+	StatusNoStatusReceived   StatusCode = 1005 // not sent; no code in the Close frame
+	StatusAbnormalClosure    StatusCode = 1006 // not sent; connection lost
+	StatusInvalidPayload     StatusCode = 1007
+	StatusPolicyViolation    StatusCode = 1008
+	StatusMessageTooBig      StatusCode = 1009
+	StatusMandatoryExtension StatusCode = 1010
+	StatusInternalError      StatusCode = 1011
+)
+
+// validCloseCode reports whether code may appear in a Close frame.
+func validCloseCode(code StatusCode) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1011:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
+
+// A CloseError is returned by the read methods of a Conn after the
+// peer closed the connection.
+type CloseError struct {
+	Code   StatusCode
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("websocket: close %d", e.Code)
+	}
+	return fmt.Sprintf("websocket: close %d: %s", e.Code, e.Reason)
+}
+
+var (
+	// ErrCloseSent is returned by the write methods of a Conn after
+	// it sent a Close frame.
+	ErrCloseSent = errors.New("websocket: close frame already sent")
+
+	// ErrDeadlineUnsupported is returned when setting a deadline on
+	// a Conn that runs over an HTTP/2 stream.
+	ErrDeadlineUnsupported = errors.New("websocket: deadlines not supported over HTTP/2")
+)
+
+// Frame opcodes. See RFC 6455, section 5.2.
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+const (
+	finBit  = 1 << 7
+	rsv1Bit = 1 << 6
+	rsv2Bit = 1 << 5
+	rsv3Bit = 1 << 4
+	maskBit = 1 << 7
+
+	maxControlPayload = 125
+
+	// writeFrameSize is the payload size of the frames a message
+	// written with NextWriter is split into.
+	writeFrameSize = 4096
+
+	// closeTimeout is how long Close waits for the peer to answer
+	// a Close frame before closing the connection.
+	closeTimeout = 5 * time.Second
+)
+
+// DefaultReadLimit is the read limit of a new Conn, the maximum size
+// in bytes of a message it reads from the peer. See Conn.SetReadLimit.
+const DefaultReadLimit = 1 << 20
+
+// A Conn is a WebSocket connection.
+//
+// A Conn supports one concurrent reader and any number of concurrent
+// writers: messages written concurrently are sent one after another,
+// and control frames may be sent between the frames of a message.
+type Conn struct {
+	isClient    bool
+	subprotocol string
+	compress    bool // permessage-deflate is in use
+
+	netConn net.Conn  // nil over HTTP/2
+	closer  io.Closer // closes the underlying connection or stream
+	br      *bufio.Reader
+
+	wmu       sync.Mutex // guards the fields below and frame writes
+	w         io.Writer
+	flush     func() // called after each frame, if non-nil
+	wbuf      []byte
+	closeSent bool
+	writeErr  error
+
+	msgSem chan struct{} // held by the message being written
+
+	readSem     chan struct{} // held while a message is being read
+	readErr     error         // sticky; guarded by readSem
+	readLimit   int64
+	msg         *messageReader // message being read, or nil
+	pingHandler func([]byte)
+	pongHandler func([]byte)
+
+	closeReceivedOnce sync.Once
+	closeReceived     chan struct{}
+	closeOnce         sync.Once
+	closed            chan struct{}
+	closeErr          error
+}
+
+func newConn(br *bufio.Reader, w io.Writer, flush func(), closer io.Closer, netConn net.Conn, isClient bool) *Conn {
+	return &Conn{
+		isClient:      isClient,
+		netConn:       netConn,
+		closer:        closer,
+		br:            br,
+		w:             w,
+		flush:         flush,
+		msgSem:        make(chan struct{}, 1),
+		readSem:       make(chan struct{}, 1),
+		readLimit:     DefaultReadLimit,
+		closeReceived: make(chan struct{}),
+		closed:        make(chan struct{}),
+	}
+}
+
+// Subprotocol returns the application protocol negotiated during the
+// handshake, or "" if none was.
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// SetReadLimit sets the maximum size in bytes of a message read from
+// the peer, after decompression. The default is DefaultReadLimit. A
+// zero or negative n means no limit, which lets the peer make the
+// reader of a message buffer any amount of data.
+//
+// When a message exceeds the limit, the read returns an error, and
+// the Conn sends a Close frame with StatusMessageTooBig and closes
+// the connection. SetReadLimit must not be called concurrently with
+// a read.
+func (c *Conn) SetReadLimit(n int64) {
+	c.readLimit = n
+}
+
+// SetReadDeadline sets the read deadline on the underlying network
+// connection. It returns ErrDeadlineUnsupported over HTTP/2.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	if c.netConn == nil {
+		return ErrDeadlineUnsupported
+	}
+	return c.netConn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the write deadline on the underlying network
+// connection. It returns ErrDeadlineUnsupported over HTTP/2.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	if c.netConn == nil {
+		return ErrDeadlineUnsupported
+	}
+	return c.netConn.SetWriteDeadline(t)
+}
+
+// SetPingHandler sets the function called with the payload of each
+// Ping frame received. The default handler answers with a Pong frame.
+// Control frames are only processed while the Conn is being read.
+func (c *Conn) SetPingHandler(h func(data []byte)) {
+	c.pingHandler = h
+}
+
+// SetPongHandler sets the function called with the payload of each
+// Pong frame received. By default, Pong frames are ignored. Control
+// frames are only processed while the Conn is being read.
+func (c *Conn) SetPongHandler(h func(data []byte)) {
+	c.pongHandler = h
+}
+
+// Ping sends a Ping frame with the given payload, of at most 125
+// bytes. The peer answers with a Pong frame with the same payload.
+func (c *Conn) Ping(data []byte) error {
+	return c.writeControl(opPing, data)
+}
+
+// Close performs the closing handshake with StatusNormalClosure and
+// closes the underlying connection.
+func (c *Conn) Close() error {
+	return c.CloseWithStatus(StatusNormalClosure, "")
+}
+
+// CloseWithStatus sends a Close frame with the given status code and
+// reason, of at most 123 bytes, waits a few seconds for the peer's
+// Close frame, and closes the underlying connection.
+//
+// Over HTTP/2, the handler that accepted the Conn must also return
+// for the stream to end.
+func (c *Conn) CloseWithStatus(code StatusCode, reason string) error {
+	if len(reason) > maxControlPayload-2 {
+		return errors.New("websocket: close reason too long")
+	}
+	err := c.writeClose(code, reason)
+	if err == nil {
+		t := time.AfterFunc(closeTimeout, func() { c.closeTransport() })
+		select {
+		case c.readSem <- struct{}{}:
+			// Nobody is reading; read up to the peer's Close.
+			c.discardUntilClose()
+			<-c.readSem
+		case <-c.closeReceived:
+		case <-c.closed:
+		}
+		t.Stop()
+	} else if err != ErrCloseSent {
+		c.closeTransport()
+		return err
+	}
+	return c.closeTransport()
+}
+
+// closeTransport closes the underlying connection, once.
+func (c *Conn) closeTransport() error {
+	c.closeOnce.Do(func() {
+		c.closeErr = c.closer.Close()
+		close(c.closed)
+	})
+	return c.closeErr
+}
+
+// discardUntilClose reads and discards frames until the peer's Close
+// frame or a read error. The caller must hold readSem.
+func (c *Conn) discardUntilClose() {
+	for c.readErr == nil {
+		h, err := c.nextDataFrame()
+		if err != nil {
+			return
+		}
+		if _, err := io.CopyN(ioutil.Discard, c.br, h.length); err != nil {
+			c.readErr = err
+		}
+	}
+}
+
+// fail fails the connection after a protocol violation by the peer,
+// as RFC 6455 section 7.1.7 describes, and returns the read error.
+func (c *Conn) fail(code StatusCode, text string) error {
+	err := errors.New("websocket: " + text)
+	if c.readErr == nil {
+		c.readErr = err
+	}
+	c.writeClose(code, "")
+	c.closeTransport()
+	return err
+}
+
+// A frameHeader is the header of a frame. See RFC 6455, section 5.2.
+type frameHeader struct {
+	fin    bool
+	rsv1   bool
+	op     byte
+	length int64
+	masked bool
+	key    [4]byte
+}
+
+func isControl(op byte) bool { return op&0x8 != 0 }
+
+// readFrameHeader reads the header of the next frame. The caller must
+// hold readSem.
+func (c *Conn) readFrameHeader() (frameHeader, error) {
+	var h frameHeader
+	var b [8]byte
+	if _, err := io.ReadFull(c.br, b[:2]); err != nil {
+		c.readErr = err
+		return h, err
+	}
+	h.fin = b[0]&finBit != 0
+	h.rsv1 = b[0]&rsv1Bit != 0
+	h.op = b[0] & 0xf
+	h.masked = b[1]&maskBit != 0
+	if b[0]&(rsv2Bit|rsv3Bit) != 0 {
+		return h, c.fail(StatusProtocolError, "reserved bits set")
+	}
+	switch h.op {
+	case opContinuation, opText, opBinary, opClose, opPing, opPong:
+	default:
+		return h, c.fail(StatusProtocolError, fmt.Sprintf("unknown opcode %d", h.op))
+	}
+	if h.rsv1 && (!c.compress || isControl(h.op)) {
+		return h, c.fail(StatusProtocolError, "RSV1 set without compression")
+	}
+	if h.masked == c.isClient {
+		if c.isClient {
+			return h, c.fail(StatusProtocolError, "masked frame from server")
+		}
+		return h, c.fail(StatusProtocolError, "unmasked frame from client")
+	}
+
+	switch n := b[1] & 0x7f; n {
+	case 126:
+		if _, err := io.ReadFull(c.br, b[:2]); err != nil {
+			c.readErr = err
+			return h, err
+		}
+		h.length = int64(binary.BigEndian.Uint16(b[:2]))
+	case 127:
+		if _, err := io.ReadFull(c.br, b[:8]); err != nil {
+			c.readErr = err
+			return h, err
+		}
+		l := binary.BigEndian.Uint64(b[:8])
+		if l>>63 != 0 {
+			return h, c.fail(StatusProtocolError, "invalid frame length")
+		}
+		h.length = int64(l)
+	default:
+		h.length = int64(n)
+	}
+	if isControl(h.op) && (!h.fin || h.length > maxControlPayload) {
+		return h, c.fail(StatusProtocolError, "invalid control frame")
+	}
+
+	if h.masked {
+		if _, err := io.ReadFull(c.br, h.key[:]); err != nil {
+			c.readErr = err
+			return h, err
+		}
+	}
+	return h, nil
+}
+
+// nextDataFrame reads frames up to the header of the next data frame,
+// handling the control frames before it. The caller must hold readSem.
+func (c *Conn) nextDataFrame() (frameHeader, error) {
+	for {
+		h, err := c.readFrameHeader()
+		if err != nil {
+			return h, err
+		}
+		if !isControl(h.op) {
+			return h, nil
+		}
+		if err := c.handleControl(h); err != nil {
+			return h, err
+		}
+	}
+}
+
+// handleControl reads the payload of the control frame with header h
+// and acts on it.
+func (c *Conn) handleControl(h frameHeader) error {
+	payload := make([]byte, h.length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		c.readErr = err
+		return err
+	}
+	if h.masked {
+		maskBytes(h.key, 0, payload)
+	}
+	switch h.op {
+	case opPing:
+		if c.pingHandler != nil {
+			c.pingHandler(payload)
+		} else {
+			c.writeControl(opPong, payload)
+		}
+	case opPong:
+		if c.pongHandler != nil {
+			c.pongHandler(payload)
+		}
+	case opClose:
+		cerr := &CloseError{Code: StatusNoStatusReceived}
+		switch {
+		case len(payload) == 1:
+			return c.fail(StatusProtocolError, "invalid close frame")
+		case len(payload) >= 2:
+			cerr.Code = StatusCode(binary.BigEndian.Uint16(payload))
+			cerr.Reason = string(payload[2:])
+			if !validCloseCode(cerr.Code) {
+				return c.fail(StatusProtocolError, fmt.Sprintf("invalid close code %d", cerr.Code))
+			}
+			if !utf8.ValidString(cerr.Reason) {
+				return c.fail(StatusInvalidPayload, "invalid UTF-8 in close reason")
+			}
+		}
+		// Echo the status code, and close the connection: the
+		// handshake is complete either way.
+		c.writeClose(cerr.Code, "")
+		c.readErr = cerr
+		c.closeReceivedOnce.Do(func() { close(c.closeReceived) })
+		c.closeTransport()
+		return cerr
+	}
+	return nil
+}
+
+// maskBytes masks b with key, as RFC 6455 section 5.3 describes, with
+// b starting at offset pos of the payload. It returns the offset of
+// the byte after b.
+func maskBytes(key [4]byte, pos int, b []byte) int {
+	for i := range b {
+		b[i] ^= key[(pos+i)&3]
+	}
+	return pos + len(b)
+}
+
+// NextReader returns the type of the next data message from the peer
+// and a reader for its payload. Any unread part of the previous
+// message is discarded.
+//
+// Once the peer has closed the connection, NextReader returns a
+// *CloseError.
+func (c *Conn) NextReader() (MessageType, io.Reader, error) {
+	if mr := c.msg; mr != nil {
+		io.Copy(ioutil.Discard, mr)
+	}
+	c.readSem <- struct{}{}
+	if c.readErr != nil {
+		<-c.readSem
+		return 0, nil, c.readErr
+	}
+	h, err := c.nextDataFrame()
+	if err == nil && h.op == opContinuation {
+		err = c.fail(StatusProtocolError, "continuation frame outside of a message")
+	}
+	if err != nil {
+		<-c.readSem
+		return 0, nil, err
+	}
+	mr := &messageReader{c: c, compressed: h.rsv1}
+	c.msg = mr
+	if err := mr.setFrame(h); err != nil {
+		return 0, nil, err
+	}
+	var r io.Reader = mr
+	if h.rsv1 {
+		r = c.decompressor(mr)
+	}
+	if h.op == opText {
+		r = &utf8Reader{mr: mr, r: r}
+	}
+	return MessageType(h.op), r, nil
+}
+
+// ReadMessage reads the next data message from the peer.
+func (c *Conn) ReadMessage() (MessageType, []byte, error) {
+	typ, r, err := c.NextReader()
+	if err != nil {
+		return 0, nil, err
+	}
+	p, err := ioutil.ReadAll(r)
+	return typ, p, err
+}
+
+// A messageReader reads the payload of the frames of a data message.
+type messageReader struct {
+	c          *Conn
+	compressed bool    // the read limit applies after decompression
+	fin        bool    // the current frame is the last
+	remaining  int64   // unread payload bytes of the current frame
+	total      int64   // payload bytes of the message so far
+	masked     bool    // the current frame is masked
+	key        [4]byte // its masking key
+	pos        int     // offset into its payload, for masking
+	err        error   // sticky; io.EOF at the end of the message
+}
+
+// setFrame starts reading the frame with header h.
+func (mr *messageReader) setFrame(h frameHeader) error {
+	mr.fin = h.fin
+	mr.remaining = h.length
+	mr.masked = h.masked
+	mr.key = h.key
+	mr.pos = 0
+	mr.total += h.length
+	if !mr.compressed && mr.c.readLimit > 0 && mr.total > mr.c.readLimit {
+		return mr.end(mr.c.fail(StatusMessageTooBig, "message exceeds read limit"))
+	}
+	return nil
+}
+
+// end ends the message with err, releasing readSem, and returns err.
+func (mr *messageReader) end(err error) error {
+	if mr.err == nil {
+		mr.err = err
+		mr.c.msg = nil
+		<-mr.c.readSem
+	}
+	return err
+}
+
+func (mr *messageReader) Read(p []byte) (int, error) {
+	if mr.err != nil {
+		return 0, mr.err
+	}
+	for mr.remaining == 0 {
+		if mr.fin {
+			return 0, mr.end(io.EOF)
+		}
+		h, err := mr.c.nextDataFrame()
+		if err != nil {
+			return 0, mr.end(err)
+		}
+		if h.op != opContinuation {
+			return 0, mr.end(mr.c.fail(StatusProtocolError, "data frame inside a fragmented message"))
+		}
+		if h.rsv1 {
+			return 0, mr.end(mr.c.fail(StatusProtocolError, "RSV1 set on continuation frame"))
+		}
+		if err := mr.setFrame(h); err != nil {
+			return 0, err
+		}
+	}
+	if int64(len(p)) > mr.remaining {
+		p = p[:mr.remaining]
+	}
+	n, err := mr.c.br.Read(p)
+	if mr.masked {
+		mr.pos = maskBytes(mr.key, mr.pos, p[:n])
+	}
+	mr.remaining -= int64(n)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		mr.c.readErr = err
+		return n, mr.end(err)
+	}
+	return n, nil
+}
+
+// utf8Reader fails the connection if the text message read from r is
+// not valid UTF-8.
+type utf8Reader struct {
+	mr      *messageReader
+	r       io.Reader
+	pending []byte // incomplete UTF-8 sequence at the end of the last read
+}
+
+func (u *utf8Reader) Read(p []byte) (int, error) {
+	n, err := u.r.Read(p)
+	data := p[:n]
+	if len(u.pending) > 0 {
+		data = append(u.pending, data...)
+	}
+	i := len(data) - incompleteRuneLen(data)
+	if !utf8.Valid(data[:i]) || (err == io.EOF && i < len(data)) {
+		return n, u.mr.end(u.mr.c.fail(StatusInvalidPayload, "invalid UTF-8 in text message"))
+	}
+	u.pending = append(u.pending[:0], data[i:]...)
+	return n, err
+}
+
+// incompleteRuneLen returns the length of the incomplete UTF-8
+// sequence at the end of b.
+func incompleteRuneLen(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax+1; i-- {
+		if utf8.RuneStart(b[i]) {
+			if utf8.FullRune(b[i:]) {
+				return 0
+			}
+			return len(b) - i
+		}
+	}
+	return 0
+}
+
+// NextWriter returns a writer for the next data message of type typ
+// to the peer. The message is sent in frames as it is written, and
+// ends when the writer is closed. Other messages are not sent until
+// then.
+func (c *Conn) NextWriter(typ MessageType) (io.WriteCloser, error) {
+	if typ != TextMessage && typ != BinaryMessage {
+		return nil, fmt.Errorf("websocket: invalid message type %d", typ)
+	}
+	c.msgSem <- struct{}{}
+	mw := &messageWriter{c: c, op: byte(typ)}
+	if c.compress {
+		mw.rsv1 = true
+		mw.holdback = len(deflateSyncMarker)
+		mw.fw = getFlateWriter(rawWriter{mw})
+	}
+	return mw, nil
+}
+
+// WriteMessage sends a data message of type typ to the peer.
+func (c *Conn) WriteMessage(typ MessageType, data []byte) error {
+	if !c.compress && (typ == TextMessage || typ == BinaryMessage) {
+		c.msgSem <- struct{}{}
+		defer func() { <-c.msgSem }()
+		return c.writeFrame(true, false, byte(typ), data)
+	}
+	w, err := c.NextWriter(typ)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// A messageWriter writes a data message as a sequence of frames.
+type messageWriter struct {
+	c        *Conn
+	op       byte // opcode of the next frame
+	rsv1     bool // set on the first frame of a compressed message
+	buf      []byte
+	holdback int          // trailing bytes of buf to keep until Close
+	fw       *flateWriter // compresses into buf, or nil
+	err      error        // sticky
+}
+
+// rawWriter writes the already compressed payload of a messageWriter.
+type rawWriter struct{ mw *messageWriter }
+
+func (w rawWriter) Write(p []byte) (int, error) { return w.mw.writeRaw(p) }
+
+func (mw *messageWriter) Write(p []byte) (int, error) {
+	if mw.err != nil {
+		return 0, mw.err
+	}
+	if mw.fw != nil {
+		return mw.fw.Write(p)
+	}
+	return mw.writeRaw(p)
+}
+
+// writeRaw buffers p as payload, sending a frame each time a full one
+// is available.
+func (mw *messageWriter) writeRaw(p []byte) (int, error) {
+	if mw.err != nil {
+		return 0, mw.err
+	}
+	mw.buf = append(mw.buf, p...)
+	for len(mw.buf)-mw.holdback >= writeFrameSize {
+		if err := mw.writeFrame(mw.buf[:writeFrameSize], false); err != nil {
+			return 0, err
+		}
+		mw.buf = mw.buf[:copy(mw.buf, mw.buf[writeFrameSize:])]
+	}
+	return len(p), nil
+}
+
+func (mw *messageWriter) writeFrame(payload []byte, fin bool) error {
+	err := mw.c.writeFrame(fin, mw.rsv1, mw.op, payload)
+	mw.op = opContinuation
+	mw.rsv1 = false
+	if err != nil {
+		mw.err = err
+	}
+	return err
+}
+
+// Close sends the rest of the message in a final frame.
+func (mw *messageWriter) Close() error {
+	if mw.err == errWriterClosed {
+		return mw.err
+	}
+	defer func() {
+		mw.err = errWriterClosed
+		<-mw.c.msgSem
+	}()
+	if mw.err != nil {
+		if mw.fw != nil {
+			putFlateWriter(mw.fw)
+		}
+		return mw.err
+	}
+	if mw.fw != nil {
+		err := mw.fw.Flush()
+		putFlateWriter(mw.fw)
+		if err != nil {
+			return err
+		}
+		// RFC 7692, section 7.2.1: remove the trailing sync marker.
+		mw.buf = mw.buf[:len(mw.buf)-len(deflateSyncMarker)]
+	}
+	return mw.writeFrame(mw.buf, true)
+}
+
+var errWriterClosed = errors.New("websocket: write to closed message writer")
+
+// writeControl sends a control frame.
+func (c *Conn) writeControl(op byte, payload []byte) error {
+	if len(payload) > maxControlPayload {
+		return errors.New("websocket: control frame payload too long")
+	}
+	return c.writeFrame(true, false, op, payload)
+}
+
+// writeClose sends a Close frame with code and reason. No code is sent
+// if code is StatusNoStatusReceived.
+func (c *Conn) writeClose(code StatusCode, reason string) error {
+	var payload []byte
+	if code != StatusNoStatusReceived {
+		payload = make([]byte, 2+len(reason))
+		binary.BigEndian.PutUint16(payload, uint16(code))
+		copy(payload[2:], reason)
+	}
+	return c.writeControl(opClose, payload)
+}
+
+// writeFrame sends a frame. Clients mask the payload, as RFC 6455
+// section 5.3 requires, without changing payload.
+func (c *Conn) writeFrame(fin, rsv1 bool, op byte, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	if c.writeErr != nil {
+		return c.writeErr
+	}
+	if op == opClose {
+		c.closeSent = true
+	}
+
+	b0 := op
+	if fin {
+		b0 |= finBit
+	}
+	if rsv1 {
+		b0 |= rsv1Bit
+	}
+	var b1 byte
+	if c.isClient {
+		b1 = maskBit
+	}
+	b := append(c.wbuf[:0], b0)
+	switch n := len(payload); {
+	case n <= 125:
+		b = append(b, b1|byte(n))
+	case n <= 0xffff:
+		b = append(b, b1|126, byte(n>>8), byte(n))
+	default:
+		b = append(b, b1|127)
+		var l [8]byte
+		binary.BigEndian.PutUint64(l[:], uint64(n))
+		b = append(b, l[:]...)
+	}
+	if c.isClient {
+		var key [4]byte
+		if _, err := io.ReadFull(rand.Reader, key[:]); err != nil {
+			return err
+		}
+		b = append(b, key[:]...)
+		start := len(b)
+		b = append(b, payload...)
+		maskBytes(key, 0, b[start:])
+	} else {
+		b = append(b, payload...)
+	}
+	if cap(b) <= 64<<10 {
+		c.wbuf = b
+	}
+
+	if _, err := c.w.Write(b); err != nil {
+		c.writeErr = err
+		return err
+	}
+	if c.flush != nil {
+		c.flush()
+	}
+	return nil
+}
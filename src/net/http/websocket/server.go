@@ -0,0 +1,225 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// acceptGUID is appended to the key of a handshake to compute the
+// Sec-WebSocket-Accept value. See RFC 6455, section 1.3.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// acceptKey returns the Sec-WebSocket-Accept value for key.
+func acceptKey(key string) string {
+	h := sha1.New()
+	io.WriteString(h, key)
+	io.WriteString(h, acceptGUID)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// ErrBadHandshake is returned when a WebSocket opening handshake
+// fails.
+var ErrBadHandshake = errors.New("websocket: bad handshake")
+
+// An Upgrader turns HTTP requests into WebSocket connections.
+type Upgrader struct {
+	// Subprotocols lists the application protocols the server
+	// supports, in order of preference. The first one the client
+	// also lists is selected.
+	Subprotocols []string
+
+	// CheckOrigin reports whether to accept a request, based on
+	// its Origin header. If nil, requests with an Origin header
+	// whose host differs from the request's Host are rejected, to
+	// keep other web sites from connecting on behalf of a browser.
+	CheckOrigin func(r *http.Request) bool
+
+	// EnableCompression enables the permessage-deflate extension
+	// when the client offers it.
+	EnableCompression bool
+}
+
+// Upgrade upgrades the connection of the request r to the WebSocket
+// protocol, and returns the server side of the WebSocket connection.
+// header holds additional headers for the handshake response, such
+// as Set-Cookie.
+//
+// Over HTTP/1.1, Upgrade hijacks the connection, which is no longer
+// the handler's to use. Over HTTP/2, r must be an extended CONNECT
+// request (RFC 8441), which the http.Server only accepts if its
+// AllowExtendedConnect field is set, and the stream ends when the
+// handler returns: the handler must not return before it is done
+// with the Conn.
+//
+// If the request is not a valid WebSocket handshake, Upgrade replies
+// with an HTTP error and returns an error.
+func (u *Upgrader) Upgrade(w http.ResponseWriter, r *http.Request, header http.Header) (*Conn, error) {
+	isH2 := r.ProtoMajor == 2
+	if isH2 {
+		if r.Method != "CONNECT" || r.Header.Get(":protocol") != "websocket" {
+			return u.fail(w, http.StatusBadRequest, "not an extended CONNECT request for websocket")
+		}
+	} else {
+		if r.Method != "GET" {
+			return u.fail(w, http.StatusMethodNotAllowed, "method not GET")
+		}
+		if !headerHasToken(r.Header, "Connection", "upgrade") || !headerHasToken(r.Header, "Upgrade", "websocket") {
+			return u.fail(w, http.StatusBadRequest, "not a websocket upgrade request")
+		}
+	}
+	if r.Header.Get("Sec-Websocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return u.fail(w, http.StatusUpgradeRequired, "unsupported version")
+	}
+	key := r.Header.Get("Sec-Websocket-Key")
+	if !isH2 {
+		if k, err := base64.StdEncoding.DecodeString(key); err != nil || len(k) != 16 {
+			return u.fail(w, http.StatusBadRequest, "invalid Sec-WebSocket-Key")
+		}
+	}
+	checkOrigin := u.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(r) {
+		return u.fail(w, http.StatusForbidden, "origin not allowed")
+	}
+
+	h := make(http.Header)
+	for k, vv := range header {
+		h[k] = vv
+	}
+	subprotocol := u.selectSubprotocol(r)
+	if subprotocol != "" {
+		h.Set("Sec-WebSocket-Protocol", subprotocol)
+	}
+	compress := false
+	if u.EnableCompression {
+		for _, ext := range parseExtensions(r.Header) {
+			if acceptDeflate(ext) {
+				compress = true
+				h.Set("Sec-WebSocket-Extensions", deflateResponse)
+				break
+			}
+		}
+	}
+
+	var c *Conn
+	if isH2 {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			return u.fail(w, http.StatusInternalServerError, "response does not implement http.Flusher")
+		}
+		for k, vv := range h {
+			w.Header()[k] = vv
+		}
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+		c = newConn(bufio.NewReader(r.Body), w, flusher.Flush, r.Body, nil, false)
+	} else {
+		hj, ok := w.(http.Hijacker)
+		if !ok {
+			return u.fail(w, http.StatusInternalServerError, "response does not implement http.Hijacker")
+		}
+		netConn, brw, err := hj.Hijack()
+		if err != nil {
+			return nil, err
+		}
+		// Clear the deadlines the Server may have set.
+		netConn.SetDeadline(time.Time{})
+
+		var buf bytes.Buffer
+		buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+		buf.WriteString("Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n")
+		h.Write(&buf)
+		buf.WriteString("\r\n")
+		if _, err := netConn.Write(buf.Bytes()); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+		c = newConn(brw.Reader, netConn, nil, netConn, netConn, false)
+	}
+	c.subprotocol = subprotocol
+	c.compress = compress
+	return c, nil
+}
+
+// fail replies to a failed handshake with an HTTP error.
+func (u *Upgrader) fail(w http.ResponseWriter, code int, reason string) (*Conn, error) {
+	http.Error(w, http.StatusText(code), code)
+	return nil, errors.New("websocket: handshake: " + reason)
+}
+
+// selectSubprotocol returns the first of u.Subprotocols that the
+// client requested in r, or "".
+func (u *Upgrader) selectSubprotocol(r *http.Request) string {
+	requested := headerTokens(r.Header, "Sec-Websocket-Protocol")
+	for _, p := range u.Subprotocols {
+		for _, q := range requested {
+			if p == q {
+				return p
+			}
+		}
+	}
+	return ""
+}
+
+// sameOrigin reports whether the Origin header of r, if any, names the
+// host r was sent to.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// IsWebSocketUpgrade reports whether r asks to be upgraded to a
+// WebSocket connection, over HTTP/1.1 or HTTP/2.
+func IsWebSocketUpgrade(r *http.Request) bool {
+	if r.ProtoMajor == 2 {
+		return r.Method == "CONNECT" && r.Header.Get(":protocol") == "websocket"
+	}
+	return headerHasToken(r.Header, "Connection", "upgrade") && headerHasToken(r.Header, "Upgrade", "websocket")
+}
+
+// headerTokens returns the comma-separated tokens of the header
+// fields named key in h.
+func headerTokens(h http.Header, key string) []string {
+	var tokens []string
+	for _, v := range h[http.CanonicalHeaderKey(key)] {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				tokens = append(tokens, t)
+			}
+		}
+	}
+	return tokens
+}
+
+// headerHasToken reports whether the header fields named key in h
+// contain token, ignoring case.
+func headerHasToken(h http.Header, key, token string) bool {
+	for _, t := range headerTokens(h, key) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
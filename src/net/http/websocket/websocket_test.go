@@ -0,0 +1,418 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newEchoServer starts a server that upgrades requests with u and
+// echoes the messages it reads.
+func newEchoServer(u *Upgrader) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := u.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		echo(c)
+	}))
+}
+
+// echo writes back the messages read from c until an error.
+func echo(c *Conn) error {
+	for {
+		typ, r, err := c.NextReader()
+		if err != nil {
+			return err
+		}
+		w, err := c.NextWriter(typ)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(w, r); err != nil {
+			w.Close()
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+	}
+}
+
+func wsURL(ts *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(ts.URL, "http")
+}
+
+func TestEcho(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		ts := newEchoServer(&Upgrader{EnableCompression: compress})
+		d := &Dialer{EnableCompression: compress}
+		c, resp, err := d.Dial(context.Background(), wsURL(ts), nil)
+		if err != nil {
+			ts.Close()
+			t.Fatalf("compress=%v: Dial: %v", compress, err)
+		}
+		if resp.StatusCode != http.StatusSwitchingProtocols {
+			t.Errorf("compress=%v: status = %d; want 101", compress, resp.StatusCode)
+		}
+		if c.compress != compress {
+			t.Errorf("compress=%v: negotiated compression = %v", compress, c.compress)
+		}
+
+		big := bytes.Repeat([]byte("0123456789"), 3*writeFrameSize/10+7)
+		msgs := []struct {
+			typ  MessageType
+			data []byte
+		}{
+			{TextMessage, []byte("hello")},
+			{BinaryMessage, []byte{0, 1, 2, 0xff}},
+			{TextMessage, []byte("")},
+			{BinaryMessage, big},
+		}
+		for _, m := range msgs {
+			if err := c.WriteMessage(m.typ, m.data); err != nil {
+				t.Fatalf("compress=%v: WriteMessage: %v", compress, err)
+			}
+			typ, data, err := c.ReadMessage()
+			if err != nil {
+				t.Fatalf("compress=%v: ReadMessage: %v", compress, err)
+			}
+			if typ != m.typ || !bytes.Equal(data, m.data) {
+				t.Errorf("compress=%v: got type %d, %d bytes; want type %d, %d bytes", compress, typ, len(data), m.typ, len(m.data))
+			}
+		}
+		if err := c.Close(); err != nil {
+			t.Errorf("compress=%v: Close: %v", compress, err)
+		}
+		ts.Close()
+	}
+}
+
+func TestFragmentedMessage(t *testing.T) {
+	ts := newEchoServer(&Upgrader{})
+	defer ts.Close()
+	c, _, err := DefaultDialer.Dial(context.Background(), wsURL(ts), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	w, err := c.NextWriter(TextMessage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want bytes.Buffer
+	for i := 0; i < 3000; i++ {
+		io.WriteString(w, "héllo ")
+		want.WriteString("héllo ")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("x")); err != errWriterClosed {
+		t.Errorf("Write after Close = %v; want %v", err, errWriterClosed)
+	}
+	typ, got, err := c.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ != TextMessage || !bytes.Equal(got, want.Bytes()) {
+		t.Errorf("got type %d, %d bytes; want type %d, %d bytes", typ, len(got), TextMessage, want.Len())
+	}
+}
+
+func TestPingPong(t *testing.T) {
+	ts := newEchoServer(&Upgrader{})
+	defer ts.Close()
+	c, _, err := DefaultDialer.Dial(context.Background(), wsURL(ts), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var pong []byte
+	c.SetPongHandler(func(data []byte) { pong = data })
+	if err := c.Ping([]byte("are you there")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.WriteMessage(TextMessage, []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.ReadMessage(); err != nil {
+		t.Fatal(err)
+	}
+	if string(pong) != "are you there" {
+		t.Errorf("pong = %q; want %q", pong, "are you there")
+	}
+	if err := c.Ping(make([]byte, maxControlPayload+1)); err == nil {
+		t.Error("Ping with a long payload succeeded")
+	}
+}
+
+func TestCloseHandshake(t *testing.T) {
+	errc := make(chan error, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := new(Upgrader).Upgrade(w, r, nil)
+		if err != nil {
+			errc <- err
+			return
+		}
+		_, _, err = c.ReadMessage()
+		errc <- err
+	}))
+	defer ts.Close()
+	c, _, err := DefaultDialer.Dial(context.Background(), wsURL(ts), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CloseWithStatus(StatusGoingAway, "bye"); err != nil {
+		t.Fatal(err)
+	}
+	cerr, ok := (<-errc).(*CloseError)
+	if !ok || cerr.Code != StatusGoingAway || cerr.Reason != "bye" {
+		t.Errorf("server read error = %v; want close %d: bye", cerr, StatusGoingAway)
+	}
+	if err := c.WriteMessage(TextMessage, []byte("x")); err != ErrCloseSent {
+		t.Errorf("WriteMessage after close = %v; want %v", err, ErrCloseSent)
+	}
+	if _, _, err := c.ReadMessage(); err == nil {
+		t.Error("ReadMessage after close succeeded")
+	}
+}
+
+func TestReadLimit(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		errc := make(chan error, 1)
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c, err := (&Upgrader{EnableCompression: compress}).Upgrade(w, r, nil)
+			if err != nil {
+				errc <- err
+				return
+			}
+			defer c.Close()
+			c.SetReadLimit(100)
+			_, _, err = c.ReadMessage()
+			errc <- err
+		}))
+		c, _, err := (&Dialer{EnableCompression: compress}).Dial(context.Background(), wsURL(ts), nil)
+		if err != nil {
+			ts.Close()
+			t.Fatal(err)
+		}
+		// Highly compressible, so that only the decompressed size
+		// is over the limit.
+		c.WriteMessage(BinaryMessage, make([]byte, 1000))
+		if err := <-errc; err == nil {
+			t.Errorf("compress=%v: message over the read limit was read", compress)
+		}
+		_, _, err = c.ReadMessage()
+		if cerr, ok := err.(*CloseError); !ok || cerr.Code != StatusMessageTooBig {
+			t.Errorf("compress=%v: client read error = %v; want close %d", compress, err, StatusMessageTooBig)
+		}
+		c.Close()
+		ts.Close()
+	}
+}
+
+func TestDefaultReadLimit(t *testing.T) {
+	errc := make(chan error, 2)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := (&Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			errc <- err
+			return
+		}
+		defer c.Close()
+		for i := 0; i < 2; i++ {
+			_, _, err = c.ReadMessage()
+			errc <- err
+		}
+	}))
+	defer ts.Close()
+	c, _, err := (&Dialer{}).Dial(context.Background(), wsURL(ts), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	go func() {
+		c.WriteMessage(BinaryMessage, make([]byte, DefaultReadLimit))
+		c.WriteMessage(BinaryMessage, make([]byte, DefaultReadLimit+1))
+	}()
+	if err := <-errc; err != nil {
+		t.Errorf("reading a message of DefaultReadLimit bytes: %v", err)
+	}
+	if err := <-errc; err == nil {
+		t.Error("message over DefaultReadLimit was read")
+	}
+}
+
+func TestInvalidUTF8(t *testing.T) {
+	client, server := newConnPair()
+	defer client.Close()
+	go func() {
+		server.WriteMessage(TextMessage, []byte("bad \xff"))
+		// Read the client's Close frame.
+		server.ReadMessage()
+	}()
+	_, _, err := client.ReadMessage()
+	if err == nil {
+		t.Fatal("invalid UTF-8 text message was read")
+	}
+}
+
+func TestSubprotocol(t *testing.T) {
+	ts := newEchoServer(&Upgrader{Subprotocols: []string{"v2.chat", "v1.chat"}})
+	defer ts.Close()
+	d := &Dialer{Subprotocols: []string{"v1.chat", "v2.chat"}}
+	c, _, err := d.Dial(context.Background(), wsURL(ts), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if got := c.Subprotocol(); got != "v2.chat" {
+		t.Errorf("Subprotocol = %q; want %q", got, "v2.chat")
+	}
+}
+
+func TestBadHandshake(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "not a websocket server")
+	}))
+	defer ts.Close()
+	_, resp, err := DefaultDialer.Dial(context.Background(), wsURL(ts), nil)
+	if err != ErrBadHandshake {
+		t.Fatalf("Dial error = %v; want %v", err, ErrBadHandshake)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	if string(body) != "not a websocket server" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestUpgradeRejects(t *testing.T) {
+	ts := newEchoServer(&Upgrader{})
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("plain GET: status = %d; want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+
+	h := http.Header{"Origin": {"http://evil.example"}}
+	_, resp, err = DefaultDialer.Dial(context.Background(), wsURL(ts), h)
+	if err != ErrBadHandshake {
+		t.Errorf("cross-origin Dial error = %v; want %v", err, ErrBadHandshake)
+	} else if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("cross-origin Dial: status = %d; want %d", resp.StatusCode, http.StatusForbidden)
+	}
+}
+
+func TestDialTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		// Accept, but never answer the handshake.
+		c, err := ln.Accept()
+		if err == nil {
+			defer c.Close()
+			io.Copy(ioutil.Discard, c)
+		}
+	}()
+	d := &Dialer{HandshakeTimeout: 50 * time.Millisecond}
+	_, _, err = d.Dial(context.Background(), "ws://"+ln.Addr().String()+"/", nil)
+	if err != context.DeadlineExceeded {
+		t.Errorf("Dial error = %v; want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestParseExtensions(t *testing.T) {
+	h := http.Header{"Sec-Websocket-Extensions": {
+		`permessage-deflate; client_max_window_bits, x-foo; a="b"`,
+		"Permessage-Deflate;server_max_window_bits=10",
+	}}
+	exts := parseExtensions(h)
+	if len(exts) != 3 {
+		t.Fatalf("got %d extensions; want 3", len(exts))
+	}
+	if exts[0].name != "permessage-deflate" || !acceptDeflate(exts[0]) {
+		t.Errorf("first offer %+v not accepted", exts[0])
+	}
+	if exts[1].name != "x-foo" || exts[1].params["a"] != "b" {
+		t.Errorf("second offer = %+v", exts[1])
+	}
+	if acceptDeflate(exts[2]) {
+		t.Errorf("offer %+v with a small server window accepted", exts[2])
+	}
+}
+
+// pipeRWC joins the halves of two pipes into a stream.
+type pipeRWC struct {
+	*io.PipeReader
+	*io.PipeWriter
+}
+
+func (p pipeRWC) Close() error {
+	p.PipeReader.Close()
+	return p.PipeWriter.Close()
+}
+
+// newConnPair returns the ends of a WebSocket connection over pipes,
+// without deadlines, like one over an HTTP/2 stream.
+func newConnPair() (client, server *Conn) {
+	cr, sw := io.Pipe()
+	sr, cw := io.Pipe()
+	cs := pipeRWC{cr, cw}
+	ss := pipeRWC{sr, sw}
+	client = newConn(bufio.NewReader(cs), cs, nil, cs, nil, true)
+	server = newConn(bufio.NewReader(ss), ss, nil, ss, nil, false)
+	return client, server
+}
+
+func TestConnWithoutDeadlines(t *testing.T) {
+	client, server := newConnPair()
+	go echo(server)
+
+	if err := client.SetReadDeadline(time.Now()); err != ErrDeadlineUnsupported {
+		t.Errorf("SetReadDeadline = %v; want %v", err, ErrDeadlineUnsupported)
+	}
+	if err := client.WriteMessage(TextMessage, []byte("over a stream")); err != nil {
+		t.Fatal(err)
+	}
+	_, data, err := client.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "over a stream" {
+		t.Errorf("got %q", data)
+	}
+	if err := client.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+}
+
+func TestAcceptKey(t *testing.T) {
+	// The example of RFC 6455, section 1.3.
+	if got, want := acceptKey("dGhlIHNhbXBsZSBub25jZQ=="), "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="; got != want {
+		t.Errorf("acceptKey = %q; want %q", got, want)
+	}
+}
@@ -12,6 +12,8 @@
 
 package net
 
+import "net/netip"
+
 // IP address lengths (bytes).
 const (
 	IPv4len = 4
@@ -501,6 +503,34 @@ func (n *IPNet) String() string {
 	return nn.String() + "/" + uitoa(uint(l))
 }
 
+// Prefix returns n as a netip.Prefix. It returns the zero Prefix if n
+// is nil or its mask is not in the canonical form.
+func (n *IPNet) Prefix() netip.Prefix {
+	if n == nil {
+		return netip.Prefix{}
+	}
+	ones, bits := n.Mask.Size()
+	ip := n.IP
+	if bits == 8*IPv4len {
+		ip = ip.To4()
+	}
+	a, ok := netip.AddrFromSlice(ip)
+	if !ok || bits != a.BitLen() {
+		return netip.Prefix{}
+	}
+	return netip.PrefixFrom(a, ones)
+}
+
+// IPNetFromPrefix returns p as an IPNet, with the bits of the address
+// beyond the prefix length cleared. It returns nil if p is not valid.
+func IPNetFromPrefix(p netip.Prefix) *IPNet {
+	if !p.IsValid() {
+		return nil
+	}
+	a := p.Masked().Addr()
+	return &IPNet{IP: IP(a.AsSlice()), Mask: CIDRMask(p.Bits(), a.BitLen())}
+}
+
 // Parse IPv4 address (d.d.d.d).
 func parseIPv4(s string) IP {
 	var p [IPv4len]byte
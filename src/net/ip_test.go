@@ -468,6 +468,53 @@ func TestNetworkNumberAndMask(t *testing.T) {
 	}
 }
 
+var ipNetPrefixTests = []struct {
+	in   *IPNet
+	want string // empty means the zero Prefix
+}{
+	{&IPNet{IP: IPv4(192, 168, 0, 0), Mask: IPv4Mask(255, 255, 255, 0)}, "192.168.0.0/24"},
+	{&IPNet{IP: IP{10, 0, 0, 0}, Mask: CIDRMask(8, 32)}, "10.0.0.0/8"},
+	{&IPNet{IP: ParseIP("2001:db8::"), Mask: CIDRMask(32, 128)}, "2001:db8::/32"},
+	{&IPNet{IP: IPv4(10, 0, 0, 0), Mask: CIDRMask(104, 128)}, "::ffff:10.0.0.0/104"},
+	{&IPNet{IP: IPv4(192, 168, 0, 0), Mask: IPv4Mask(255, 0, 255, 0)}, ""},
+	{&IPNet{IP: ParseIP("2001:db8::"), Mask: CIDRMask(8, 32)}, ""},
+	{&IPNet{IP: IP{1, 2, 3}, Mask: CIDRMask(8, 32)}, ""},
+	{nil, ""},
+}
+
+func TestIPNetPrefix(t *testing.T) {
+	for _, tt := range ipNetPrefixTests {
+		p := tt.in.Prefix()
+		if tt.want == "" {
+			if p.IsValid() {
+				t.Errorf("%v.Prefix() = %v; want zero Prefix", tt.in, p)
+			}
+			continue
+		}
+		if p.String() != tt.want {
+			t.Errorf("%v.Prefix() = %v; want %s", tt.in, p, tt.want)
+			continue
+		}
+		n := IPNetFromPrefix(p)
+		if n.String() != tt.in.String() || !n.Contains(tt.in.IP) {
+			t.Errorf("IPNetFromPrefix(%v) = %v; want %v", p, n, tt.in)
+		}
+	}
+
+	_, n, err := ParseCIDR("192.0.2.77/26")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := n.Prefix()
+	if got := IPNetFromPrefix(p); !reflect.DeepEqual(got, n) {
+		t.Errorf("IPNetFromPrefix(%v) = %#v; want %#v", p, got, n)
+	}
+	p, _ = p.Addr().Next().Prefix(32)
+	if n := IPNetFromPrefix(p); n.String() != "192.0.2.65/32" {
+		t.Errorf("IPNetFromPrefix = %v; want 192.0.2.65/32", n)
+	}
+}
+
 var splitJoinTests = []struct {
 	host string
 	port string
@@ -120,7 +120,7 @@ func (c *IPConn) writeMsg(b, oob []byte, addr *IPAddr) (n, oobn int, err error)
 	return c.fd.writeMsg(b, oob, sa)
 }
 
-func dialIP(ctx context.Context, netProto string, laddr, raddr *IPAddr) (*IPConn, error) {
+func dialIP(ctx context.Context, netProto string, laddr, raddr *IPAddr, ctrlFn func(string, string, syscall.RawConn) error) (*IPConn, error) {
 	network, proto, err := parseNetwork(ctx, netProto)
 	if err != nil {
 		return nil, err
@@ -133,14 +133,14 @@ func dialIP(ctx context.Context, netProto string, laddr, raddr *IPAddr) (*IPConn
 	if raddr == nil {
 		return nil, errMissingAddress
 	}
-	fd, err := internetSocket(ctx, network, laddr, raddr, syscall.SOCK_RAW, proto, "dial")
+	fd, err := internetSocket(ctx, network, laddr, raddr, syscall.SOCK_RAW, proto, "dial", ctrlFn)
 	if err != nil {
 		return nil, err
 	}
 	return newIPConn(fd), nil
 }
 
-func listenIP(ctx context.Context, netProto string, laddr *IPAddr) (*IPConn, error) {
+func listenIP(ctx context.Context, netProto string, laddr *IPAddr, ctrlFn func(string, string, syscall.RawConn) error) (*IPConn, error) {
 	network, proto, err := parseNetwork(ctx, netProto)
 	if err != nil {
 		return nil, err
@@ -150,7 +150,7 @@ func listenIP(ctx context.Context, netProto string, laddr *IPAddr) (*IPConn, err
 	default:
 		return nil, UnknownNetworkError(netProto)
 	}
-	fd, err := internetSocket(ctx, network, laddr, nil, syscall.SOCK_RAW, proto, "listen")
+	fd, err := internetSocket(ctx, network, laddr, nil, syscall.SOCK_RAW, proto, "listen", ctrlFn)
 	if err != nil {
 		return nil, err
 	}
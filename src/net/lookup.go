@@ -97,15 +97,53 @@ type Resolver struct {
 	// GODEBUG=netdns=go, but scoped to just this resolver.
 	PreferGo bool
 
-	// TODO(bradfitz): optional interface impl override hook
+	// Dial optionally specifies an alternate dialer for use by
+	// Go's built-in DNS resolver to make TCP and UDP connections
+	// to DNS services. The host in the address parameter will
+	// always be a literal IP address and not a host name, and the
+	// port in the address parameter will be a literal port number
+	// and not a service name.
+	//
+	// If the Conn returned is also a PacketConn, sent and received
+	// DNS messages must adhere to RFC 1035 section 4.2.1, "UDP
+	// usage". Otherwise, DNS messages transmitted over Conn must
+	// adhere to RFC 7766 section 5, "Transport Protocol Selection".
+	// This allows a resolver to use only TCP, by always returning a
+	// TCP connection, or to use DNS over TLS (RFC 7858), by returning
+	// a TLS connection such as one made by the function returned by
+	// crypto/tls.DNSDialer.
+	//
+	// If nil, the default dialer is used.
+	Dial func(ctx context.Context, network, address string) (Conn, error)
+
+	// lookupGroup merges LookupIPAddr calls together for lookups for
+	// the same host. The lookupGroup key is the LookupIPAddr.host
+	// argument, or for lookups of a single address family, the
+	// network and host joined by a colon.
+	// The return values are ([]IPAddr, error).
+	lookupGroup singleflight.Group
+
 	// TODO(bradfitz): Timeout time.Duration?
 }
 
+func (r *Resolver) preferGo() bool { return r != nil && r.PreferGo }
+
+// getLookupGroup returns the group that merges lookups made through
+// r. Each Resolver has its own group, so that lookups made through
+// one Resolver are never answered by another that may reach
+// different servers; a nil Resolver shares DefaultResolver's group.
+func (r *Resolver) getLookupGroup() *singleflight.Group {
+	if r == nil {
+		return &DefaultResolver.lookupGroup
+	}
+	return &r.lookupGroup
+}
+
 func (r *Resolver) lookupIPFunc() func(context.Context, string) ([]IPAddr, error) {
-	if r != nil && r.PreferGo {
-		return goLookupIP
+	if r.preferGo() {
+		return r.goLookupIP
 	}
-	return lookupIP
+	return r.lookupIP
 }
 
 // LookupHost looks up the given host using the local resolver.
@@ -125,7 +163,7 @@ func (r *Resolver) LookupHost(ctx context.Context, host string) (addrs []string,
 	if ip := ParseIP(host); ip != nil {
 		return []string{host}, nil
 	}
-	return lookupHost(ctx, host)
+	return r.lookupHost(ctx, host)
 }
 
 // LookupIP looks up host using the local resolver.
@@ -165,6 +203,7 @@ func (r *Resolver) LookupIPAddr(ctx context.Context, host string) ([]IPAddr, err
 		resolverFunc = alt
 	}
 
+	lookupGroup := r.getLookupGroup()
 	ch := lookupGroup.DoChan(host, func() (interface{}, error) {
 		return testHookLookupIP(ctx, resolverFunc, host)
 	})
@@ -190,11 +229,59 @@ func (r *Resolver) LookupIPAddr(ctx context.Context, host string) ([]IPAddr, err
 	}
 }
 
-// lookupGroup merges LookupIPAddr calls together for lookups
-// for the same host. The lookupGroup key is is the LookupIPAddr.host
-// argument.
-// The return values are ([]IPAddr, error).
-var lookupGroup singleflight.Group
+// lookupIPAddrFamily is like LookupIPAddr but returns only the
+// addresses of network, which must be "ip4" or "ip6". Where the
+// underlying resolver allows it, only the query for that address
+// family is sent, so that the two families of a dual-stack host can
+// be looked up independently of each other. It does not call the
+// DNS trace hooks; that is left to the caller.
+func (r *Resolver) lookupIPAddrFamily(ctx context.Context, network, host string) ([]IPAddr, error) {
+	if host == "" {
+		return nil, &DNSError{Err: errNoSuchHost.Error(), Name: host}
+	}
+	if ip := ParseIP(host); ip != nil {
+		return filterIPAddrs(network, []IPAddr{{IP: ip}}), nil
+	}
+	resolverFunc := func(ctx context.Context, host string) ([]IPAddr, error) {
+		return r.lookupIPFamily(ctx, network, host)
+	}
+	if alt, _ := ctx.Value(nettrace.LookupIPAltResolverKey{}).(func(context.Context, string) ([]IPAddr, error)); alt != nil {
+		resolverFunc = alt
+	}
+
+	// Keep the key distinct from the keys used by LookupIPAddr,
+	// which hold the addresses of both families.
+	key := network + ":" + host
+	lookupGroup := r.getLookupGroup()
+	ch := lookupGroup.DoChan(key, func() (interface{}, error) {
+		// Alternate resolvers may return both families.
+		addrs, err := testHookLookupIPFamily(ctx, resolverFunc, network, host)
+		return filterIPAddrs(network, addrs), err
+	})
+
+	select {
+	case <-ctx.Done():
+		lookupGroup.Forget(key)
+		return nil, mapErr(ctx.Err())
+	case r := <-ch:
+		return lookupIPReturn(r.Val, r.Err, r.Shared)
+	}
+}
+
+// filterIPAddrs returns the addresses in addrs that belong to
+// network, which is "ip", "ip4" or "ip6".
+func filterIPAddrs(network string, addrs []IPAddr) []IPAddr {
+	if network == "ip" {
+		return addrs
+	}
+	var filtered []IPAddr
+	for _, addr := range addrs {
+		if (addr.IP.To4() != nil) == (network == "ip4") {
+			filtered = append(filtered, addr)
+		}
+	}
+	return filtered
+}
 
 // lookupIPReturn turns the return values from singleflight.Do into
 // the return values from LookupIP.
@@ -229,7 +316,7 @@ func LookupPort(network, service string) (port int, err error) {
 func (r *Resolver) LookupPort(ctx context.Context, network, service string) (port int, err error) {
 	port, needsLookup := parsePort(service)
 	if needsLookup {
-		port, err = lookupPort(ctx, network, service)
+		port, err = r.lookupPort(ctx, network, service)
 		if err != nil {
 			return 0, err
 		}
@@ -245,7 +332,7 @@ func (r *Resolver) LookupPort(ctx context.Context, network, service string) (por
 // LookupHost or LookupIP directly; both take care of resolving
 // the canonical name as part of the lookup.
 func LookupCNAME(name string) (cname string, err error) {
-	return lookupCNAME(context.Background(), name)
+	return DefaultResolver.lookupCNAME(context.Background(), name)
 }
 
 // LookupCNAME returns the canonical DNS host for the given name.
@@ -253,7 +340,7 @@ func LookupCNAME(name string) (cname string, err error) {
 // LookupHost or LookupIP directly; both take care of resolving
 // the canonical name as part of the lookup.
 func (r *Resolver) LookupCNAME(ctx context.Context, name string) (cname string, err error) {
-	return lookupCNAME(ctx, name)
+	return r.lookupCNAME(ctx, name)
 }
 
 // LookupSRV tries to resolve an SRV query of the given service,
@@ -266,7 +353,7 @@ func (r *Resolver) LookupCNAME(ctx context.Context, name string) (cname string,
 // publishing SRV records under non-standard names, if both service
 // and proto are empty strings, LookupSRV looks up name directly.
 func LookupSRV(service, proto, name string) (cname string, addrs []*SRV, err error) {
-	return lookupSRV(context.Background(), service, proto, name)
+	return DefaultResolver.lookupSRV(context.Background(), service, proto, name)
 }
 
 // LookupSRV tries to resolve an SRV query of the given service,
@@ -279,47 +366,58 @@ func LookupSRV(service, proto, name string) (cname string, addrs []*SRV, err err
 // publishing SRV records under non-standard names, if both service
 // and proto are empty strings, LookupSRV looks up name directly.
 func (r *Resolver) LookupSRV(ctx context.Context, service, proto, name string) (cname string, addrs []*SRV, err error) {
-	return lookupSRV(ctx, service, proto, name)
+	return r.lookupSRV(ctx, service, proto, name)
 }
 
 // LookupMX returns the DNS MX records for the given domain name sorted by preference.
 func LookupMX(name string) ([]*MX, error) {
-	return lookupMX(context.Background(), name)
+	return DefaultResolver.lookupMX(context.Background(), name)
 }
 
 // LookupMX returns the DNS MX records for the given domain name sorted by preference.
 func (r *Resolver) LookupMX(ctx context.Context, name string) ([]*MX, error) {
-	return lookupMX(ctx, name)
+	return r.lookupMX(ctx, name)
 }
 
 // LookupNS returns the DNS NS records for the given domain name.
 func LookupNS(name string) ([]*NS, error) {
-	return lookupNS(context.Background(), name)
+	return DefaultResolver.lookupNS(context.Background(), name)
 }
 
 // LookupNS returns the DNS NS records for the given domain name.
 func (r *Resolver) LookupNS(ctx context.Context, name string) ([]*NS, error) {
-	return lookupNS(ctx, name)
+	return r.lookupNS(ctx, name)
 }
 
 // LookupTXT returns the DNS TXT records for the given domain name.
 func LookupTXT(name string) ([]string, error) {
-	return lookupTXT(context.Background(), name)
+	return DefaultResolver.lookupTXT(context.Background(), name)
 }
 
 // LookupTXT returns the DNS TXT records for the given domain name.
 func (r *Resolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
-	return lookupTXT(ctx, name)
+	return r.lookupTXT(ctx, name)
 }
 
 // LookupAddr performs a reverse lookup for the given address, returning a list
 // of names mapping to that address.
 func LookupAddr(addr string) (names []string, err error) {
-	return lookupAddr(context.Background(), addr)
+	return DefaultResolver.lookupAddr(context.Background(), addr)
 }
 
 // LookupAddr performs a reverse lookup for the given address, returning a list
 // of names mapping to that address.
 func (r *Resolver) LookupAddr(ctx context.Context, addr string) (names []string, err error) {
-	return lookupAddr(ctx, addr)
+	return r.lookupAddr(ctx, addr)
+}
+
+// LookupRecords returns the DNS records of the given type for name,
+// following CNAME records. Unlike the other Lookup methods, it reports
+// each record's time to live and supports any record type; see
+// DNSRecord for how record data is decoded.
+//
+// LookupRecords always uses Go's built-in DNS resolver. It is not
+// supported on platforms that lack one.
+func (r *Resolver) LookupRecords(ctx context.Context, name string, typ DNSType) ([]DNSRecord, error) {
+	return r.lookupRecords(ctx, name, typ)
 }
@@ -0,0 +1,212 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import (
+	"internal/syscall/unix"
+	"net/netip"
+	"os"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// An mmsgBuf holds the message headers, I/O vectors and socket
+// addresses for one recvmmsg or sendmmsg call.
+type mmsgBuf struct {
+	hs    []unix.Mmsghdr
+	iovs  []syscall.Iovec
+	names []syscall.RawSockaddrInet6
+}
+
+var mmsgPool = sync.Pool{
+	New: func() interface{} { return new(mmsgBuf) },
+}
+
+func getMmsgBuf(n int) *mmsgBuf {
+	b := mmsgPool.Get().(*mmsgBuf)
+	if cap(b.hs) < n {
+		b.hs = make([]unix.Mmsghdr, n)
+		b.iovs = make([]syscall.Iovec, n)
+		b.names = make([]syscall.RawSockaddrInet6, n)
+	}
+	b.hs, b.iovs, b.names = b.hs[:n], b.iovs[:n], b.names[:n]
+	return b
+}
+
+func putMmsgBuf(b *mmsgBuf) {
+	// Drop the references to the caller's buffers.
+	for i := range b.hs {
+		b.hs[i] = unix.Mmsghdr{}
+		b.iovs[i] = syscall.Iovec{}
+	}
+	mmsgPool.Put(b)
+}
+
+// setMsg points the i'th message header of b at the payload and
+// out-of-band data of m.
+func (b *mmsgBuf) setMsg(i int, m *UDPMessage) {
+	h := &b.hs[i].Hdr
+	iov := &b.iovs[i]
+	if len(m.Buf) > 0 {
+		iov.Base = &m.Buf[0]
+		iov.SetLen(len(m.Buf))
+	}
+	h.Iov = iov
+	h.Iovlen = 1
+	if len(m.OOB) > 0 {
+		h.Control = &m.OOB[0]
+		h.SetControllen(len(m.OOB))
+	}
+}
+
+func (c *UDPConn) readBatch(ms []UDPMessage) (int, error) {
+	b := getMmsgBuf(len(ms))
+	defer putMmsgBuf(b)
+	for i := range ms {
+		b.setMsg(i, &ms[i])
+		b.hs[i].Hdr.Name = (*byte)(unsafe.Pointer(&b.names[i]))
+		b.hs[i].Hdr.Namelen = syscall.SizeofSockaddrInet6
+	}
+	n, err := c.fd.readMsgs(b.hs)
+	for i := 0; i < n; i++ {
+		h := &b.hs[i]
+		ms[i].N = int(h.Len)
+		ms[i].NN = int(h.Hdr.Controllen)
+		ms[i].Flags = int(h.Hdr.Flags)
+		ms[i].Addr = addrPortFromRawSockaddr(&b.names[i], h.Hdr.Namelen)
+	}
+	return n, err
+}
+
+func (c *UDPConn) writeBatch(ms []UDPMessage) (int, error) {
+	if len(ms) == 0 {
+		return 0, nil
+	}
+	b := getMmsgBuf(len(ms))
+	defer putMmsgBuf(b)
+	var err error
+	n := len(ms)
+	for i := range ms {
+		m := &ms[i]
+		if m.Addr.IsValid() {
+			if c.fd.isConnected {
+				err = ErrWriteToConnected
+			} else {
+				var salen uint32
+				salen, err = rawSockaddrFromAddrPort(c.fd.family, m.Addr, &b.names[i])
+				b.hs[i].Hdr.Name = (*byte)(unsafe.Pointer(&b.names[i]))
+				b.hs[i].Hdr.Namelen = salen
+			}
+		} else if !c.fd.isConnected {
+			err = errMissingAddress
+		}
+		if err != nil {
+			n = i
+			break
+		}
+		b.setMsg(i, m)
+	}
+	sent, werr := c.fd.writeMsgs(b.hs[:n])
+	for i := 0; i < sent; i++ {
+		ms[i].N = int(b.hs[i].Len)
+	}
+	if werr != nil {
+		return sent, werr
+	}
+	return sent, err
+}
+
+// addrPortFromRawSockaddr returns the address in rsa, which holds a
+// socket address of namelen bytes.
+func addrPortFromRawSockaddr(rsa *syscall.RawSockaddrInet6, namelen uint32) netip.AddrPort {
+	switch {
+	case rsa.Family == syscall.AF_INET && namelen >= syscall.SizeofSockaddrInet4:
+		sa := (*syscall.RawSockaddrInet4)(unsafe.Pointer(rsa))
+		p := (*[2]byte)(unsafe.Pointer(&sa.Port))
+		return netip.AddrPortFrom(netip.AddrFrom4(sa.Addr), uint16(p[0])<<8|uint16(p[1]))
+	case rsa.Family == syscall.AF_INET6 && namelen >= syscall.SizeofSockaddrInet6:
+		p := (*[2]byte)(unsafe.Pointer(&rsa.Port))
+		ip := netip.AddrFrom16(rsa.Addr).WithZone(zoneToString(int(rsa.Scope_id)))
+		return netip.AddrPortFrom(ip, uint16(p[0])<<8|uint16(p[1]))
+	}
+	return netip.AddrPort{}
+}
+
+// rawSockaddrFromAddrPort stores ap in rsa as a socket address of the
+// given family, and returns the length of the socket address.
+func rawSockaddrFromAddrPort(family int, ap netip.AddrPort, rsa *syscall.RawSockaddrInet6) (uint32, error) {
+	ip := ap.Addr()
+	switch family {
+	case syscall.AF_INET:
+		ip = ip.Unmap()
+		if !ip.Is4() {
+			return 0, &AddrError{Err: "non-IPv4 address", Addr: ip.String()}
+		}
+		sa := (*syscall.RawSockaddrInet4)(unsafe.Pointer(rsa))
+		*sa = syscall.RawSockaddrInet4{Family: syscall.AF_INET, Addr: ip.As4()}
+		p := (*[2]byte)(unsafe.Pointer(&sa.Port))
+		p[0], p[1] = byte(ap.Port()>>8), byte(ap.Port())
+		return syscall.SizeofSockaddrInet4, nil
+	case syscall.AF_INET6:
+		*rsa = syscall.RawSockaddrInet6{
+			Family:   syscall.AF_INET6,
+			Addr:     ip.As16(),
+			Scope_id: uint32(zoneToInt(ip.Zone())),
+		}
+		p := (*[2]byte)(unsafe.Pointer(&rsa.Port))
+		p[0], p[1] = byte(ap.Port()>>8), byte(ap.Port())
+		return syscall.SizeofSockaddrInet6, nil
+	}
+	return 0, &AddrError{Err: "invalid address family", Addr: ip.String()}
+}
+
+func (fd *netFD) readMsgs(hs []unix.Mmsghdr) (int, error) {
+	if err := fd.readLock(); err != nil {
+		return 0, err
+	}
+	defer fd.readUnlock()
+	if err := fd.pd.prepareRead(); err != nil {
+		return 0, err
+	}
+	for {
+		n, err := unix.Recvmmsg(fd.sysfd, hs, 0)
+		if err == syscall.EAGAIN {
+			if err = fd.pd.waitRead(); err == nil {
+				continue
+			}
+			return 0, err
+		}
+		if err != nil {
+			return 0, os.NewSyscallError("recvmmsg", err)
+		}
+		return n, nil
+	}
+}
+
+func (fd *netFD) writeMsgs(hs []unix.Mmsghdr) (int, error) {
+	if err := fd.writeLock(); err != nil {
+		return 0, err
+	}
+	defer fd.writeUnlock()
+	if err := fd.pd.prepareWrite(); err != nil {
+		return 0, err
+	}
+	var sent int
+	for sent < len(hs) {
+		n, err := unix.Sendmmsg(fd.sysfd, hs[sent:], 0)
+		if err == syscall.EAGAIN {
+			if err = fd.pd.waitWrite(); err == nil {
+				continue
+			}
+			return sent, err
+		}
+		if err != nil {
+			return sent, os.NewSyscallError("sendmmsg", err)
+		}
+		sent += n
+	}
+	return sent, nil
+}
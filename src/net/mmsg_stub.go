@@ -0,0 +1,34 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package net
+
+func (c *UDPConn) readBatch(ms []UDPMessage) (int, error) {
+	m := &ms[0]
+	n, oobn, flags, addr, err := c.readMsg(m.Buf, m.OOB)
+	if err != nil {
+		return 0, err
+	}
+	m.N, m.NN, m.Flags = n, oobn, flags
+	m.Addr = addr.AddrPort()
+	return 1, nil
+}
+
+func (c *UDPConn) writeBatch(ms []UDPMessage) (int, error) {
+	for i := range ms {
+		m := &ms[i]
+		var addr *UDPAddr
+		if m.Addr.IsValid() {
+			addr = UDPAddrFromAddrPort(m.Addr)
+		}
+		n, _, err := c.writeMsg(m.Buf, m.OOB, addr)
+		if err != nil {
+			return i, err
+		}
+		m.N = n
+	}
+	return len(ms), nil
+}
@@ -0,0 +1,129 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import (
+	"context"
+	"internal/syscall/unix"
+	"os"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	sysIPPROTO_MPTCP = 0x106
+	sysSOL_MPTCP     = 0x11c
+	sysMPTCP_INFO    = 0x1
+)
+
+var (
+	mptcpOnce      sync.Once
+	mptcpAvailable bool
+	hasSOLMPTCP    bool
+)
+
+// initMPTCPavailable records whether the kernel lets us create MPTCP
+// sockets, and whether it supports the SOL_MPTCP socket options.
+func initMPTCPavailable() {
+	// The socket fails with EPROTONOSUPPORT or EINVAL if the kernel
+	// lacks MPTCP, and with ENOPROTOOPT if it is disabled with the
+	// net.mptcp.enabled sysctl. The probe bypasses the socket test
+	// hooks so that it does not show up in their statistics.
+	if s, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_STREAM|syscall.SOCK_CLOEXEC, sysIPPROTO_MPTCP); err == nil {
+		syscall.Close(s)
+		mptcpAvailable = true
+	}
+	major, minor := kernelVersion()
+	// SOL_MPTCP options arrived in Linux 5.16.
+	hasSOLMPTCP = major > 5 || major == 5 && minor >= 16
+}
+
+func dialMPTCP(ctx context.Context, net string, laddr, raddr *TCPAddr, ctrlFn func(string, string, syscall.RawConn) error) (*TCPConn, error) {
+	mptcpOnce.Do(initMPTCPavailable)
+	if !mptcpAvailable || testHookDialTCP != nil {
+		return dialTCP(ctx, net, laddr, raddr, ctrlFn)
+	}
+	c, err := doDialTCPProto(ctx, net, laddr, raddr, sysIPPROTO_MPTCP, ctrlFn)
+	if err != nil && mptcpUnsupported(err) {
+		return doDialTCP(ctx, net, laddr, raddr, ctrlFn)
+	}
+	return c, err
+}
+
+func listenMPTCP(ctx context.Context, network string, laddr *TCPAddr, ctrlFn func(string, string, syscall.RawConn) error) (*TCPListener, error) {
+	mptcpOnce.Do(initMPTCPavailable)
+	if !mptcpAvailable {
+		return listenTCP(ctx, network, laddr, ctrlFn)
+	}
+	ln, err := listenTCPProto(ctx, network, laddr, sysIPPROTO_MPTCP, ctrlFn)
+	if err != nil && mptcpUnsupported(err) {
+		return listenTCP(ctx, network, laddr, ctrlFn)
+	}
+	return ln, err
+}
+
+// mptcpUnsupported reports whether err, from setting up an MPTCP
+// socket, means that the kernel does not create MPTCP sockets for the
+// address family, in which case plain TCP is used instead. Other
+// errors, such as a refused connection, are returned as they are.
+func mptcpUnsupported(err error) bool {
+	se, ok := err.(*os.SyscallError)
+	if !ok || se.Syscall != "socket" {
+		return false
+	}
+	switch se.Err {
+	case syscall.EPROTONOSUPPORT, syscall.ENOPROTOOPT, syscall.EINVAL:
+		return true
+	}
+	return false
+}
+
+// isUsingMultipathTCP reports whether fd is an MPTCP socket that has
+// not fallen back to plain TCP.
+func isUsingMultipathTCP(fd *netFD) bool {
+	if err := fd.incref(); err != nil {
+		return false
+	}
+	defer fd.decref()
+	proto, err := syscall.GetsockoptInt(fd.sysfd, syscall.SOL_SOCKET, syscall.SO_PROTOCOL)
+	if err != nil || proto != sysIPPROTO_MPTCP {
+		return false
+	}
+	mptcpOnce.Do(initMPTCPavailable)
+	if !hasSOLMPTCP {
+		// Older kernels cannot tell us about a fallback.
+		return true
+	}
+	// MPTCP_INFO fails with EOPNOTSUPP (IPv4) or ENOPROTOOPT
+	// (IPv6) once the connection has fallen back to TCP.
+	var info [64]byte
+	n := uint32(len(info))
+	err = unix.Getsockopt(fd.sysfd, sysSOL_MPTCP, sysMPTCP_INFO, unsafe.Pointer(&info[0]), &n)
+	return err != syscall.EOPNOTSUPP && err != syscall.ENOPROTOOPT
+}
+
+// kernelVersion returns the major and minor version of the running
+// Linux kernel, or 0, 0 if it cannot be determined.
+func kernelVersion() (major, minor int) {
+	var uts syscall.Utsname
+	if err := syscall.Uname(&uts); err != nil {
+		return 0, 0
+	}
+	var v [2]int
+	i := 0
+	for _, c := range uts.Release {
+		b := byte(c)
+		switch {
+		case '0' <= b && b <= '9':
+			v[i] = v[i]*10 + int(b-'0')
+		case b == '.' && i == 0:
+			i++
+		default:
+			return v[0], v[1]
+		}
+	}
+	return v[0], v[1]
+}
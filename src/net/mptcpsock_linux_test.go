@@ -0,0 +1,148 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestMultipathTCP(t *testing.T) {
+	mptcpOnce.Do(initMPTCPavailable)
+	if !mptcpAvailable {
+		t.Skip("MPTCP is not available")
+	}
+
+	tests := []struct {
+		listen, dial bool
+	}{
+		{true, true},
+		{true, false},
+		{false, true},
+		{false, false},
+	}
+	for _, tt := range tests {
+		var lc ListenConfig
+		lc.SetMultipathTCP(tt.listen)
+		if lc.MultipathTCP() != tt.listen {
+			t.Fatalf("ListenConfig.MultipathTCP() = %v; want %v", lc.MultipathTCP(), tt.listen)
+		}
+		ln, err := lc.Listen(context.Background(), "tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ch := make(chan *TCPConn, 1)
+		go func() {
+			c, err := ln.Accept()
+			if err != nil {
+				t.Error(err)
+				ch <- nil
+				return
+			}
+			ch <- c.(*TCPConn)
+		}()
+
+		var d Dialer
+		d.SetMultipathTCP(tt.dial)
+		if d.MultipathTCP() != tt.dial {
+			t.Fatalf("Dialer.MultipathTCP() = %v; want %v", d.MultipathTCP(), tt.dial)
+		}
+		c, err := d.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			ln.Close()
+			t.Fatal(err)
+		}
+		sc := <-ch
+		ln.Close()
+		if sc == nil {
+			c.Close()
+			t.FailNow()
+		}
+
+		// MPTCP is in use only if both ends asked for it.
+		want := tt.listen && tt.dial
+		if got, err := c.(*TCPConn).MultipathTCP(); err != nil || got != want {
+			t.Errorf("listen=%v dial=%v: client MultipathTCP() = %v, %v; want %v, nil", tt.listen, tt.dial, got, err, want)
+		}
+		if got, err := sc.MultipathTCP(); err != nil || got != want {
+			t.Errorf("listen=%v dial=%v: server MultipathTCP() = %v, %v; want %v, nil", tt.listen, tt.dial, got, err, want)
+		}
+
+		if _, err := c.Write([]byte("hello")); err != nil {
+			t.Error(err)
+		}
+		var b [5]byte
+		if _, err := sc.Read(b[:]); err != nil || string(b[:]) != "hello" {
+			t.Errorf("got %q, %v; want %q, nil", b[:], err, "hello")
+		}
+		c.Close()
+		sc.Close()
+	}
+}
+
+func TestMultipathTCPFallback(t *testing.T) {
+	mptcpOnce.Do(initMPTCPavailable)
+	origAvailable := mptcpAvailable
+	origSocket := socketFunc
+	defer func() {
+		mptcpAvailable = origAvailable
+		socketFunc = origSocket
+	}()
+	mptcpAvailable = true
+
+	for _, tt := range []struct {
+		err      error
+		fallback bool
+	}{
+		{syscall.EPROTONOSUPPORT, true},
+		{syscall.ENOPROTOOPT, true},
+		{syscall.EINVAL, true},
+		{syscall.EACCES, false},
+		{syscall.EMFILE, false},
+	} {
+		socketFunc = func(family, sotype, proto int) (int, error) {
+			if proto == sysIPPROTO_MPTCP {
+				return -1, tt.err
+			}
+			return origSocket(family, sotype, proto)
+		}
+		var lc ListenConfig
+		lc.SetMultipathTCP(true)
+		ln, err := lc.Listen(context.Background(), "tcp", "127.0.0.1:0")
+		if !tt.fallback {
+			if err == nil {
+				ln.Close()
+				t.Errorf("%v: Listen succeeded; want error", tt.err)
+			} else if oe, ok := err.(*OpError); !ok {
+				t.Errorf("%v: Listen error = %v; want OpError", tt.err, err)
+			} else if se, ok := oe.Err.(*os.SyscallError); !ok || se.Err != tt.err {
+				t.Errorf("%v: Listen error = %v", tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", tt.err, err)
+			continue
+		}
+		d := Dialer{}
+		d.SetMultipathTCP(true)
+		c, err := d.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Errorf("%v: %v", tt.err, err)
+		} else {
+			c.Close()
+		}
+		ln.Close()
+	}
+}
+
+func TestKernelVersion(t *testing.T) {
+	major, minor := kernelVersion()
+	if major < 2 || minor < 0 {
+		t.Errorf("kernelVersion() = %d, %d", major, minor)
+	}
+}
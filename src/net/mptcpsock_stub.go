@@ -0,0 +1,22 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package net
+
+import (
+	"context"
+	"syscall"
+)
+
+func dialMPTCP(ctx context.Context, net string, laddr, raddr *TCPAddr, ctrlFn func(string, string, syscall.RawConn) error) (*TCPConn, error) {
+	return dialTCP(ctx, net, laddr, raddr, ctrlFn)
+}
+
+func listenMPTCP(ctx context.Context, network string, laddr *TCPAddr, ctrlFn func(string, string, syscall.RawConn) error) (*TCPListener, error) {
+	return listenTCP(ctx, network, laddr, ctrlFn)
+}
+
+func isUsingMultipathTCP(fd *netFD) bool { return false }
@@ -192,6 +192,53 @@ func (c *conn) Write(b []byte) (int, error) {
 	return n, err
 }
 
+// ReadContext is like Read, but if ctx is done before the read
+// completes, it interrupts the read and returns ctx.Err(). The
+// connection stays open, and data not yet read stays queued for the
+// next read. Only this read is interrupted: other reads and writes on
+// the connection are not affected. If another read is in progress,
+// ReadContext waits for it to finish before ctx is watched.
+func (c *conn) ReadContext(ctx context.Context, b []byte) (int, error) {
+	if !c.ok() {
+		return 0, syscall.EINVAL
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, interrupted, err := c.fd.readContext(ctx, b)
+	if interrupted && err == errInterrupted {
+		return n, ctx.Err()
+	}
+	if err != nil && err != io.EOF {
+		err = &OpError{Op: "read", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
+	}
+	return n, err
+}
+
+// WriteContext is like Write, but if ctx is done before the write
+// completes, it interrupts the write and returns ctx.Err(). The
+// connection stays open. As with a write that times out, n may be
+// positive if some of b was written. Only this write is interrupted:
+// other reads and writes on the connection are not affected. If
+// another write is in progress, WriteContext waits for it to finish
+// before ctx is watched.
+func (c *conn) WriteContext(ctx context.Context, b []byte) (int, error) {
+	if !c.ok() {
+		return 0, syscall.EINVAL
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, interrupted, err := c.fd.writeContext(ctx, b)
+	if interrupted && err == errInterrupted {
+		return n, ctx.Err()
+	}
+	if err != nil {
+		err = &OpError{Op: "write", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
+	}
+	return n, err
+}
+
 // Close closes the connection.
 func (c *conn) Close() error {
 	if !c.ok() {
@@ -378,6 +425,7 @@ var (
 	errTimeout          error = &timeoutError{}
 	errCanceled               = errors.New("operation was canceled")
 	errClosing                = errors.New("use of closed network connection")
+	errInterrupted            = errors.New("i/o interrupted by done context")
 	ErrWriteToConnected       = errors.New("use of WriteTo with pre-connected connection")
 )
 
@@ -0,0 +1,470 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netip_test
+
+import (
+	"encoding"
+	"encoding/json"
+	"net"
+	. "net/netip"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+var parseAddrTests = []struct {
+	in   string
+	want Addr
+	str  string // expected String; empty means in
+}{
+	{"0.0.0.0", IPv4Unspecified(), ""},
+	{"192.168.140.255", AddrFrom4([4]byte{192, 168, 140, 255}), ""},
+	{"::", IPv6Unspecified(), ""},
+	{"::1", AddrFrom16([16]byte{15: 1}), ""},
+	{"fd7a:115c:a1e0:ab12:4843:cd96:626b:430b", AddrFrom16([16]byte{0xfd, 0x7a, 0x11, 0x5c, 0xa1, 0xe0, 0xab, 0x12, 0x48, 0x43, 0xcd, 0x96, 0x62, 0x6b, 0x43, 0x0b}), ""},
+	{"fd7a:115c::626b:430b", AddrFrom16([16]byte{0xfd, 0x7a, 0x11, 0x5c, 12: 0x62, 13: 0x6b, 14: 0x43, 15: 0x0b}), ""},
+	{"FD9E:1A04:F01D::1", AddrFrom16([16]byte{0xfd, 0x9e, 0x1a, 0x04, 0xf0, 0x1d, 15: 1}), "fd9e:1a04:f01d::1"},
+	{"fe80::1cc0:3e8c:119f:c2e1%ens18", AddrFrom16([16]byte{0xfe, 0x80, 8: 0x1c, 9: 0xc0, 10: 0x3e, 11: 0x8c, 12: 0x11, 13: 0x9f, 14: 0xc2, 15: 0xe1}).WithZone("ens18"), ""},
+	{"::ffff:192.168.140.255", AddrFrom16([16]byte{10: 0xff, 11: 0xff, 12: 192, 13: 168, 14: 140, 15: 255}), ""},
+	{"::ffff:c0a8:8cff", AddrFrom16([16]byte{10: 0xff, 11: 0xff, 12: 192, 13: 168, 14: 140, 15: 255}), "::ffff:192.168.140.255"},
+	{"1:0:0:2:0:0:0:3", AddrFrom16([16]byte{1: 1, 7: 2, 15: 3}), "1:0:0:2::3"},
+	{"2001:db8:0:0:1:0:0:1", AddrFrom16([16]byte{0x20, 0x01, 0x0d, 0xb8, 9: 1, 15: 1}), "2001:db8::1:0:0:1"},
+}
+
+var invalidAddrs = []string{
+	"",
+	"bad",
+	"1234",
+	"1a.2.3.4",
+	"1.2.3.4.5",
+	"1.2.3",
+	"1.2.3.256",
+	"01.2.3.4",
+	"1..2.3",
+	"1.2.3.4%eth0",
+	"%eth0",
+	"fe80::1%",
+	"1:2:3:4:5:6:7",
+	"1:2:3:4:5:6:7:8:9",
+	"1::2::3",
+	"12345::",
+	"1:2:3:4:5:6:7::8",
+	":1:2::3",
+	"1:2::3:",
+	"::ffff:1.2.3",
+	"1:2:3:4:5:6:1.2.3.4:5",
+	"fe80:1?:1",
+}
+
+func TestParseAddr(t *testing.T) {
+	for _, tt := range parseAddrTests {
+		got, err := ParseAddr(tt.in)
+		if err != nil {
+			t.Errorf("ParseAddr(%q) = %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseAddr(%q) = %#v; want %#v", tt.in, got, tt.want)
+		}
+		want := tt.str
+		if want == "" {
+			want = tt.in
+		}
+		if s := got.String(); s != want {
+			t.Errorf("ParseAddr(%q).String() = %q; want %q", tt.in, s, want)
+		}
+		if got2, err := ParseAddr(got.String()); err != nil || got2 != got {
+			t.Errorf("round trip of %q = %v, %v", tt.in, got2, err)
+		}
+	}
+	for _, s := range invalidAddrs {
+		if got, err := ParseAddr(s); err == nil {
+			t.Errorf("ParseAddr(%q) = %v; want error", s, got)
+		}
+	}
+}
+
+func TestAddrProperties(t *testing.T) {
+	for _, tt := range []struct {
+		ip                                                       Addr
+		bitLen                                                   int
+		is4, is6, is4In6                                         bool
+		loopback, multicast, llu, llm, private, unspecified, gua bool
+	}{
+		{Addr{}, 0, false, false, false, false, false, false, false, false, false, false},
+		{MustParseAddr("127.0.0.1"), 32, true, false, false, true, false, false, false, false, false, false},
+		{MustParseAddr("::1"), 128, false, true, false, true, false, false, false, false, false, false},
+		{MustParseAddr("224.0.0.1"), 32, true, false, false, false, true, false, true, false, false, false},
+		{MustParseAddr("ff02::1"), 128, false, true, false, false, true, false, true, false, false, false},
+		{MustParseAddr("169.254.1.1"), 32, true, false, false, false, false, true, false, false, false, false},
+		{MustParseAddr("fe80::1%eth0"), 128, false, true, false, false, false, true, false, false, false, false},
+		{MustParseAddr("10.1.2.3"), 32, true, false, false, false, false, false, false, true, false, true},
+		{MustParseAddr("172.31.0.1"), 32, true, false, false, false, false, false, false, true, false, true},
+		{MustParseAddr("fd00::1"), 128, false, true, false, false, false, false, false, true, false, true},
+		{MustParseAddr("0.0.0.0"), 32, true, false, false, false, false, false, false, false, true, false},
+		{MustParseAddr("::"), 128, false, true, false, false, false, false, false, false, true, false},
+		{MustParseAddr("8.8.8.8"), 32, true, false, false, false, false, false, false, false, false, true},
+		{MustParseAddr("::ffff:8.8.8.8"), 128, false, true, true, false, false, false, false, false, false, true},
+		{MustParseAddr("2001:db8::1"), 128, false, true, false, false, false, false, false, false, false, true},
+	} {
+		ip := tt.ip
+		if ip.BitLen() != tt.bitLen || ip.Is4() != tt.is4 || ip.Is6() != tt.is6 || ip.Is4In6() != tt.is4In6 {
+			t.Errorf("%v: BitLen, Is4, Is6, Is4In6 = %d, %v, %v, %v", ip, ip.BitLen(), ip.Is4(), ip.Is6(), ip.Is4In6())
+		}
+		if ip.IsValid() != (tt.bitLen != 0) {
+			t.Errorf("%v: IsValid = %v", ip, ip.IsValid())
+		}
+		if ip.IsLoopback() != tt.loopback {
+			t.Errorf("%v: IsLoopback = %v", ip, ip.IsLoopback())
+		}
+		if ip.IsMulticast() != tt.multicast {
+			t.Errorf("%v: IsMulticast = %v", ip, ip.IsMulticast())
+		}
+		if ip.IsLinkLocalUnicast() != tt.llu {
+			t.Errorf("%v: IsLinkLocalUnicast = %v", ip, ip.IsLinkLocalUnicast())
+		}
+		if ip.IsLinkLocalMulticast() != tt.llm {
+			t.Errorf("%v: IsLinkLocalMulticast = %v", ip, ip.IsLinkLocalMulticast())
+		}
+		if ip.IsPrivate() != tt.private {
+			t.Errorf("%v: IsPrivate = %v", ip, ip.IsPrivate())
+		}
+		if ip.IsUnspecified() != tt.unspecified {
+			t.Errorf("%v: IsUnspecified = %v", ip, ip.IsUnspecified())
+		}
+		if ip.IsGlobalUnicast() != tt.gua {
+			t.Errorf("%v: IsGlobalUnicast = %v", ip, ip.IsGlobalUnicast())
+		}
+	}
+}
+
+func TestAddrComparable(t *testing.T) {
+	m := map[Addr]int{
+		MustParseAddr("192.0.2.1"):      1,
+		MustParseAddr("2001:db8::1"):    2,
+		MustParseAddr("fe80::1%eth0"):   3,
+		MustParseAddr("fe80::1%eth1"):   4,
+		MustParseAddr("::ffff:1.2.3.4"): 5,
+	}
+	for s, want := range map[string]int{
+		"192.0.2.1":                    1,
+		"2001:0db8:0000::1":            2,
+		"fe80::1%eth0":                 3,
+		"fe80::1%eth1":                 4,
+		"0:0:0:0:0:ffff:0102:0304":     5,
+		"1.2.3.4":                      0,
+		"fe80::1":                      0,
+		"0000:0000:0000::ffff:1.2.3.5": 0,
+	} {
+		if got := m[MustParseAddr(s)]; got != want {
+			t.Errorf("m[%q] = %d; want %d", s, got, want)
+		}
+	}
+	if MustParseAddr("::ffff:1.2.3.4").Unmap() != MustParseAddr("1.2.3.4") {
+		t.Error("Unmap of ::ffff:1.2.3.4 != 1.2.3.4")
+	}
+	if a := MustParseAddr("fe80::1%eth0"); a.Zone() != "eth0" || a.WithZone("") != MustParseAddr("fe80::1") {
+		t.Errorf("zone handling of %v is broken", a)
+	}
+	if a := MustParseAddr("1.2.3.4"); a.WithZone("eth0") != a {
+		t.Error("WithZone changed an IPv4 address")
+	}
+}
+
+func TestAddrSort(t *testing.T) {
+	ips := []Addr{
+		MustParseAddr("fe80::1%eth0"),
+		MustParseAddr("::1"),
+		MustParseAddr("10.0.0.2"),
+		MustParseAddr("fe80::1"),
+		Addr{},
+		MustParseAddr("10.0.0.1"),
+	}
+	sort.Slice(ips, func(i, j int) bool { return ips[i].Less(ips[j]) })
+	want := []Addr{
+		Addr{},
+		MustParseAddr("10.0.0.1"),
+		MustParseAddr("10.0.0.2"),
+		MustParseAddr("::1"),
+		MustParseAddr("fe80::1"),
+		MustParseAddr("fe80::1%eth0"),
+	}
+	if !reflect.DeepEqual(ips, want) {
+		t.Errorf("got %v; want %v", ips, want)
+	}
+}
+
+func TestAddrNextPrev(t *testing.T) {
+	for _, tt := range []struct {
+		ip, next string
+	}{
+		{"10.0.0.255", "10.0.1.0"},
+		{"::ffff", "::1:0"},
+		{"0:0:0:0:ffff:ffff:ffff:ffff", "0:0:0:1::"},
+	} {
+		ip, next := MustParseAddr(tt.ip), MustParseAddr(tt.next)
+		if got := ip.Next(); got != next {
+			t.Errorf("%v.Next() = %v; want %v", ip, got, next)
+		}
+		if got := next.Prev(); got != ip {
+			t.Errorf("%v.Prev() = %v; want %v", next, got, ip)
+		}
+	}
+	if got := MustParseAddr("255.255.255.255").Next(); got.IsValid() {
+		t.Errorf("Next of broadcast = %v; want zero Addr", got)
+	}
+	if got := MustParseAddr("ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff").Next(); got.IsValid() {
+		t.Errorf("Next of last IPv6 address = %v; want zero Addr", got)
+	}
+	if got := IPv4Unspecified().Prev(); got.IsValid() {
+		t.Errorf("Prev of 0.0.0.0 = %v; want zero Addr", got)
+	}
+	if got := IPv6Unspecified().Prev(); got.IsValid() {
+		t.Errorf("Prev of :: = %v; want zero Addr", got)
+	}
+}
+
+func TestAddrFromSlice(t *testing.T) {
+	for _, tt := range []struct {
+		ip   net.IP
+		want Addr
+		ok   bool
+	}{
+		{net.IP{192, 0, 2, 1}, MustParseAddr("192.0.2.1"), true},
+		{net.ParseIP("192.0.2.1"), MustParseAddr("::ffff:192.0.2.1"), true},
+		{net.ParseIP("2001:db8::1"), MustParseAddr("2001:db8::1"), true},
+		{net.IP{1, 2, 3}, Addr{}, false},
+		{nil, Addr{}, false},
+	} {
+		got, ok := AddrFromSlice(tt.ip)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("AddrFromSlice(%v) = %v, %v; want %v, %v", tt.ip, got, ok, tt.want, tt.ok)
+		}
+		if ok && !net.IP(got.AsSlice()).Equal(tt.ip) {
+			t.Errorf("AsSlice of %v = %v", got, got.AsSlice())
+		}
+	}
+}
+
+func TestParseAddrPort(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want AddrPort
+		ok   bool
+	}{
+		{"1.2.3.4:1234", AddrPortFrom(MustParseAddr("1.2.3.4"), 1234), true},
+		{"1.1.1.1:65535", AddrPortFrom(MustParseAddr("1.1.1.1"), 65535), true},
+		{"[::1]:0", AddrPortFrom(MustParseAddr("::1"), 0), true},
+		{"[fe80::1%eth0]:80", AddrPortFrom(MustParseAddr("fe80::1%eth0"), 80), true},
+		{"[::ffff:1.2.3.4]:80", AddrPortFrom(MustParseAddr("::ffff:1.2.3.4"), 80), true},
+		{"1.1.1.1:65536", AddrPort{}, false},
+		{"1.1.1.1:-1", AddrPort{}, false},
+		{"1.1.1.1", AddrPort{}, false},
+		{"1.1.1.1:", AddrPort{}, false},
+		{":80", AddrPort{}, false},
+		{"[1.1.1.1]:80", AddrPort{}, false},
+		{"::1:80", AddrPort{}, false},
+		{"[::1:80", AddrPort{}, false},
+		{"localhost:80", AddrPort{}, false},
+	} {
+		got, err := ParseAddrPort(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseAddrPort(%q) = %v, %v; want %v, ok=%v", tt.in, got, err, tt.want, tt.ok)
+			continue
+		}
+		if tt.ok && got.String() != tt.in {
+			t.Errorf("ParseAddrPort(%q).String() = %q", tt.in, got.String())
+		}
+	}
+}
+
+func TestParsePrefix(t *testing.T) {
+	for _, tt := range []struct {
+		in     string
+		ip     Addr
+		bits   int
+		masked string
+	}{
+		{"192.168.0.0/24", MustParseAddr("192.168.0.0"), 24, "192.168.0.0/24"},
+		{"192.168.0.7/24", MustParseAddr("192.168.0.7"), 24, "192.168.0.0/24"},
+		{"0.0.0.0/0", MustParseAddr("0.0.0.0"), 0, "0.0.0.0/0"},
+		{"10.1.2.3/32", MustParseAddr("10.1.2.3"), 32, "10.1.2.3/32"},
+		{"2001:db8::1/32", MustParseAddr("2001:db8::1"), 32, "2001:db8::/32"},
+		{"::ffff:10.1.2.3/120", MustParseAddr("::ffff:10.1.2.3"), 120, "::ffff:10.1.2.0/120"},
+		{"2001:db8::1/128", MustParseAddr("2001:db8::1"), 128, "2001:db8::1/128"},
+	} {
+		p, err := ParsePrefix(tt.in)
+		if err != nil {
+			t.Errorf("ParsePrefix(%q) = %v", tt.in, err)
+			continue
+		}
+		if p.Addr() != tt.ip || p.Bits() != tt.bits || !p.IsValid() {
+			t.Errorf("ParsePrefix(%q) = %v, %d", tt.in, p.Addr(), p.Bits())
+		}
+		if p.String() != tt.in {
+			t.Errorf("ParsePrefix(%q).String() = %q", tt.in, p.String())
+		}
+		if s := p.Masked().String(); s != tt.masked {
+			t.Errorf("ParsePrefix(%q).Masked() = %q; want %q", tt.in, s, tt.masked)
+		}
+	}
+	for _, s := range []string{
+		"",
+		"192.168.0.0",
+		"192.168.0.0/",
+		"192.168.0.0/33",
+		"192.168.0.0/-1",
+		"192.168.0.0/+1",
+		"192.168.0.0/08",
+		"2001:db8::/129",
+		"fe80::1%eth0/64",
+		"foo/8",
+	} {
+		if p, err := ParsePrefix(s); err == nil {
+			t.Errorf("ParsePrefix(%q) = %v; want error", s, p)
+		}
+	}
+}
+
+func TestPrefixContainsOverlaps(t *testing.T) {
+	for _, tt := range []struct {
+		p    string
+		ip   string
+		want bool
+	}{
+		{"192.168.0.0/24", "192.168.0.1", true},
+		{"192.168.0.0/24", "192.168.1.1", false},
+		{"192.168.0.0/24", "::ffff:192.168.0.1", false},
+		{"0.0.0.0/0", "8.8.8.8", true},
+		{"0.0.0.0/0", "::1", false},
+		{"2001:db8::/32", "2001:db8:1::1", true},
+		{"2001:db8::/32", "2001:db9::1", false},
+		{"fe80::/64", "fe80::1%eth0", false},
+		{"::/0", "2001:db8::1", true},
+		{"10.0.0.1/32", "10.0.0.1", true},
+		{"10.0.0.1/32", "10.0.0.2", false},
+	} {
+		p, ip := MustParsePrefix(tt.p), MustParseAddr(tt.ip)
+		if got := p.Contains(ip); got != tt.want {
+			t.Errorf("%v.Contains(%v) = %v; want %v", p, ip, got, tt.want)
+		}
+	}
+	if (Prefix{}).Contains(MustParseAddr("1.2.3.4")) {
+		t.Error("zero Prefix contains an address")
+	}
+
+	for _, tt := range []struct {
+		a, b string
+		want bool
+	}{
+		{"10.0.0.0/8", "10.1.0.0/16", true},
+		{"10.1.0.0/16", "10.0.0.0/8", true},
+		{"10.0.0.0/16", "10.1.0.0/16", false},
+		{"0.0.0.0/0", "192.168.0.0/24", true},
+		{"0.0.0.0/0", "::/0", false},
+		{"2001:db8::/32", "2001:db8:1::/48", true},
+		{"2001:db8::/48", "2001:db8:1::/48", false},
+	} {
+		a, b := MustParsePrefix(tt.a), MustParsePrefix(tt.b)
+		if got := a.Overlaps(b); got != tt.want {
+			t.Errorf("%v.Overlaps(%v) = %v; want %v", a, b, got, tt.want)
+		}
+	}
+}
+
+func TestAddrPrefix(t *testing.T) {
+	p, err := MustParseAddr("10.1.2.3").Prefix(20)
+	if err != nil || p != MustParsePrefix("10.1.0.0/20") {
+		t.Errorf("Prefix(20) = %v, %v", p, err)
+	}
+	p, err = MustParseAddr("fe80::1%eth0").Prefix(64)
+	if err != nil || p != MustParsePrefix("fe80::/64") {
+		t.Errorf("Prefix(64) = %v, %v", p, err)
+	}
+	if _, err := MustParseAddr("10.1.2.3").Prefix(33); err == nil {
+		t.Error("Prefix(33) of an IPv4 address should fail")
+	}
+	if _, err := MustParseAddr("::1").Prefix(-1); err == nil {
+		t.Error("Prefix(-1) should fail")
+	}
+	if p := PrefixFrom(MustParseAddr("10.1.2.3"), 33); p.IsValid() || p.Bits() != -1 {
+		t.Errorf("PrefixFrom with bad bits = %v", p)
+	}
+	if !MustParsePrefix("10.1.2.3/32").IsSingleIP() || MustParsePrefix("10.1.2.3/31").IsSingleIP() {
+		t.Error("IsSingleIP is broken")
+	}
+}
+
+func TestTextMarshal(t *testing.T) {
+	for _, tt := range []struct {
+		v    encoding.TextMarshaler
+		u    encoding.TextUnmarshaler
+		want string
+	}{
+		{MustParseAddr("1.2.3.4"), new(Addr), "1.2.3.4"},
+		{MustParseAddr("fe80::1%eth0"), new(Addr), "fe80::1%eth0"},
+		{Addr{}, new(Addr), ""},
+		{MustParseAddrPort("[::1]:80"), new(AddrPort), "[::1]:80"},
+		{AddrPort{}, new(AddrPort), ""},
+		{MustParsePrefix("10.0.0.0/8"), new(Prefix), "10.0.0.0/8"},
+		{Prefix{}, new(Prefix), ""},
+	} {
+		b, err := tt.v.MarshalText()
+		if err != nil || string(b) != tt.want {
+			t.Errorf("%#v.MarshalText() = %q, %v; want %q", tt.v, b, err, tt.want)
+			continue
+		}
+		if err := tt.u.UnmarshalText(b); err != nil {
+			t.Errorf("UnmarshalText(%q) = %v", b, err)
+			continue
+		}
+		if got := reflect.ValueOf(tt.u).Elem().Interface(); got != tt.v {
+			t.Errorf("UnmarshalText(%q) = %v; want %v", b, got, tt.v)
+		}
+	}
+
+	var v struct {
+		A Addr
+		P Prefix
+	}
+	if err := json.Unmarshal([]byte(`{"A":"2001:db8::1","P":"192.0.2.0/24"}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.A != MustParseAddr("2001:db8::1") || v.P != MustParsePrefix("192.0.2.0/24") {
+		t.Errorf("json.Unmarshal = %+v", v)
+	}
+	if err := json.Unmarshal([]byte(`{"A":"bogus"}`), &v); err == nil {
+		t.Error("json.Unmarshal of bad address should fail")
+	}
+}
+
+func TestParseAddrAllocs(t *testing.T) {
+	for _, s := range []string{"192.168.1.1", "2001:db8::1", "::ffff:10.0.0.1"} {
+		if n := testing.AllocsPerRun(1000, func() {
+			ParseAddr(s)
+		}); n != 0 {
+			t.Errorf("ParseAddr(%q) allocates %v times; want 0", s, n)
+		}
+	}
+	ap := MustParseAddrPort("192.168.1.1:80")
+	if n := testing.AllocsPerRun(1000, func() {
+		ParseAddrPort("192.168.1.1:80")
+		ap.Addr().Is4()
+		MustParsePrefix("192.168.0.0/16").Contains(ap.Addr())
+	}); n != 0 {
+		t.Errorf("AddrPort and Prefix operations allocate %v times; want 0", n)
+	}
+}
+
+func BenchmarkParseAddr(b *testing.B) {
+	for _, s := range []string{"192.168.1.1", "2001:db8::1", "fe80::1%eth0"} {
+		b.Run(s, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				ParseAddr(s)
+			}
+		})
+	}
+}
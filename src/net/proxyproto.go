@@ -0,0 +1,606 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// This file implements version 1 and 2 of the PROXY protocol, as
+// described in http://www.haproxy.org/download/1.8/doc/proxy-protocol.txt.
+// A proxy or load balancer sends a PROXY protocol header at the start
+// of a connection to pass on the addresses of the connection it
+// accepted from the original client.
+
+// A ProxyCommand is the command of a PROXY protocol header.
+type ProxyCommand int
+
+const (
+	// ProxyCommandProxy marks a connection relayed on behalf of the
+	// client whose addresses the header carries.
+	ProxyCommandProxy ProxyCommand = iota
+
+	// ProxyCommandLocal marks a connection that the proxy made on
+	// its own behalf, such as for a health check. The addresses of
+	// the connection itself are to be used.
+	ProxyCommandLocal
+)
+
+// Types of PROXY protocol version 2 TLVs.
+const (
+	ProxyTLVALPN      = 0x01
+	ProxyTLVAuthority = 0x02
+	ProxyTLVCRC32C    = 0x03
+	ProxyTLVNoop      = 0x04
+	ProxyTLVUniqueID  = 0x05
+	ProxyTLVSSL       = 0x20
+	ProxyTLVNetNS     = 0x30
+)
+
+// A ProxyTLV is a type-length-value vector of a PROXY protocol
+// version 2 header.
+type ProxyTLV struct {
+	Type  byte
+	Value []byte
+}
+
+// A ProxyHeader represents a PROXY protocol header.
+type ProxyHeader struct {
+	// Version is the protocol version, 1 or 2.
+	Version int
+
+	// Command is the header's command. Version 1 headers always
+	// carry ProxyCommandProxy, the zero value.
+	Command ProxyCommand
+
+	// Source and Destination are the addresses of the client and
+	// of the server the client connected to, as seen by the proxy.
+	// They are *TCPAddr, *UDPAddr or *UnixAddr values, or both nil
+	// if the header does not carry addresses.
+	Source      Addr
+	Destination Addr
+
+	// TLVs are the type-length-value vectors that follow the
+	// addresses in a version 2 header.
+	TLVs []ProxyTLV
+}
+
+// TLV returns the value of the first TLV of type typ in h, and
+// whether there is one.
+func (h *ProxyHeader) TLV(typ byte) ([]byte, bool) {
+	for _, tlv := range h.TLVs {
+		if tlv.Type == typ {
+			return tlv.Value, true
+		}
+	}
+	return nil, false
+}
+
+const (
+	proxyV1Sig    = "PROXY "
+	proxyV1MaxLen = 107
+	proxyV2Sig    = "\r\n\r\n\x00\r\nQUIT\n"
+	proxyV2HdrLen = 16
+
+	proxyV2AddrLenInet  = 12
+	proxyV2AddrLenInet6 = 36
+	proxyV2AddrLenUnix  = 216
+)
+
+var (
+	errNoProxyHeader        = errors.New("no PROXY protocol header")
+	errMalformedProxyHeader = errors.New("malformed PROXY protocol header")
+	errProxyHeaderTooLong   = errors.New("PROXY protocol header too long")
+	errProxyHeaderVersion   = errors.New("unsupported PROXY protocol version")
+	errProxyHeaderNetwork   = errors.New("PROXY protocol header on a non-stream network")
+)
+
+// isStreamNetwork reports whether network carries a byte stream, so
+// that a PROXY protocol header can be sent ahead of the data.
+func isStreamNetwork(network string) bool {
+	switch network {
+	case "tcp", "tcp4", "tcp6", "unix", "vsock":
+		return true
+	}
+	return false
+}
+
+// Format returns the wire encoding of h.
+//
+// A version 1 header can only carry TCP addresses of a single
+// address family; for other addresses, or ProxyCommandLocal, it
+// is encoded as a header of the UNKNOWN protocol. Version 1 headers
+// cannot carry TLVs.
+func (h *ProxyHeader) Format() ([]byte, error) {
+	switch h.Version {
+	case 1:
+		return h.formatV1()
+	case 2:
+		return h.formatV2()
+	}
+	return nil, errProxyHeaderVersion
+}
+
+func (h *ProxyHeader) formatV1() ([]byte, error) {
+	if len(h.TLVs) > 0 {
+		return nil, errors.New("PROXY protocol version 1 header cannot carry TLVs")
+	}
+	src, _ := h.Source.(*TCPAddr)
+	dst, _ := h.Destination.(*TCPAddr)
+	if h.Command != ProxyCommandProxy || src == nil || dst == nil {
+		return []byte(proxyV1Sig + "UNKNOWN\r\n"), nil
+	}
+	proto := "TCP6"
+	if src.IP.To4() != nil && dst.IP.To4() != nil {
+		proto = "TCP4"
+	} else if src.IP.To4() != nil || dst.IP.To4() != nil || len(src.IP) != IPv6len || len(dst.IP) != IPv6len {
+		return []byte(proxyV1Sig + "UNKNOWN\r\n"), nil
+	}
+	s := proxyV1Sig + proto + " " + src.IP.String() + " " + dst.IP.String() + " " + itoa(src.Port) + " " + itoa(dst.Port) + "\r\n"
+	return []byte(s), nil
+}
+
+func (h *ProxyHeader) formatV2() ([]byte, error) {
+	b := make([]byte, proxyV2HdrLen, proxyV2HdrLen+proxyV2AddrLenInet6)
+	copy(b, proxyV2Sig)
+	switch h.Command {
+	case ProxyCommandLocal:
+		b[12] = 0x20
+	case ProxyCommandProxy:
+		b[12] = 0x21
+	default:
+		return nil, errors.New("invalid PROXY protocol command")
+	}
+	b[13], b = appendProxyV2Addrs(b, h.Source, h.Destination)
+	for _, tlv := range h.TLVs {
+		if len(tlv.Value) > 0xffff {
+			return nil, errProxyHeaderTooLong
+		}
+		b = append(b, tlv.Type, byte(len(tlv.Value)>>8), byte(len(tlv.Value)))
+		b = append(b, tlv.Value...)
+	}
+	n := len(b) - proxyV2HdrLen
+	if n > 0xffff {
+		return nil, errProxyHeaderTooLong
+	}
+	b[14], b[15] = byte(n>>8), byte(n)
+	return b, nil
+}
+
+// appendProxyV2Addrs appends the address block of a version 2 header
+// for src and dst to b. It returns the block's family and protocol
+// byte along with the extended buffer. Addresses that cannot be
+// encoded are left out and reported as unspecified.
+func appendProxyV2Addrs(b []byte, src, dst Addr) (byte, []byte) {
+	var sip, dip IP
+	var sport, dport int
+	var proto byte
+	switch src := src.(type) {
+	case *TCPAddr:
+		dst, ok := dst.(*TCPAddr)
+		if !ok {
+			return 0x00, b
+		}
+		sip, sport, dip, dport, proto = src.IP, src.Port, dst.IP, dst.Port, 0x1
+	case *UDPAddr:
+		dst, ok := dst.(*UDPAddr)
+		if !ok {
+			return 0x00, b
+		}
+		sip, sport, dip, dport, proto = src.IP, src.Port, dst.IP, dst.Port, 0x2
+	case *UnixAddr:
+		dst, ok := dst.(*UnixAddr)
+		if !ok || len(src.Name) > 108 || len(dst.Name) > 108 {
+			return 0x00, b
+		}
+		proto = 0x1
+		if src.Net == "unixgram" {
+			proto = 0x2
+		}
+		var paths [proxyV2AddrLenUnix]byte
+		copy(paths[:108], src.Name)
+		copy(paths[108:], dst.Name)
+		return 0x30 | proto, append(b, paths[:]...)
+	default:
+		return 0x00, b
+	}
+	fam := byte(0x20)
+	if s4, d4 := sip.To4(), dip.To4(); s4 != nil && d4 != nil {
+		fam = 0x10
+		sip, dip = s4, d4
+	} else if sip = sip.To16(); sip == nil {
+		return 0x00, b
+	} else if dip = dip.To16(); dip == nil {
+		return 0x00, b
+	}
+	b = append(b, sip...)
+	b = append(b, dip...)
+	b = append(b, byte(sport>>8), byte(sport), byte(dport>>8), byte(dport))
+	return fam | proto, b
+}
+
+// matchPrefix reports whether b and sig agree on their common
+// prefix.
+func matchPrefix(b []byte, sig string) bool {
+	for i := 0; i < len(b) && i < len(sig); i++ {
+		if b[i] != sig[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// parseProxyHeader parses the PROXY protocol header at the start of
+// b and returns it along with its length. It returns a zero length
+// and a nil error if b holds only the start of a header, and
+// errNoProxyHeader if b does not start with one.
+func parseProxyHeader(b []byte) (*ProxyHeader, int, error) {
+	switch {
+	case len(b) == 0:
+		return nil, 0, nil
+	case matchPrefix(b, proxyV1Sig):
+		return parseProxyHeaderV1(b)
+	case matchPrefix(b, proxyV2Sig):
+		return parseProxyHeaderV2(b)
+	}
+	return nil, 0, errNoProxyHeader
+}
+
+func parseProxyHeaderV1(b []byte) (*ProxyHeader, int, error) {
+	n := -1
+	for i := len(proxyV1Sig); i+1 < len(b) && i+1 < proxyV1MaxLen; i++ {
+		if b[i] == '\r' && b[i+1] == '\n' {
+			n = i + 2
+			break
+		}
+	}
+	if n < 0 {
+		if len(b) >= proxyV1MaxLen {
+			return nil, 0, errProxyHeaderTooLong
+		}
+		return nil, 0, nil
+	}
+	h := &ProxyHeader{Version: 1, Command: ProxyCommandProxy}
+	f := splitAtBytes(string(b[len(proxyV1Sig):n-2]), " ")
+	if len(f) > 0 && f[0] == "UNKNOWN" {
+		return h, n, nil
+	}
+	if len(f) != 5 || f[0] != "TCP4" && f[0] != "TCP6" {
+		return nil, 0, errMalformedProxyHeader
+	}
+	var ports [2]int
+	for i, s := range f[3:] {
+		port, j, ok := dtoi(s)
+		if !ok || j != len(s) || port > 0xffff {
+			return nil, 0, errMalformedProxyHeader
+		}
+		ports[i] = port
+	}
+	var ips [2]IP
+	for i, s := range f[1:3] {
+		if f[0] == "TCP4" {
+			ips[i] = parseIPv4(s).To4()
+		} else {
+			ips[i], _ = parseIPv6(s, false)
+		}
+		if ips[i] == nil {
+			return nil, 0, errMalformedProxyHeader
+		}
+	}
+	h.Source = &TCPAddr{IP: ips[0], Port: ports[0]}
+	h.Destination = &TCPAddr{IP: ips[1], Port: ports[1]}
+	return h, n, nil
+}
+
+func parseProxyHeaderV2(b []byte) (*ProxyHeader, int, error) {
+	if len(b) < proxyV2HdrLen {
+		return nil, 0, nil
+	}
+	if b[12]>>4 != 2 {
+		return nil, 0, errProxyHeaderVersion
+	}
+	h := &ProxyHeader{Version: 2}
+	switch b[12] & 0xf {
+	case 0x0:
+		h.Command = ProxyCommandLocal
+	case 0x1:
+		h.Command = ProxyCommandProxy
+	default:
+		return nil, 0, errMalformedProxyHeader
+	}
+	n := proxyV2HdrLen + (int(b[14])<<8 | int(b[15]))
+	if len(b) < n {
+		return nil, 0, nil
+	}
+	fam, proto := b[13]>>4, b[13]&0xf
+	if proto > 0x2 {
+		return nil, 0, errMalformedProxyHeader
+	}
+	body := b[proxyV2HdrLen:n]
+	var addrLen int
+	switch fam {
+	case 0x0:
+		addrLen = 0
+	case 0x1:
+		addrLen = proxyV2AddrLenInet
+	case 0x2:
+		addrLen = proxyV2AddrLenInet6
+	case 0x3:
+		addrLen = proxyV2AddrLenUnix
+	default:
+		return nil, 0, errMalformedProxyHeader
+	}
+	if len(body) < addrLen {
+		return nil, 0, errMalformedProxyHeader
+	}
+	switch fam {
+	case 0x1, 0x2:
+		l := (addrLen - 4) / 2
+		sip, dip := make(IP, l), make(IP, l)
+		copy(sip, body[:l])
+		copy(dip, body[l:2*l])
+		sport := int(body[2*l])<<8 | int(body[2*l+1])
+		dport := int(body[2*l+2])<<8 | int(body[2*l+3])
+		switch proto {
+		case 0x1:
+			h.Source = &TCPAddr{IP: sip, Port: sport}
+			h.Destination = &TCPAddr{IP: dip, Port: dport}
+		case 0x2:
+			h.Source = &UDPAddr{IP: sip, Port: sport}
+			h.Destination = &UDPAddr{IP: dip, Port: dport}
+		}
+	case 0x3:
+		net := "unix"
+		if proto == 0x2 {
+			net = "unixgram"
+		}
+		if proto != 0x0 {
+			h.Source = &UnixAddr{Name: proxyV2UnixPath(body[:108]), Net: net}
+			h.Destination = &UnixAddr{Name: proxyV2UnixPath(body[108:216]), Net: net}
+		}
+	}
+	for tlvs := body[addrLen:]; len(tlvs) > 0; {
+		if len(tlvs) < 3 {
+			return nil, 0, errMalformedProxyHeader
+		}
+		l := int(tlvs[1])<<8 | int(tlvs[2])
+		if len(tlvs) < 3+l {
+			return nil, 0, errMalformedProxyHeader
+		}
+		value := make([]byte, l)
+		copy(value, tlvs[3:3+l])
+		h.TLVs = append(h.TLVs, ProxyTLV{Type: tlvs[0], Value: value})
+		tlvs = tlvs[3+l:]
+	}
+	return h, n, nil
+}
+
+// proxyV2UnixPath returns the NUL-padded path in b.
+func proxyV2UnixPath(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
+
+// WriteProxyHeader writes h to c. If h carries ProxyCommandProxy but
+// no addresses, the local and remote addresses of c are sent as the
+// source and destination.
+func WriteProxyHeader(c Conn, h *ProxyHeader) error {
+	if h.Command == ProxyCommandProxy && h.Source == nil && h.Destination == nil {
+		hh := *h
+		hh.Source, hh.Destination = c.LocalAddr(), c.RemoteAddr()
+		h = &hh
+	}
+	b, err := h.Format()
+	if err != nil {
+		return &OpError{Op: "write", Net: c.LocalAddr().Network(), Source: c.LocalAddr(), Addr: c.RemoteAddr(), Err: err}
+	}
+	_, err = c.Write(b)
+	return err
+}
+
+// A ProxyListener wraps a Listener whose connections arrive through
+// a proxy or load balancer that speaks the PROXY protocol, such as
+// HAProxy. The connections it accepts report the addresses of the
+// original client and destination from the PROXY protocol header as
+// their RemoteAddr and LocalAddr.
+//
+// Only headers from the peers listed in TrustedSources are honored.
+// Connections from trusted peers that do not start with a PROXY
+// protocol header are passed through unchanged, as are all
+// connections from untrusted peers.
+type ProxyListener struct {
+	Listener
+
+	// ReadHeaderTimeout is the maximum amount of time to wait for
+	// the PROXY protocol header of a connection.
+	// If zero, a timeout of 10 seconds is used.
+	// If negative, there is no timeout.
+	ReadHeaderTimeout time.Duration
+
+	// TrustedSources lists the networks of the peers whose PROXY
+	// protocol headers are honored, typically those of the proxies
+	// in front of the listener. If nil, no peer is trusted.
+	//
+	// A peer whose header is honored chooses the addresses its
+	// connection reports. Honoring headers from every peer, by
+	// listing 0.0.0.0/0 and ::/0, is only safe if nothing but the
+	// proxies can reach the listener.
+	TrustedSources []*IPNet
+}
+
+// defaultProxyHeaderTimeout is the ReadHeaderTimeout used when the
+// field is zero.
+const defaultProxyHeaderTimeout = 10 * time.Second
+
+// Accept waits for and returns the next connection to the listener.
+// The returned connection is a *ProxyConn.
+func (l *ProxyListener) Accept() (Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	timeout := l.ReadHeaderTimeout
+	if timeout == 0 {
+		timeout = defaultProxyHeaderTimeout
+	}
+	return &ProxyConn{conn: c, trusted: l.trusted(c.RemoteAddr()), timeout: timeout}, nil
+}
+
+func (l *ProxyListener) trusted(addr Addr) bool {
+	var ip IP
+	switch addr := addr.(type) {
+	case *TCPAddr:
+		ip = addr.IP
+	case *UDPAddr:
+		ip = addr.IP
+	case *IPAddr:
+		ip = addr.IP
+	default:
+		return false
+	}
+	for _, n := range l.TrustedSources {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// A ProxyConn is a connection accepted by a ProxyListener.
+//
+// The PROXY protocol header is read by the first call to Read,
+// RemoteAddr, LocalAddr, SetDeadline, SetReadDeadline or
+// ProxyHeader, which blocks until the header has arrived, the
+// listener's ReadHeaderTimeout has passed or the connection turns
+// out not to start with a header.
+type ProxyConn struct {
+	conn    Conn
+	trusted bool
+	timeout time.Duration
+
+	once   sync.Once
+	header *ProxyHeader
+	err    error
+	buf    []byte // data read past the header
+}
+
+func (c *ProxyConn) readHeader() {
+	if !c.trusted {
+		return
+	}
+	if c.timeout > 0 {
+		c.conn.SetReadDeadline(time.Now().Add(c.timeout))
+		defer c.conn.SetReadDeadline(noDeadline)
+	}
+	b := make([]byte, 0, 256)
+	for {
+		h, n, err := parseProxyHeader(b)
+		if err == errNoProxyHeader {
+			c.buf = b
+			return
+		}
+		if err != nil {
+			c.err = &OpError{Op: "read", Net: c.conn.LocalAddr().Network(), Source: c.conn.LocalAddr(), Addr: c.conn.RemoteAddr(), Err: err}
+			return
+		}
+		if h != nil {
+			c.header, c.buf = h, b[n:]
+			return
+		}
+		if len(b) == cap(b) {
+			nb := make([]byte, len(b), 2*cap(b))
+			copy(nb, b)
+			b = nb
+		}
+		m, err := c.conn.Read(b[len(b):cap(b)])
+		b = b[:len(b)+m]
+		if err != nil && m == 0 {
+			if err == io.EOF {
+				// Leave what arrived to the reader.
+				c.buf = b
+			} else {
+				c.err = err
+			}
+			return
+		}
+	}
+}
+
+// ProxyHeader returns the PROXY protocol header of the connection,
+// or nil if it did not start with one or its peer is not trusted.
+// A non-nil error reports a malformed header or a failure to read
+// one.
+func (c *ProxyConn) ProxyHeader() (*ProxyHeader, error) {
+	c.once.Do(c.readHeader)
+	return c.header, c.err
+}
+
+// Read implements the Conn Read method.
+func (c *ProxyConn) Read(b []byte) (int, error) {
+	c.once.Do(c.readHeader)
+	if c.err != nil {
+		return 0, c.err
+	}
+	if len(c.buf) > 0 {
+		n := copy(b, c.buf)
+		c.buf = c.buf[n:]
+		return n, nil
+	}
+	return c.conn.Read(b)
+}
+
+// Write implements the Conn Write method.
+func (c *ProxyConn) Write(b []byte) (int, error) {
+	return c.conn.Write(b)
+}
+
+// Close closes the connection.
+func (c *ProxyConn) Close() error {
+	return c.conn.Close()
+}
+
+// LocalAddr returns the destination address of the PROXY protocol
+// header, or else the local network address.
+func (c *ProxyConn) LocalAddr() Addr {
+	c.once.Do(c.readHeader)
+	if h := c.header; h != nil && h.Command == ProxyCommandProxy && h.Destination != nil {
+		return h.Destination
+	}
+	return c.conn.LocalAddr()
+}
+
+// RemoteAddr returns the source address of the PROXY protocol
+// header, or else the remote network address.
+func (c *ProxyConn) RemoteAddr() Addr {
+	c.once.Do(c.readHeader)
+	if h := c.header; h != nil && h.Command == ProxyCommandProxy && h.Source != nil {
+		return h.Source
+	}
+	return c.conn.RemoteAddr()
+}
+
+// SetDeadline implements the Conn SetDeadline method.
+func (c *ProxyConn) SetDeadline(t time.Time) error {
+	c.once.Do(c.readHeader)
+	return c.conn.SetDeadline(t)
+}
+
+// SetReadDeadline implements the Conn SetReadDeadline method.
+func (c *ProxyConn) SetReadDeadline(t time.Time) error {
+	c.once.Do(c.readHeader)
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline implements the Conn SetWriteDeadline method.
+func (c *ProxyConn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
@@ -0,0 +1,409 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import (
+	"io"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var proxyHeaderTests = []struct {
+	wire string
+	h    *ProxyHeader
+}{
+	{
+		"PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\n",
+		&ProxyHeader{
+			Version:     1,
+			Source:      &TCPAddr{IP: ParseIP("192.0.2.1").To4(), Port: 56324},
+			Destination: &TCPAddr{IP: ParseIP("198.51.100.1").To4(), Port: 443},
+		},
+	},
+	{
+		"PROXY TCP6 2001:db8::1 2001:db8::2 1 65535\r\n",
+		&ProxyHeader{
+			Version:     1,
+			Source:      &TCPAddr{IP: ParseIP("2001:db8::1"), Port: 1},
+			Destination: &TCPAddr{IP: ParseIP("2001:db8::2"), Port: 65535},
+		},
+	},
+	{
+		"PROXY UNKNOWN\r\n",
+		&ProxyHeader{Version: 1},
+	},
+	{
+		proxyV2Sig + "\x21\x11\x00\x0c" +
+			"\xc0\x00\x02\x01" + "\xc6\x33\x64\x01" + "\xdc\x04\x01\xbb",
+		&ProxyHeader{
+			Version:     2,
+			Source:      &TCPAddr{IP: ParseIP("192.0.2.1").To4(), Port: 56324},
+			Destination: &TCPAddr{IP: ParseIP("198.51.100.1").To4(), Port: 443},
+		},
+	},
+	{
+		proxyV2Sig + "\x21\x22\x00\x2f" +
+			"\x20\x01\x0d\xb8\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01" +
+			"\x20\x01\x0d\xb8\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x02" +
+			"\x00\x35\x00\x35" +
+			"\x02\x00\x05golan" + "\x05\x00\x00",
+		&ProxyHeader{
+			Version:     2,
+			Source:      &UDPAddr{IP: ParseIP("2001:db8::1"), Port: 53},
+			Destination: &UDPAddr{IP: ParseIP("2001:db8::2"), Port: 53},
+			TLVs: []ProxyTLV{
+				{Type: ProxyTLVAuthority, Value: []byte("golan")},
+				{Type: ProxyTLVUniqueID, Value: []byte{}},
+			},
+		},
+	},
+	{
+		proxyV2Sig + "\x20\x00\x00\x00",
+		&ProxyHeader{Version: 2, Command: ProxyCommandLocal},
+	},
+}
+
+func TestProxyHeader(t *testing.T) {
+	for i, tt := range proxyHeaderTests {
+		h, n, err := parseProxyHeader([]byte(tt.wire + "GET /"))
+		if err != nil {
+			t.Errorf("#%d: %v", i, err)
+			continue
+		}
+		if n != len(tt.wire) {
+			t.Errorf("#%d: got length %d; want %d", i, n, len(tt.wire))
+		}
+		if !reflect.DeepEqual(h, tt.h) {
+			t.Errorf("#%d: got %+v; want %+v", i, h, tt.h)
+		}
+
+		// Every prefix of the header asks for more data.
+		for j := 0; j < len(tt.wire); j++ {
+			if h, n, err := parseProxyHeader([]byte(tt.wire[:j])); h != nil || n != 0 || err != nil {
+				t.Errorf("#%d: prefix of length %d: got %v, %d, %v", i, j, h, n, err)
+				break
+			}
+		}
+
+		b, err := tt.h.Format()
+		if err != nil {
+			t.Errorf("#%d: %v", i, err)
+			continue
+		}
+		if string(b) != tt.wire {
+			t.Errorf("#%d: got %q; want %q", i, b, tt.wire)
+		}
+	}
+}
+
+var malformedProxyHeaderTests = []struct {
+	wire string
+	err  error
+}{
+	{"GET / HTTP/1.1\r\n", errNoProxyHeader},
+	{"\r\n\r\nfoo", errNoProxyHeader},
+	{"PROXY TCP4 192.0.2.1 198.51.100.1 56324\r\n", errMalformedProxyHeader},
+	{"PROXY TCP4 2001:db8::1 198.51.100.1 56324 443\r\n", errMalformedProxyHeader},
+	{"PROXY TCP6 192.0.2.1 2001:db8::2 56324 443\r\n", errMalformedProxyHeader},
+	{"PROXY TCP4 192.0.2.1 198.51.100.1 56324 65536\r\n", errMalformedProxyHeader},
+	{"PROXY UDP4 192.0.2.1 198.51.100.1 56324 443\r\n", errMalformedProxyHeader},
+	{"PROXY UNKNOWN " + string(make([]byte, proxyV1MaxLen)), errProxyHeaderTooLong},
+	{proxyV2Sig + "\x11\x11\x00\x00", errProxyHeaderVersion},
+	{proxyV2Sig + "\x22\x11\x00\x00", errMalformedProxyHeader},
+	{proxyV2Sig + "\x21\x11\x00\x04\x00\x00\x00\x00", errMalformedProxyHeader},
+	{proxyV2Sig + "\x21\x13\x00\x00", errMalformedProxyHeader},
+	{proxyV2Sig + "\x21\x41\x00\x00", errMalformedProxyHeader},
+	{proxyV2Sig + "\x21\x11\x00\x0e" + "\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00" + "\x01\x00", errMalformedProxyHeader},
+	{proxyV2Sig + "\x21\x11\x00\x0f" + "\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00" + "\x01\x00\x01", errMalformedProxyHeader},
+}
+
+func TestMalformedProxyHeader(t *testing.T) {
+	for i, tt := range malformedProxyHeaderTests {
+		if _, _, err := parseProxyHeader([]byte(tt.wire)); err != tt.err {
+			t.Errorf("#%d: got %v; want %v", i, err, tt.err)
+		}
+	}
+}
+
+func TestProxyHeaderFormatV1Unknown(t *testing.T) {
+	for _, h := range []*ProxyHeader{
+		{Version: 1, Command: ProxyCommandLocal},
+		{Version: 1, Source: &UDPAddr{IP: IPv4(192, 0, 2, 1)}, Destination: &UDPAddr{IP: IPv4(192, 0, 2, 2)}},
+		{Version: 1, Source: &TCPAddr{IP: IPv4(192, 0, 2, 1)}, Destination: &TCPAddr{IP: ParseIP("2001:db8::1")}},
+	} {
+		b, err := h.Format()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != "PROXY UNKNOWN\r\n" {
+			t.Errorf("%+v: got %q; want UNKNOWN header", h, b)
+		}
+	}
+	if _, err := (&ProxyHeader{Version: 1, TLVs: []ProxyTLV{{Type: ProxyTLVNoop}}}).Format(); err == nil {
+		t.Error("version 1 header with TLVs formatted without error")
+	}
+	if _, err := (&ProxyHeader{Version: 3}).Format(); err != errProxyHeaderVersion {
+		t.Errorf("got %v; want %v", err, errProxyHeaderVersion)
+	}
+}
+
+// loopbackNets returns the loopback networks, from which the test
+// listeners accept PROXY protocol headers.
+func loopbackNets() []*IPNet {
+	_, lo4, _ := ParseCIDR("127.0.0.0/8")
+	_, lo6, _ := ParseCIDR("::1/128")
+	return []*IPNet{lo4, lo6}
+}
+
+func TestProxyListener(t *testing.T) {
+	if !testableNetwork("tcp4") {
+		t.Skip("tcp4 is not supported")
+	}
+	ln, err := newLocalListener("tcp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pln := &ProxyListener{Listener: ln, ReadHeaderTimeout: time.Second, TrustedSources: loopbackNets()}
+	defer pln.Close()
+
+	src := &TCPAddr{IP: ParseIP("192.0.2.1").To4(), Port: 56324}
+	dst := &TCPAddr{IP: ParseIP("198.51.100.1").To4(), Port: 443}
+	tlvs := []ProxyTLV{{Type: ProxyTLVALPN, Value: []byte("h2")}}
+	for _, h := range []*ProxyHeader{
+		{Version: 1, Source: src, Destination: dst},
+		{Version: 2, Source: src, Destination: dst, TLVs: tlvs},
+		{Version: 2, Command: ProxyCommandLocal, TLVs: tlvs},
+	} {
+		d := Dialer{ProxyHeader: h}
+		c, err := d.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.Write([]byte("hello")); err != nil {
+			t.Fatal(err)
+		}
+		c.Close()
+
+		sc, err := pln.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := sc.(*ProxyConn).ProxyHeader()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Version != h.Version || got.Command != h.Command {
+			t.Errorf("got version %d, command %d; want %d, %d", got.Version, got.Command, h.Version, h.Command)
+		}
+		wantRemote, wantLocal := Addr(src), Addr(dst)
+		if h.Command == ProxyCommandLocal {
+			wantRemote, wantLocal = c.LocalAddr(), c.RemoteAddr()
+		}
+		if !reflect.DeepEqual(sc.RemoteAddr(), wantRemote) || !reflect.DeepEqual(sc.LocalAddr(), wantLocal) {
+			t.Errorf("got %v -> %v; want %v -> %v", sc.RemoteAddr(), sc.LocalAddr(), wantRemote, wantLocal)
+		}
+		if h.Version == 2 {
+			if v, ok := got.TLV(ProxyTLVALPN); !ok || string(v) != "h2" {
+				t.Errorf("got ALPN TLV %q, %v; want h2", v, ok)
+			}
+		}
+		b, err := ioutil.ReadAll(sc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != "hello" {
+			t.Errorf("got %q; want hello", b)
+		}
+		sc.Close()
+	}
+}
+
+func TestProxyListenerFillsAddrs(t *testing.T) {
+	if !testableNetwork("tcp4") {
+		t.Skip("tcp4 is not supported")
+	}
+	ln, err := newLocalListener("tcp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pln := &ProxyListener{Listener: ln, TrustedSources: loopbackNets()}
+	defer pln.Close()
+
+	d := Dialer{ProxyHeader: &ProxyHeader{Version: 2}}
+	c, err := d.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	sc, err := pln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+	if got, want := sc.RemoteAddr().String(), c.LocalAddr().String(); got != want {
+		t.Errorf("got remote address %s; want %s", got, want)
+	}
+}
+
+func TestDialProxyHeaderPacketNetwork(t *testing.T) {
+	d := Dialer{ProxyHeader: &ProxyHeader{Version: 2}}
+	for _, tt := range []struct {
+		network, address string
+	}{
+		{"udp", "127.0.0.1:0"},
+		{"udp6", "[::1]:0"},
+		{"ip4:icmp", "127.0.0.1"},
+		{"unixgram", "/tmp/nonexistent"},
+		{"unixpacket", "/tmp/nonexistent"},
+	} {
+		c, err := d.Dial(tt.network, tt.address)
+		if err == nil {
+			c.Close()
+			t.Errorf("Dial(%q) with a PROXY header succeeded", tt.network)
+			continue
+		}
+		if oe, ok := err.(*OpError); !ok || oe.Err != errProxyHeaderNetwork {
+			t.Errorf("Dial(%q) with a PROXY header: %v; want %v", tt.network, err, errProxyHeaderNetwork)
+		}
+	}
+}
+
+func TestProxyListenerUntrusted(t *testing.T) {
+	if !testableNetwork("tcp4") {
+		t.Skip("tcp4 is not supported")
+	}
+	_, other, _ := ParseCIDR("192.0.2.0/24")
+	for _, trusted := range [][]*IPNet{nil, {other}} {
+		ln, err := newLocalListener("tcp4")
+		if err != nil {
+			t.Fatal(err)
+		}
+		pln := &ProxyListener{Listener: ln, TrustedSources: trusted}
+
+		const wire = "PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\n"
+		c, err := Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.Write([]byte(wire)); err != nil {
+			t.Fatal(err)
+		}
+		c.Close()
+		sc, err := pln.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := sc.RemoteAddr().String(), c.LocalAddr().String(); got != want {
+			t.Errorf("trusted %v: got remote address %s; want %s", trusted, got, want)
+		}
+		b, err := ioutil.ReadAll(sc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != wire {
+			t.Errorf("trusted %v: got %q; want header passed through", trusted, b)
+		}
+		sc.Close()
+		pln.Close()
+	}
+}
+
+func TestProxyListenerDefaultTimeout(t *testing.T) {
+	if !testableNetwork("tcp4") {
+		t.Skip("tcp4 is not supported")
+	}
+	ln, err := newLocalListener("tcp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pln := &ProxyListener{Listener: ln, TrustedSources: loopbackNets()}
+	defer pln.Close()
+
+	c, err := Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	sc, err := pln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+	if got := sc.(*ProxyConn).timeout; got != defaultProxyHeaderTimeout {
+		t.Errorf("got header timeout %v; want %v", got, defaultProxyHeaderTimeout)
+	}
+}
+
+func TestProxyListenerTimeout(t *testing.T) {
+	if !testableNetwork("tcp4") {
+		t.Skip("tcp4 is not supported")
+	}
+	ln, err := newLocalListener("tcp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pln := &ProxyListener{Listener: ln, ReadHeaderTimeout: 50 * time.Millisecond, TrustedSources: loopbackNets()}
+	defer pln.Close()
+
+	c, err := Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, err := c.Write([]byte("PROXY TCP4")); err != nil {
+		t.Fatal(err)
+	}
+	sc, err := pln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+	_, err = sc.Read(make([]byte, 1))
+	if perr := parseReadError(err); perr != nil {
+		t.Error(perr)
+	}
+	if nerr, ok := err.(Error); !ok || !nerr.Timeout() {
+		t.Errorf("got %v; want timeout error", err)
+	}
+	if _, err := sc.(*ProxyConn).ProxyHeader(); err == nil {
+		t.Error("got nil error for incomplete header")
+	}
+}
+
+func TestProxyConnEOF(t *testing.T) {
+	if !testableNetwork("tcp4") {
+		t.Skip("tcp4 is not supported")
+	}
+	ln, err := newLocalListener("tcp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pln := &ProxyListener{Listener: ln, TrustedSources: loopbackNets()}
+	defer pln.Close()
+
+	c, err := Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Write([]byte("PROXY"))
+	c.Close()
+	sc, err := pln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+	b, err := ioutil.ReadAll(sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "PROXY" {
+		t.Errorf("got %q; want PROXY", b)
+	}
+	if _, err := sc.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("got %v; want io.EOF", err)
+	}
+}
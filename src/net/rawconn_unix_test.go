@@ -0,0 +1,236 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package net
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func readRawConn(c syscall.RawConn, b []byte) (int, error) {
+	var operr error
+	var n int
+	err := c.Read(func(s uintptr) bool {
+		n, operr = syscall.Read(int(s), b)
+		if operr == syscall.EAGAIN {
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return n, err
+	}
+	return n, operr
+}
+
+func writeRawConn(c syscall.RawConn, b []byte) error {
+	var operr error
+	err := c.Write(func(s uintptr) bool {
+		_, operr = syscall.Write(int(s), b)
+		if operr == syscall.EAGAIN {
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return operr
+}
+
+func TestRawConnReadWrite(t *testing.T) {
+	ln, err := newLocalListener("tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			done <- err
+			return
+		}
+		defer c.Close()
+		cc, err := c.(*TCPConn).SyscallConn()
+		if err != nil {
+			done <- err
+			return
+		}
+		b := make([]byte, 32)
+		n, err := readRawConn(cc, b)
+		if err != nil {
+			done <- err
+			return
+		}
+		done <- writeRawConn(cc, b[:n])
+	}()
+
+	c, err := Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	cc, err := c.(*TCPConn).SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("HELLO-R-U-THERE")
+	if err := writeRawConn(cc, data); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 32)
+	n, err := readRawConn(cc, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b[:n], data) {
+		t.Fatalf("got %q; want %q", b[:n], data)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	c.Close()
+	if err := cc.Control(func(uintptr) {}); err == nil {
+		t.Fatal("Control after Close should fail")
+	}
+	if _, err := readRawConn(cc, b); err == nil {
+		t.Fatal("Read after Close should fail")
+	}
+}
+
+func TestRawConnControl(t *testing.T) {
+	c, err := newLocalPacketListener("udp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	cc, err := c.(*UDPConn).SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var operr error
+	if err := cc.Control(func(s uintptr) {
+		operr = syscall.SetsockoptInt(int(s), syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if operr != nil {
+		t.Fatal(operr)
+	}
+	var v int
+	cc.Control(func(s uintptr) {
+		v, operr = syscall.GetsockoptInt(int(s), syscall.SOL_SOCKET, syscall.SO_REUSEADDR)
+	})
+	if operr != nil {
+		t.Fatal(operr)
+	}
+	if v == 0 {
+		t.Fatal("SO_REUSEADDR is not set")
+	}
+}
+
+// controlOnConnSetup returns a control function that sets
+// SO_REUSEADDR on the socket and records the network it was called
+// with.
+func controlOnConnSetup(networks *[]string) func(string, string, syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		*networks = append(*networks, network)
+		var operr error
+		if err := c.Control(func(s uintptr) {
+			operr = syscall.SetsockoptInt(int(s), syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1)
+		}); err != nil {
+			return err
+		}
+		return operr
+	}
+}
+
+func TestDialerControl(t *testing.T) {
+	if !supportsIPv4 {
+		t.Skip("IPv4 is not supported")
+	}
+	ln, err := newLocalListener("tcp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	var networks []string
+	d := Dialer{Control: controlOnConnSetup(&networks)}
+	c, err := d.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if len(networks) != 1 || networks[0] != "tcp4" {
+		t.Errorf("got %v; want [tcp4]", networks)
+	}
+
+	errCtrl := errors.New("control failed")
+	d = Dialer{Control: func(string, string, syscall.RawConn) error { return errCtrl }}
+	if c, err := d.Dial("tcp", ln.Addr().String()); err == nil {
+		c.Close()
+		t.Fatal("should fail")
+	} else if perr, ok := err.(*OpError); !ok || perr.Err != errCtrl {
+		t.Fatalf("got %v; want OpError wrapping %v", err, errCtrl)
+	}
+}
+
+func TestListenConfigControl(t *testing.T) {
+	for _, tt := range []struct {
+		network, address, want string
+		packet                 bool
+	}{
+		{"tcp4", "127.0.0.1:0", "tcp4", false},
+		{"tcp6", "[::1]:0", "tcp6", false},
+		{"udp4", "127.0.0.1:0", "udp4", true},
+		{"udp6", "[::1]:0", "udp6", true},
+		{"unix", testUnixAddr(), "unix", false},
+		{"unixgram", testUnixAddr(), "unixgram", true},
+	} {
+		if !testableNetwork(tt.network) {
+			continue
+		}
+		var networks []string
+		lc := ListenConfig{Control: controlOnConnSetup(&networks)}
+		if tt.packet {
+			c, err := lc.ListenPacket(context.Background(), tt.network, tt.address)
+			if err != nil {
+				t.Error(err)
+				continue
+			}
+			c.Close()
+			if tt.network == "unixgram" {
+				os.Remove(tt.address)
+			}
+		} else {
+			ln, err := lc.Listen(context.Background(), tt.network, tt.address)
+			if err != nil {
+				t.Error(err)
+				continue
+			}
+			ln.Close()
+		}
+		if len(networks) != 1 || networks[0] != tt.want {
+			t.Errorf("%s: got %v; want [%s]", tt.network, networks, tt.want)
+		}
+	}
+
+	errCtrl := errors.New("control failed")
+	lc := ListenConfig{Control: func(string, string, syscall.RawConn) error { return errCtrl }}
+	if ln, err := lc.Listen(context.Background(), "tcp", "127.0.0.1:0"); err == nil {
+		ln.Close()
+		t.Fatal("should fail")
+	}
+}
@@ -34,7 +34,7 @@ type sockaddr interface {
 
 // socket returns a network file descriptor that is ready for
 // asynchronous I/O using the network poller.
-func socket(ctx context.Context, net string, family, sotype, proto int, ipv6only bool, laddr, raddr sockaddr) (fd *netFD, err error) {
+func socket(ctx context.Context, net string, family, sotype, proto int, ipv6only bool, laddr, raddr sockaddr, ctrlFn func(string, string, syscall.RawConn) error) (fd *netFD, err error) {
 	s, err := sysSocket(family, sotype, proto)
 	if err != nil {
 		return nil, err
@@ -73,20 +73,20 @@ func socket(ctx context.Context, net string, family, sotype, proto int, ipv6only
 	if laddr != nil && raddr == nil {
 		switch sotype {
 		case syscall.SOCK_STREAM, syscall.SOCK_SEQPACKET:
-			if err := fd.listenStream(laddr, listenerBacklog); err != nil {
+			if err := fd.listenStream(laddr, listenerBacklog, ctrlFn); err != nil {
 				fd.Close()
 				return nil, err
 			}
 			return fd, nil
 		case syscall.SOCK_DGRAM:
-			if err := fd.listenDatagram(laddr); err != nil {
+			if err := fd.listenDatagram(laddr, ctrlFn); err != nil {
 				fd.Close()
 				return nil, err
 			}
 			return fd, nil
 		}
 	}
-	if err := fd.dial(ctx, laddr, raddr); err != nil {
+	if err := fd.dial(ctx, laddr, raddr, ctrlFn); err != nil {
 		fd.Close()
 		return nil, err
 	}
@@ -113,11 +113,55 @@ func (fd *netFD) addrFunc() func(syscall.Sockaddr) Addr {
 		case syscall.SOCK_SEQPACKET:
 			return sockaddrToUnixpacket
 		}
+	case sysAF_VSOCK:
+		switch fd.sotype {
+		case syscall.SOCK_STREAM:
+			return sockaddrToVsock
+		case syscall.SOCK_SEQPACKET:
+			return sockaddrToVsockpacket
+		}
 	}
 	return func(syscall.Sockaddr) Addr { return nil }
 }
 
-func (fd *netFD) dial(ctx context.Context, laddr, raddr sockaddr) error {
+func (fd *netFD) ctrlNetwork() string {
+	switch fd.net {
+	case "unix", "unixgram", "unixpacket", "vsock", "vsockpacket":
+		return fd.net
+	}
+	switch fd.net[len(fd.net)-1] {
+	case '4', '6':
+		return fd.net
+	}
+	if fd.family == syscall.AF_INET {
+		return fd.net + "4"
+	}
+	return fd.net + "6"
+}
+
+// control calls ctrlFn, if not nil, with the raw connection of fd and
+// the address it is about to be bound or connected to.
+func (fd *netFD) control(addr sockaddr, ctrlFn func(string, string, syscall.RawConn) error) error {
+	if ctrlFn == nil {
+		return nil
+	}
+	c, err := newRawConn(fd)
+	if err != nil {
+		return err
+	}
+	return ctrlFn(fd.ctrlNetwork(), addr.String(), c)
+}
+
+func (fd *netFD) dial(ctx context.Context, laddr, raddr sockaddr, ctrlFn func(string, string, syscall.RawConn) error) error {
+	ctrlAddr := raddr
+	if ctrlAddr == nil {
+		ctrlAddr = laddr
+	}
+	if ctrlAddr != nil {
+		if err := fd.control(ctrlAddr, ctrlFn); err != nil {
+			return err
+		}
+	}
 	var err error
 	var lsa syscall.Sockaddr
 	if laddr != nil {
@@ -152,10 +196,13 @@ func (fd *netFD) dial(ctx context.Context, laddr, raddr sockaddr) error {
 	return nil
 }
 
-func (fd *netFD) listenStream(laddr sockaddr, backlog int) error {
+func (fd *netFD) listenStream(laddr sockaddr, backlog int, ctrlFn func(string, string, syscall.RawConn) error) error {
 	if err := setDefaultListenerSockopts(fd.sysfd); err != nil {
 		return err
 	}
+	if err := fd.control(laddr, ctrlFn); err != nil {
+		return err
+	}
 	if lsa, err := laddr.sockaddr(fd.family); err != nil {
 		return err
 	} else if lsa != nil {
@@ -174,7 +221,7 @@ func (fd *netFD) listenStream(laddr sockaddr, backlog int) error {
 	return nil
 }
 
-func (fd *netFD) listenDatagram(laddr sockaddr) error {
+func (fd *netFD) listenDatagram(laddr sockaddr, ctrlFn func(string, string, syscall.RawConn) error) error {
 	switch addr := laddr.(type) {
 	case *UDPAddr:
 		// We provide a socket that listens to a wildcard
@@ -198,6 +245,9 @@ func (fd *netFD) listenDatagram(laddr sockaddr) error {
 			laddr = &addr
 		}
 	}
+	if err := fd.control(laddr, ctrlFn); err != nil {
+		return err
+	}
 	if lsa, err := laddr.sockaddr(fd.family); err != nil {
 		return err
 	} else if lsa != nil {
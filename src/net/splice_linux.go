@@ -0,0 +1,224 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import (
+	"internal/syscall/unix"
+	"io"
+	"os"
+	"syscall"
+)
+
+// maxSpliceSize is the largest chunk size we ask the kernel to move
+// at a time. The kernel moves no more than the capacity of the pipe,
+// 64 KB by default, per call anyway.
+const maxSpliceSize = 1 << 20
+
+// splice transfers data from r to c using the splice system call to
+// minimize copies from and to userspace. c must be a TCP connection.
+// r may be a TCP connection, a "unix" stream connection, a pipe, or an
+// io.LimitedReader of any of them.
+//
+// If splice returns handled == false, it has performed no work.
+func splice(c *netFD, r io.Reader) (written int64, err error, handled bool) {
+	var remain int64 = 1 << 62 // by default, copy until EOF
+	lr, ok := r.(*io.LimitedReader)
+	if ok {
+		remain, r = lr.N, lr.R
+		if remain <= 0 {
+			return 0, nil, true
+		}
+	}
+
+	var s *netFD
+	switch r := r.(type) {
+	case *TCPConn:
+		if !r.ok() {
+			return 0, nil, false
+		}
+		s = r.fd
+	case *UnixConn:
+		if !r.ok() || r.fd.net != "unix" {
+			return 0, nil, false
+		}
+		s = r.fd
+	case *os.File:
+		if !isBlockingPipe(r) {
+			return 0, nil, false
+		}
+		written, err, handled = spliceFromPipe(c, int(r.Fd()), remain)
+	default:
+		return 0, nil, false
+	}
+	if s != nil {
+		written, err, handled = spliceFromSocket(c, s, remain)
+	}
+	if lr != nil {
+		lr.N -= written
+	}
+	return written, err, handled
+}
+
+// spliceFromSocket moves up to remain bytes from the socket src to the
+// socket dst through a temporary pipe.
+func spliceFromSocket(dst, src *netFD, remain int64) (written int64, err error, handled bool) {
+	var p [2]int
+	if err := syscall.Pipe2(p[:], syscall.O_CLOEXEC|syscall.O_NONBLOCK); err != nil {
+		return 0, nil, false
+	}
+	defer syscall.Close(p[0])
+	defer syscall.Close(p[1])
+
+	for remain > 0 {
+		max := maxSpliceSize
+		if int64(max) > remain {
+			max = int(remain)
+		}
+		var inPipe int
+		inPipe, err = spliceDrain(p[1], src, max)
+		// The kernel reports EINVAL if it can't splice from the
+		// socket. Unless some data was moved already, leave the
+		// copy to the caller then.
+		if err == syscall.EINVAL && written == 0 {
+			return 0, nil, false
+		}
+		handled = true
+		if err != nil || inPipe == 0 {
+			break
+		}
+		var n int
+		n, err = splicePump(dst, p[0], inPipe)
+		written += int64(n)
+		remain -= int64(n)
+		if err != nil {
+			break
+		}
+	}
+	if _, ok := err.(syscall.Errno); ok {
+		err = os.NewSyscallError("splice", err)
+	}
+	return written, err, handled
+}
+
+// spliceDrain moves up to max bytes from the socket sock to the empty
+// pipe with write end pipefd. It returns the number of bytes moved;
+// zero means EOF.
+func spliceDrain(pipefd int, sock *netFD, max int) (int, error) {
+	if err := sock.readLock(); err != nil {
+		return 0, err
+	}
+	defer sock.readUnlock()
+	if err := sock.pd.prepareRead(); err != nil {
+		return 0, err
+	}
+	for {
+		n, err := unix.Splice(sock.sysfd, pipefd, max, unix.SPLICE_F_NONBLOCK)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != syscall.EAGAIN {
+			return n, err
+		}
+		if err := sock.pd.waitRead(); err != nil {
+			return n, err
+		}
+	}
+}
+
+// splicePump moves the inPipe bytes in the pipe with read end pipefd
+// to the socket sock.
+func splicePump(sock *netFD, pipefd int, inPipe int) (int, error) {
+	if err := sock.writeLock(); err != nil {
+		return 0, err
+	}
+	defer sock.writeUnlock()
+	if err := sock.pd.prepareWrite(); err != nil {
+		return 0, err
+	}
+	written := 0
+	for inPipe > 0 {
+		n, err := unix.Splice(pipefd, sock.sysfd, inPipe, unix.SPLICE_F_NONBLOCK)
+		if n > 0 {
+			inPipe -= n
+			written += n
+			continue
+		}
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != syscall.EAGAIN {
+			if err == nil {
+				err = io.ErrUnexpectedEOF
+			}
+			return written, err
+		}
+		if err := sock.pd.waitWrite(); err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// spliceFromPipe moves up to remain bytes from the pipe with read end
+// pipefd to the socket dst.
+//
+// The pipe is in blocking mode, so a splice from it waits for data.
+// The data is moved through a temporary pipe instead of straight to
+// dst, so that the wait happens without holding dst's write lock,
+// and splicePump then moves it on to dst through the poller.
+func spliceFromPipe(dst *netFD, pipefd int, remain int64) (written int64, err error, handled bool) {
+	// The kernel treats a splice between two pipes as non-blocking
+	// if either of them is, so the temporary pipe must be blocking.
+	var p [2]int
+	if err := syscall.Pipe2(p[:], syscall.O_CLOEXEC); err != nil {
+		return 0, nil, false
+	}
+	defer syscall.Close(p[0])
+	defer syscall.Close(p[1])
+
+	for remain > 0 {
+		max := maxSpliceSize
+		if int64(max) > remain {
+			max = int(remain)
+		}
+		inPipe, err1 := unix.Splice(pipefd, p[1], max, 0)
+		if err1 == syscall.EINTR {
+			continue
+		}
+		if err1 == syscall.EINVAL && written == 0 {
+			return 0, nil, false
+		}
+		if err1 != nil {
+			err = err1
+			break
+		}
+		if inPipe == 0 {
+			break // EOF
+		}
+		n, err1 := splicePump(dst, p[0], inPipe)
+		written += int64(n)
+		remain -= int64(n)
+		if err1 != nil {
+			err = err1
+			break
+		}
+	}
+	if _, ok := err.(syscall.Errno); ok {
+		err = os.NewSyscallError("splice", err)
+	}
+	return written, err, true
+}
+
+// isBlockingPipe reports whether f is a pipe in blocking mode, such
+// as one created by os.Pipe.
+func isBlockingPipe(f *os.File) bool {
+	fd := int(f.Fd())
+	var st syscall.Stat_t
+	if err := syscall.Fstat(fd, &st); err != nil || st.Mode&syscall.S_IFMT != syscall.S_IFIFO {
+		return false
+	}
+	flags, _, errno := syscall.Syscall(syscall.SYS_FCNTL, uintptr(fd), syscall.F_GETFL, 0)
+	return errno == 0 && flags&syscall.O_NONBLOCK == 0
+}
@@ -0,0 +1,264 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+var spliceTestData = bytes.Repeat([]byte("0123456789abcdef"), 64<<10) // 1 MB
+
+// newSpliceConnPair returns the two ends of a connection of the given
+// network.
+func newSpliceConnPair(t *testing.T, network string) (Conn, Conn) {
+	ln, err := newLocalListener(network)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	ch := make(chan Conn, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			t.Error(err)
+		}
+		ch <- c
+	}()
+	c, err := Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := <-ch
+	if sc == nil {
+		c.Close()
+		t.FailNow()
+	}
+	return c, sc
+}
+
+// testSpliceTo copies from src to a TCP connection with ReadFrom,
+// after writeSrc has written data to the other end of src, and checks
+// that the data arrive.
+func testSpliceTo(t *testing.T, src io.Reader, limit int64, writeSrc func([]byte)) {
+	dst, peer := newSpliceConnPair(t, "tcp")
+	defer dst.Close()
+	defer peer.Close()
+
+	want := spliceTestData
+	if limit >= 0 {
+		want = want[:limit]
+		src = &io.LimitedReader{R: src, N: limit}
+	}
+	go writeSrc(spliceTestData)
+	done := make(chan []byte)
+	go func() {
+		b, err := ioutil.ReadAll(peer)
+		if err != nil {
+			t.Error(err)
+		}
+		done <- b
+	}()
+
+	n, err := dst.(*TCPConn).ReadFrom(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(want)) {
+		t.Errorf("ReadFrom copied %d bytes; want %d", n, len(want))
+	}
+	dst.Close()
+	if got := <-done; !bytes.Equal(got, want) {
+		t.Errorf("got %d bytes; want %d bytes", len(got), len(want))
+	}
+	if lr, ok := src.(*io.LimitedReader); ok && lr.N != 0 {
+		t.Errorf("LimitedReader.N = %d; want 0", lr.N)
+	}
+}
+
+func TestSpliceFromSocket(t *testing.T) {
+	for _, network := range []string{"tcp", "unix"} {
+		if !testableNetwork(network) {
+			continue
+		}
+		for _, limit := range []int64{-1, 100000} {
+			src, peer := newSpliceConnPair(t, network)
+			testSpliceTo(t, src, limit, func(b []byte) {
+				peer.Write(b)
+				peer.Close()
+			})
+			src.Close()
+			peer.Close()
+		}
+	}
+}
+
+func TestSpliceFromPipe(t *testing.T) {
+	for _, limit := range []int64{-1, 100000} {
+		pr, pw, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		testSpliceTo(t, pr, limit, func(b []byte) {
+			pw.Write(b)
+			pw.Close()
+		})
+		pr.Close()
+		pw.Close()
+	}
+}
+
+func TestSpliceFromPipeConcurrentWrite(t *testing.T) {
+	dst, peer := newSpliceConnPair(t, "tcp")
+	defer dst.Close()
+	defer peer.Close()
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.Close()
+	defer pw.Close()
+
+	// While the copy waits for the empty pipe, writes to dst
+	// must not block.
+	copied := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(dst, pr)
+		copied <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	wrote := make(chan error, 1)
+	go func() {
+		_, err := dst.Write([]byte("hello"))
+		wrote <- err
+	}()
+	select {
+	case err := <-wrote:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Write blocked behind a splice from an empty pipe")
+	}
+	pw.Write([]byte(", world"))
+	pw.Close()
+	if err := <-copied; err != nil {
+		t.Fatal(err)
+	}
+	dst.Close()
+	b, err := ioutil.ReadAll(peer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "hello, world" {
+		t.Errorf("got %q; want %q", b, "hello, world")
+	}
+}
+
+func TestSpliceToPipe(t *testing.T) {
+	src, peer := newSpliceConnPair(t, "tcp")
+	defer src.Close()
+	defer peer.Close()
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.Close()
+	defer pw.Close()
+
+	go func() {
+		peer.Write(spliceTestData)
+		peer.Close()
+	}()
+	done := make(chan []byte)
+	go func() {
+		b, err := ioutil.ReadAll(pr)
+		if err != nil {
+			t.Error(err)
+		}
+		done <- b
+	}()
+	n, err := io.Copy(pw, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(spliceTestData)) {
+		t.Errorf("io.Copy copied %d bytes; want %d", n, len(spliceTestData))
+	}
+	pw.Close()
+	if got := <-done; !bytes.Equal(got, spliceTestData) {
+		t.Errorf("got %d bytes; want %d bytes", len(got), len(spliceTestData))
+	}
+}
+
+func TestSpliceNotHandled(t *testing.T) {
+	dst, peer := newSpliceConnPair(t, "tcp")
+	defer dst.Close()
+	defer peer.Close()
+	for _, r := range []io.Reader{
+		bytes.NewReader(nil),
+		&io.LimitedReader{R: bytes.NewReader(nil), N: 10},
+		&UDPConn{},
+	} {
+		if _, _, handled := splice(dst.(*TCPConn).fd, r); handled {
+			t.Errorf("splice handled %T", r)
+		}
+	}
+	f, err := ioutil.TempFile("", "splice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if _, _, handled := splice(dst.(*TCPConn).fd, f); handled {
+		t.Error("splice handled a regular file")
+	}
+}
+
+func BenchmarkSpliceTCP(b *testing.B) {
+	ln, err := newLocalListener("tcp")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer ln.Close()
+	accept := func() Conn {
+		c, err := ln.Accept()
+		if err != nil {
+			b.Fatal(err)
+		}
+		return c
+	}
+	src, err := Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer src.Close()
+	srcPeer := accept()
+	defer srcPeer.Close()
+	dst, err := Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer dst.Close()
+	dstPeer := accept()
+	defer dstPeer.Close()
+
+	go io.Copy(ioutil.Discard, dstPeer)
+	go func() {
+		for i := 0; i < b.N; i++ {
+			srcPeer.Write(spliceTestData)
+		}
+		srcPeer.Close()
+	}()
+	b.SetBytes(int64(len(spliceTestData)))
+	b.ResetTimer()
+	if _, err := dst.(*TCPConn).ReadFrom(src); err != nil {
+		b.Fatal(err)
+	}
+}
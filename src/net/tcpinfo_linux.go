@@ -0,0 +1,99 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import (
+	"internal/syscall/unix"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// sysTCPInfo is the leading part of the Linux tcp_info structure, up
+// to and including tcpi_delivery_rate. Older kernels fill in less of
+// it; the rest stays zero.
+type sysTCPInfo struct {
+	State         uint8
+	CAState       uint8
+	Retransmits   uint8
+	Probes        uint8
+	Backoff       uint8
+	Options       uint8
+	Wscale        uint8
+	Flags         uint8
+	RTO           uint32
+	ATO           uint32
+	SndMSS        uint32
+	RcvMSS        uint32
+	Unacked       uint32
+	Sacked        uint32
+	Lost          uint32
+	Retrans       uint32
+	Fackets       uint32
+	LastDataSent  uint32
+	LastAckSent   uint32
+	LastDataRecv  uint32
+	LastAckRecv   uint32
+	PMTU          uint32
+	RcvSsthresh   uint32
+	RTT           uint32
+	RTTVar        uint32
+	SndSsthresh   uint32
+	SndCwnd       uint32
+	AdvMSS        uint32
+	Reordering    uint32
+	RcvRTT        uint32
+	RcvSpace      uint32
+	TotalRetrans  uint32
+	PacingRate    uint64
+	MaxPacingRate uint64
+	BytesAcked    uint64
+	BytesReceived uint64
+	SegsOut       uint32
+	SegsIn        uint32
+	NotSentBytes  uint32
+	MinRTT        uint32
+	DataSegsIn    uint32
+	DataSegsOut   uint32
+	DeliveryRate  uint64
+}
+
+func getTCPInfo(fd *netFD) (*TCPInfo, error) {
+	if err := fd.incref(); err != nil {
+		return nil, err
+	}
+	defer fd.decref()
+	var si sysTCPInfo
+	n := uint32(unsafe.Sizeof(si))
+	if err := unix.Getsockopt(fd.sysfd, syscall.IPPROTO_TCP, syscall.TCP_INFO, unsafe.Pointer(&si), &n); err != nil {
+		return nil, os.NewSyscallError("getsockopt", err)
+	}
+	// The kernel reports times in microseconds.
+	us := func(v uint32) time.Duration { return time.Duration(v) * time.Microsecond }
+	return &TCPInfo{
+		State:         si.State,
+		Retransmits:   si.Retransmits,
+		RTO:           us(si.RTO),
+		RTT:           us(si.RTT),
+		RTTVar:        us(si.RTTVar),
+		MinRTT:        us(si.MinRTT),
+		SndMSS:        si.SndMSS,
+		RcvMSS:        si.RcvMSS,
+		SndCwnd:       si.SndCwnd,
+		SndSsthresh:   si.SndSsthresh,
+		Unacked:       si.Unacked,
+		Lost:          si.Lost,
+		Retrans:       si.Retrans,
+		TotalRetrans:  si.TotalRetrans,
+		BytesAcked:    si.BytesAcked,
+		BytesReceived: si.BytesReceived,
+		SegsOut:       si.SegsOut,
+		SegsIn:        si.SegsIn,
+		NotSentBytes:  si.NotSentBytes,
+		PacingRate:    si.PacingRate,
+		DeliveryRate:  si.DeliveryRate,
+	}, nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import (
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func TestTCPConnInfo(t *testing.T) {
+	ln, err := newLocalListener("tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	const size = 1 << 20
+	done := make(chan error, 1)
+	hold := make(chan bool)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			done <- err
+			return
+		}
+		defer c.Close()
+		_, err = io.CopyN(c, c, size)
+		done <- err
+		<-hold // keep the connection established
+	}()
+
+	c, err := Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	defer close(hold)
+	go c.Write(make([]byte, size))
+	if _, err := io.CopyN(ioutil.Discard, c, size); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := c.(*TCPConn).Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.State != 1 {
+		t.Errorf("got state %d; want 1 (established)", info.State)
+	}
+	if info.RTT <= 0 {
+		t.Errorf("got RTT %v; want > 0", info.RTT)
+	}
+	if info.SndCwnd == 0 {
+		t.Error("got zero congestion window")
+	}
+	if info.SndMSS == 0 {
+		t.Error("got zero send MSS")
+	}
+	// BytesAcked and BytesReceived arrived in Linux 4.1; they are
+	// zero on older kernels.
+	if info.BytesAcked != 0 && info.BytesAcked < size {
+		t.Errorf("got %d bytes acked; want at least %d", info.BytesAcked, size)
+	}
+	if info.BytesReceived != 0 && info.BytesReceived < size {
+		t.Errorf("got %d bytes received; want at least %d", info.BytesReceived, size)
+	}
+
+	c.Close()
+	if _, err := c.(*TCPConn).Info(); err == nil {
+		t.Error("Info on closed connection succeeded")
+	}
+}
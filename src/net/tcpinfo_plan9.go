@@ -0,0 +1,11 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import "syscall"
+
+func getTCPInfo(fd *netFD) (*TCPInfo, error) {
+	return nil, syscall.EPLAN9
+}
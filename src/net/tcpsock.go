@@ -7,6 +7,7 @@ package net
 import (
 	"context"
 	"io"
+	"net/netip"
 	"os"
 	"syscall"
 	"time"
@@ -22,6 +23,31 @@ type TCPAddr struct {
 	Zone string // IPv6 scoped addressing zone
 }
 
+// AddrPort returns the TCPAddr a as a netip.AddrPort.
+//
+// If a.Port does not fit in a uint16, it's silently truncated.
+//
+// If a is nil, a zero value is returned.
+func (a *TCPAddr) AddrPort() netip.AddrPort {
+	if a == nil {
+		return netip.AddrPort{}
+	}
+	na, _ := netip.AddrFromSlice(a.IP)
+	na = na.WithZone(a.Zone)
+	return netip.AddrPortFrom(na, uint16(a.Port))
+}
+
+// TCPAddrFromAddrPort returns addr as a TCPAddr. If addr.IsValid()
+// is false, then the returned TCPAddr will contain a nil IP field,
+// indicating an address family-agnostic unspecified address.
+func TCPAddrFromAddrPort(addr netip.AddrPort) *TCPAddr {
+	return &TCPAddr{
+		IP:   addr.Addr().AsSlice(),
+		Zone: addr.Addr().Zone(),
+		Port: int(addr.Port()),
+	}
+}
+
 // Network returns the address's network name, "tcp".
 func (a *TCPAddr) Network() string { return "tcp" }
 
@@ -77,6 +103,15 @@ type TCPConn struct {
 	conn
 }
 
+// SyscallConn returns a raw network connection.
+// This implements the syscall.Conn interface.
+func (c *TCPConn) SyscallConn() (syscall.RawConn, error) {
+	if !c.ok() {
+		return nil, syscall.EINVAL
+	}
+	return newRawConn(c.fd)
+}
+
 // ReadFrom implements the io.ReaderFrom ReadFrom method.
 func (c *TCPConn) ReadFrom(r io.Reader) (int64, error) {
 	if !c.ok() {
@@ -172,6 +207,70 @@ func (c *TCPConn) SetNoDelay(noDelay bool) error {
 	return nil
 }
 
+// TCPInfo holds statistics the operating system keeps about a TCP
+// connection. Fields the operating system does not report are zero.
+type TCPInfo struct {
+	// State is the operating system's code for the connection
+	// state. On Linux, 1 means established.
+	State uint8
+
+	// Retransmits is the number of times the oldest unacknowledged
+	// segment has been retransmitted after a timeout.
+	Retransmits uint8
+
+	RTO    time.Duration // retransmission timeout
+	RTT    time.Duration // smoothed round-trip time
+	RTTVar time.Duration // round-trip time variance
+	MinRTT time.Duration // minimum round-trip time observed
+
+	SndMSS       uint32 // maximum segment size for sending
+	RcvMSS       uint32 // maximum segment size for receiving
+	SndCwnd      uint32 // congestion window, in segments
+	SndSsthresh  uint32 // slow start threshold, in segments
+	Unacked      uint32 // segments sent but not yet acknowledged
+	Lost         uint32 // segments presumed lost
+	Retrans      uint32 // segments being retransmitted
+	TotalRetrans uint32 // segments retransmitted over the connection's lifetime
+
+	BytesAcked    uint64 // bytes sent and acknowledged by the peer
+	BytesReceived uint64 // bytes received from the peer
+	SegsOut       uint32 // segments sent
+	SegsIn        uint32 // segments received
+	NotSentBytes  uint32 // bytes queued but not yet sent
+
+	PacingRate   uint64 // current pacing rate, in bytes per second
+	DeliveryRate uint64 // most recent delivery rate, in bytes per second
+}
+
+// Info returns statistics the operating system keeps about the
+// connection.
+//
+// Info is supported on Linux only. On other systems it returns an
+// error.
+func (c *TCPConn) Info() (*TCPInfo, error) {
+	if !c.ok() {
+		return nil, syscall.EINVAL
+	}
+	info, err := getTCPInfo(c.fd)
+	if err != nil {
+		return nil, &OpError{Op: "get", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
+	}
+	return info, nil
+}
+
+// MultipathTCP reports whether the connection is using Multipath
+// TCP, that is, whether it was set up with MPTCP and the peer agreed
+// to use it.
+//
+// MultipathTCP is supported on Linux only. On other systems it
+// always reports false.
+func (c *TCPConn) MultipathTCP() (bool, error) {
+	if !c.ok() {
+		return false, syscall.EINVAL
+	}
+	return isUsingMultipathTCP(c.fd), nil
+}
+
 func newTCPConn(fd *netFD) *TCPConn {
 	c := &TCPConn{conn{fd}}
 	setNoDelay(c.fd, true)
@@ -190,7 +289,7 @@ func DialTCP(net string, laddr, raddr *TCPAddr) (*TCPConn, error) {
 	if raddr == nil {
 		return nil, &OpError{Op: "dial", Net: net, Source: laddr.opAddr(), Addr: nil, Err: errMissingAddress}
 	}
-	c, err := dialTCP(context.Background(), net, laddr, raddr)
+	c, err := dialTCP(context.Background(), net, laddr, raddr, nil)
 	if err != nil {
 		return nil, &OpError{Op: "dial", Net: net, Source: laddr.opAddr(), Addr: raddr.opAddr(), Err: err}
 	}
@@ -229,6 +328,29 @@ func (l *TCPListener) Accept() (Conn, error) {
 	return c, nil
 }
 
+// AcceptContext is like Accept, but if ctx is done before a
+// connection arrives, it stops waiting and returns ctx.Err(). The
+// listener stays open, and other calls to Accept are not affected. If
+// another Accept is in progress, AcceptContext waits for it to finish
+// before ctx is watched.
+// Returned connections will be of type *TCPConn.
+func (l *TCPListener) AcceptContext(ctx context.Context) (Conn, error) {
+	if !l.ok() {
+		return nil, syscall.EINVAL
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c, interrupted, err := l.acceptContext(ctx)
+	if interrupted && err == errInterrupted {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, &OpError{Op: "accept", Net: l.fd.net, Source: nil, Addr: l.fd.laddr, Err: err}
+	}
+	return c, nil
+}
+
 // Close stops listening on the TCP address.
 // Already Accepted connections are not closed.
 func (l *TCPListener) Close() error {
@@ -289,7 +411,7 @@ func ListenTCP(net string, laddr *TCPAddr) (*TCPListener, error) {
 	if laddr == nil {
 		laddr = &TCPAddr{}
 	}
-	ln, err := listenTCP(context.Background(), net, laddr)
+	ln, err := listenTCP(context.Background(), net, laddr, nil)
 	if err != nil {
 		return nil, &OpError{Op: "listen", Net: net, Source: nil, Addr: laddr.opAddr(), Err: err}
 	}
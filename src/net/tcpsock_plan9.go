@@ -8,20 +8,21 @@ import (
 	"context"
 	"io"
 	"os"
+	"syscall"
 )
 
 func (c *TCPConn) readFrom(r io.Reader) (int64, error) {
 	return genericReadFrom(c, r)
 }
 
-func dialTCP(ctx context.Context, net string, laddr, raddr *TCPAddr) (*TCPConn, error) {
+func dialTCP(ctx context.Context, net string, laddr, raddr *TCPAddr, _ func(string, string, syscall.RawConn) error) (*TCPConn, error) {
 	if testHookDialTCP != nil {
 		return testHookDialTCP(ctx, net, laddr, raddr)
 	}
-	return doDialTCP(ctx, net, laddr, raddr)
+	return doDialTCP(ctx, net, laddr, raddr, nil)
 }
 
-func doDialTCP(ctx context.Context, net string, laddr, raddr *TCPAddr) (*TCPConn, error) {
+func doDialTCP(ctx context.Context, net string, laddr, raddr *TCPAddr, _ func(string, string, syscall.RawConn) error) (*TCPConn, error) {
 	switch net {
 	case "tcp", "tcp4", "tcp6":
 	default:
@@ -47,6 +48,10 @@ func (ln *TCPListener) accept() (*TCPConn, error) {
 	return newTCPConn(fd), nil
 }
 
+func (ln *TCPListener) acceptContext(ctx context.Context) (*TCPConn, bool, error) {
+	return nil, false, syscall.EPLAN9
+}
+
 func (ln *TCPListener) close() error {
 	if _, err := ln.fd.ctl.WriteString("hangup"); err != nil {
 		ln.fd.ctl.Close()
@@ -66,7 +71,7 @@ func (ln *TCPListener) file() (*os.File, error) {
 	return f, nil
 }
 
-func listenTCP(ctx context.Context, network string, laddr *TCPAddr) (*TCPListener, error) {
+func listenTCP(ctx context.Context, network string, laddr *TCPAddr, _ func(string, string, syscall.RawConn) error) (*TCPListener, error) {
 	fd, err := listenPlan9(ctx, network, laddr)
 	if err != nil {
 		return nil, err
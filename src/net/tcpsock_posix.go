@@ -41,21 +41,28 @@ func (a *TCPAddr) sockaddr(family int) (syscall.Sockaddr, error) {
 }
 
 func (c *TCPConn) readFrom(r io.Reader) (int64, error) {
+	if n, err, handled := splice(c.fd, r); handled {
+		return n, err
+	}
 	if n, err, handled := sendFile(c.fd, r); handled {
 		return n, err
 	}
 	return genericReadFrom(c, r)
 }
 
-func dialTCP(ctx context.Context, net string, laddr, raddr *TCPAddr) (*TCPConn, error) {
+func dialTCP(ctx context.Context, net string, laddr, raddr *TCPAddr, ctrlFn func(string, string, syscall.RawConn) error) (*TCPConn, error) {
 	if testHookDialTCP != nil {
 		return testHookDialTCP(ctx, net, laddr, raddr)
 	}
-	return doDialTCP(ctx, net, laddr, raddr)
+	return doDialTCP(ctx, net, laddr, raddr, ctrlFn)
 }
 
-func doDialTCP(ctx context.Context, net string, laddr, raddr *TCPAddr) (*TCPConn, error) {
-	fd, err := internetSocket(ctx, net, laddr, raddr, syscall.SOCK_STREAM, 0, "dial")
+func doDialTCP(ctx context.Context, net string, laddr, raddr *TCPAddr, ctrlFn func(string, string, syscall.RawConn) error) (*TCPConn, error) {
+	return doDialTCPProto(ctx, net, laddr, raddr, 0, ctrlFn)
+}
+
+func doDialTCPProto(ctx context.Context, net string, laddr, raddr *TCPAddr, proto int, ctrlFn func(string, string, syscall.RawConn) error) (*TCPConn, error) {
+	fd, err := internetSocket(ctx, net, laddr, raddr, syscall.SOCK_STREAM, proto, "dial", ctrlFn)
 
 	// TCP has a rarely used mechanism called a 'simultaneous connection' in
 	// which Dial("tcp", addr1, addr2) run on the machine at addr1 can
@@ -85,7 +92,7 @@ func doDialTCP(ctx context.Context, net string, laddr, raddr *TCPAddr) (*TCPConn
 		if err == nil {
 			fd.Close()
 		}
-		fd, err = internetSocket(ctx, net, laddr, raddr, syscall.SOCK_STREAM, 0, "dial")
+		fd, err = internetSocket(ctx, net, laddr, raddr, syscall.SOCK_STREAM, proto, "dial", ctrlFn)
 	}
 
 	if err != nil {
@@ -136,6 +143,14 @@ func (ln *TCPListener) accept() (*TCPConn, error) {
 	return newTCPConn(fd), nil
 }
 
+func (ln *TCPListener) acceptContext(ctx context.Context) (*TCPConn, bool, error) {
+	fd, interrupted, err := ln.fd.acceptContext(ctx)
+	if err != nil {
+		return nil, interrupted, err
+	}
+	return newTCPConn(fd), interrupted, nil
+}
+
 func (ln *TCPListener) close() error {
 	return ln.fd.Close()
 }
@@ -148,8 +163,12 @@ func (ln *TCPListener) file() (*os.File, error) {
 	return f, nil
 }
 
-func listenTCP(ctx context.Context, network string, laddr *TCPAddr) (*TCPListener, error) {
-	fd, err := internetSocket(ctx, network, laddr, nil, syscall.SOCK_STREAM, 0, "listen")
+func listenTCP(ctx context.Context, network string, laddr *TCPAddr, ctrlFn func(string, string, syscall.RawConn) error) (*TCPListener, error) {
+	return listenTCPProto(ctx, network, laddr, 0, ctrlFn)
+}
+
+func listenTCPProto(ctx context.Context, network string, laddr *TCPAddr, proto int, ctrlFn func(string, string, syscall.RawConn) error) (*TCPListener, error) {
+	fd, err := internetSocket(ctx, network, laddr, nil, syscall.SOCK_STREAM, proto, "listen", ctrlFn)
 	if err != nil {
 		return nil, err
 	}
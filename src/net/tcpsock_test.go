@@ -7,6 +7,7 @@ package net
 import (
 	"internal/testenv"
 	"io"
+	"net/netip"
 	"reflect"
 	"runtime"
 	"sync"
@@ -312,6 +313,20 @@ var resolveTCPAddrTests = []resolveTCPAddrTest{
 	{"http", "127.0.0.1:0", nil, UnknownNetworkError("http")},
 }
 
+func TestTCPAddrAddrPort(t *testing.T) {
+	a := &TCPAddr{IP: ParseIP("2001:db8::1"), Port: 443}
+	ap := a.AddrPort()
+	if ap.String() != "[2001:db8::1]:443" {
+		t.Errorf("%v.AddrPort() = %v", a, ap)
+	}
+	if a2 := TCPAddrFromAddrPort(ap); !reflect.DeepEqual(a2, a) {
+		t.Errorf("TCPAddrFromAddrPort(%v) = %#v; want %#v", ap, a2, a)
+	}
+	if a := TCPAddrFromAddrPort(netip.AddrPort{}); a.IP != nil || a.Port != 0 {
+		t.Errorf("TCPAddrFromAddrPort of zero AddrPort = %v", a)
+	}
+}
+
 func TestResolveTCPAddr(t *testing.T) {
 	origTestHookLookupIP := testHookLookupIP
 	defer func() { testHookLookupIP = origTestHookLookupIP }()
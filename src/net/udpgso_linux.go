@@ -0,0 +1,71 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	sysUDP_SEGMENT = 0x67
+	sysUDP_GRO     = 0x68
+)
+
+// AppendUDPSegmentSize appends to oob a control message that asks the
+// kernel to split the payload of the datagram into datagrams of size
+// bytes each, using UDP generic segmentation offload, and returns the
+// extended buffer. The last datagram may be shorter than size.
+//
+// On platforms other than Linux, AppendUDPSegmentSize returns oob
+// unchanged.
+func AppendUDPSegmentSize(oob []byte, size int) []byte {
+	off := len(oob)
+	for i := 0; i < syscall.CmsgSpace(2); i++ {
+		oob = append(oob, 0)
+	}
+	h := (*syscall.Cmsghdr)(unsafe.Pointer(&oob[off]))
+	h.Level = syscall.IPPROTO_UDP
+	h.Type = sysUDP_SEGMENT
+	h.SetLen(syscall.CmsgLen(2))
+	*(*uint16)(unsafe.Pointer(&oob[off+syscall.CmsgLen(0)])) = uint16(size)
+	return oob
+}
+
+// ParseUDPSegmentSize returns the size of the datagrams that the
+// kernel coalesced into one buffer, as reported by the UDP generic
+// receive offload control message in oob. It reports false if oob
+// holds no such message, in which case the buffer holds a single
+// datagram. See UDPConn.SetGRO.
+//
+// On platforms other than Linux, ParseUDPSegmentSize always reports
+// false.
+func ParseUDPSegmentSize(oob []byte) (size int, ok bool) {
+	for len(oob) >= syscall.CmsgLen(0) {
+		h := (*syscall.Cmsghdr)(unsafe.Pointer(&oob[0]))
+		l := int(h.Len)
+		if l < syscall.CmsgLen(0) || l > len(oob) {
+			break
+		}
+		if h.Level == syscall.IPPROTO_UDP && h.Type == sysUDP_GRO && l >= syscall.CmsgLen(4) {
+			return int(*(*int32)(unsafe.Pointer(&oob[syscall.CmsgLen(0)]))), true
+		}
+		l = syscall.CmsgSpace(l - syscall.CmsgLen(0))
+		if l > len(oob) {
+			break
+		}
+		oob = oob[l:]
+	}
+	return 0, false
+}
+
+func setUDPGRO(fd *netFD, enable bool) error {
+	if err := fd.incref(); err != nil {
+		return err
+	}
+	defer fd.decref()
+	return os.NewSyscallError("setsockopt", syscall.SetsockoptInt(fd.sysfd, syscall.IPPROTO_UDP, sysUDP_GRO, boolint(enable)))
+}
@@ -0,0 +1,99 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func TestUDPSegmentSizeOOB(t *testing.T) {
+	oob := AppendUDPSegmentSize([]byte{}, 1200)
+	if len(oob) != syscall.CmsgSpace(2) {
+		t.Fatalf("got %d bytes; want %d", len(oob), syscall.CmsgSpace(2))
+	}
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 || msgs[0].Header.Level != syscall.IPPROTO_UDP || msgs[0].Header.Type != sysUDP_SEGMENT {
+		t.Fatalf("got %+v", msgs)
+	}
+	if size := *(*uint16)(unsafe.Pointer(&msgs[0].Data[0])); size != 1200 {
+		t.Errorf("got segment size %d; want 1200", size)
+	}
+	if _, ok := ParseUDPSegmentSize(oob); ok {
+		t.Error("ParseUDPSegmentSize found a GRO message in a GSO message")
+	}
+
+	// Build the message the kernel sends for GRO, after another
+	// control message.
+	gro := make([]byte, syscall.CmsgSpace(4))
+	h := (*syscall.Cmsghdr)(unsafe.Pointer(&gro[0]))
+	h.Level = syscall.IPPROTO_UDP
+	h.Type = sysUDP_GRO
+	h.SetLen(syscall.CmsgLen(4))
+	*(*int32)(unsafe.Pointer(&gro[syscall.CmsgLen(0)])) = 1350
+	oob = append(oob, gro...)
+	if size, ok := ParseUDPSegmentSize(oob); !ok || size != 1350 {
+		t.Errorf("ParseUDPSegmentSize = %d, %v; want 1350, true", size, ok)
+	}
+	if _, ok := ParseUDPSegmentSize(oob[:len(oob)-len(gro)+syscall.CmsgLen(4)-1]); ok {
+		t.Error("ParseUDPSegmentSize accepted a truncated message")
+	}
+}
+
+func TestUDPGSO(t *testing.T) {
+	if !testableNetwork("udp4") {
+		t.Skip("udp4 is not supported")
+	}
+	s, err := ListenUDP("udp4", &UDPAddr{IP: IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	c, err := DialUDP("udp4", nil, s.LocalAddr().(*UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := s.SetGRO(false); err != nil {
+		if perr, ok := err.(*OpError).Err.(*os.SyscallError); ok && perr.Err == syscall.ENOPROTOOPT {
+			t.Skip("UDP GRO is not supported by the kernel")
+		}
+		t.Fatal(err)
+	}
+
+	payload := bytes.Repeat([]byte("0123456789"), 25)
+	ms := []UDPMessage{{Buf: payload, OOB: AppendUDPSegmentSize(nil, 100)}}
+	if _, err := c.WriteBatch(ms); err != nil {
+		if perr, ok := err.(*OpError).Err.(*os.SyscallError); ok && (perr.Err == syscall.EINVAL || perr.Err == syscall.EIO) {
+			t.Skip("UDP GSO is not supported by the kernel")
+		}
+		t.Fatal(err)
+	}
+
+	s.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var got [][]byte
+	b := make([]byte, 512)
+	for len(got) < 3 {
+		n, _, err := s.ReadFromUDP(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, append([]byte(nil), b[:n]...))
+	}
+	want := [][]byte{payload[:100], payload[100:200], payload[200:]}
+	for i := range want {
+		if !bytes.Equal(got[i], want[i]) {
+			t.Errorf("datagram %d = %q; want %q", i, got[i], want[i])
+		}
+	}
+}
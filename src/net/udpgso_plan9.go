@@ -0,0 +1,19 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import "syscall"
+
+func AppendUDPSegmentSize(oob []byte, size int) []byte {
+	return oob
+}
+
+func ParseUDPSegmentSize(oob []byte) (size int, ok bool) {
+	return 0, false
+}
+
+func setUDPGRO(fd *netFD, enable bool) error {
+	return syscall.EPLAN9
+}
@@ -6,11 +6,13 @@ package net
 
 import (
 	"context"
+	"net/netip"
 	"syscall"
 )
 
-// BUG(mikio): On NaCl, Plan 9 and Windows, the ReadMsgUDP and
-// WriteMsgUDP methods of UDPConn are not implemented.
+// BUG(mikio): On NaCl, Plan 9 and Windows, the ReadMsgUDP,
+// WriteMsgUDP, ReadBatch and WriteBatch methods of UDPConn are not
+// implemented.
 
 // BUG(mikio): On Windows, the File method of UDPConn is not
 // implemented.
@@ -25,6 +27,31 @@ type UDPAddr struct {
 	Zone string // IPv6 scoped addressing zone
 }
 
+// AddrPort returns the UDPAddr a as a netip.AddrPort.
+//
+// If a.Port does not fit in a uint16, it's silently truncated.
+//
+// If a is nil, a zero value is returned.
+func (a *UDPAddr) AddrPort() netip.AddrPort {
+	if a == nil {
+		return netip.AddrPort{}
+	}
+	na, _ := netip.AddrFromSlice(a.IP)
+	na = na.WithZone(a.Zone)
+	return netip.AddrPortFrom(na, uint16(a.Port))
+}
+
+// UDPAddrFromAddrPort returns addr as a UDPAddr. If addr.IsValid()
+// is false, then the returned UDPAddr will contain a nil IP field,
+// indicating an address family-agnostic unspecified address.
+func UDPAddrFromAddrPort(addr netip.AddrPort) *UDPAddr {
+	return &UDPAddr{
+		IP:   addr.Addr().AsSlice(),
+		Zone: addr.Addr().Zone(),
+		Port: int(addr.Port()),
+	}
+}
+
 // Network returns the address's network name, "udp".
 func (a *UDPAddr) Network() string { return "udp" }
 
@@ -80,6 +107,15 @@ type UDPConn struct {
 	conn
 }
 
+// SyscallConn returns a raw network connection.
+// This implements the syscall.Conn interface.
+func (c *UDPConn) SyscallConn() (syscall.RawConn, error) {
+	if !c.ok() {
+		return nil, syscall.EINVAL
+	}
+	return newRawConn(c.fd)
+}
+
 // ReadFromUDP reads a UDP packet from c, copying the payload into b.
 // It returns the number of bytes copied into b and the return address
 // that was on the packet.
@@ -179,6 +215,91 @@ func (c *UDPConn) WriteMsgUDP(b, oob []byte, addr *UDPAddr) (n, oobn int, err er
 	return
 }
 
+// A UDPMessage is a UDP datagram and its out-of-band data, as read by
+// ReadBatch or written by WriteBatch.
+type UDPMessage struct {
+	// Buf holds the payload of the datagram.
+	Buf []byte
+
+	// OOB holds the out-of-band data, a sequence of socket
+	// control messages.
+	OOB []byte
+
+	// Addr is the source address of a datagram read, and the
+	// destination address of a datagram to be written. It must be
+	// the zero AddrPort to write on a connected UDPConn.
+	Addr netip.AddrPort
+
+	// N is the number of bytes of Buf read or written.
+	N int
+
+	// NN is the number of bytes of OOB read.
+	NN int
+
+	// Flags holds the flags that were set on a datagram read.
+	Flags int
+}
+
+// ReadBatch reads datagrams from c into ms, and returns the number of
+// messages that were filled in. It blocks until at least one datagram
+// is available.
+//
+// On Linux, ReadBatch uses the recvmmsg system call to read as many
+// datagrams as are queued, up to len(ms), in one call. On other
+// platforms it reads one datagram per call.
+func (c *UDPConn) ReadBatch(ms []UDPMessage) (int, error) {
+	if !c.ok() {
+		return 0, syscall.EINVAL
+	}
+	if len(ms) == 0 {
+		return 0, nil
+	}
+	n, err := c.readBatch(ms)
+	if err != nil {
+		err = &OpError{Op: "read", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
+	}
+	return n, err
+}
+
+// WriteBatch writes the datagrams in ms via c, and returns the number
+// of messages written. If it returns fewer than len(ms), the error
+// reports why the next message could not be written.
+//
+// On Linux, WriteBatch uses the sendmmsg system call to write several
+// datagrams in one call. On other platforms it writes one datagram at
+// a time.
+func (c *UDPConn) WriteBatch(ms []UDPMessage) (int, error) {
+	if !c.ok() {
+		return 0, syscall.EINVAL
+	}
+	n, err := c.writeBatch(ms)
+	if err != nil {
+		addr := c.fd.raddr
+		if n < len(ms) && ms[n].Addr.IsValid() {
+			addr = UDPAddrFromAddrPort(ms[n].Addr)
+		}
+		err = &OpError{Op: "write", Net: c.fd.net, Source: c.fd.laddr, Addr: addr, Err: err}
+	}
+	return n, err
+}
+
+// SetGRO enables or disables UDP generic receive offload on c. With
+// GRO enabled, the kernel may coalesce datagrams of the same flow into
+// a single larger buffer, and reports the size of the original
+// datagrams in a control message; see ParseUDPSegmentSize. The buffers
+// passed to ReadBatch or ReadMsgUDP should then be up to 64 KB.
+//
+// SetGRO is only supported on Linux.
+func (c *UDPConn) SetGRO(enable bool) error {
+	if !c.ok() {
+		return syscall.EINVAL
+	}
+	if err := setUDPGRO(c.fd, enable); err != nil {
+		return &OpError{Op: "set", Net: c.fd.net, Source: nil, Addr: c.fd.laddr, Err: err}
+	}
+	return nil
+}
+
 func newUDPConn(fd *netFD) *UDPConn { return &UDPConn{conn{fd}} }
 
 // DialUDP connects to the remote address raddr on the network net,
@@ -193,7 +314,7 @@ func DialUDP(net string, laddr, raddr *UDPAddr) (*UDPConn, error) {
 	if raddr == nil {
 		return nil, &OpError{Op: "dial", Net: net, Source: laddr.opAddr(), Addr: nil, Err: errMissingAddress}
 	}
-	c, err := dialUDP(context.Background(), net, laddr, raddr)
+	c, err := dialUDP(context.Background(), net, laddr, raddr, nil)
 	if err != nil {
 		return nil, &OpError{Op: "dial", Net: net, Source: laddr.opAddr(), Addr: raddr.opAddr(), Err: err}
 	}
@@ -216,7 +337,7 @@ func ListenUDP(net string, laddr *UDPAddr) (*UDPConn, error) {
 	if laddr == nil {
 		laddr = &UDPAddr{}
 	}
-	c, err := listenUDP(context.Background(), net, laddr)
+	c, err := listenUDP(context.Background(), net, laddr, nil)
 	if err != nil {
 		return nil, &OpError{Op: "listen", Net: net, Source: nil, Addr: laddr.opAddr(), Err: err}
 	}
@@ -243,7 +364,7 @@ func ListenMulticastUDP(network string, ifi *Interface, gaddr *UDPAddr) (*UDPCon
 	if gaddr == nil || gaddr.IP == nil {
 		return nil, &OpError{Op: "listen", Net: network, Source: nil, Addr: gaddr.opAddr(), Err: errMissingAddress}
 	}
-	c, err := listenMulticastUDP(context.Background(), network, ifi, gaddr)
+	c, err := listenMulticastUDP(context.Background(), network, ifi, gaddr, nil)
 	if err != nil {
 		return nil, &OpError{Op: "listen", Net: network, Source: nil, Addr: gaddr.opAddr(), Err: err}
 	}
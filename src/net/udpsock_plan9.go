@@ -55,7 +55,7 @@ func (c *UDPConn) writeMsg(b, oob []byte, addr *UDPAddr) (n, oobn int, err error
 	return 0, 0, syscall.EPLAN9
 }
 
-func dialUDP(ctx context.Context, net string, laddr, raddr *UDPAddr) (*UDPConn, error) {
+func dialUDP(ctx context.Context, net string, laddr, raddr *UDPAddr, _ func(string, string, syscall.RawConn) error) (*UDPConn, error) {
 	fd, err := dialPlan9(ctx, net, laddr, raddr)
 	if err != nil {
 		return nil, err
@@ -91,7 +91,7 @@ func unmarshalUDPHeader(b []byte) (*udpHeader, []byte) {
 	return h, b
 }
 
-func listenUDP(ctx context.Context, network string, laddr *UDPAddr) (*UDPConn, error) {
+func listenUDP(ctx context.Context, network string, laddr *UDPAddr, _ func(string, string, syscall.RawConn) error) (*UDPConn, error) {
 	l, err := listenPlan9(ctx, network, laddr)
 	if err != nil {
 		return nil, err
@@ -108,6 +108,6 @@ func listenUDP(ctx context.Context, network string, laddr *UDPAddr) (*UDPConn, e
 	return newUDPConn(fd), err
 }
 
-func listenMulticastUDP(ctx context.Context, network string, ifi *Interface, gaddr *UDPAddr) (*UDPConn, error) {
+func listenMulticastUDP(ctx context.Context, network string, ifi *Interface, gaddr *UDPAddr, _ func(string, string, syscall.RawConn) error) (*UDPConn, error) {
 	return nil, syscall.EPLAN9
 }
@@ -98,6 +98,31 @@ func TestResolveUDPAddr(t *testing.T) {
 	}
 }
 
+func TestUDPAddrAddrPort(t *testing.T) {
+	for _, tt := range []struct {
+		in   *UDPAddr
+		want string
+	}{
+		{&UDPAddr{IP: IP{192, 0, 2, 1}, Port: 53}, "192.0.2.1:53"},
+		{&UDPAddr{IP: IPv4(192, 0, 2, 1), Port: 53}, "[::ffff:192.0.2.1]:53"},
+		{&UDPAddr{IP: ParseIP("fe80::1"), Port: 5353, Zone: "eth0"}, "[fe80::1%eth0]:5353"},
+		{&UDPAddr{Port: 53}, "invalid AddrPort"},
+		{nil, "invalid AddrPort"},
+	} {
+		ap := tt.in.AddrPort()
+		if ap.String() != tt.want {
+			t.Errorf("%v.AddrPort() = %v; want %s", tt.in, ap, tt.want)
+		}
+		if tt.in == nil {
+			continue
+		}
+		a := UDPAddrFromAddrPort(ap)
+		if !a.IP.Equal(tt.in.IP) || a.Port != tt.in.Port || a.Zone != tt.in.Zone {
+			t.Errorf("UDPAddrFromAddrPort(%v) = %v; want %v", ap, a, tt.in)
+		}
+	}
+}
+
 func TestWriteToUDP(t *testing.T) {
 	switch runtime.GOOS {
 	case "plan9":
@@ -206,6 +231,86 @@ func testWriteToPacketConn(t *testing.T, raddr string) {
 	}
 }
 
+func TestUDPConnBatch(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9", "windows":
+		t.Skipf("not supported on %s", runtime.GOOS)
+	}
+	if !testableNetwork("udp4") {
+		t.Skip("udp4 is not supported")
+	}
+
+	s, err := ListenUDP("udp4", &UDPAddr{IP: IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	c, err := ListenUDP("udp4", &UDPAddr{IP: IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	dst := s.LocalAddr().(*UDPAddr).AddrPort()
+	src := c.LocalAddr().(*UDPAddr).AddrPort()
+	ws := make([]UDPMessage, 4)
+	for i := range ws {
+		ws[i] = UDPMessage{Buf: []byte{'A' + byte(i), 'B'}, Addr: dst}
+	}
+	n, err := c.WriteBatch(ws)
+	if err != nil || n != len(ws) {
+		t.Fatalf("WriteBatch = %d, %v; want %d, nil", n, err, len(ws))
+	}
+	for i, m := range ws {
+		if m.N != 2 {
+			t.Errorf("message %d: wrote %d bytes; want 2", i, m.N)
+		}
+	}
+
+	s.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var got []string
+	for len(got) < len(ws) {
+		rs := make([]UDPMessage, 8)
+		for i := range rs {
+			rs[i].Buf = make([]byte, 16)
+		}
+		n, err := s.ReadBatch(rs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n == 0 || n > len(rs) {
+			t.Fatalf("ReadBatch = %d", n)
+		}
+		for _, m := range rs[:n] {
+			if m.Addr != src {
+				t.Errorf("got source %v; want %v", m.Addr, src)
+			}
+			got = append(got, string(m.Buf[:m.N]))
+		}
+	}
+	if want := []string{"AB", "BB", "CB", "DB"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q; want %q", got, want)
+	}
+
+	// The destination is missing on an unconnected socket, and
+	// redundant on a connected one.
+	ws = []UDPMessage{{Buf: []byte("X"), Addr: dst}, {Buf: []byte("Y")}}
+	if n, err := c.WriteBatch(ws); n != 1 || err == nil || err.(*OpError).Err != errMissingAddress {
+		t.Errorf("WriteBatch = %d, %v; want 1, errMissingAddress", n, err)
+	}
+	cc, err := DialUDP("udp4", nil, UDPAddrFromAddrPort(dst))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+	if n, err := cc.WriteBatch([]UDPMessage{{Buf: []byte("Z"), Addr: dst}}); n != 0 || err == nil || err.(*OpError).Err != ErrWriteToConnected {
+		t.Errorf("WriteBatch = %d, %v; want 0, ErrWriteToConnected", n, err)
+	}
+	if n, err := cc.WriteBatch([]UDPMessage{{Buf: []byte("Z")}}); n != 1 || err != nil {
+		t.Errorf("WriteBatch = %d, %v; want 1, nil", n, err)
+	}
+}
+
 var udpConnLocalNameTests = []struct {
 	net   string
 	laddr *UDPAddr
@@ -0,0 +1,47 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import "syscall"
+
+// UnixCredentials holds the credentials of a process, as passed in
+// a control message over a Unix domain socket or reported for the
+// peer of a connection.
+type UnixCredentials struct {
+	PID int
+	UID int
+	GID int
+}
+
+// SetPassCredentials sets whether the operating system attaches the
+// sender's credentials to each message received on c. The
+// credentials can then be parsed from the out-of-band data returned
+// by ReadMsgUnix with ParseUnixCredentials.
+//
+// SetPassCredentials is supported on Linux only.
+func (c *UnixConn) SetPassCredentials(enable bool) error {
+	if !c.ok() {
+		return syscall.EINVAL
+	}
+	if err := setPassCredentials(c.fd, enable); err != nil {
+		return &OpError{Op: "set", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
+	}
+	return nil
+}
+
+// PeerCredentials returns the credentials of the process at the
+// other end of c, as they were when the connection was established.
+//
+// PeerCredentials is supported on Linux only.
+func (c *UnixConn) PeerCredentials() (*UnixCredentials, error) {
+	if !c.ok() {
+		return nil, syscall.EINVAL
+	}
+	cred, err := peerCredentials(c.fd)
+	if err != nil {
+		return nil, &OpError{Op: "get", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
+	}
+	return cred, nil
+}
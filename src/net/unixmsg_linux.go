@@ -0,0 +1,71 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import (
+	"os"
+	"syscall"
+)
+
+// AppendUnixCredentials appends to oob a control message that passes
+// the credentials cred to the receiving process, for use with
+// WriteMsgUnix. Unless the sending process is privileged, cred must
+// hold its own process, user and group IDs.
+//
+// On platforms other than Linux, AppendUnixCredentials returns oob
+// unchanged.
+func AppendUnixCredentials(oob []byte, cred *UnixCredentials) []byte {
+	return append(oob, syscall.UnixCredentials(&syscall.Ucred{
+		Pid: int32(cred.PID),
+		Uid: uint32(cred.UID),
+		Gid: uint32(cred.GID),
+	})...)
+}
+
+// ParseUnixCredentials returns the credentials passed in the control
+// messages in oob, the out-of-band data returned by ReadMsgUnix.
+// The receiving connection must have been set up with
+// SetPassCredentials. It reports whether oob held credentials.
+//
+// On platforms other than Linux, ParseUnixCredentials always reports
+// false.
+func ParseUnixCredentials(oob []byte) (cred *UnixCredentials, ok bool) {
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return nil, false
+	}
+	for i := range msgs {
+		m := &msgs[i]
+		if m.Header.Level != syscall.SOL_SOCKET || m.Header.Type != syscall.SCM_CREDENTIALS {
+			continue
+		}
+		uc, err := syscall.ParseUnixCredentials(m)
+		if err != nil {
+			return nil, false
+		}
+		return &UnixCredentials{PID: int(uc.Pid), UID: int(uc.Uid), GID: int(uc.Gid)}, true
+	}
+	return nil, false
+}
+
+func setPassCredentials(fd *netFD, enable bool) error {
+	if err := fd.incref(); err != nil {
+		return err
+	}
+	defer fd.decref()
+	return os.NewSyscallError("setsockopt", syscall.SetsockoptInt(fd.sysfd, syscall.SOL_SOCKET, syscall.SO_PASSCRED, boolint(enable)))
+}
+
+func peerCredentials(fd *netFD) (*UnixCredentials, error) {
+	if err := fd.incref(); err != nil {
+		return nil, err
+	}
+	defer fd.decref()
+	uc, err := syscall.GetsockoptUcred(fd.sysfd, syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	if err != nil {
+		return nil, os.NewSyscallError("getsockopt", err)
+	}
+	return &UnixCredentials{PID: int(uc.Pid), UID: int(uc.Uid), GID: int(uc.Gid)}, nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import (
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestUnixCredentials(t *testing.T) {
+	c1, c2 := newUnixConnPair(t, syscall.SOCK_STREAM)
+	defer c1.Close()
+	defer c2.Close()
+
+	self := &UnixCredentials{PID: os.Getpid(), UID: os.Getuid(), GID: os.Getgid()}
+	peer, err := c2.PeerCredentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *peer != *self {
+		t.Errorf("PeerCredentials() = %+v; want %+v", peer, self)
+	}
+
+	if err := c2.SetPassCredentials(true); err != nil {
+		t.Fatal(err)
+	}
+	oob := AppendUnixCredentials(nil, self)
+	if _, _, err := c1.WriteMsgUnix([]byte("x"), oob, nil); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 1)
+	roob := make([]byte, syscall.CmsgSpace(syscall.SizeofUcred))
+	_, oobn, _, _, err := c2.ReadMsgUnix(b, roob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred, ok := ParseUnixCredentials(roob[:oobn])
+	if !ok {
+		t.Fatal("no credentials received")
+	}
+	if *cred != *self {
+		t.Errorf("got credentials %+v; want %+v", cred, self)
+	}
+
+	if _, ok := ParseUnixCredentials(nil); ok {
+		t.Error("ParseUnixCredentials(nil) reported credentials")
+	}
+}
@@ -0,0 +1,25 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin dragonfly freebsd nacl netbsd openbsd solaris windows
+
+package net
+
+import "syscall"
+
+func AppendUnixCredentials(oob []byte, cred *UnixCredentials) []byte {
+	return oob
+}
+
+func ParseUnixCredentials(oob []byte) (cred *UnixCredentials, ok bool) {
+	return nil, false
+}
+
+func setPassCredentials(fd *netFD, enable bool) error {
+	return syscall.ENOPROTOOPT
+}
+
+func peerCredentials(fd *netFD) (*UnixCredentials, error) {
+	return nil, syscall.ENOPROTOOPT
+}
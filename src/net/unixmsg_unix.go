@@ -0,0 +1,61 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package net
+
+import (
+	"os"
+	"syscall"
+)
+
+// AppendUnixRights appends to oob a control message that passes the
+// open files to the receiving process, for use with WriteMsgUnix.
+// The files stay open in the sending process.
+//
+// On Windows and Plan 9, AppendUnixRights returns oob unchanged.
+func AppendUnixRights(oob []byte, files ...*os.File) []byte {
+	if len(files) == 0 {
+		return oob
+	}
+	fds := make([]int, len(files))
+	for i, f := range files {
+		fds[i] = int(f.Fd())
+	}
+	return append(oob, syscall.UnixRights(fds...)...)
+}
+
+// ParseUnixRights returns the files passed in the control messages
+// in oob, the out-of-band data returned by ReadMsgUnix. The caller
+// is responsible for closing them. If oob holds no files,
+// ParseUnixRights returns nil and no error.
+//
+// On Windows and Plan 9, ParseUnixRights always returns nil and no
+// error.
+func ParseUnixRights(oob []byte) ([]*os.File, error) {
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return nil, os.NewSyscallError("parse socket control message", err)
+	}
+	var files []*os.File
+	for i := range msgs {
+		m := &msgs[i]
+		if m.Header.Level != syscall.SOL_SOCKET || m.Header.Type != syscall.SCM_RIGHTS {
+			continue
+		}
+		fds, err := syscall.ParseUnixRights(m)
+		if err != nil {
+			for _, f := range files {
+				f.Close()
+			}
+			return nil, os.NewSyscallError("parse unix rights", err)
+		}
+		for _, fd := range fds {
+			syscall.CloseOnExec(fd)
+			files = append(files, os.NewFile(uintptr(fd), "unix-rights"))
+		}
+	}
+	return files, nil
+}
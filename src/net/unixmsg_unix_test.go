@@ -0,0 +1,101 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package net
+
+import (
+	"io/ioutil"
+	"os"
+	"runtime"
+	"syscall"
+	"testing"
+)
+
+// newUnixConnPair returns a connected pair of Unix domain sockets of
+// the given socket type.
+func newUnixConnPair(t *testing.T, sotype int) (*UnixConn, *UnixConn) {
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, sotype, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cs [2]*UnixConn
+	for i, fd := range fds {
+		f := os.NewFile(uintptr(fd), "socketpair")
+		c, err := FileConn(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cs[i] = c.(*UnixConn)
+	}
+	return cs[0], cs[1]
+}
+
+func TestUnixRights(t *testing.T) {
+	if !testableNetwork("unixgram") {
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+	c1, c2 := newUnixConnPair(t, syscall.SOCK_DGRAM)
+	defer c1.Close()
+	defer c2.Close()
+
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.Close()
+	defer pw.Close()
+	tmp, err := ioutil.TempFile("", "unixrights")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	oob := AppendUnixRights(nil, pw, tmp)
+	if _, _, err := c1.WriteMsgUnix([]byte("x"), oob, nil); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 1)
+	roob := make([]byte, syscall.CmsgSpace(2*4))
+	_, oobn, _, _, err := c2.ReadMsgUnix(b, roob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := ParseUnixRights(roob[:oobn])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("got %d files; want 2", len(files))
+	}
+	defer files[0].Close()
+	defer files[1].Close()
+
+	// The received pipe end must write to our pipe.
+	if _, err := files[0].Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	var buf [5]byte
+	if _, err := pr.Read(buf[:]); err != nil || string(buf[:]) != "hello" {
+		t.Errorf("got %q, %v; want %q, nil", buf[:], err, "hello")
+	}
+	// The received file must be the same file.
+	var st1, st2 syscall.Stat_t
+	if err := syscall.Fstat(int(tmp.Fd()), &st1); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Fstat(int(files[1].Fd()), &st2); err != nil {
+		t.Fatal(err)
+	}
+	if st1.Ino != st2.Ino || st1.Dev != st2.Dev {
+		t.Error("received a different file")
+	}
+
+	if files, err := ParseUnixRights(nil); files != nil || err != nil {
+		t.Errorf("ParseUnixRights(nil) = %v, %v; want nil, nil", files, err)
+	}
+}
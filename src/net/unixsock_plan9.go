@@ -26,7 +26,7 @@ func (c *UnixConn) writeMsg(b, oob []byte, addr *UnixAddr) (n, oobn int, err err
 	return 0, 0, syscall.EPLAN9
 }
 
-func dialUnix(ctx context.Context, network string, laddr, raddr *UnixAddr) (*UnixConn, error) {
+func dialUnix(ctx context.Context, network string, laddr, raddr *UnixAddr, _ func(string, string, syscall.RawConn) error) (*UnixConn, error) {
 	return nil, syscall.EPLAN9
 }
 
@@ -34,6 +34,10 @@ func (ln *UnixListener) accept() (*UnixConn, error) {
 	return nil, syscall.EPLAN9
 }
 
+func (ln *UnixListener) acceptContext(ctx context.Context) (*UnixConn, bool, error) {
+	return nil, false, syscall.EPLAN9
+}
+
 func (ln *UnixListener) close() error {
 	return syscall.EPLAN9
 }
@@ -42,10 +46,10 @@ func (ln *UnixListener) file() (*os.File, error) {
 	return nil, syscall.EPLAN9
 }
 
-func listenUnix(ctx context.Context, network string, laddr *UnixAddr) (*UnixListener, error) {
+func listenUnix(ctx context.Context, network string, laddr *UnixAddr, _ func(string, string, syscall.RawConn) error) (*UnixListener, error) {
 	return nil, syscall.EPLAN9
 }
 
-func listenUnixgram(ctx context.Context, network string, laddr *UnixAddr) (*UnixConn, error) {
+func listenUnixgram(ctx context.Context, network string, laddr *UnixAddr, _ func(string, string, syscall.RawConn) error) (*UnixConn, error) {
 	return nil, syscall.EPLAN9
 }
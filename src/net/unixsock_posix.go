@@ -13,7 +13,7 @@ import (
 	"syscall"
 )
 
-func unixSocket(ctx context.Context, net string, laddr, raddr sockaddr, mode string) (*netFD, error) {
+func unixSocket(ctx context.Context, net string, laddr, raddr sockaddr, mode string, ctrlFn func(string, string, syscall.RawConn) error) (*netFD, error) {
 	var sotype int
 	switch net {
 	case "unix":
@@ -42,7 +42,7 @@ func unixSocket(ctx context.Context, net string, laddr, raddr sockaddr, mode str
 		return nil, errors.New("unknown mode: " + mode)
 	}
 
-	fd, err := socket(ctx, net, syscall.AF_UNIX, sotype, 0, false, laddr, raddr)
+	fd, err := socket(ctx, net, syscall.AF_UNIX, sotype, 0, false, laddr, raddr, ctrlFn)
 	if err != nil {
 		return nil, err
 	}
@@ -146,8 +146,8 @@ func (c *UnixConn) writeMsg(b, oob []byte, addr *UnixAddr) (n, oobn int, err err
 	return c.fd.writeMsg(b, oob, sa)
 }
 
-func dialUnix(ctx context.Context, net string, laddr, raddr *UnixAddr) (*UnixConn, error) {
-	fd, err := unixSocket(ctx, net, laddr, raddr, "dial")
+func dialUnix(ctx context.Context, net string, laddr, raddr *UnixAddr, ctrlFn func(string, string, syscall.RawConn) error) (*UnixConn, error) {
+	fd, err := unixSocket(ctx, net, laddr, raddr, "dial", ctrlFn)
 	if err != nil {
 		return nil, err
 	}
@@ -162,6 +162,14 @@ func (ln *UnixListener) accept() (*UnixConn, error) {
 	return newUnixConn(fd), nil
 }
 
+func (ln *UnixListener) acceptContext(ctx context.Context) (*UnixConn, bool, error) {
+	fd, interrupted, err := ln.fd.acceptContext(ctx)
+	if err != nil {
+		return nil, interrupted, err
+	}
+	return newUnixConn(fd), interrupted, nil
+}
+
 func (ln *UnixListener) close() error {
 	// The operating system doesn't clean up
 	// the file that announcing created, so
@@ -187,16 +195,16 @@ func (ln *UnixListener) file() (*os.File, error) {
 	return f, nil
 }
 
-func listenUnix(ctx context.Context, network string, laddr *UnixAddr) (*UnixListener, error) {
-	fd, err := unixSocket(ctx, network, laddr, nil, "listen")
+func listenUnix(ctx context.Context, network string, laddr *UnixAddr, ctrlFn func(string, string, syscall.RawConn) error) (*UnixListener, error) {
+	fd, err := unixSocket(ctx, network, laddr, nil, "listen", ctrlFn)
 	if err != nil {
 		return nil, err
 	}
 	return &UnixListener{fd: fd, path: fd.laddr.String(), unlink: true}, nil
 }
 
-func listenUnixgram(ctx context.Context, network string, laddr *UnixAddr) (*UnixConn, error) {
-	fd, err := unixSocket(ctx, network, laddr, nil, "listen")
+func listenUnixgram(ctx context.Context, network string, laddr *UnixAddr, ctrlFn func(string, string, syscall.RawConn) error) (*UnixConn, error) {
+	fd, err := unixSocket(ctx, network, laddr, nil, "listen", ctrlFn)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,275 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"time"
+)
+
+// sysAF_VSOCK is the Linux AF_VSOCK address family.
+const sysAF_VSOCK = 0x28
+
+// Well-known vsock context IDs and ports.
+const (
+	// VsockCIDHypervisor is the context ID of the hypervisor.
+	VsockCIDHypervisor = 0
+
+	// VsockCIDLocal is the context ID for communication within
+	// the local machine, like a loopback address.
+	VsockCIDLocal = 1
+
+	// VsockCIDHost is the context ID of the host, as seen from a
+	// virtual machine.
+	VsockCIDHost = 2
+
+	// VsockCIDAny is the wildcard context ID. It can be used
+	// to listen on any context ID of the local machine.
+	VsockCIDAny = 0xffffffff
+
+	// VsockPortAny is the wildcard port. It can be used to let
+	// the system pick a port.
+	VsockPortAny = 0xffffffff
+)
+
+// VsockAddr represents the address of a vsock (AF_VSOCK) end point,
+// used for communication between virtual machines and their host.
+type VsockAddr struct {
+	CID  uint32 // context ID of a virtual machine or of the host
+	Port uint32
+	Net  string // "vsock" or "vsockpacket"
+}
+
+// Network returns the address's network name, "vsock" or
+// "vsockpacket".
+func (a *VsockAddr) Network() string {
+	return a.Net
+}
+
+func (a *VsockAddr) String() string {
+	if a == nil {
+		return "<nil>"
+	}
+	return uitoa(uint(a.CID)) + ":" + uitoa(uint(a.Port))
+}
+
+func (a *VsockAddr) isWildcard() bool {
+	return a == nil || a.CID == VsockCIDAny
+}
+
+func (a *VsockAddr) opAddr() Addr {
+	if a == nil {
+		return nil
+	}
+	return a
+}
+
+// ResolveVsockAddr parses addr as a vsock address of the form
+// "cid:port", where cid and port are decimal numbers, such as
+// "2:1024". An empty cid, as in ":1024", means VsockCIDAny.
+// The string net gives the network name, "vsock" or "vsockpacket".
+func ResolveVsockAddr(net, addr string) (*VsockAddr, error) {
+	switch net {
+	case "vsock", "vsockpacket":
+	default:
+		return nil, UnknownNetworkError(net)
+	}
+	i := last(addr, ':')
+	if i < 0 {
+		return nil, &AddrError{Err: "missing port in address", Addr: addr}
+	}
+	cid := uint32(VsockCIDAny)
+	if i > 0 {
+		n, ok := parseUint32(addr[:i])
+		if !ok {
+			return nil, &AddrError{Err: "invalid context ID", Addr: addr}
+		}
+		cid = n
+	}
+	port, ok := parseUint32(addr[i+1:])
+	if !ok {
+		return nil, &AddrError{Err: "invalid port", Addr: addr}
+	}
+	return &VsockAddr{CID: cid, Port: port, Net: net}, nil
+}
+
+// parseUint32 parses s as a decimal uint32.
+func parseUint32(s string) (uint32, bool) {
+	if s == "" || len(s) > 10 {
+		return 0, false
+	}
+	var n uint64
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return 0, false
+		}
+		n = n*10 + uint64(s[i]-'0')
+	}
+	if n > 1<<32-1 {
+		return 0, false
+	}
+	return uint32(n), true
+}
+
+// VsockConn is an implementation of the Conn interface for vsock
+// connections.
+type VsockConn struct {
+	conn
+}
+
+// SyscallConn returns a raw network connection.
+// This implements the syscall.Conn interface.
+func (c *VsockConn) SyscallConn() (syscall.RawConn, error) {
+	if !c.ok() {
+		return nil, syscall.EINVAL
+	}
+	return newRawConn(c.fd)
+}
+
+// CloseRead shuts down the reading side of the vsock connection.
+// Most callers should just use Close.
+func (c *VsockConn) CloseRead() error {
+	if !c.ok() {
+		return syscall.EINVAL
+	}
+	if err := c.fd.closeRead(); err != nil {
+		return &OpError{Op: "close", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
+	}
+	return nil
+}
+
+// CloseWrite shuts down the writing side of the vsock connection.
+// Most callers should just use Close.
+func (c *VsockConn) CloseWrite() error {
+	if !c.ok() {
+		return syscall.EINVAL
+	}
+	if err := c.fd.closeWrite(); err != nil {
+		return &OpError{Op: "close", Net: c.fd.net, Source: c.fd.laddr, Addr: c.fd.raddr, Err: err}
+	}
+	return nil
+}
+
+func newVsockConn(fd *netFD) *VsockConn { return &VsockConn{conn{fd}} }
+
+// DialVsock connects to the remote address raddr on the network net,
+// which must be "vsock" or "vsockpacket". If laddr is not nil, it is
+// used as the local address for the connection.
+//
+// Vsock is supported on Linux only.
+func DialVsock(net string, laddr, raddr *VsockAddr) (*VsockConn, error) {
+	switch net {
+	case "vsock", "vsockpacket":
+	default:
+		return nil, &OpError{Op: "dial", Net: net, Source: laddr.opAddr(), Addr: raddr.opAddr(), Err: UnknownNetworkError(net)}
+	}
+	if raddr == nil {
+		return nil, &OpError{Op: "dial", Net: net, Source: laddr.opAddr(), Addr: nil, Err: errMissingAddress}
+	}
+	c, err := dialVsock(context.Background(), net, laddr, raddr, nil)
+	if err != nil {
+		return nil, &OpError{Op: "dial", Net: net, Source: laddr.opAddr(), Addr: raddr.opAddr(), Err: err}
+	}
+	return c, nil
+}
+
+// VsockListener is a vsock listener. Clients should typically use
+// variables of type Listener instead of assuming vsock.
+type VsockListener struct {
+	fd *netFD
+}
+
+func (ln *VsockListener) ok() bool { return ln != nil && ln.fd != nil }
+
+// AcceptVsock accepts the next incoming call and returns the new
+// connection.
+func (l *VsockListener) AcceptVsock() (*VsockConn, error) {
+	if !l.ok() {
+		return nil, syscall.EINVAL
+	}
+	c, err := l.accept()
+	if err != nil {
+		return nil, &OpError{Op: "accept", Net: l.fd.net, Source: nil, Addr: l.fd.laddr, Err: err}
+	}
+	return c, nil
+}
+
+// Accept implements the Accept method in the Listener interface.
+// Returned connections will be of type *VsockConn.
+func (l *VsockListener) Accept() (Conn, error) {
+	if !l.ok() {
+		return nil, syscall.EINVAL
+	}
+	c, err := l.accept()
+	if err != nil {
+		return nil, &OpError{Op: "accept", Net: l.fd.net, Source: nil, Addr: l.fd.laddr, Err: err}
+	}
+	return c, nil
+}
+
+// Close stops listening on the vsock address. Already accepted
+// connections are not closed.
+func (l *VsockListener) Close() error {
+	if !l.ok() {
+		return syscall.EINVAL
+	}
+	if err := l.fd.Close(); err != nil {
+		return &OpError{Op: "close", Net: l.fd.net, Source: nil, Addr: l.fd.laddr, Err: err}
+	}
+	return nil
+}
+
+// Addr returns the listener's network address, a *VsockAddr.
+// The Addr returned is shared by all invocations of Addr, so
+// do not modify it.
+func (l *VsockListener) Addr() Addr { return l.fd.laddr }
+
+// SetDeadline sets the deadline associated with the listener.
+// A zero time value disables the deadline.
+func (l *VsockListener) SetDeadline(t time.Time) error {
+	if !l.ok() {
+		return syscall.EINVAL
+	}
+	if err := l.fd.setDeadline(t); err != nil {
+		return &OpError{Op: "set", Net: l.fd.net, Source: nil, Addr: l.fd.laddr, Err: err}
+	}
+	return nil
+}
+
+// File returns a copy of the underlying os.File, set to blocking
+// mode. It is the caller's responsibility to close f when finished.
+// Closing l does not affect f, and closing f does not affect l.
+func (l *VsockListener) File() (f *os.File, err error) {
+	if !l.ok() {
+		return nil, syscall.EINVAL
+	}
+	f, err = l.fd.dup()
+	if err != nil {
+		err = &OpError{Op: "file", Net: l.fd.net, Source: nil, Addr: l.fd.laddr, Err: err}
+	}
+	return
+}
+
+// ListenVsock announces on the vsock address laddr and returns a
+// vsock listener. The network net must be "vsock" or "vsockpacket".
+//
+// Vsock is supported on Linux only.
+func ListenVsock(net string, laddr *VsockAddr) (*VsockListener, error) {
+	switch net {
+	case "vsock", "vsockpacket":
+	default:
+		return nil, &OpError{Op: "listen", Net: net, Source: nil, Addr: laddr.opAddr(), Err: UnknownNetworkError(net)}
+	}
+	if laddr == nil {
+		laddr = &VsockAddr{CID: VsockCIDAny, Port: VsockPortAny, Net: net}
+	}
+	ln, err := listenVsock(context.Background(), net, laddr, nil)
+	if err != nil {
+		return nil, &OpError{Op: "listen", Net: net, Source: nil, Addr: laddr.opAddr(), Err: err}
+	}
+	return ln, nil
+}
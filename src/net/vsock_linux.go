@@ -0,0 +1,77 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import (
+	"context"
+	"syscall"
+)
+
+func vsockSocket(ctx context.Context, net string, laddr, raddr sockaddr, ctrlFn func(string, string, syscall.RawConn) error) (*netFD, error) {
+	var sotype int
+	switch net {
+	case "vsock":
+		sotype = syscall.SOCK_STREAM
+	case "vsockpacket":
+		sotype = syscall.SOCK_SEQPACKET
+	default:
+		return nil, UnknownNetworkError(net)
+	}
+	return socket(ctx, net, sysAF_VSOCK, sotype, 0, false, laddr, raddr, ctrlFn)
+}
+
+func sockaddrToVsock(sa syscall.Sockaddr) Addr {
+	if s, ok := sa.(*syscall.SockaddrVM); ok {
+		return &VsockAddr{CID: s.CID, Port: s.Port, Net: "vsock"}
+	}
+	return nil
+}
+
+func sockaddrToVsockpacket(sa syscall.Sockaddr) Addr {
+	if s, ok := sa.(*syscall.SockaddrVM); ok {
+		return &VsockAddr{CID: s.CID, Port: s.Port, Net: "vsockpacket"}
+	}
+	return nil
+}
+
+func (a *VsockAddr) family() int {
+	return sysAF_VSOCK
+}
+
+func (a *VsockAddr) sockaddr(family int) (syscall.Sockaddr, error) {
+	if a == nil {
+		return nil, nil
+	}
+	return &syscall.SockaddrVM{CID: a.CID, Port: a.Port}, nil
+}
+
+func dialVsock(ctx context.Context, net string, laddr, raddr *VsockAddr, ctrlFn func(string, string, syscall.RawConn) error) (*VsockConn, error) {
+	// A nil *VsockAddr must become a nil sockaddr interface.
+	var la sockaddr
+	if laddr != nil {
+		la = laddr
+	}
+	fd, err := vsockSocket(ctx, net, la, raddr, ctrlFn)
+	if err != nil {
+		return nil, err
+	}
+	return newVsockConn(fd), nil
+}
+
+func (ln *VsockListener) accept() (*VsockConn, error) {
+	fd, err := ln.fd.accept()
+	if err != nil {
+		return nil, err
+	}
+	return newVsockConn(fd), nil
+}
+
+func listenVsock(ctx context.Context, net string, laddr *VsockAddr, ctrlFn func(string, string, syscall.RawConn) error) (*VsockListener, error) {
+	fd, err := vsockSocket(ctx, net, laddr, nil, ctrlFn)
+	if err != nil {
+		return nil, err
+	}
+	return &VsockListener{fd}, nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import (
+	"io"
+	"testing"
+)
+
+var resolveVsockAddrTests = []struct {
+	network, address string
+	want             *VsockAddr
+	ok               bool
+}{
+	{"vsock", "2:1024", &VsockAddr{CID: 2, Port: 1024, Net: "vsock"}, true},
+	{"vsockpacket", "3:4294967295", &VsockAddr{CID: 3, Port: VsockPortAny, Net: "vsockpacket"}, true},
+	{"vsock", ":80", &VsockAddr{CID: VsockCIDAny, Port: 80, Net: "vsock"}, true},
+	{"vsock", "2", nil, false},
+	{"vsock", "2:", nil, false},
+	{"vsock", "x:80", nil, false},
+	{"vsock", "2:4294967296", nil, false},
+	{"tcp", "2:80", nil, false},
+}
+
+func TestResolveVsockAddr(t *testing.T) {
+	for _, tt := range resolveVsockAddrTests {
+		got, err := ResolveVsockAddr(tt.network, tt.address)
+		if (err == nil) != tt.ok {
+			t.Errorf("ResolveVsockAddr(%q, %q) error = %v; want ok %v", tt.network, tt.address, err, tt.ok)
+			continue
+		}
+		if tt.ok && *got != *tt.want {
+			t.Errorf("ResolveVsockAddr(%q, %q) = %+v; want %+v", tt.network, tt.address, got, tt.want)
+		}
+	}
+	if s := (&VsockAddr{CID: 2, Port: 1024}).String(); s != "2:1024" {
+		t.Errorf("String() = %q; want %q", s, "2:1024")
+	}
+}
+
+// listenLocalVsock listens on the local vsock context, skipping the
+// test if the kernel does not provide vsock loopback.
+func listenLocalVsock(t *testing.T, network string) Listener {
+	ln, err := Listen(network, "1:4294967295") // VsockCIDLocal, VsockPortAny
+	if err != nil {
+		t.Skipf("vsock loopback not available: %v", err)
+	}
+	return ln
+}
+
+func TestVsockDialListen(t *testing.T) {
+	for _, network := range []string{"vsock", "vsockpacket"} {
+		ln := listenLocalVsock(t, network)
+		la := ln.Addr().(*VsockAddr)
+		if la.CID != VsockCIDLocal || la.Port == VsockPortAny || la.Net != network {
+			t.Fatalf("listener address %+v", la)
+		}
+		ch := make(chan error, 1)
+		go func() {
+			c, err := ln.Accept()
+			if err != nil {
+				ch <- err
+				return
+			}
+			defer c.Close()
+			if _, ok := c.(*VsockConn); !ok {
+				t.Errorf("accepted %T; want *VsockConn", c)
+			}
+			_, err = io.Copy(c, c)
+			ch <- err
+		}()
+
+		c, err := Dial(network, la.String())
+		if err != nil {
+			ln.Close()
+			t.Fatal(err)
+		}
+		if ra := c.RemoteAddr().(*VsockAddr); *ra != *la {
+			t.Errorf("remote address %+v; want %+v", ra, la)
+		}
+		if _, err := c.Write([]byte("hello")); err != nil {
+			t.Fatal(err)
+		}
+		var b [5]byte
+		if _, err := io.ReadFull(c, b[:]); err != nil || string(b[:]) != "hello" {
+			t.Errorf("got %q, %v; want %q, nil", b[:], err, "hello")
+		}
+		c.Close()
+		if err := <-ch; err != nil {
+			t.Error(err)
+		}
+		ln.Close()
+	}
+}
+
+func TestListenVsockAny(t *testing.T) {
+	ln, err := ListenVsock("vsock", nil)
+	if err != nil {
+		t.Skipf("vsock not available: %v", err)
+	}
+	defer ln.Close()
+	la := ln.Addr().(*VsockAddr)
+	if la.CID != VsockCIDAny || la.Port == VsockPortAny || la.Net != "vsock" {
+		t.Errorf("listener address %+v; want CID %d and a chosen port", la, uint32(VsockCIDAny))
+	}
+}
+
+func TestDialVsockErrors(t *testing.T) {
+	if _, err := DialVsock("tcp", nil, &VsockAddr{CID: 1, Port: 1}); err == nil {
+		t.Error("DialVsock with network tcp succeeded")
+	}
+	if _, err := DialVsock("vsock", nil, nil); err == nil {
+		t.Error("DialVsock with nil address succeeded")
+	}
+}
@@ -0,0 +1,22 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import (
+	"context"
+	"syscall"
+)
+
+func dialVsock(ctx context.Context, net string, laddr, raddr *VsockAddr, ctrlFn func(string, string, syscall.RawConn) error) (*VsockConn, error) {
+	return nil, syscall.EPLAN9
+}
+
+func (ln *VsockListener) accept() (*VsockConn, error) {
+	return nil, syscall.EPLAN9
+}
+
+func listenVsock(ctx context.Context, net string, laddr *VsockAddr, ctrlFn func(string, string, syscall.RawConn) error) (*VsockListener, error) {
+	return nil, syscall.EPLAN9
+}
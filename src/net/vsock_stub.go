@@ -0,0 +1,28 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin dragonfly freebsd nacl netbsd openbsd solaris windows
+
+package net
+
+import (
+	"context"
+	"syscall"
+)
+
+func sockaddrToVsock(sa syscall.Sockaddr) Addr { return nil }
+
+func sockaddrToVsockpacket(sa syscall.Sockaddr) Addr { return nil }
+
+func dialVsock(ctx context.Context, net string, laddr, raddr *VsockAddr, ctrlFn func(string, string, syscall.RawConn) error) (*VsockConn, error) {
+	return nil, syscall.EAFNOSUPPORT
+}
+
+func (ln *VsockListener) accept() (*VsockConn, error) {
+	return nil, syscall.EAFNOSUPPORT
+}
+
+func listenVsock(ctx context.Context, net string, laddr *VsockAddr, ctrlFn func(string, string, syscall.RawConn) error) (*VsockListener, error) {
+	return nil, syscall.EAFNOSUPPORT
+}
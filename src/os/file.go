@@ -175,6 +175,35 @@ func (f *File) WriteAt(b []byte, off int64) (n int, err error) {
 	return
 }
 
+// ReadFrom implements the io.ReaderFrom interface.
+//
+// On Linux, if r is a *File, ReadFrom copies the data within the
+// kernel with the copy_file_range system call, and if f is a pipe and
+// r is a stream-oriented network connection such as a *net.TCPConn,
+// ReadFrom moves the data with the splice system call. Otherwise, or
+// if the kernel can't copy between the files, it reads from r and
+// writes to f as io.Copy does.
+func (f *File) ReadFrom(r io.Reader) (n int64, err error) {
+	if f == nil {
+		return 0, ErrInvalid
+	}
+	n, handled, err := f.readFrom(r)
+	if !handled {
+		return genericReadFrom(f, r)
+	}
+	return n, err
+}
+
+func genericReadFrom(f *File, r io.Reader) (int64, error) {
+	return io.Copy(onlyWriter{f}, r)
+}
+
+// onlyWriter hides the ReadFrom method of a File from io.Copy, which
+// would otherwise call it again.
+type onlyWriter struct {
+	io.Writer
+}
+
 // Seek sets the offset for the next Read or Write on file to offset, interpreted
 // according to whence: 0 means relative to the origin of the file, 1 means
 // relative to the current offset, and 2 means relative to the end.
@@ -0,0 +1,139 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package os
+
+import (
+	"internal/syscall/unix"
+	"io"
+	"syscall"
+)
+
+// maxCopyFileRange is the largest chunk size we ask the kernel to
+// copy at a time.
+const maxCopyFileRange = 1 << 30
+
+// maxSplice is the largest chunk size we ask the kernel to move at a
+// time. The kernel moves no more than the capacity of the pipe per
+// call anyway.
+const maxSplice = 1 << 20
+
+func (f *File) readFrom(r io.Reader) (n int64, handled bool, err error) {
+	var remain int64 = 1 << 62 // by default, copy until EOF
+	lr, ok := r.(*io.LimitedReader)
+	if ok {
+		remain, r = lr.N, lr.R
+		if remain <= 0 {
+			return 0, true, nil
+		}
+	}
+	switch src := r.(type) {
+	case *File:
+		n, handled, err = f.copyFileRange(src, remain)
+	case syscall.Conn:
+		n, handled, err = f.spliceFrom(src, remain)
+	}
+	if lr != nil {
+		lr.N -= n
+	}
+	return n, handled, err
+}
+
+// copyFileRange copies up to remain bytes from src to f with the
+// copy_file_range system call.
+func (f *File) copyFileRange(src *File, remain int64) (written int64, handled bool, err error) {
+	if src == nil || src.file == nil {
+		return 0, false, nil
+	}
+	for remain > 0 {
+		max := maxCopyFileRange
+		if int64(max) > remain {
+			max = int(remain)
+		}
+		n, err := unix.CopyFileRange(src.fd, f.fd, max)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			if written == 0 {
+				// The kernel can't copy between these files:
+				// ENOSYS and EXDEV on older kernels, EINVAL
+				// and EOPNOTSUPP for some file types, EBADF
+				// if f is in append mode, and so on. Leave
+				// the copy, and the reporting of any real
+				// error, to the generic path.
+				return 0, false, nil
+			}
+			epipecheck(f, err)
+			return written, true, &PathError{"write", f.name, NewSyscallError("copy_file_range", err)}
+		}
+		if n == 0 {
+			// Either EOF, or a file, such as those in /proc,
+			// whose size the kernel reports as zero. Let the
+			// generic path tell them apart if nothing was
+			// copied yet.
+			return written, written > 0, nil
+		}
+		written += int64(n)
+		remain -= int64(n)
+	}
+	return written, true, nil
+}
+
+// spliceFrom moves up to remain bytes from the network connection c
+// to f with the splice system call, if f is a pipe in blocking mode.
+func (f *File) spliceFrom(c syscall.Conn, remain int64) (written int64, handled bool, err error) {
+	if !isBlockingPipe(f.fd) {
+		// A full pipe in non-blocking mode makes splice fail
+		// with EAGAIN, which would be taken for a socket with
+		// no data.
+		return 0, false, nil
+	}
+	rc, err := c.SyscallConn()
+	if err != nil {
+		return 0, false, nil
+	}
+	for remain > 0 {
+		max := maxSplice
+		if int64(max) > remain {
+			max = int(remain)
+		}
+		// The socket is in non-blocking mode, so an EAGAIN means
+		// that it has no data, and rc.Read waits for some. The
+		// pipe may block, as a write to it would.
+		var n int
+		var serr error
+		if err := rc.Read(func(fd uintptr) bool {
+			n, serr = unix.Splice(int(fd), f.fd, max, 0)
+			return serr != syscall.EAGAIN && serr != syscall.EINTR
+		}); err != nil {
+			return written, true, err
+		}
+		if serr != nil {
+			if written == 0 && serr == syscall.EINVAL {
+				// Not a socket the kernel can splice from.
+				return 0, false, nil
+			}
+			epipecheck(f, serr)
+			return written, true, &PathError{"write", f.name, NewSyscallError("splice", serr)}
+		}
+		if n == 0 {
+			break // EOF
+		}
+		written += int64(n)
+		remain -= int64(n)
+	}
+	return written, true, nil
+}
+
+// isBlockingPipe reports whether fd is a pipe in blocking mode, such
+// as one created by Pipe.
+func isBlockingPipe(fd int) bool {
+	var st syscall.Stat_t
+	if err := syscall.Fstat(fd, &st); err != nil || st.Mode&syscall.S_IFMT != syscall.S_IFIFO {
+		return false
+	}
+	flags, _, errno := syscall.Syscall(syscall.SYS_FCNTL, uintptr(fd), syscall.F_GETFL, 0)
+	return errno == 0 && flags&syscall.O_NONBLOCK == 0
+}
@@ -0,0 +1,139 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package os_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	. "os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+var readFromData = bytes.Repeat([]byte("0123456789abcdef"), 64<<10) // 1 MB
+
+func newReadFromFiles(t *testing.T, dir string, flag int) (dst, src *File) {
+	src, err := Create(filepath.Join(dir, "src"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := src.Write(readFromData); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	dst, err = OpenFile(filepath.Join(dir, "dst"), O_RDWR|O_CREATE|O_TRUNC|flag, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dst, src
+}
+
+func TestReadFromFile(t *testing.T) {
+	tests := []struct {
+		name  string
+		flag  int
+		limit int64
+	}{
+		{"whole", 0, -1},
+		{"limited", 0, 100001},
+		{"append", O_APPEND, -1},
+	}
+	for _, tt := range tests {
+		dir, err := ioutil.TempDir("", "readfrom")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer RemoveAll(dir)
+		dst, src := newReadFromFiles(t, dir, tt.flag)
+		defer dst.Close()
+		defer src.Close()
+
+		want := readFromData
+		var r io.Reader = src
+		if tt.limit >= 0 {
+			want = want[:tt.limit]
+			r = &io.LimitedReader{R: src, N: tt.limit}
+		}
+		n, err := dst.ReadFrom(r)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if n != int64(len(want)) {
+			t.Errorf("%s: ReadFrom copied %d bytes; want %d", tt.name, n, len(want))
+		}
+		if lr, ok := r.(*io.LimitedReader); ok && lr.N != 0 {
+			t.Errorf("%s: LimitedReader.N = %d; want 0", tt.name, lr.N)
+		}
+
+		// Both file offsets must have advanced past the copied data.
+		if off, _ := src.Seek(0, io.SeekCurrent); off != int64(len(want)) {
+			t.Errorf("%s: source offset = %d; want %d", tt.name, off, len(want))
+		}
+		if off, _ := dst.Seek(0, io.SeekCurrent); off != int64(len(want)) {
+			t.Errorf("%s: destination offset = %d; want %d", tt.name, off, len(want))
+		}
+		got, err := ioutil.ReadFile(dst.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: got %d bytes; want %d bytes", tt.name, len(got), len(want))
+		}
+	}
+}
+
+func TestReadFromProcFile(t *testing.T) {
+	// Files in /proc report a size of zero, so copy_file_range copies
+	// nothing from them; ReadFrom must still copy their contents.
+	src, err := Open("/proc/self/stat")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer src.Close()
+	dst, err := ioutil.TempFile("", "readfrom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(dst.Name())
+	defer dst.Close()
+	n, err := dst.ReadFrom(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n == 0 {
+		t.Error("ReadFrom copied nothing from /proc/self/stat")
+	}
+}
+
+func TestSpliceFromNonblockingPipe(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	pr, pw, err := Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.Close()
+	defer pw.Close()
+	if err := syscall.SetNonblock(int(pw.Fd()), true); err != nil {
+		t.Fatal(err)
+	}
+	if _, handled, _ := SpliceFrom(pw, c.(syscall.Conn), 10); handled {
+		t.Error("spliceFrom handled a copy to a pipe in non-blocking mode")
+	}
+}
@@ -46,10 +46,10 @@ const pollBlockSize = 4 * 1024
 type pollDesc struct {
 	link *pollDesc // in pollcache, protected by pollcache.lock
 
-	// The lock protects pollOpen, pollSetDeadline, pollUnblock and deadlineimpl operations.
-	// This fully covers seq, rt and wt variables. fd is constant throughout the PollDesc lifetime.
+	// The lock protects pollOpen, pollSetDeadline, pollUnblock, pollInterrupt and deadlineimpl operations.
+	// This fully covers seq, rt, wt, iseq, ra and wa variables. fd is constant throughout the PollDesc lifetime.
 	// pollReset, pollWait, pollWaitCanceled and runtime·netpollready (IO readiness notification)
-	// proceed w/o taking the lock. So closing, rg, rd, wg and wd are manipulated
+	// proceed w/o taking the lock. So closing, rg, rd, ri, wg, wd and wi are manipulated
 	// in a lock-free way by all operations.
 	// NOTE(dvyukov): the following code uses uintptr to store *g (rg/wg),
 	// that will blow up when GC starts moving objects.
@@ -63,6 +63,11 @@ type pollDesc struct {
 	wg      uintptr // pdReady, pdWait, G waiting for write or nil
 	wt      timer   // write deadline timer
 	wd      int64   // write deadline
+	iseq    uint64  // last interruptible operation token handed out, kept across reuse
+	ra      uint64  // token of the armed interruptible read, or 0
+	ri      bool    // armed read interrupted
+	wa      uint64  // token of the armed interruptible write, or 0
+	wi      bool    // armed write interrupted
 	user    uint32  // user settable cookie
 }
 
@@ -108,6 +113,10 @@ func net_runtime_pollOpen(fd uintptr) (*pollDesc, int) {
 	pd.rd = 0
 	pd.wg = 0
 	pd.wd = 0
+	pd.ra = 0
+	pd.ri = false
+	pd.wa = 0
+	pd.wi = false
 	unlock(&pd.lock)
 
 	var errno int32
@@ -280,6 +289,80 @@ func net_runtime_pollUnblock(pd *pollDesc) {
 	}
 }
 
+// net_runtime_pollArmInterrupt starts an interruptible IO operation
+// in mode 'r' or 'w' and returns its token. The caller must make sure
+// that no other IO in that mode runs until it calls
+// net_runtime_pollDisarmInterrupt with the token, so that an interrupt
+// only ever reaches the operation it was meant for.
+//go:linkname net_runtime_pollArmInterrupt net.runtime_pollArmInterrupt
+func net_runtime_pollArmInterrupt(pd *pollDesc, mode int) uint64 {
+	lock(&pd.lock)
+	// Tokens are never reused, not even after pd is, so that an
+	// interrupt that arrives late is ignored.
+	pd.iseq++
+	t := pd.iseq
+	if mode == 'r' {
+		pd.ra = t
+		pd.ri = false
+	} else {
+		pd.wa = t
+		pd.wi = false
+	}
+	unlock(&pd.lock)
+	return t
+}
+
+// net_runtime_pollInterrupt makes the armed operation with token t
+// fail with errInterrupted. Unlike a deadline in the past, it leaves
+// the deadlines alone. It does nothing if that operation has already
+// been disarmed.
+//go:linkname net_runtime_pollInterrupt net.runtime_pollInterrupt
+func net_runtime_pollInterrupt(pd *pollDesc, mode int, t uint64) {
+	lock(&pd.lock)
+	if pd.closing {
+		unlock(&pd.lock)
+		return
+	}
+	var rg, wg *g
+	if mode == 'r' && pd.ra == t {
+		pd.ri = true
+		atomicstorep(unsafe.Pointer(&rg), nil) // full memory barrier between store to ri and load of rg in netpollunblock
+		rg = netpollunblock(pd, 'r', false)
+	}
+	if mode == 'w' && pd.wa == t {
+		pd.wi = true
+		atomicstorep(unsafe.Pointer(&wg), nil) // full memory barrier between store to wi and load of wg in netpollunblock
+		wg = netpollunblock(pd, 'w', false)
+	}
+	unlock(&pd.lock)
+	if rg != nil {
+		goready(rg, 3)
+	}
+	if wg != nil {
+		goready(wg, 3)
+	}
+}
+
+// net_runtime_pollDisarmInterrupt ends the interruptible operation
+// with token t and reports whether it was interrupted.
+//go:linkname net_runtime_pollDisarmInterrupt net.runtime_pollDisarmInterrupt
+func net_runtime_pollDisarmInterrupt(pd *pollDesc, mode int, t uint64) bool {
+	interrupted := false
+	lock(&pd.lock)
+	if mode == 'r' && pd.ra == t {
+		interrupted = pd.ri
+		pd.ra = 0
+		pd.ri = false
+	}
+	if mode == 'w' && pd.wa == t {
+		interrupted = pd.wi
+		pd.wa = 0
+		pd.wi = false
+	}
+	unlock(&pd.lock)
+	return interrupted
+}
+
 // make pd ready, newly runnable goroutines (if any) are returned in rg/wg
 // May run during STW, so write barriers are not allowed.
 //go:nowritebarrier
@@ -308,6 +391,9 @@ func netpollcheckerr(pd *pollDesc, mode int32) int {
 	if (mode == 'r' && pd.rd < 0) || (mode == 'w' && pd.wd < 0) {
 		return 2 // errTimeout
 	}
+	if (mode == 'r' && pd.ri) || (mode == 'w' && pd.wi) {
+		return 3 // errInterrupted
+	}
 	return 0
 }
 
@@ -339,8 +425,8 @@ func netpollblock(pd *pollDesc, mode int32, waitio bool) bool {
 	}
 
 	// need to recheck error states after setting gpp to WAIT
-	// this is necessary because runtime_pollUnblock/runtime_pollSetDeadline/deadlineimpl
-	// do the opposite: store to closing/rd/wd, membarrier, load of rg/wg
+	// this is necessary because runtime_pollUnblock/runtime_pollSetDeadline/runtime_pollInterrupt/deadlineimpl
+	// do the opposite: store to closing/rd/wd/ri/wi, membarrier, load of rg/wg
 	if waitio || netpollcheckerr(pd, mode) == 0 {
 		gopark(netpollblockcommit, unsafe.Pointer(gpp), "IO wait", traceEvGoBlockNet, 5)
 	}
@@ -359,8 +359,41 @@ func (sa *SockaddrNetlink) sockaddr() (unsafe.Pointer, _Socklen, error) {
 	return unsafe.Pointer(&sa.raw), SizeofSockaddrNetlink, nil
 }
 
+// afVSOCK is AF_VSOCK, which the generated constants lack on some
+// architectures.
+const afVSOCK = 0x28
+
+// rawSockaddrVM is the Linux sockaddr_vm structure.
+type rawSockaddrVM struct {
+	Family    uint16
+	Reserved1 uint16
+	Port      uint32
+	Cid       uint32
+	Zero      [4]uint8
+}
+
+// SockaddrVM is a socket address of the AF_VSOCK family, used for
+// communication between virtual machines and their host.
+type SockaddrVM struct {
+	// CID is the context ID of the virtual machine or host;
+	// Port is the port within it.
+	CID  uint32
+	Port uint32
+	raw  rawSockaddrVM
+}
+
+func (sa *SockaddrVM) sockaddr() (unsafe.Pointer, _Socklen, error) {
+	sa.raw = rawSockaddrVM{Family: afVSOCK, Port: sa.Port, Cid: sa.CID}
+	return unsafe.Pointer(&sa.raw), _Socklen(unsafe.Sizeof(sa.raw)), nil
+}
+
 func anyToSockaddr(rsa *RawSockaddrAny) (Sockaddr, error) {
 	switch rsa.Addr.Family {
+	case afVSOCK:
+		pp := (*rawSockaddrVM)(unsafe.Pointer(rsa))
+		sa := &SockaddrVM{CID: pp.Cid, Port: pp.Port}
+		return sa, nil
+
 	case AF_NETLINK:
 		pp := (*RawSockaddrNetlink)(unsafe.Pointer(rsa))
 		sa := new(SockaddrNetlink)